@@ -30,6 +30,7 @@ import (
 	"github.com/mindoc-org/mindoc/cache"
 	"github.com/mindoc-org/mindoc/conf"
 	"github.com/mindoc-org/mindoc/models"
+	"github.com/mindoc-org/mindoc/utils/diagram"
 	"github.com/mindoc-org/mindoc/utils/filetil"
 )
 
@@ -140,6 +141,29 @@ func RegisterModel() {
 		new(models.Comment),
 		new(models.WorkWeixinAccount),
 		new(models.DingTalkAccount),
+		new(models.MemberTwoFactor),
+		new(models.LoginLockout),
+		new(models.LoginLog),
+		new(models.MemberSession),
+		new(models.RecycleBin),
+		new(models.RecycleBinDocument),
+		new(models.Redirect),
+		new(models.BrokenLink),
+		new(models.LinkCheck),
+		new(models.DocumentReference),
+		new(models.CustomField),
+		new(models.DocumentField),
+		new(models.StaleReminder),
+		new(models.Notification),
+		new(models.Subscription),
+		new(models.DocumentChange),
+		new(models.BookRobot),
+		new(models.MailTemplate),
+		new(models.MailQueue),
+		new(models.DocumentView),
+		new(models.DocumentViewDaily),
+		new(models.BookSearchTerm),
+		new(models.DocumentReadStatus),
 	)
 	gob.Register(models.Blog{})
 	gob.Register(models.Document{})
@@ -150,6 +174,9 @@ func RegisterModel() {
 		logs.Error("注册Model失败 ->", err)
 		os.Exit(1)
 	}
+	if err := models.InitDocumentContentTime(); err != nil {
+		logs.Error("初始化文档内容修改时间失败 ->", err)
+	}
 }
 
 // RegisterLogger 注册日志
@@ -427,6 +454,7 @@ func ResolveCommand(args []string) {
 	RegisterCache()
 	RegisterModel()
 	RegisterLogger(conf.LogFile)
+	RegisterDiagramRenderer()
 
 	ModifyPassword()
 
@@ -531,6 +559,39 @@ func RegisterCache() {
 	logs.Info("缓存初始化完成.")
 }
 
+// RegisterDiagramRenderer 注册服务端图表渲染命令.
+func RegisterDiagramRenderer() {
+	commands := conf.GetDiagramCommands()
+	format := conf.GetDiagramFormat()
+
+	for lang, name := range conf.DiagramLanguages {
+		command, ok := commands[name]
+		if !ok {
+			diagram.Register(lang, nil)
+			continue
+		}
+		renderer, err := diagram.NewCommandRenderer(command, format)
+		if err != nil {
+			logs.Error("注册图表渲染命令失败 ->", name, err)
+			continue
+		}
+		renderer.Timeout = conf.GetDiagramTimeout()
+		if conf.GetDiagramSandbox() {
+			switch name {
+			case "plantuml":
+				renderer.Env = []string{"PLANTUML_SECURITY_PROFILE=SANDBOX"}
+				renderer.Check = diagram.CheckPlantUML
+			case "graphviz":
+				renderer.Check = diagram.CheckGraphviz
+			}
+		}
+		diagram.Register(lang, renderer)
+	}
+	if len(commands) > 0 {
+		logs.Info("图表渲染命令注册完成 ->", len(commands))
+	}
+}
+
 // 自动加载配置文件.修改了监听端口号和数据库配置无法自动生效.
 func RegisterAutoLoadConfig() {
 	if conf.AutoLoadDelay > 0 {
@@ -552,6 +613,7 @@ func RegisterAutoLoadConfig() {
 						}
 						RegisterCache()
 						RegisterLogger("")
+						RegisterDiagramRenderer()
 						logs.Info("配置文件已加载 ->", conf.ConfigurationFile)
 					} else if ev.IsRename() {
 						_ = watcher.WatchFlags(conf.ConfigurationFile, fsnotify.FSN_MODIFY|fsnotify.FSN_RENAME)
@@ -572,6 +634,47 @@ func RegisterAutoLoadConfig() {
 	}
 }
 
+// 注册后台定时任务.
+func RegisterTask() {
+	models.StartMailQueue()
+	models.StartDocumentViewCounter()
+	models.StartDocumentViewWriter()
+	models.StartReadProgressWriter()
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		lastLinkCheck := time.Now()
+		lastStaleCheck := time.Now()
+
+		for {
+			models.PurgeExpiredRecycleBin()
+
+			hours, _ := strconv.Atoi(models.GetOptionValue("LINK_CHECK_INTERVAL_HOURS", "24"))
+			if hours > 0 && time.Since(lastLinkCheck) >= time.Duration(hours)*time.Hour {
+				lastLinkCheck = time.Now()
+				if err := models.CheckAllBookLinks(); err != nil {
+					logs.Error("全站链接检查失败 ->", err)
+				}
+			}
+			if time.Since(lastStaleCheck) >= 24*time.Hour {
+				lastStaleCheck = time.Now()
+				if err := models.RemindStaleDocuments(); err != nil {
+					logs.Error("过期文档检查失败 ->", err)
+				}
+			}
+			models.AggregateAllDocumentViews()
+			<-ticker.C
+
+			// 启动时模板尚未编译，订阅摘要从第一个周期结束后开始发送
+			if err := models.SendSubscriptionDigests(); err != nil {
+				logs.Error("发送订阅摘要失败 ->", err)
+			}
+			models.CleanDocumentChanges()
+		}
+	}()
+}
+
 // 注册错误处理方法.
 func RegisterError() {
 	web.ErrorHandler("404", func(writer http.ResponseWriter, request *http.Request) {
@@ -616,3 +719,16 @@ func init() {
 		conf.WorkingDirectory = filepath.Dir(p)
 	}
 }
+
+// Shutdown 程序退出前保存尚未写入数据库的数据.
+func Shutdown() {
+	if err := models.StopDocumentViewCounter(); err != nil {
+		logs.Error("保存文档阅读次数失败 ->", err)
+	}
+	if err := models.StopDocumentViewWriter(); err != nil {
+		logs.Error("保存文档阅读记录失败 ->", err)
+	}
+	if err := models.StopReadProgressWriter(); err != nil {
+		logs.Error("保存阅读进度失败 ->", err)
+	}
+}
@@ -52,6 +52,8 @@ func (d *Daemon) Run() {
 
 	commands.RegisterAutoLoadConfig()
 
+	commands.RegisterTask()
+
 	commands.RegisterError()
 
 	web.ErrorController(&controllers.ErrorController{})
@@ -68,6 +70,7 @@ func (d *Daemon) Run() {
 }
 
 func (d *Daemon) Stop(s service.Service) error {
+	commands.Shutdown()
 	if service.Interactive() {
 		os.Exit(0)
 	}
@@ -0,0 +1,56 @@
+package conf
+
+import (
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// DiagramTypes 支持服务端渲染的图表类型.
+var DiagramTypes = []string{"mermaid", "plantuml", "graphviz"}
+
+// DiagramLanguages 代码块语言对应的图表类型.
+var DiagramLanguages = map[string]string{
+	"mermaid":  "mermaid",
+	"plantuml": "plantuml",
+	"puml":     "plantuml",
+	"uml":      "plantuml",
+	"graphviz": "graphviz",
+	"dot":      "graphviz",
+}
+
+// GetDiagramCommands 获取图表类型对应的渲染命令，未配置的图表类型不会在服务端渲染.
+func GetDiagramCommands() map[string]string {
+	commands := make(map[string]string)
+
+	for _, name := range DiagramTypes {
+		if command := strings.TrimSpace(web.AppConfig.DefaultString("diagram_"+name, "")); command != "" {
+			commands[name] = command
+		}
+	}
+	return commands
+}
+
+// GetDiagramFormat 服务端渲染图表的图片格式.
+func GetDiagramFormat() string {
+	format := strings.ToLower(web.AppConfig.DefaultString("diagram_format", "svg"))
+	if format != "svg" && format != "png" {
+		format = "svg"
+	}
+	return format
+}
+
+// GetDiagramTimeout 单个图表的渲染超时时间.
+func GetDiagramTimeout() time.Duration {
+	timeout := web.AppConfig.DefaultInt("diagram_timeout", 30)
+	if timeout <= 0 {
+		timeout = 30
+	}
+	return time.Duration(timeout) * time.Second
+}
+
+// GetDiagramSandbox 是否限制图表源码读取本地文件和网络资源，默认开启.
+func GetDiagramSandbox() bool {
+	return web.AppConfig.DefaultBool("diagram_sandbox", true)
+}
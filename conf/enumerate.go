@@ -17,6 +17,24 @@ const LoginSessionName = "LoginSessionName"
 
 const CaptchaSessionName = "__captcha__"
 
+// 密码校验通过、等待两步验证的用户Session名
+const TwoFactorSessionName = "__two_factor__"
+
+// 两步验证时是否记住登录状态
+const TwoFactorRememberSessionName = "__two_factor_remember__"
+
+// 两步验证通过后记录的登录方式
+const TwoFactorAuthMethodSessionName = "__two_factor_auth_method__"
+
+// 两步验证失败次数
+const TwoFactorAttemptsSessionName = "__two_factor_attempts__"
+
+// 启用两步验证时待确认的密钥
+const TwoFactorSecretSessionName = "__two_factor_secret__"
+
+// 当前登录会话的令牌
+const SessionTokenName = "__session_token__"
+
 const RegexpEmail = "^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$"
 
 // 允许用户名中出现点号
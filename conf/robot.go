@@ -0,0 +1,24 @@
+package conf
+
+import (
+	"strings"
+
+	"github.com/beego/beego/v2/server/web"
+)
+
+// RobotWebhookHosts 群机器人平台的 Webhook 域名，只允许通过 https 访问.
+var RobotWebhookHosts = map[string][]string{
+	"dingtalk": {"oapi.dingtalk.com"},
+	"wecom":    {"qyapi.weixin.qq.com"},
+}
+
+// GetRobotWebhookHosts 额外允许的群机器人 Webhook 主机，例如本地测试服务或代理，多个使用逗号分隔.
+func GetRobotWebhookHosts() []string {
+	var hosts []string
+	for _, host := range strings.Split(web.AppConfig.DefaultString("robot_webhook_hosts", ""), ",") {
+		if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,33 @@
+package conf
+
+import (
+	"github.com/beego/beego/v2/server/web"
+)
+
+type ScimConf struct {
+	Enable   bool       // 是否启用SCIM接口
+	Token    string     // Bearer 令牌
+	UserRole SystemRole // 自动创建用户的系统角色
+	TeamRole BookRole   // 加入团队时的项目角色
+}
+
+func GetScimConfig() *ScimConf {
+	token, _ := web.AppConfig.String("scim_token")
+
+	userRole := SystemRole(web.AppConfig.DefaultInt("scim_user_role", int(MemberGeneralRole)))
+	if userRole < MemberAdminRole || userRole > MemberReaderRole {
+		userRole = MemberGeneralRole
+	}
+	teamRole := BookRole(web.AppConfig.DefaultInt("scim_team_role", int(BookEditor)))
+	if teamRole < BookAdmin || teamRole > BookObserver {
+		teamRole = BookEditor
+	}
+
+	c := &ScimConf{
+		Enable:   web.AppConfig.DefaultBool("scim_enable", false),
+		Token:    token,
+		UserRole: userRole,
+		TeamRole: teamRole,
+	}
+	return c
+}
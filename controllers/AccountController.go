@@ -22,7 +22,6 @@ import (
 	"github.com/beego/i18n"
 	"github.com/lifei6671/gocaptcha"
 	"github.com/mindoc-org/mindoc/conf"
-	"github.com/mindoc-org/mindoc/mail"
 	"github.com/mindoc-org/mindoc/models"
 	"github.com/mindoc-org/mindoc/utils"
 )
@@ -105,16 +104,10 @@ func (c *AccountController) Login() {
 		}
 		c.Redirect(u, 302)
 	}
-	var remember CookieRemember
 	// 如果 Cookie 中存在登录信息
-	if cookie, ok := c.GetSecureCookie(conf.GetAppKey(), "login"); ok {
-		if err := utils.Decode(cookie, &remember); err == nil {
-			if member, err := models.NewMember().Find(remember.MemberId); err == nil {
-				c.SetMember(*member)
-				c.LoggedIn(false)
-				c.StopRun()
-			}
-		}
+	if _, ok := c.rememberedMember(); ok {
+		c.LoggedIn(false)
+		c.StopRun()
 	}
 
 	if c.Ctx.Input.IsPost() {
@@ -134,27 +127,22 @@ func (c *AccountController) Login() {
 		if account == "" || password == "" {
 			c.JsonResult(6002, i18n.Tr(c.Lang, "message.account_or_password_empty"))
 		}
+		if until, locked := models.IsLoginLocked(account, c.Ctx.Input.IP()); locked {
+			c.addLoginLog(nil, account, "", models.LoginFailedLocked)
+			c.JsonResult(6003, i18n.Tr(c.Lang, "message.login_locked", until.Format("2006-01-02 15:04:05")))
+		}
 
 		member, err := models.NewMember().Login(account, password)
 		if err == nil {
-			member.LastLoginTime = time.Now()
-			_ = member.Update("last_login_time")
-
-			c.SetMember(*member)
-
-			if strings.EqualFold(isRemember, "yes") {
-				remember.MemberId = member.MemberId
-				remember.Account = member.Account
-				remember.Time = time.Now()
-				v, err := utils.Encode(remember)
-				if err == nil {
-					c.SetSecureCookie(conf.GetAppKey(), "login", v, time.Now().Add(time.Hour*24*30).Unix())
-				}
+			//已启用两步验证的用户需要再输入验证码
+			if u, ok := c.startTwoFactor(member, strings.EqualFold(isRemember, "yes"), ""); ok {
+				c.JsonResult(0, "ok", u)
 			}
-
-			c.JsonResult(0, "ok", c.referer())
+			c.JsonResult(0, "ok", c.loginSucceeded(member, strings.EqualFold(isRemember, "yes"), ""))
 		} else {
 			logs.Error("用户登录 ->", err)
+			models.RecordLoginFailure(account, c.Ctx.Input.IP())
+			c.addLoginLog(member, account, "", models.LoginFailedPassword)
 			c.JsonResult(500, i18n.Tr(c.Lang, "message.wrong_account_password"), nil)
 		}
 		return
@@ -187,6 +175,137 @@ func (c *AccountController) Login() {
 	return
 }
 
+// TwoFactor 登录时的两步验证
+func (c *AccountController) TwoFactor() {
+	c.TplName = "account/two_factor.tpl"
+
+	memberId, ok := c.GetSession(conf.TwoFactorSessionName).(int)
+	if !ok || memberId <= 0 {
+		if c.Ctx.Input.IsPost() {
+			c.JsonResult(6001, i18n.Tr(c.Lang, "message.two_factor_session_expired"), conf.URLFor("AccountController.Login"))
+		}
+		c.Redirect(conf.URLFor("AccountController.Login"), 302)
+		c.StopRun()
+	}
+
+	if c.Ctx.Input.IsPost() {
+		code := strings.TrimSpace(c.GetString("code"))
+		if code == "" {
+			c.JsonResult(6002, i18n.Tr(c.Lang, "message.two_factor_code_empty"))
+		}
+		attempts, _ := c.GetSession(conf.TwoFactorAttemptsSessionName).(int)
+		if attempts >= 5 {
+			c.clearTwoFactorSession()
+			c.JsonResult(6003, i18n.Tr(c.Lang, "message.two_factor_too_many_attempts"), conf.URLFor("AccountController.Login"))
+		}
+
+		member, err := models.NewMember().Find(memberId)
+		if err != nil || member.Status != 0 {
+			c.clearTwoFactorSession()
+			c.JsonResult(6004, i18n.Tr(c.Lang, "message.user_not_existed"), conf.URLFor("AccountController.Login"))
+		}
+		if until, locked := models.IsLoginLocked(member.Account, c.Ctx.Input.IP()); locked {
+			c.clearTwoFactorSession()
+			c.addLoginLog(member, member.Account, "", models.LoginFailedLocked)
+			c.JsonResult(6006, i18n.Tr(c.Lang, "message.login_locked", until.Format("2006-01-02 15:04:05")), conf.URLFor("AccountController.Login"))
+		}
+		twoFactor, err := models.NewMemberTwoFactor().FindByMemberId(memberId)
+		if err != nil || !twoFactor.Verify(code) {
+			c.SetSession(conf.TwoFactorAttemptsSessionName, attempts+1)
+			logs.Warn("两步验证失败 ->", member.Account)
+			models.RecordLoginFailure(member.Account, c.Ctx.Input.IP())
+			c.addLoginLog(member, member.Account, "", models.LoginFailedTwoFactor)
+			c.JsonResult(6005, i18n.Tr(c.Lang, "message.two_factor_code_invalid"))
+		}
+		isRemember, _ := c.GetSession(conf.TwoFactorRememberSessionName).(bool)
+		authMethod, _ := c.GetSession(conf.TwoFactorAuthMethodSessionName).(string)
+		c.clearTwoFactorSession()
+
+		c.JsonResult(0, "ok", c.loginSucceeded(member, isRemember, authMethod))
+	}
+	c.Data["url"] = c.referer()
+}
+
+// startTwoFactor 已启用两步验证的用户先记录待验证的登录，返回两步验证页面的地址.
+// 账号密码登录和第三方登录都需要经过这一步，authMethod 为登录方式.
+func (c *AccountController) startTwoFactor(member *models.Member, isRemember bool, authMethod string) (string, bool) {
+	if !models.NewMemberTwoFactor().IsEnabled(member.MemberId) {
+		return "", false
+	}
+	c.SetSession(conf.TwoFactorSessionName, member.MemberId)
+	c.SetSession(conf.TwoFactorRememberSessionName, isRemember)
+	c.SetSession(conf.TwoFactorAuthMethodSessionName, authMethod)
+	c.DelSession(conf.TwoFactorAttemptsSessionName)
+
+	return conf.URLFor("AccountController.TwoFactor", "url", url.PathEscape(c.referer())), true
+}
+
+func (c *AccountController) clearTwoFactorSession() {
+	c.DelSession(conf.TwoFactorSessionName)
+	c.DelSession(conf.TwoFactorRememberSessionName)
+	c.DelSession(conf.TwoFactorAuthMethodSessionName)
+	c.DelSession(conf.TwoFactorAttemptsSessionName)
+}
+
+// loginSucceeded 完成登录并返回登录后跳转的地址.
+func (c *AccountController) loginSucceeded(member *models.Member, isRemember bool, authMethod string) string {
+	member.LastLoginTime = time.Now()
+	_ = member.Update("last_login_time")
+
+	if err := c.SetMember(*member); err != nil {
+		c.JsonResult(500, i18n.Tr(c.Lang, "message.login_session_failed"))
+	}
+	models.ResetLoginFailure(member.Account)
+	c.addLoginLog(member, member.Account, authMethod, "")
+
+	if isRemember {
+		if err := c.rememberLogin(member, time.Hour*24*30); err != nil {
+			logs.Error("保存登录状态失败 ->", member.Account, err)
+		}
+	}
+	//系统要求管理员启用两步验证时，先跳转到设置页面
+	if models.IsTwoFactorRequired(member) && !models.NewMemberTwoFactor().IsEnabled(member.MemberId) {
+		return conf.URLFor("SettingController.TwoFactor")
+	}
+	if models.GetPasswordPolicy().IsExpired(member) {
+		return conf.URLFor("SettingController.Password")
+	}
+	return c.referer()
+}
+
+// addLoginLog 记录登录历史，reason 为空表示登录成功.
+func (c *AccountController) addLoginLog(member *models.Member, account string, authMethod string, reason string) {
+	loginLog := models.NewLoginLog()
+	loginLog.Account = account
+	loginLog.AuthMethod = authMethod
+	if member != nil {
+		loginLog.MemberId = member.MemberId
+		if authMethod == "" {
+			loginLog.AuthMethod = member.AuthMethod
+		}
+	}
+	if reason != "" {
+		loginLog.Status = 1
+		loginLog.Reason = reason
+	}
+	loginLog.IPAddress = c.Ctx.Input.IP()
+	loginLog.UserAgent = c.Ctx.Input.UserAgent()
+
+	_ = loginLog.Insert()
+
+	action := "login"
+	if reason != "" {
+		action = "login_failed"
+	}
+	logger := c.newAuditLog(models.LoggerOperate, action, account)
+	logger.MemberId = loginLog.MemberId
+	logger.Account = account
+	logger.SetData(nil, map[string]string{"auth_method": loginLog.AuthMethod, "reason": reason})
+	if err := logger.Add(); err != nil {
+		logs.Error("记录审计日志失败 ->", action, err)
+	}
+}
+
 /*
 Auth2.0 第三方对接思路:
 1. Auth2Redirect: 点击相应第三方接口，路由重定向至第三方提供的Auth2.0地址
@@ -346,16 +465,10 @@ func (c *AccountController) Auth2Callback() {
 		c.Redirect(u, 302)
 	}
 
-	var remember CookieRemember
 	// 如果 Cookie 中存在登录信息
-	if cookie, ok := c.GetSecureCookie(conf.GetAppKey(), "login"); ok {
-		if err := utils.Decode(cookie, &remember); err == nil {
-			if member, err := models.NewMember().Find(remember.MemberId); err == nil {
-				c.SetMember(*member)
-				c.LoggedIn(false)
-				c.StopRun()
-			}
-		}
+	if _, ok := c.rememberedMember(); ok {
+		c.LoggedIn(false)
+		c.StopRun()
 	}
 
 	c.TplName = "account/auth2_callback.tpl"
@@ -419,16 +532,19 @@ func (c *AccountController) Auth2Callback() {
 	bindExisted = "true"
 	errMsg = ""
 
+	if u, ok := c.startTwoFactor(member, true, "auth2"); ok {
+		c.Redirect(u, 302)
+		return
+	}
 	member.LastLoginTime = time.Now()
 	_ = member.Update("last_login_time")
 
-	c.SetMember(*member)
-	remember.MemberId = member.MemberId
-	remember.Account = member.Account
-	remember.Time = time.Now()
-	v, err := utils.Encode(remember)
-	if err == nil {
-		c.SetSecureCookie(conf.GetAppKey(), "login", v, time.Now().Add(time.Hour*24*30*5).Unix())
+	if err := c.SetMember(*member); err != nil {
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.login_session_failed"))
+	}
+	c.addLoginLog(member, member.Account, "auth2", "")
+	if err := c.rememberLogin(member, time.Hour*24*30*5); err != nil {
+		logs.Error("保存登录状态失败 ->", member.Account, err)
 	}
 	u := c.GetString("url")
 	if u == "" {
@@ -454,9 +570,16 @@ func (c *AccountController) Auth2BindAccount() {
 		return
 	}
 
+	if until, locked := models.IsLoginLocked(account, c.Ctx.Input.IP()); locked {
+		c.addLoginLog(nil, account, "", models.LoginFailedLocked)
+		c.JsonResult(500, i18n.Tr(c.Lang, "message.login_locked", until.Format("2006-01-02 15:04:05")), nil)
+		return
+	}
 	member, err := models.NewMember().Login(account, password)
 	if err != nil {
 		logs.Error("用户登录 ->", err)
+		models.RecordLoginFailure(account, c.Ctx.Input.IP())
+		c.addLoginLog(member, account, "", models.LoginFailedPassword)
 		c.JsonResult(500, "账号或密码错误", nil)
 		return
 	}
@@ -507,19 +630,20 @@ func (c *AccountController) Auth2BindAccount() {
 	}
 
 	c.DelSession(SessionUserInfoKey)
-	c.SetMember(*member)
+	if u, ok := c.startTwoFactor(member, true, "auth2"); ok {
+		c.JsonResult(0, "绑定成功", u)
+		return
+	}
+	if err := c.SetMember(*member); err != nil {
+		c.JsonResult(500, "绑定成功, 但自动登录失败, 请返回首页重新登录", nil)
+	}
+	models.ResetLoginFailure(member.Account)
+	c.addLoginLog(member, member.Account, "auth2", "")
 
-	var remember CookieRemember
-	remember.MemberId = member.MemberId
-	remember.Account = member.Account
-	remember.Time = time.Now()
-	v, err := utils.Encode(remember)
-	if err != nil {
+	if err := c.rememberLogin(member, time.Hour*24*30*5); err != nil {
 		c.JsonResult(500, "绑定成功, 但自动登录失败, 请返回首页重新登录", nil)
 		return
 	}
-
-	c.SetSecureCookie(conf.GetAppKey(), "login", v, time.Now().Add(time.Hour*24*30*5).Unix())
 	c.JsonResult(0, "绑定成功", nil)
 }
 
@@ -565,6 +689,7 @@ func (c *AccountController) Auth2AutoAccount() {
 	logs.Debug("member.Password: ", member.Password)
 	logs.Debug("hash: ", hash)
 	member.Password = hash
+	member.PasswordUpdateTime = time.Now()
 
 	member.Role = conf.MemberGeneralRole
 	member.Avatar = userInfo.Avatar
@@ -605,19 +730,14 @@ func (c *AccountController) Auth2AutoAccount() {
 	member.LastLoginTime = time.Now()
 	_ = member.Update("last_login_time")
 
-	c.SetMember(*member)
+	if err := c.SetMember(*member); err != nil {
+		c.JsonResult(500, "绑定成功, 但自动登录失败, 请返回首页重新登录", nil)
+	}
 
-	var remember CookieRemember
-	remember.MemberId = member.MemberId
-	remember.Account = member.Account
-	remember.Time = time.Now()
-	v, err := utils.Encode(remember)
-	if err != nil {
+	if err := c.rememberLogin(member, time.Hour*24*30*5); err != nil {
 		c.JsonResult(500, "绑定成功, 但自动登录失败, 请返回首页重新登录", nil)
 		return
 	}
-
-	c.SetSecureCookie(conf.GetAppKey(), "login", v, time.Now().Add(time.Hour*24*30*5).Unix())
 	c.JsonResult(0, "绑定成功", nil)
 }
 
@@ -1174,8 +1294,8 @@ func (c *AccountController) Register() {
 		if ok, err := regexp.MatchString(conf.RegexpAccount, account); account == "" || !ok || err != nil {
 			c.JsonResult(6001, i18n.Tr(c.Lang, "message.username_invalid_format"))
 		}
-		if l := strings.Count(password1, ""); password1 == "" || l > 50 || l < 6 {
-			c.JsonResult(6002, i18n.Tr(c.Lang, "message.password_length_invalid"))
+		if policy := models.GetPasswordPolicy(); policy.Check(password1) != nil {
+			c.JsonResult(6002, policy.Tips(c.Lang))
 		}
 		if password1 != password2 {
 			c.JsonResult(6003, i18n.Tr(c.Lang, "message.incorrect_confirm_password"))
@@ -1269,65 +1389,14 @@ func (c *AccountController) FindPassword() {
 		}
 
 		data := map[string]interface{}{
-			"SITE_NAME": c.Option["SITE_NAME"],
-			"url":       conf.URLFor("AccountController.FindPassword", "token", memberToken.Token, "mail", email),
-			"BaseUrl":   c.BaseUrl(),
+			"url":     conf.URLFor("AccountController.FindPassword", "token", memberToken.Token, "mail", email),
+			"BaseUrl": c.BaseUrl(),
 		}
-
-		body, err := c.ExecuteViewPathTemplate("account/mail_template.tpl", data)
-		if err != nil {
-			logs.Error(err)
+		if err := models.SendTemplateMail(email, models.MailFindPassword, c.Lang, data); err != nil {
+			logs.Error("发送找回密码邮件失败 ->", email, err)
 			c.JsonResult(6003, i18n.Tr(c.Lang, "message.failed_send_mail"))
 		}
 
-		go func(mailConf *conf.SmtpConf, email string, body string) {
-
-			mailConfig := &mail.SMTPConfig{
-				Username: mailConf.SmtpUserName,
-				Password: mailConf.SmtpPassword,
-				Host:     mailConf.SmtpHost,
-				Port:     mailConf.SmtpPort,
-				Secure:   mailConf.Secure,
-				Identity: "",
-			}
-			logs.Info(mailConfig)
-
-			c := mail.NewSMTPClient(mailConfig)
-			m := mail.NewMail()
-
-			m.AddFrom(mailConf.FormUserName)
-			m.AddFromName(mailConf.FormUserName)
-			m.AddSubject("找回密码")
-			m.AddHTML(body)
-			m.AddTo(email)
-
-			if e := c.Send(m); e != nil {
-				logs.Error("发送邮件失败：" + e.Error())
-			} else {
-				logs.Info("邮件发送成功：" + email)
-			}
-			//auth := smtp.PlainAuth(
-			//	"",
-			//	mail_conf.SmtpUserName,
-			//	mail_conf.SmtpPassword,
-			//	mail_conf.SmtpHost,
-			//)
-			//
-			//mime := "MIME-version: 1.0;\nContent-Type: text/html; charset=\"UTF-8\";\n\n"
-			//subject := "Subject: 找回密码!\n"
-			//
-			//err = smtp.SendMail(
-			//	mail_conf.SmtpHost+":"+strconv.Itoa(mail_conf.SmtpPort),
-			//	auth,
-			//	mail_conf.FormUserName,
-			//	[]string{email},
-			//	[]byte(subject+mime+"\n"+body),
-			//)
-			//if err != nil {
-			//	logs.Error("邮件发送失败 => ", email, err)
-			//}
-		}(mailConf, email, body)
-
 		c.JsonResult(0, "ok", conf.URLFor("AccountController.Login"))
 	}
 
@@ -1367,8 +1436,8 @@ func (c *AccountController) ValidEmail() {
 	if password1 == "" {
 		c.JsonResult(6001, i18n.Tr(c.Lang, "message.password_empty"))
 	}
-	if l := strings.Count(password1, ""); l < 6 || l > 50 {
-		c.JsonResult(6001, i18n.Tr(c.Lang, "message.password_length_invalid"))
+	if policy := models.GetPasswordPolicy(); policy.Check(password1) != nil {
+		c.JsonResult(6001, policy.Tips(c.Lang))
 	}
 	if password2 == "" {
 		c.JsonResult(6002, i18n.Tr(c.Lang, "message.confirm_password_empty"))
@@ -1408,8 +1477,9 @@ func (c *AccountController) ValidEmail() {
 	}
 
 	member.Password = hash
+	member.PasswordUpdateTime = time.Now()
 
-	err = member.Update("password")
+	err = member.Update("password", "password_update_time")
 	memberToken.ValidTime = time.Now()
 	memberToken.IsValid = true
 	memberToken.InsertOrUpdate()
@@ -1418,6 +1488,8 @@ func (c *AccountController) ValidEmail() {
 		logs.Error(err)
 		c.JsonResult(6006, i18n.Tr(c.Lang, "message.failed_save_password"))
 	}
+	//找回密码后注销该用户的全部登录会话
+	_ = models.NewMemberSession().DeleteByMemberId(member.MemberId, "")
 	c.JsonResult(0, "ok", conf.URLFor("AccountController.Login"))
 }
 
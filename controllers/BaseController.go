@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"io"
+	"net/http"
 	"strings"
 	"time"
 
@@ -32,6 +33,8 @@ type CookieRemember struct {
 	MemberId int
 	Account  string
 	Time     time.Time
+	//登录会话的令牌，会话被注销后 Cookie 随之失效
+	Token string
 }
 
 // Prepare 预处理.
@@ -47,20 +50,23 @@ func (c *BaseController) Prepare() {
 	c.EnableDocumentHistory = false
 
 	if member, ok := c.GetSession(conf.LoginSessionName).(models.Member); ok && member.MemberId > 0 {
-		c.Member = &member
-		c.Data["Member"] = c.Member
-	} else {
-		var remember CookieRemember
-		// //如果Cookie中存在登录信息，从cookie中获取用户信息
-		if cookie, ok := c.GetSecureCookie(conf.GetAppKey(), "login"); ok {
-			if err := utils.Decode(cookie, &remember); err == nil {
-				if member, err := models.NewMember().Find(remember.MemberId); err == nil {
-					c.Member = member
-					c.Data["Member"] = member
-					c.SetMember(*member)
-				}
+		if c.checkMemberSession(member.MemberId) {
+			c.Member = &member
+			c.Data["Member"] = c.Member
+		} else {
+			//会话已被注销，清除登录状态后重新请求，由登录过滤器决定是否跳转到登录页
+			c.SetMember(models.Member{})
+			c.SetSecureCookie(conf.GetAppKey(), "login", "", -3600)
+			if c.IsAjax() {
+				c.JsonResult(403, "请登录后再操作")
 			}
+			c.Redirect(c.Ctx.Request.URL.RequestURI(), 302)
+			c.StopRun()
 		}
+	} else if member, ok := c.rememberedMember(); ok {
+		//如果Cookie中存在登录信息，从cookie中获取用户信息
+		c.Member = member
+		c.Data["Member"] = member
 	}
 	conf.BaseUrl = c.BaseUrl()
 	c.Data["BaseUrl"] = c.BaseUrl()
@@ -81,6 +87,32 @@ func (c *BaseController) Prepare() {
 	}
 
 	c.SetLang()
+
+	if c.isUserLoggedIn() && !c.IsAjax() {
+		c.Data["UnreadNotifications"] = models.NewNotification().CountUnread(c.Member.MemberId)
+	}
+
+	//系统要求管理员启用两步验证时，未启用的管理员只能访问两步验证设置页面
+	if c.isUserLoggedIn() && c.Member.IsAdministrator() && strings.EqualFold(c.Option["REQUIRE_ADMIN_TWO_FACTOR"], "true") &&
+		controller != "AccountController" && !(controller == "SettingController" && strings.HasPrefix(action, "TwoFactor")) &&
+		!models.NewMemberTwoFactor().IsEnabled(c.Member.MemberId) {
+		if c.IsAjax() {
+			c.JsonResult(403, i18n.Tr(c.Lang, "message.two_factor_required"))
+		}
+		c.Redirect(conf.URLFor("SettingController.TwoFactor"), 302)
+		c.StopRun()
+	}
+	//密码过期的用户只能访问修改密码页面
+	if c.isUserLoggedIn() && controller != "AccountController" && !(controller == "SettingController" && action == "Password") {
+		policy := models.NewPasswordPolicy(c.Option["PASSWORD_MIN_LENGTH"], c.Option["PASSWORD_COMPLEXITY"], c.Option["PASSWORD_EXPIRE_DAYS"])
+		if policy.IsExpired(c.Member) {
+			if c.IsAjax() {
+				c.JsonResult(403, i18n.Tr(c.Lang, "message.password_expired"))
+			}
+			c.Redirect(conf.URLFor("SettingController.Password"), 302)
+			c.StopRun()
+		}
+	}
 }
 
 // 判断用户是否登录.
@@ -89,15 +121,138 @@ func (c *BaseController) isUserLoggedIn() bool {
 }
 
 // SetMember 获取或设置当前登录用户信息,如果 MemberId 小于 0 则标识删除 Session
-func (c *BaseController) SetMember(member models.Member) {
+// 登录时无法登记会话令牌则清除登录状态并返回错误.
+func (c *BaseController) SetMember(member models.Member) error {
 
 	if member.MemberId <= 0 {
+		if token, ok := c.GetSession(conf.SessionTokenName).(string); ok && token != "" {
+			_ = models.NewMemberSession().DeleteByToken(token)
+		}
 		c.DelSession(conf.LoginSessionName)
+		c.DelSession(conf.SessionTokenName)
 		c.DelSession("uid")
 		c.DestroySession()
-	} else {
-		c.SetSession(conf.LoginSessionName, member)
-		c.SetSession("uid", member.MemberId)
+		return nil
+	}
+	if err := c.registerMemberSession(member.MemberId); err != nil {
+		logs.Error("登记登录会话失败 ->", member.Account, err)
+		c.DelSession(conf.LoginSessionName)
+		c.DelSession("uid")
+		return err
+	}
+	c.SetSession(conf.LoginSessionName, member)
+	c.SetSession("uid", member.MemberId)
+	return nil
+}
+
+// currentSessionToken 获取当前登录会话的令牌.
+func (c *BaseController) currentSessionToken() string {
+	token, _ := c.GetSession(conf.SessionTokenName).(string)
+	return token
+}
+
+// registerMemberSession 当前 Session 中没有该用户有效的会话令牌时，登记一个新的会话.
+func (c *BaseController) registerMemberSession(memberId int) error {
+	if token := c.currentSessionToken(); token != "" {
+		if session, err := models.NewMemberSession().FindByToken(token); err == nil && session.MemberId == memberId {
+			return nil
+		}
+	}
+	session, err := models.NewMemberSession().Create(memberId, c.Ctx.Input.IP(), c.Ctx.Input.UserAgent())
+	if err != nil {
+		c.DelSession(conf.SessionTokenName)
+		return err
+	}
+	c.SetSession(conf.SessionTokenName, session.Token)
+	return nil
+}
+
+// checkMemberSession 校验当前会话是否仍然有效，并更新最后访问时间.
+// 没有令牌或令牌已被注销的会话都视为未登录，不会重新登记.
+func (c *BaseController) checkMemberSession(memberId int) bool {
+	token := c.currentSessionToken()
+	if token == "" {
+		return false
+	}
+	session, err := models.NewMemberSession().FindByToken(token)
+	if err != nil || session.MemberId != memberId {
+		return false
+	}
+	session.Touch(c.Ctx.Input.IP())
+	return true
+}
+
+// rememberedMember 从“记住登录”的 Cookie 中恢复登录状态.
+func (c *BaseController) rememberedMember() (*models.Member, bool) {
+	cookie, ok := c.GetSecureCookie(conf.GetAppKey(), "login")
+	if !ok || cookie == "" {
+		return nil, false
+	}
+	var remember CookieRemember
+	if err := utils.Decode(cookie, &remember); err != nil || remember.Token == "" {
+		c.SetSecureCookie(conf.GetAppKey(), "login", "", -3600)
+		return nil, false
+	}
+	session, err := models.NewMemberSession().FindByToken(remember.Token)
+	if err != nil || session.MemberId != remember.MemberId {
+		c.SetSecureCookie(conf.GetAppKey(), "login", "", -3600)
+		return nil, false
+	}
+	member, err := models.NewMember().Find(remember.MemberId)
+	if err != nil {
+		return nil, false
+	}
+	session.Touch(c.Ctx.Input.IP())
+	c.SetSession(conf.SessionTokenName, session.Token)
+	if err := c.SetMember(*member); err != nil {
+		return nil, false
+	}
+
+	return member, true
+}
+
+// rememberLogin 写入“记住登录”的 Cookie，并延长当前会话的有效期.
+func (c *BaseController) rememberLogin(member *models.Member, d time.Duration) error {
+	session, err := models.NewMemberSession().FindByToken(c.currentSessionToken())
+	if err != nil {
+		return err
+	}
+	expire := time.Now().Add(d)
+
+	remember := CookieRemember{
+		MemberId: member.MemberId,
+		Account:  member.Account,
+		Time:     time.Now(),
+		Token:    session.Token,
+	}
+	v, err := utils.Encode(remember)
+	if err != nil {
+		return err
+	}
+	c.SetSecureCookie(conf.GetAppKey(), "login", v, expire.Unix())
+
+	return session.SetRemember(expire)
+}
+
+// newAuditLog 创建一条以当前用户为操作人的审计日志.
+func (c *BaseController) newAuditLog(category, action, content string) *models.Logger {
+	logger := models.NewLogger()
+	logger.Category = category
+	logger.Action = action
+	logger.Content = content
+	logger.IPAddress = c.Ctx.Input.IP()
+	logger.UserAgent = c.Ctx.Input.UserAgent()
+	if c.isUserLoggedIn() {
+		logger.MemberId = c.Member.MemberId
+		logger.Account = c.Member.Account
+	}
+	return logger
+}
+
+// addAuditLog 记录审计日志，original 和 present 分别为变更前后的数据.
+func (c *BaseController) addAuditLog(category, action, content string, original, present interface{}) {
+	if err := c.newAuditLog(category, action, content).SetData(original, present).Add(); err != nil {
+		logs.Error("记录审计日志失败 ->", action, err)
 	}
 }
 
@@ -182,6 +337,15 @@ func (c *BaseController) BaseUrl() string {
 	return baseUrl
 }
 
+// redirectPermanent 永久跳转到新的地址并保留查询参数.
+func (c *BaseController) redirectPermanent(u string) {
+	if query := c.Ctx.Request.URL.RawQuery; query != "" {
+		u += "?" + query
+	}
+	c.Redirect(u, http.StatusMovedPermanently)
+	c.StopRun()
+}
+
 // 显示错误信息页面.
 func (c *BaseController) ShowErrorPage(errCode int, errMsg string) {
 	c.TplName = "errors/error.tpl"
@@ -40,6 +40,15 @@ func (c *BlogController) Index() {
 	c.TplName = "blog/index.tpl"
 	blogId, _ := strconv.Atoi(c.Ctx.Input.Param(":id"))
 
+	// 通过文章标识访问时，标识已变更的跳转到新的地址
+	if identify := c.Ctx.Input.Param(":key"); blogId <= 0 && identify != "" {
+		if blog, err := models.NewBlog().FindByIdentify(identify); err == nil {
+			blogId = blog.BlogId
+		} else if blog, err := models.NewRedirect().FindBlog(identify); err == nil {
+			c.redirectPermanent(conf.URLFor("BlogController.Index", ":key", blog.BlogIdentify))
+		}
+	}
+
 	if blogId <= 0 {
 		c.ShowErrorPage(404, i18n.Tr(c.Lang, "message.page_not_existed"))
 	}
@@ -424,6 +433,8 @@ func (c *BlogController) ManageDelete() {
 	if err := blog.Delete(blogId); err != nil {
 		c.JsonResult(6003, i18n.Tr(c.Lang, "message.failed"))
 	} else {
+		c.addAuditLog(models.LoggerDocument, "blog_delete", blog.BlogTitle,
+			map[string]interface{}{"blog_id": blog.BlogId, "blog_title": blog.BlogTitle, "member_id": blog.MemberId}, nil)
 		c.JsonResult(0, i18n.Tr(c.Lang, "message.success"))
 	}
 
@@ -619,6 +630,7 @@ func (c *BlogController) RemoveAttachment() {
 
 	os.Remove(filepath.Join(conf.WorkingDirectory, attach.FilePath))
 
+	c.addAuditLog(models.LoggerDocument, "attachment_delete", attach.FileName, attach, nil)
 	c.JsonResult(0, "ok", attach)
 }
 
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -91,6 +92,58 @@ func (c *BookController) Dashboard() {
 
 	c.Data["Description"] = template.HTML(blackfriday.Run([]byte(book.Description)))
 	c.Data["Model"] = *book
+
+	if check, err := models.NewLinkCheck().FindByBookId(book.BookId); err == nil {
+		links, err := models.NewBrokenLink().FindByBookId(book.BookId)
+		if err != nil {
+			logs.Error("查询失效链接失败 ->", err)
+		}
+		for _, link := range links {
+			if !strings.HasPrefix(link.Reason, "HTTP ") {
+				link.Reason = i18n.Tr(c.Lang, "blog.link_reason_"+link.Reason)
+			}
+		}
+		c.Data["LinkCheck"] = check
+		c.Data["BrokenLinks"] = links
+	}
+	c.Data["LinkCheckRunning"] = models.IsBookLinkCheckRunning(book.BookId)
+	if book.ReviewDays > 0 {
+		if bookModel, err := models.NewBook().Find(book.BookId); err == nil {
+			staleDocs, err := models.FindStaleDocuments(bookModel)
+			if err != nil {
+				logs.Error("查询过期文档失败 ->", err)
+			}
+			c.Data["StaleDocuments"] = staleDocs
+		}
+	}
+}
+
+// CheckLinks 在后台检查项目已发布文档中的失效链接.
+func (c *BookController) CheckLinks() {
+	c.Prepare()
+
+	book, err := models.NewBookResult().FindByIdentify(c.Ctx.Input.Param(":key"), c.Member.MemberId)
+	if err != nil {
+		if err == models.ErrPermissionDenied {
+			c.JsonResult(6001, i18n.Tr(c.Lang, "message.no_permission"))
+		}
+		if err == orm.ErrNoRows {
+			c.JsonResult(6002, i18n.Tr(c.Lang, "message.item_not_exist"))
+		}
+		logs.Error(err)
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.unknown_exception"))
+	}
+	if book.RoleId != conf.BookAdmin && book.RoleId != conf.BookFounder && book.RoleId != conf.BookEditor {
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.no_permission"))
+	}
+
+	if err := models.StartBookLinkCheck(book.BookId); err == models.ErrLinkCheckRunning {
+		c.JsonResult(6004, i18n.Tr(c.Lang, "message.link_check_running"))
+	} else if err != nil {
+		logs.Error("检查项目链接失败 ->", err)
+		c.JsonResult(6005, i18n.Tr(c.Lang, "message.failed"))
+	}
+	c.JsonResult(0, i18n.Tr(c.Lang, "message.book_link_check_started"))
 }
 
 // Setting 项目设置 .
@@ -162,16 +215,30 @@ func (c *BookController) SaveBook() {
 	autoRelease := strings.TrimSpace(c.GetString("auto_release")) == "on"
 	publisher := strings.TrimSpace(c.GetString("publisher"))
 	historyCount, _ := c.GetInt("history_count", 0)
+	reviewDays, _ := c.GetInt("review_days", 0)
+	commentModerate := strings.TrimSpace(c.GetString("comment_moderate")) == "on"
 	isDownload := strings.TrimSpace(c.GetString("is_download")) == "on"
 	enableShare := strings.TrimSpace(c.GetString("enable_share")) == "on"
 	isUseFirstDocument := strings.TrimSpace(c.GetString("is_use_first_document")) == "on"
 	autoSave := strings.TrimSpace(c.GetString("auto_save")) == "on"
 	itemIds := c.GetStrings("itemId")
 	pringState := strings.TrimSpace(c.GetString("print_state")) == "on"
+	identify := strings.TrimSpace(c.GetString("book_identify", book.Identify))
 
 	if strings.Count(description, "") > 500 {
 		c.JsonResult(6004, i18n.Tr(c.Lang, "message.project_desc_tips"))
 	}
+	if identify != book.Identify {
+		if ok, err := regexp.MatchString(`^[a-z]+[a-zA-Z0-9_\-]*$`, identify); !ok || err != nil {
+			c.JsonResult(6003, i18n.Tr(c.Lang, "message.project_id_tips"))
+		}
+		if strings.Count(identify, "") > 50 {
+			c.JsonResult(6004, i18n.Tr(c.Lang, "message.project_id_length"))
+		}
+		if books, _ := models.NewBook().FindByField("identify", identify, "book_id"); len(books) > 0 {
+			c.JsonResult(6006, i18n.Tr(c.Lang, "message.project_id_existed"))
+		}
+	}
 	if commentStatus != "open" && commentStatus != "closed" && commentStatus != "group_only" && commentStatus != "registered_only" {
 		commentStatus = "closed"
 	}
@@ -200,7 +267,9 @@ func (c *BookController) SaveBook() {
 		editor = EditorMarkdown
 	}
 
+	oldIdentify := book.Identify
 	book.BookName = bookName
+	book.Identify = identify
 	book.Description = description
 	book.CommentStatus = commentStatus
 	book.Publisher = publisher
@@ -213,6 +282,9 @@ func (c *BookController) SaveBook() {
 		book.Theme = "cherry"
 	}
 	book.HistoryCount = historyCount
+	if reviewDays >= 0 {
+		book.ReviewDays = reviewDays
+	}
 	book.IsDownload = 0
 	book.BookPassword = strings.TrimSpace(c.GetString("bPassword"))
 	book.ItemId = firstItemId // 兼容旧版本，设置第一个项目空间为主项目空间
@@ -222,6 +294,11 @@ func (c *BookController) SaveBook() {
 	} else {
 		book.AutoRelease = 0
 	}
+	if commentModerate {
+		book.CommentModerate = 1
+	} else {
+		book.CommentModerate = 0
+	}
 	if isDownload {
 		book.IsDownload = 0
 	} else {
@@ -264,9 +341,373 @@ func (c *BookController) SaveBook() {
 		c.JsonResult(6005, err.Error())
 	}
 
+	if oldIdentify != book.Identify {
+		c.addAuditLog(models.LoggerOperate, "book_identify", book.BookName, oldIdentify, book.Identify)
+		c.JsonResult(0, "ok", conf.URLFor("BookController.Setting", ":key", book.Identify))
+	}
+	c.JsonResult(0, "ok")
+}
+
+// Recycle 项目回收站.
+func (c *BookController) Recycle() {
+	c.Prepare()
+	c.TplName = "book/recycle.tpl"
+
+	book := c.managedBook()
+	pageIndex, _ := c.GetInt("page", 1)
+
+	list, totalCount, err := models.NewRecycleBin().FindToPager(book.BookId, pageIndex, conf.PageSize)
+	if err != nil {
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	if totalCount > 0 {
+		pager := pagination.NewPagination(c.Ctx.Request, totalCount, conf.PageSize, c.BaseUrl())
+		c.Data["PageHtml"] = pager.HtmlPages()
+	} else {
+		c.Data["PageHtml"] = ""
+	}
+	c.Data["Model"] = book
+	c.Data["Lists"] = list
+}
+
+// RecycleRestore 恢复回收站中的文档.
+func (c *BookController) RecycleRestore() {
+	c.Prepare()
+
+	item := c.recycleItem(c.managedBook())
+	if err := item.Restore(); err != nil {
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.failed")+": "+err.Error())
+	}
+	c.addAuditLog(models.LoggerDocument, "recycle_restore", item.Title, nil, item)
+	c.JsonResult(0, "ok")
+}
+
+// RecyclePurge 彻底删除回收站中的文档.
+func (c *BookController) RecyclePurge() {
+	c.Prepare()
+
+	item := c.recycleItem(c.managedBook())
+	if err := item.Purge(); err != nil {
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.failed"))
+	}
+	c.addAuditLog(models.LoggerDocument, "recycle_purge", item.Title, item, nil)
+	c.JsonResult(0, "ok")
+}
+
+// Comments 评论审核队列.
+func (c *BookController) Comments() {
+	c.Prepare()
+	c.TplName = "book/comments.tpl"
+
+	book := c.managedBook()
+	pageIndex, _ := c.GetInt("page", 1)
+	approved, _ := c.GetInt("approved", models.CommentPending)
+
+	orphaned, _ := c.GetBool("orphaned", false)
+
+	list, totalCount, err := models.FindModerationComments(book.BookId, approved, pageIndex, conf.PageSize)
+	if orphaned {
+		list, totalCount, err = models.FindOrphanedComments(book.BookId, pageIndex, conf.PageSize)
+	}
+	if err != nil {
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	if totalCount > 0 {
+		pager := pagination.NewPagination(c.Ctx.Request, totalCount, conf.PageSize, c.BaseUrl())
+		c.Data["PageHtml"] = pager.HtmlPages()
+	} else {
+		c.Data["PageHtml"] = ""
+	}
+	c.Data["Model"] = book
+	c.Data["Lists"] = list
+	c.Data["Approved"] = approved
+	c.Data["Counts"] = models.CountModerationComments(book.BookId)
+	c.Data["Orphaned"] = orphaned
+	c.Data["OrphanedCount"] = models.CountOrphanedComments(book.BookId)
+	c.Data["ModerateUrl"] = conf.URLFor("BookController.CommentModerate", ":key", book.Identify)
+}
+
+// CommentModerate 批量审核项目中的评论.
+func (c *BookController) CommentModerate() {
+	c.Prepare()
+	commentModerator{&c.BaseController}.moderate(c.managedBook().BookId)
+}
+
+// managedBook 获取当前项目，只有创始人和管理员可以管理回收站和自定义字段.
+func (c *BookController) managedBook() *models.BookResult {
+	key := c.Ctx.Input.Param(":key")
+	if key == "" {
+		c.ShowErrorPage(404, i18n.Tr(c.Lang, "message.item_not_exist"))
+	}
+	book, err := models.NewBookResult().FindByIdentify(key, c.Member.MemberId)
+	if err != nil || book == nil {
+		if err == models.ErrPermissionDenied {
+			c.ShowErrorPage(403, i18n.Tr(c.Lang, "message.no_permission"))
+		}
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	if book.RoleId != conf.BookFounder && book.RoleId != conf.BookAdmin {
+		c.ShowErrorPage(403, i18n.Tr(c.Lang, "message.no_permission"))
+	}
+	return book
+}
+
+// 文档自定义字段
+func (c *BookController) Fields() {
+	c.Prepare()
+	c.TplName = "book/fields.tpl"
+
+	book := c.managedBook()
+
+	itemFields, err := models.NewCustomField().FindByItemId(book.ItemId)
+	if err != nil {
+		logs.Error("查询项目空间字段失败 ->", err)
+	}
+	fields, err := models.NewCustomField().FindByBookId(book.BookId)
+	if err != nil {
+		logs.Error("查询项目字段失败 ->", err)
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	c.Data["Model"] = book
+	c.Data["ItemFields"] = itemFields
+	c.Data["Lists"] = fields
+	c.Data["FieldTypes"] = models.FieldTypes
+}
+
+// 添加或修改文档自定义字段
+func (c *BookController) FieldSave() {
+	c.Prepare()
+	book := c.managedBook()
+
+	field := models.NewCustomField()
+	if fieldId, _ := c.GetInt("field_id", 0); fieldId > 0 {
+		if _, err := field.Find(fieldId); err != nil || field.BookId != book.BookId {
+			c.JsonResult(6002, i18n.Tr(c.Lang, "message.field_not_exist"))
+		}
+	}
+	field.BookId = book.BookId
+	customFieldHandler{&c.BaseController}.save(field)
+}
+
+// 删除文档自定义字段
+func (c *BookController) FieldDelete() {
+	c.Prepare()
+	book := c.managedBook()
+
+	fieldId, _ := c.GetInt("field_id", 0)
+	field, err := models.NewCustomField().Find(fieldId)
+	if err != nil || field.BookId != book.BookId {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.field_not_exist"))
+	}
+	customFieldHandler{&c.BaseController}.delete(field)
+}
+
+// 项目群机器人
+func (c *BookController) Robots() {
+	c.Prepare()
+	c.TplName = "book/robots.tpl"
+
+	book := c.managedBook()
+
+	robots, err := models.NewBookRobot().FindByBookId(book.BookId)
+	if err != nil {
+		logs.Error("查询群机器人失败 ->", err)
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	c.Data["Model"] = book
+	c.Data["Lists"] = robots
+	c.Data["Platforms"] = models.RobotPlatforms
+	c.Data["Events"] = models.RobotEvents
+}
+
+// 添加或修改群机器人
+func (c *BookController) RobotSave() {
+	c.Prepare()
+	book := c.managedBook()
+
+	robot := models.NewBookRobot()
+	if robotId, _ := c.GetInt("robot_id", 0); robotId > 0 {
+		if _, err := robot.Find(robotId); err != nil || robot.BookId != book.BookId {
+			c.JsonResult(6002, i18n.Tr(c.Lang, "message.robot_not_exist"))
+		}
+	}
+	original := *robot.Masked()
+
+	robot.BookId = book.BookId
+	robot.Platform = c.GetString("platform")
+	robot.Name = c.GetString("name")
+	robot.Webhook = c.GetString("webhook")
+	robot.Events = strings.Join(c.GetStrings("events"), ",")
+	robot.Enabled, _ = c.GetInt("enabled", 0)
+	// 修改时不填写密钥则保留原来的密钥
+	if secret := c.GetString("secret"); secret != "" || robot.RobotId == 0 {
+		robot.Secret = secret
+	}
+	if robot.Platform != models.RobotDingTalk {
+		robot.Secret = ""
+	}
+	if err := robot.Save(); err != nil {
+		if err == models.ErrRobotInvalid {
+			c.JsonResult(6003, i18n.Tr(c.Lang, "message.robot_invalid"))
+		}
+		logs.Error("保存群机器人失败 ->", err)
+		c.JsonResult(6004, i18n.Tr(c.Lang, "message.failed"))
+	}
+	if original.RobotId > 0 {
+		c.addAuditLog(models.LoggerOperate, "robot_save", robot.Name, original, robot.Masked())
+	} else {
+		c.addAuditLog(models.LoggerOperate, "robot_save", robot.Name, nil, robot.Masked())
+	}
+	c.JsonResult(0, "ok", robot.Masked())
+}
+
+// 删除群机器人
+func (c *BookController) RobotDelete() {
+	c.Prepare()
+	book := c.managedBook()
+
+	robotId, _ := c.GetInt("robot_id", 0)
+	robot, err := models.NewBookRobot().Find(robotId)
+	if err != nil || robot.BookId != book.BookId {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.robot_not_exist"))
+	}
+	if err := robot.Delete(); err != nil {
+		logs.Error("删除群机器人失败 ->", err)
+		c.JsonResult(6004, i18n.Tr(c.Lang, "message.failed"))
+	}
+	c.addAuditLog(models.LoggerOperate, "robot_delete", robot.Name, robot.Masked(), nil)
 	c.JsonResult(0, "ok")
 }
 
+// 向群机器人发送测试消息
+func (c *BookController) RobotTest() {
+	c.Prepare()
+	book := c.managedBook()
+
+	robotId, _ := c.GetInt("robot_id", 0)
+	robot, err := models.NewBookRobot().Find(robotId)
+	if err != nil || robot.BookId != book.BookId {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.robot_not_exist"))
+	}
+	title := i18n.Tr(c.Lang, "message.robot_test_title")
+	text := fmt.Sprintf("#### %s\n\n%s", title, i18n.Tr(c.Lang, "message.robot_book", fmt.Sprintf("[%s](%s)", book.BookName, conf.URLFor("DocumentController.Index", ":key", book.Identify))))
+
+	if err := robot.Send(title, text); err != nil {
+		logs.Error("发送群机器人测试消息失败 ->", robot.RobotId, err)
+		c.JsonResult(6005, i18n.Tr(c.Lang, "message.robot_send_failed"))
+	}
+	c.JsonResult(0, "ok")
+}
+
+func (c *BookController) recycleItem(book *models.BookResult) *models.RecycleBin {
+	recycleId, _ := c.GetInt("recycle_id", 0)
+
+	item, err := models.NewRecycleBin().Find(recycleId)
+	if err != nil || item.BookId != book.BookId || item.ObjectType != models.RecycleDocument {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.doc_not_exist"))
+	}
+	return item
+}
+
+// 项目阅读统计
+func (c *BookController) Analytics() {
+	c.Prepare()
+	c.TplName = "book/analytics.tpl"
+
+	book := c.managedBook()
+	days, _ := c.GetInt("days", models.AnalyticsPeriods[0])
+
+	analytics, err := models.FindBookAnalytics(book.BookId, days)
+	if err != nil {
+		logs.Error("查询项目阅读统计失败 ->", err)
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	c.Data["Model"] = book
+	c.Data["Analytics"] = analytics
+	c.Data["Periods"] = models.AnalyticsPeriods
+}
+
+// 导出项目阅读统计，type 为 pages、trend 或 searches
+func (c *BookController) AnalyticsExport() {
+	c.Prepare()
+	book := c.managedBook()
+
+	days, _ := c.GetInt("days", models.AnalyticsPeriods[0])
+	if !models.IsAnalyticsPeriod(days) {
+		days = models.AnalyticsPeriods[0]
+	}
+	exportType := c.GetString("type", "pages")
+
+	var header []string
+	var rows [][]string
+	switch exportType {
+	case "pages":
+		pages, err := models.FindBookAnalyticsPages(book.BookId, days)
+		if err != nil {
+			logs.Error("导出项目阅读统计失败 ->", err)
+			c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+		}
+		header = []string{"document_id", "document_name", "views", "readers", "average_duration", "exits", "exit_rate"}
+		for _, page := range pages {
+			rows = append(rows, []string{
+				strconv.Itoa(page.DocumentId),
+				page.DocumentName,
+				strconv.Itoa(page.Views),
+				strconv.Itoa(page.Readers),
+				strconv.Itoa(page.AverageDuration()),
+				strconv.Itoa(page.Exits),
+				strconv.Itoa(page.ExitRate()),
+			})
+		}
+	case "trend":
+		trend, err := models.FindBookAnalyticsTrend(book.BookId, days)
+		if err != nil {
+			logs.Error("导出项目阅读统计失败 ->", err)
+			c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+		}
+		header = []string{"day", "views", "readers"}
+		for _, item := range trend {
+			rows = append(rows, []string{item.Day, strconv.Itoa(item.Views), strconv.Itoa(item.Readers)})
+		}
+	case "searches":
+		searches, err := models.FindBookAnalyticsSearches(book.BookId, days, false, 0)
+		if err != nil {
+			logs.Error("导出项目阅读统计失败 ->", err)
+			c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+		}
+		header = []string{"keyword", "searches", "zero_results"}
+		for _, item := range searches {
+			rows = append(rows, []string{item.Keyword, strconv.Itoa(item.Searches), strconv.Itoa(item.ZeroResults)})
+		}
+	default:
+		c.Abort("404")
+	}
+
+	filename := fmt.Sprintf("%s-%s-%dd-%s.csv", book.Identify, exportType, days, time.Now().Format("20060102"))
+	w := c.Ctx.ResponseWriter
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	//写入 BOM，避免 Excel 打开中文乱码
+	_, _ = w.Write([]byte("\xEF\xBB\xBF"))
+	writer := csv.NewWriter(w)
+	_ = writer.Write(header)
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = escapeCsvFormula(cell)
+		}
+	}
+	_ = writer.WriteAll(rows)
+	c.StopRun()
+}
+
+// escapeCsvFormula 在以公式字符开头的单元格前加单引号，避免文档名称和搜索词在 Excel 中被当作公式执行.
+func escapeCsvFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
 // 设置项目私有状态.
 func (c *BookController) PrivatelyOwned() {
 
@@ -299,6 +740,7 @@ func (c *BookController) PrivatelyOwned() {
 		c.JsonResult(6005, i18n.Tr(c.Lang, "message.item_not_exist"))
 		return
 	}
+	original := book.PrivatelyOwned
 	book.PrivatelyOwned = state
 
 	err = book.Update()
@@ -308,6 +750,7 @@ func (c *BookController) PrivatelyOwned() {
 		c.JsonResult(6004, i18n.Tr(c.Lang, "message.failed"))
 	}
 	logs.Info("用户 【", c.Member.Account, "]修改了项目权限 ->", state)
+	c.addAuditLog(models.LoggerDocument, "book_privacy", book.Identify, map[string]int{"privately_owned": original}, map[string]int{"privately_owned": state})
 	c.JsonResult(0, "ok")
 }
 
@@ -344,6 +787,9 @@ func (c *BookController) Transfer() {
 		logs.Error("转让项目失败 -> ", err)
 		c.JsonResult(6008, err.Error())
 	}
+	c.addAuditLog(models.LoggerDocument, "book_transfer", bookResult.Identify,
+		map[string]interface{}{"member_id": c.Member.MemberId, "account": c.Member.Account},
+		map[string]interface{}{"member_id": member.MemberId, "account": member.Account})
 	c.JsonResult(0, "ok")
 }
 
@@ -773,7 +1219,7 @@ func (c *BookController) Delete() {
 	if bookResult.RoleId != conf.BookFounder {
 		c.JsonResult(6002, "只有创始人才能删除项目")
 	}
-	err = models.NewBook().ThoroughDeleteBook(bookResult.BookId)
+	err = models.NewRecycleBin().AddBook(bookResult.BookId, c.Member.MemberId)
 
 	if err == orm.ErrNoRows {
 		c.JsonResult(6002, i18n.Tr(c.Lang, "message.item_not_exist"))
@@ -783,6 +1229,7 @@ func (c *BookController) Delete() {
 		c.JsonResult(6003, "删除失败")
 	}
 	logs.Info("用户[", c.Member.Account, "]删除了项目 ->", bookResult)
+	c.addAuditLog(models.LoggerDocument, "book_delete", bookResult.Identify, bookResult, nil)
 	c.JsonResult(0, "ok")
 }
 
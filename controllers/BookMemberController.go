@@ -52,6 +52,8 @@ func (c *BookMemberController) AddMember() {
 	relationship.RoleId = conf.BookRole(roleId)
 
 	if err := relationship.Insert(); err == nil {
+		models.NotifyBookMember(book, member.MemberId, c.Member.MemberId, models.NotificationMemberAdd, relationship.RoleId)
+
 		memberRelationshipResult := models.NewMemberRelationshipResult().FromMember(member)
 		memberRelationshipResult.RoleId = conf.BookRole(roleId)
 		memberRelationshipResult.RelationshipId = relationship.RelationshipId
@@ -103,12 +105,20 @@ func (c *BookMemberController) ChangeRole() {
 		c.JsonResult(6003, i18n.Tr(c.Lang, "message.readusr_only_observer"))
 	}
 
+	originalRoleId, _ := models.NewRelationship().FindForRoleId(book.BookId, memberId)
+
 	relationship, err := models.NewRelationship().UpdateRoleId(book.BookId, memberId, conf.BookRole(role))
 
 	if err != nil {
 		logs.Error("变更用户在项目中的权限 => ", err)
 		c.JsonResult(6005, err.Error())
 	}
+	c.addAuditLog(models.LoggerDocument, "book_member_role", book.Identify,
+		map[string]interface{}{"account": member.Account, "role_id": originalRoleId},
+		map[string]interface{}{"account": member.Account, "role_id": relationship.RoleId})
+	if originalRoleId != relationship.RoleId {
+		models.NotifyBookMember(book, member.MemberId, c.Member.MemberId, models.NotificationMemberRole, relationship.RoleId)
+	}
 
 	memberRelationshipResult := models.NewMemberRelationshipResult().FromMember(member)
 	memberRelationshipResult.RoleId = relationship.RoleId
@@ -145,11 +155,15 @@ func (c *BookMemberController) RemoveMember() {
 	if book.RoleId != conf.BookFounder && book.RoleId != conf.BookAdmin {
 		c.JsonResult(403, i18n.Tr(c.Lang, "message.no_permission"))
 	}
+	originalRoleId, _ := models.NewRelationship().FindForRoleId(book.BookId, member_id)
+
 	err = models.NewRelationship().DeleteByBookIdAndMemberId(book.BookId, member_id)
 
 	if err != nil {
 		c.JsonResult(6007, err.Error())
 	}
+	c.addAuditLog(models.LoggerDocument, "book_member_remove", book.Identify, map[string]interface{}{"member_id": member_id, "role_id": originalRoleId}, nil)
+	models.NotifyBookMember(book, member_id, c.Member.MemberId, models.NotificationMemberRemove, originalRoleId)
 	c.JsonResult(0, "ok")
 }
 
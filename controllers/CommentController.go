@@ -1,12 +1,15 @@
 package controllers
 
 import (
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/beego/beego/v2/core/logs"
 	"github.com/mindoc-org/mindoc/conf"
 	"github.com/mindoc-org/mindoc/models"
 	"github.com/mindoc-org/mindoc/utils/pagination"
+	"github.com/mindoc-org/mindoc/utils/textanchor"
 )
 
 type CommentController struct {
@@ -33,16 +36,22 @@ func (c *CommentController) Lists() {
 }
 
 func (c *CommentController) Create() {
-	content := c.GetString("content")
+	content := strings.TrimSpace(c.GetString("content"))
 	id, _ := c.GetInt("doc_id")
+	parentId, _ := c.GetInt("parent_id", 0)
 
-	_, err := models.NewDocument().Find(id)
+	doc, err := models.NewDocument().Find(id)
 	if err != nil {
 		c.JsonResult(1, "文章不存在")
 	}
+	book, err := models.NewBook().Find(doc.BookId)
+	if err != nil {
+		c.JsonResult(1, "项目不存在")
+	}
 
 	m := models.NewComment()
 	m.DocumentId = id
+	m.ParentId = parentId
 	if c.Member == nil {
 		c.JsonResult(1, "请先登录，再评论")
 	}
@@ -54,14 +63,48 @@ func (c *CommentController) Create() {
 	m.MemberId = c.Member.MemberId
 	m.IPAddress = c.Ctx.Request.RemoteAddr
 	m.IPAddress = strings.Split(m.IPAddress, ":")[0]
+	m.UserAgent = c.Ctx.Request.UserAgent()
+	if len(m.UserAgent) > 500 {
+		m.UserAgent = m.UserAgent[:500]
+	}
 	m.CommentDate = time.Now()
 	m.Content = content
-	m.Insert()
+
+	// 行内评论记录引用的原文，回复沿用所属评论的位置
+	if parentId == 0 {
+		m.SetAnchor(doc, textanchor.Selector{
+			Exact:  c.GetString("anchor_text"),
+			Prefix: c.GetString("anchor_prefix"),
+			Suffix: c.GetString("anchor_suffix"),
+		}, c.GetString("anchor_heading"))
+	}
+
+	bookRole, _ := models.NewRelationship().FindForRoleId(book.BookId, c.Member.MemberId)
+	m.Approved = models.CommentApprovedStatus(book, content, bookRole)
+
+	if err := m.Insert(); err != nil {
+		c.JsonResult(1, err.Error())
+	}
+	if m.Approved == models.CommentPending {
+		go func(comment *models.Comment) {
+			if err := models.NotifyCommentModerators(comment); err != nil {
+				logs.Error("通知评论审核人失败 ->", comment.CommentId, err)
+			}
+		}(m)
+	} else if m.Approved == models.CommentApproved {
+		go func(comment *models.Comment) {
+			if err := models.NotifyCommentRecipients(comment); err != nil {
+				logs.Error("发送评论通知失败 ->", comment.CommentId, err)
+			}
+		}(m)
+	}
 
 	var data struct {
-		DocId int `json:"doc_id"`
+		DocId    int `json:"doc_id"`
+		Approved int `json:"approved"`
 	}
 	data.DocId = id
+	data.Approved = m.Approved
 
 	c.JsonResult(0, "ok", data)
 }
@@ -91,6 +134,7 @@ func (c *CommentController) Delete() {
 			if err != nil {
 				c.JsonResult(1, "删除错误")
 			} else {
+				c.addAuditLog(models.LoggerDocument, "comment_delete", strconv.Itoa(m.CommentId), m, nil)
 				c.JsonResult(0, "ok")
 			}
 		} else {
@@ -98,3 +142,26 @@ func (c *CommentController) Delete() {
 		}
 	}
 }
+
+// Resolve 解决或重新打开行内评论.
+func (c *CommentController) Resolve() {
+	id, _ := c.GetInt("id", 0)
+	resolved, _ := c.GetBool("resolved", true)
+
+	if c.Member == nil {
+		c.JsonResult(1, "请先登录")
+	}
+	m, err := models.NewComment().Find(id)
+	if err != nil || m.ParentId > 0 || m.AnchorText == "" {
+		c.JsonResult(1, "评论不存在")
+	}
+	bookRole, _ := models.NewRelationship().FindForRoleId(m.BookId, c.Member.MemberId)
+	if !m.CanResolve(c.Member.MemberId, bookRole) {
+		c.JsonResult(1, "没有权限")
+	}
+	if err := m.Resolve(c.Member.MemberId, resolved); err != nil {
+		logs.Error("解决评论失败 ->", id, err)
+		c.JsonResult(1, "操作失败")
+	}
+	c.JsonResult(0, "ok", m)
+}
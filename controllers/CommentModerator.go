@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/i18n"
+	"github.com/mindoc-org/mindoc/models"
+)
+
+// commentModerator 处理评论的批量审核，由项目评论管理和后台评论管理共用.
+type commentModerator struct {
+	*BaseController
+}
+
+// commentModerations 审核操作对应的评论状态和操作日志类型.
+var commentModerations = map[string]struct {
+	Approved int
+	Action   string
+}{
+	"approve": {models.CommentApproved, "comment_approve"},
+	"spam":    {models.CommentSpam, "comment_spam"},
+	"delete":  {models.CommentDeleted, "comment_delete"},
+}
+
+// moderate 批量审核评论，bookId 为 0 时可以审核全部项目的评论.
+func (c commentModerator) moderate(bookId int) {
+	moderation, ok := commentModerations[c.GetString("action")]
+	if !ok {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.param_error"))
+	}
+	var commentIds []int
+	for _, id := range c.GetStrings("comment_id") {
+		if commentId, err := strconv.Atoi(id); err == nil && commentId > 0 {
+			commentIds = append(commentIds, commentId)
+		}
+	}
+	if len(commentIds) == 0 {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.param_error"))
+	}
+	count, err := models.ModerateComments(bookId, commentIds, moderation.Approved)
+	if err != nil {
+		logs.Error("审核评论失败 ->", err)
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.failed"))
+	}
+	c.addAuditLog(models.LoggerDocument, moderation.Action, strconv.Itoa(bookId), nil, commentIds)
+	c.JsonResult(0, "ok", count)
+}
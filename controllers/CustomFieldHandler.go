@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"strings"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/i18n"
+	"github.com/mindoc-org/mindoc/models"
+)
+
+// customFieldHandler 处理文档自定义字段的保存和删除，由项目设置和项目空间管理共用.
+type customFieldHandler struct {
+	*BaseController
+}
+
+// save 根据表单保存项目或项目空间的文档自定义字段.
+func (c customFieldHandler) save(field *models.CustomField) {
+	original := *field
+
+	field.FieldName = c.GetString("field_name")
+	field.FieldTitle = c.GetString("field_title")
+	field.FieldType = c.GetString("field_type", models.FieldTypeText)
+	field.Options = strings.TrimSpace(c.GetString("options"))
+	field.OrderSort, _ = c.GetInt("order_sort", 0)
+
+	if field.FieldId > 0 && field.FieldName != original.FieldName {
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.field_name_readonly"))
+	}
+	if err := field.Save(); err != nil {
+		if err == models.ErrCustomFieldExist {
+			c.JsonResult(6004, i18n.Tr(c.Lang, "message.field_name_exist"))
+		}
+		if err == models.ErrInvalidParameter {
+			c.JsonResult(6005, i18n.Tr(c.Lang, "message.field_invalid"))
+		}
+		logs.Error("保存自定义字段失败 ->", err)
+		c.JsonResult(6006, i18n.Tr(c.Lang, "message.failed"))
+	}
+	if original.FieldId > 0 {
+		c.addAuditLog(models.LoggerOperate, "custom_field_save", field.FieldName, original, field)
+	} else {
+		c.addAuditLog(models.LoggerOperate, "custom_field_save", field.FieldName, nil, field)
+	}
+	c.JsonResult(0, "ok", field)
+}
+
+// delete 删除文档自定义字段.
+func (c customFieldHandler) delete(field *models.CustomField) {
+	if err := field.Delete(); err != nil {
+		logs.Error("删除自定义字段失败 ->", err)
+		c.JsonResult(6006, i18n.Tr(c.Lang, "message.failed"))
+	}
+	c.addAuditLog(models.LoggerOperate, "custom_field_delete", field.FieldName, field, nil)
+	c.JsonResult(0, "ok")
+}
@@ -7,6 +7,7 @@ import (
 	"html/template"
 	"image/png"
 	"io"
+	"math"
 	"mime/multipart"
 	"net/http"
 	"net/url"
@@ -33,6 +34,9 @@ import (
 	"github.com/russross/blackfriday/v2"
 )
 
+// documentVisitorCookie 匿名读者标识的 Cookie 名称.
+const documentVisitorCookie = "visitor"
+
 // DocumentController struct
 type DocumentController struct {
 	BaseController
@@ -65,14 +69,20 @@ func (c *DocumentController) Index() {
 		return
 	}
 
+	c.redirectRenamedBook(identify, "")
 	bookResult := c.isReadable(identify, token)
 
 	// 记录阅读历史
 	if c.Member != nil && c.Member.MemberId > 0 {
-		history := models.NewBookReadHistory()
-		_, err := history.GetOrCreate(c.Member.MemberId, bookResult.BookId)
+		history, err := models.NewBookReadHistory().GetOrCreate(c.Member.MemberId, bookResult.BookId)
 		if err != nil {
 			logs.Error("添加阅读历史失败:", err)
+		} else if history.LastDocumentId > 0 {
+			// 上次阅读的文档，用于继续阅读
+			if doc, err := models.NewDocument().Find(history.LastDocumentId); err == nil && doc.BookId == bookResult.BookId {
+				c.Data["ContinueUrl"] = models.ContinueReadingUrl(bookResult.Identify, doc)
+				c.Data["ContinueName"] = doc.DocumentName
+			}
 		}
 	}
 
@@ -106,7 +116,7 @@ func (c *DocumentController) Index() {
 		c.Data["FoldSetting"] = "closed"
 	}
 
-	tree, err := models.NewDocument().CreateDocumentTreeForHtml(bookResult.BookId, selected)
+	tree, err := models.NewDocument().CreateDocumentTreeForHtml(bookResult.BookId, selected, c.memberId())
 
 	if err != nil {
 		if err == orm.ErrNoRows {
@@ -170,6 +180,7 @@ func (c *DocumentController) Read() {
 		return
 	}
 
+	c.redirectRenamedBook(identify, id)
 	bookResult := c.isReadable(identify, token)
 
 	c.TplName = fmt.Sprintf("document/%s_read.tpl", bookResult.Theme)
@@ -179,6 +190,7 @@ func (c *DocumentController) Read() {
 		doc, err = doc.FromCacheById(docId)
 		if err != nil || doc == nil {
 			logs.Error("从缓存中读取文档时失败 ->", err)
+			c.redirectRenamedDocument(bookResult.BookId, id)
 			c.ShowErrorPage(404, i18n.Tr(c.Lang, "message.doc_not_exist"))
 			return
 		}
@@ -186,6 +198,7 @@ func (c *DocumentController) Read() {
 		doc, err = doc.FromCacheByIdentify(id, bookResult.BookId)
 		if err != nil || doc == nil {
 			if err == orm.ErrNoRows {
+				c.redirectRenamedDocument(bookResult.BookId, id)
 				c.ShowErrorPage(404, i18n.Tr(c.Lang, "message.doc_not_exist"))
 			} else {
 				logs.Error("从数据库查询文档时出错 ->", err)
@@ -196,6 +209,7 @@ func (c *DocumentController) Read() {
 	}
 
 	if doc.BookId != bookResult.BookId {
+		c.redirectRenamedDocument(bookResult.BookId, id)
 		c.ShowErrorPage(404, i18n.Tr(c.Lang, "message.doc_not_exist"))
 	}
 	doc.Lang = c.Lang
@@ -244,6 +258,11 @@ func (c *DocumentController) Read() {
 	doc.IncrViewCount(doc.DocumentId)
 	doc.ViewCount = doc.ViewCount + 1
 	doc.PutToCache()
+	viewKey := c.recordDocumentView(bookResult.BookId, doc.DocumentId)
+	scrollRatio := c.recordReadProgress(bookResult.BookId, doc.DocumentId)
+	referencedBy := c.referencedByHtml(bookResult, doc.DocumentId)
+	fields, _ := models.NewDocumentField().FindByDocumentId(bookResult.BookId, doc.DocumentId)
+	fieldsHtml := c.documentFieldsHtml(fields)
 
 	if c.IsAjax() {
 		var data struct {
@@ -256,15 +275,21 @@ func (c *DocumentController) Read() {
 			ViewCount     int    `json:"view_count"`
 			MarkdownTheme string `json:"markdown_theme"`
 			IsMarkdown    bool   `json:"is_markdown"`
+			// Fields 文档的自定义字段
+			Fields []*models.DocumentFieldResult `json:"fields"`
+			// ViewKey 本次阅读记录的标识，离开文档时用于上报阅读时长
+			ViewKey string `json:"view_key"`
 		}
 		data.DocId = doc.DocumentId
 		data.DocIdentify = doc.Identify
 		data.DocTitle = doc.DocumentName
-		data.Body = doc.Release + "<div class='wiki-bottom-left'>" + i18n.Tr(c.Lang, "doc.prev") + "： <a href='/docs/" + PrevPath + "' rel='prev'>" + PrevName + "</a><br />" + i18n.Tr(c.Lang, "doc.next") + "： <a href='/docs/" + NextPath + "' rel='next'>" + NextName + "</a><br /></div>"
+		data.Body = fieldsHtml + doc.Release + referencedBy + "<div class='wiki-bottom-left'>" + i18n.Tr(c.Lang, "doc.prev") + "： <a href='/docs/" + PrevPath + "' rel='prev'>" + PrevName + "</a><br />" + i18n.Tr(c.Lang, "doc.next") + "： <a href='/docs/" + NextPath + "' rel='next'>" + NextName + "</a><br /></div>"
 		data.Title = doc.DocumentName + " - Powered by MinDoc"
 		data.Version = doc.Version
 		data.ViewCount = doc.ViewCount
 		data.MarkdownTheme = doc.MarkdownTheme
+		data.Fields = fields
+		data.ViewKey = viewKey
 		if bookResult.Editor == EditorCherryMarkdown {
 			data.IsMarkdown = true
 		}
@@ -272,6 +297,8 @@ func (c *DocumentController) Read() {
 	} else {
 		c.Data["DocumentId"] = doc.DocumentId
 		c.Data["DocIdentify"] = doc.Identify
+		c.Data["ViewKey"] = viewKey
+		c.Data["ScrollRatio"] = scrollRatio
 		if bookResult.IsDisplayComment {
 			// 获取评论、分页
 			comments, count, _ := models.NewComment().QueryCommentByDocumentId(doc.DocumentId, 1, conf.PageSize, c.Member)
@@ -280,7 +307,7 @@ func (c *DocumentController) Read() {
 		}
 	}
 
-	tree, err := models.NewDocument().CreateDocumentTreeForHtml(bookResult.BookId, doc.DocumentId)
+	tree, err := models.NewDocument().CreateDocumentTreeForHtml(bookResult.BookId, doc.DocumentId, c.memberId())
 
 	if err != nil && err != orm.ErrNoRows {
 		logs.Error("生成项目文档树时出错 ->", err)
@@ -292,7 +319,7 @@ func (c *DocumentController) Read() {
 	c.Data["Model"] = bookResult
 	c.Data["Result"] = template.HTML(tree)
 	c.Data["Title"] = doc.DocumentName
-	c.Data["Content"] = template.HTML(doc.Release + "<div class='wiki-bottom-left'>" + i18n.Tr(c.Lang, "doc.prev") + "： <a href='/docs/" + PrevPath + "' rel='prev'>" + PrevName + "</a><br />" + i18n.Tr(c.Lang, "doc.next") + "： <a href='/docs/" + NextPath + "' rel='next'>" + NextName + "</a><br /></div>")
+	c.Data["Content"] = template.HTML(fieldsHtml + doc.Release + referencedBy + "<div class='wiki-bottom-left'>" + i18n.Tr(c.Lang, "doc.prev") + "： <a href='/docs/" + PrevPath + "' rel='prev'>" + PrevName + "</a><br />" + i18n.Tr(c.Lang, "doc.next") + "： <a href='/docs/" + NextPath + "' rel='next'>" + NextName + "</a><br /></div>")
 	c.Data["ViewCount"] = doc.ViewCount
 	c.Data["FoldSetting"] = "closed"
 	if bookResult.Editor == EditorCherryMarkdown {
@@ -305,6 +332,139 @@ func (c *DocumentController) Read() {
 	}
 }
 
+// recordDocumentView 记录一次文档阅读，匿名用户使用 Cookie 中的随机标识区分读者.
+func (c *DocumentController) recordDocumentView(bookId, docId int) string {
+	view := models.NewDocumentView()
+	view.BookId = bookId
+	view.DocumentId = docId
+
+	if c.Member != nil && c.Member.MemberId > 0 {
+		view.MemberId = c.Member.MemberId
+		view.VisitorId = "m:" + strconv.Itoa(c.Member.MemberId)
+	} else {
+		visitor := c.Ctx.GetCookie(documentVisitorCookie)
+		if len(visitor) != 32 {
+			visitor = string(utils.Krand(32, utils.KC_RAND_KIND_ALL))
+			c.Ctx.SetCookie(documentVisitorCookie, visitor, 365*24*3600, "/")
+		}
+		view.VisitorId = "v:" + visitor
+	}
+	// 只记录从其他站点进入的来源，站内跳转不算来源
+	if !c.IsAjax() {
+		if u, err := url.Parse(c.Ctx.Request.Referer()); err == nil && u.Host != "" && u.Host != c.Ctx.Request.Host {
+			view.Referer = u.String()
+			view.RefererHost = u.Hostname()
+		}
+	}
+	return view.Record()
+}
+
+// memberId 获取登录用户的id，未登录时为 0.
+func (c *DocumentController) memberId() int {
+	if c.Member != nil {
+		return c.Member.MemberId
+	}
+	return 0
+}
+
+// recordReadProgress 记录登录用户最后阅读的文档并标记为已读，从继续阅读打开时返回上次的滚动位置.
+func (c *DocumentController) recordReadProgress(bookId, docId int) float64 {
+	if !c.isUserLoggedIn() {
+		return 0
+	}
+	memberId := c.Member.MemberId
+	ratio := 0.0
+	if c.GetString("resume") == "1" {
+		if history, err := models.NewBookReadHistory().Find(memberId, bookId); err == nil && history.LastDocumentId == docId {
+			ratio = history.ScrollRatio
+		}
+	}
+	models.RecordReadProgress(memberId, bookId, docId)
+	return ratio
+}
+
+// SaveProgress 保存登录用户在文档中的滚动位置，从缓存打开的文档也通过它记录为最后阅读的文档.
+func (c *DocumentController) SaveProgress() {
+	identify := c.Ctx.Input.Param(":key")
+	docId, _ := c.GetInt("doc_id")
+	ratio, _ := c.GetFloat("ratio")
+
+	if !c.isUserLoggedIn() {
+		c.JsonResult(6000, i18n.Tr(c.Lang, "message.need_relogin"))
+	}
+	bookResult := c.isReadable(identify, c.GetString("token"))
+
+	doc, err := models.NewDocument().Find(docId)
+	if err != nil || doc.BookId != bookResult.BookId {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.doc_not_exist"))
+	}
+	if err := models.NewBookReadHistory().SaveProgress(c.Member.MemberId, bookResult.BookId, docId, ratio); err != nil {
+		logs.Error("保存阅读进度失败 ->", docId, err)
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.failed"))
+	}
+	c.JsonResult(0, "ok")
+}
+
+// MarkBookRead 将项目中的全部文档标记为已读.
+func (c *DocumentController) MarkBookRead() {
+	identify := c.Ctx.Input.Param(":key")
+
+	if !c.isUserLoggedIn() {
+		c.JsonResult(6000, i18n.Tr(c.Lang, "message.need_relogin"))
+	}
+	bookResult := c.isReadable(identify, c.GetString("token"))
+
+	count, err := models.NewDocumentReadStatus().MarkBookRead(c.Member.MemberId, bookResult.BookId)
+	if err != nil {
+		logs.Error("标记项目已读失败 ->", bookResult.BookId, err)
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.failed"))
+	}
+	c.JsonResult(0, i18n.Tr(c.Lang, "message.book_marked_read", count), count)
+}
+
+// RecordView 记录从阅读页面缓存中打开的文档的阅读，返回新的阅读记录标识和阅读次数.
+func (c *DocumentController) RecordView() {
+	identify := c.Ctx.Input.Param(":key")
+	docId, _ := c.GetInt("doc_id")
+
+	bookResult := c.isReadable(identify, c.GetString("token"))
+
+	doc, err := models.NewDocument().FromCacheById(docId)
+	if err != nil || doc == nil || doc.BookId != bookResult.BookId {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.doc_not_exist"))
+	}
+	doc.IncrViewCount(doc.DocumentId)
+	doc.ViewCount = doc.ViewCount + 1
+	doc.PutToCache()
+	viewKey := c.recordDocumentView(bookResult.BookId, doc.DocumentId)
+	c.recordReadProgress(bookResult.BookId, doc.DocumentId)
+
+	c.JsonResult(0, "ok", map[string]interface{}{"view_key": viewKey, "view_count": doc.ViewCount})
+}
+
+// LeaveDocument 离开文档时上报阅读时长，exit 为 1 表示离开了项目.
+func (c *DocumentController) LeaveDocument() {
+	identify := c.Ctx.Input.Param(":key")
+	viewKey := c.GetString("view_key")
+	duration, _ := c.GetInt("duration")
+	exit, _ := c.GetInt("exit")
+
+	if viewKey == "" {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.param_error"))
+	}
+	book, err := models.NewBook().FindByFieldFirst("identify", identify)
+	if err != nil {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.param_error"))
+	}
+	// 只接受本人的上报，写入时按项目和读者匹配阅读记录
+	visitor := "v:" + c.Ctx.GetCookie(documentVisitorCookie)
+	if c.Member != nil && c.Member.MemberId > 0 {
+		visitor = "m:" + strconv.Itoa(c.Member.MemberId)
+	}
+	models.LeaveDocumentView(viewKey, book.BookId, visitor, duration, exit == 1)
+	c.JsonResult(0, "ok")
+}
+
 // 递归得到树状结构体
 func getTreeRecursive(list []*models.DocumentTree, parentId int) (res []*models.DocumentTree) {
 	for _, v := range list {
@@ -794,6 +954,7 @@ func (c *DocumentController) RemoveAttachment() {
 
 	os.Remove(filepath.Join(conf.WorkingDirectory, attach.FilePath))
 
+	c.addAuditLog(models.LoggerDocument, "attachment_delete", attach.FileName, attach, nil)
 	c.JsonResult(0, "ok", attach)
 }
 
@@ -841,17 +1002,141 @@ func (c *DocumentController) Delete() {
 		c.JsonResult(6004, i18n.Tr(c.Lang, "message.param_error"))
 	}
 
-	// 递归删除项目下的文档以及子文档
-	err = doc.RecursiveDocument(doc.DocumentId)
+	// 将文档以及子文档移入回收站
+	err = models.NewRecycleBin().AddDocument(doc, c.Member.MemberId)
 	if err != nil {
+		logs.Error("删除文档失败 ->", err)
 		c.JsonResult(6005, i18n.Tr(c.Lang, "message.failed"))
 	}
 
 	// 重置文档数量统计
 	models.NewBook().ResetDocumentNumber(doc.BookId)
+	c.addAuditLog(models.LoggerDocument, "document_delete", doc.DocumentName, documentAuditData(identify, doc), nil)
 	c.JsonResult(0, "ok")
 }
 
+// References 获取引用了指定文档及其子文档的其他文档.
+func (c *DocumentController) References() {
+	c.Prepare()
+
+	book := c.editableBook(c.Ctx.Input.Param(":key"))
+	if book == nil {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.item_not_exist_or_no_permit"))
+	}
+	docId, _ := c.GetInt("doc_id", 0)
+	if docId <= 0 {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.param_error"))
+	}
+	references, err := models.NewDocumentReference().FindReferencedBySubtree(book.BookId, docId)
+	if err != nil {
+		logs.Error("查询文档引用失败 ->", err)
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.failed"))
+	}
+	c.JsonResult(0, "ok", references)
+}
+
+// 获取可以移动或复制到的项目，指定 target 时返回该项目的文档列表
+func (c *DocumentController) TransferTargets() {
+	c.Prepare()
+
+	if c.editableBook(c.Ctx.Input.Param(":key")) == nil {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.item_not_exist_or_no_permit"))
+	}
+
+	if target := c.GetString("target"); target != "" {
+		book := c.editableBook(target)
+		if book == nil {
+			c.JsonResult(6002, i18n.Tr(c.Lang, "message.item_not_exist_or_no_permit"))
+		}
+		trees, err := models.NewDocument().FindDocumentTree(book.BookId)
+		if err != nil {
+			logs.Error("获取文档列表失败 ->", err)
+			c.JsonResult(6003, i18n.Tr(c.Lang, "message.failed"))
+		}
+		c.JsonResult(0, "ok", trees)
+	}
+
+	var books []*models.BookResult
+	var err error
+	if c.Member.IsAdministrator() {
+		books, _, err = models.NewBookResult().FindToPager(1, math.MaxInt32)
+	} else {
+		books, _, err = models.NewBook().FindToPager(1, math.MaxInt32, c.Member.MemberId, c.Lang)
+	}
+	if err != nil {
+		logs.Error("获取项目列表失败 ->", err)
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.failed"))
+	}
+
+	result := make([]map[string]interface{}, 0, len(books))
+	for _, book := range books {
+		if !c.Member.IsAdministrator() && book.RoleId == conf.BookObserver {
+			continue
+		}
+		result = append(result, map[string]interface{}{
+			"identify":  book.Identify,
+			"book_name": book.BookName,
+		})
+	}
+	c.JsonResult(0, "ok", result)
+}
+
+// 将文档及其子文档移动或复制到其他项目
+func (c *DocumentController) Transfer() {
+	c.Prepare()
+
+	identify := c.Ctx.Input.Param(":key")
+	docId, _ := c.GetInt("doc_id", 0)
+	parentId, _ := c.GetInt("parent_id", 0)
+	isCopy := c.GetString("action") == "copy"
+	withHistory, _ := c.GetBool("with_history", false)
+
+	if docId <= 0 {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.param_error"))
+	}
+
+	source := c.editableBook(identify)
+	if source == nil {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.item_not_exist_or_no_permit"))
+	}
+	target := c.editableBook(c.GetString("target"))
+	if target == nil {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.item_not_exist_or_no_permit"))
+	}
+
+	transfer := models.NewDocumentTransfer(source, target)
+	transfer.DocumentId = docId
+	transfer.ParentId = parentId
+	transfer.IsCopy = isCopy
+	transfer.WithHistory = withHistory
+	transfer.MemberId = c.Member.MemberId
+
+	doc, err := transfer.Transfer()
+	if err != nil {
+		logs.Error("移动或复制文档失败 ->", err)
+		switch err {
+		case models.ErrTransferSameBook:
+			c.JsonResult(6004, i18n.Tr(c.Lang, "message.transfer_same_book"))
+		case models.ErrTransferParentInvalid:
+			c.JsonResult(6005, i18n.Tr(c.Lang, "message.parent_id_not_existed"))
+		case models.ErrDataNotExist, orm.ErrNoRows:
+			c.JsonResult(6006, i18n.Tr(c.Lang, "message.doc_not_exist"))
+		}
+		c.JsonResult(6007, i18n.Tr(c.Lang, "message.failed"))
+	}
+
+	action := "document_move"
+	if isCopy {
+		action = "document_copy"
+	}
+	c.addAuditLog(models.LoggerDocument, action, doc.DocumentName, map[string]interface{}{
+		"book_identify": source.Identify,
+		"doc_id":        docId,
+	}, documentAuditData(target.Identify, doc))
+
+	c.JsonResult(0, "ok", doc)
+}
+
 // 获取文档内容
 func (c *DocumentController) Content() {
 	c.Prepare()
@@ -914,6 +1199,17 @@ func (c *DocumentController) Content() {
 			c.JsonResult(6005, i18n.Tr(c.Lang, "message.confirm_override_doc"))
 		}
 
+		// Markdown 开头的元数据保存为文档的自定义字段，不显示在文档内容中
+		fieldValues, hasFrontMatter, err := models.ParseFrontMatter(markdown)
+		if err != nil {
+			c.JsonResult(6007, c.fieldErrorMessage(err))
+		} else if hasFrontMatter {
+			if err := models.NewDocumentField().Check(doc, fieldValues); err != nil {
+				c.JsonResult(6007, c.fieldErrorMessage(err))
+			}
+			content = models.StripFrontMatterHtml(markdown, content)
+		}
+
 		history := models.NewDocumentHistory()
 		history.DocumentId = docId
 		history.Content = doc.Content
@@ -941,6 +1237,12 @@ func (c *DocumentController) Content() {
 			logs.Error("InsertOrUpdate => ", err)
 			c.JsonResult(6006, i18n.Tr(c.Lang, "message.failed"))
 		}
+		if hasFrontMatter {
+			if err := models.NewDocumentField().Save(doc, fieldValues); err != nil {
+				logs.Error("保存文档自定义字段失败 ->", doc.DocumentId, err)
+				c.JsonResult(6007, c.fieldErrorMessage(err))
+			}
+		}
 
 		// 如果启用了文档历史，则添加历史文档
 		///如果两次保存的MD5值不同则保存为历史，否则忽略
@@ -957,13 +1259,19 @@ func (c *DocumentController) Content() {
 		if autoRelease {
 			go func() {
 				doc.Lang = c.Lang
+				previous := doc.Release
 				err := doc.ReleaseContent()
 				if err == nil {
 					logs.Informational(i18n.Tr(c.Lang, "message.doc_auto_published")+"-> document_id=%d;document_name=%s", doc.DocumentId, doc.DocumentName)
+					if doc.Release != previous {
+						models.NotifyRobotsDocumentRelease(doc)
+					}
 				}
 			}()
 		}
 
+		doc.Fields, _ = models.NewDocumentField().FindByDocumentId(doc.BookId, doc.DocumentId)
+
 		c.JsonResult(0, "ok", doc)
 	}
 
@@ -977,10 +1285,55 @@ func (c *DocumentController) Content() {
 	if err == nil {
 		doc.AttachList = attach
 	}
+	doc.Fields, _ = models.NewDocumentField().FindByDocumentId(doc.BookId, doc.DocumentId)
 
 	c.JsonResult(0, "ok", doc)
 }
 
+// 获取或保存文档的自定义字段
+func (c *DocumentController) Fields() {
+	c.Prepare()
+
+	book := c.editableBook(c.Ctx.Input.Param(":key"))
+	if book == nil {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.item_not_exist_or_no_permit"))
+	}
+	docId, _ := c.GetInt("doc_id", 0)
+
+	doc, err := models.NewDocument().Find(docId)
+	if err != nil || doc.BookId != book.BookId {
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.doc_not_exist"))
+	}
+	_, hasFrontMatter, _ := models.ParseFrontMatter(doc.Markdown)
+
+	if c.Ctx.Input.IsPost() {
+		// 使用元数据的文档只能在 Markdown 中修改字段，否则保存文档时会被元数据覆盖
+		if hasFrontMatter {
+			c.JsonResult(6005, i18n.Tr(c.Lang, "message.field_in_front_matter"))
+		}
+		fields, err := models.NewCustomField().FindForBook(book.BookId)
+		if err != nil {
+			c.JsonResult(6004, i18n.Tr(c.Lang, "message.failed"))
+		}
+		values := make(map[string]string, len(fields))
+		for _, field := range fields {
+			values[field.FieldName] = c.GetString("field_" + field.FieldName)
+		}
+		if err := models.NewDocumentField().Save(doc, values); err != nil {
+			c.JsonResult(6006, c.fieldErrorMessage(err))
+		}
+	}
+	fields, err := models.NewDocumentField().FindByDocumentId(book.BookId, doc.DocumentId)
+	if err != nil {
+		logs.Error("查询文档字段失败 ->", err)
+		c.JsonResult(6004, i18n.Tr(c.Lang, "message.failed"))
+	}
+	c.JsonResult(0, "ok", map[string]interface{}{
+		"fields":       fields,
+		"front_matter": hasFrontMatter,
+	})
+}
+
 // Export 导出
 func (c *DocumentController) Export() {
 	c.Prepare()
@@ -1135,6 +1488,7 @@ func (c *DocumentController) Search() {
 		logs.Error(err)
 		c.JsonResult(6002, i18n.Tr(c.Lang, "message.search_result_error"))
 	}
+	models.RecordBookSearch(bookResult.BookId, keyword, len(docs))
 
 	if len(docs) < 0 {
 		c.JsonResult(404, i18n.Tr(c.Lang, "message.no_data"))
@@ -1275,6 +1629,7 @@ func (c *DocumentController) DeleteHistory() {
 		c.JsonResult(6002, i18n.Tr(c.Lang, "message.failed"))
 	}
 
+	c.addAuditLog(models.LoggerDocument, "history_delete", doc.DocumentName, map[string]int{"history_id": historyId}, nil)
 	c.JsonResult(0, "ok")
 }
 
@@ -1333,6 +1688,7 @@ func (c *DocumentController) RestoreHistory() {
 		c.JsonResult(6002, i18n.Tr(c.Lang, "message.failed"))
 	}
 
+	c.addAuditLog(models.LoggerDocument, "history_restore", doc.DocumentName, documentAuditData(identify, doc), map[string]int{"history_id": historyId})
 	c.JsonResult(0, "ok", doc)
 }
 
@@ -1475,6 +1831,123 @@ func (c *DocumentController) isReadable(identify, token string) *models.BookResu
 	return bookResult
 }
 
+// canReadDirectly 判断当前用户是否可以直接阅读项目，与 isReadable 的规则相同但不显示密码页面.
+// 跳转到新地址前使用，避免没有权限的用户通过旧地址获取私有项目或文档的新标识.
+func (c *DocumentController) canReadDirectly(book *models.Book, token string) bool {
+	if book.PrivatelyOwned == 0 {
+		return true
+	}
+	if c.isUserLoggedIn() {
+		if c.Member.IsAdministrator() {
+			return true
+		}
+		if _, err := models.NewBook().FindForRoleId(book.BookId, c.Member.MemberId); err == nil {
+			return true
+		}
+	}
+	if tokenOrPassword, ok := c.GetSession(book.Identify).(string); ok {
+		if strings.EqualFold(book.PrivateToken, tokenOrPassword) || strings.EqualFold(book.BookPassword, tokenOrPassword) {
+			return true
+		}
+	}
+	return book.PrivateToken != "" && book.PrivateToken == token
+}
+
+// redirectRenamedBook 项目标识变更后，当前用户可以阅读时将旧地址永久跳转到新地址.
+func (c *DocumentController) redirectRenamedBook(identify, id string) {
+	if models.NewBook().QueryTable().Filter("identify", identify).Exist() {
+		return
+	}
+	book, err := models.NewRedirect().FindBook(identify)
+	if err != nil || !c.canReadDirectly(book, c.GetString("token")) {
+		return
+	}
+	if id == "" {
+		c.redirectPermanent(conf.URLFor("DocumentController.Index", ":key", book.Identify))
+	}
+	c.redirectPermanent(conf.URLFor("DocumentController.Read", ":key", book.Identify, ":id", id))
+}
+
+// referencedByHtml 生成引用了当前文档的文档列表，只列出当前用户可以阅读的文档.
+func (c *DocumentController) referencedByHtml(bookResult *models.BookResult, docId int) string {
+	references, err := models.NewDocumentReference().FindReferencedBy(docId)
+	if err != nil {
+		logs.Error("查询文档引用失败 ->", err)
+		return ""
+	}
+	var buf strings.Builder
+
+	for _, item := range references {
+		if item.BookId != bookResult.BookId && item.PrivatelyOwned == 1 && !c.Member.IsAdministrator() {
+			if !c.isUserLoggedIn() {
+				continue
+			}
+			if _, err := models.NewBook().FindForRoleId(item.BookId, c.Member.MemberId); err != nil {
+				continue
+			}
+		}
+		docKey := item.Identify
+		if docKey == "" {
+			docKey = strconv.Itoa(item.DocumentId)
+		}
+		buf.WriteString("<li><a href='" + conf.URLFor("DocumentController.Read", ":key", item.BookIdentify, ":id", docKey) + "'>" + template.HTMLEscapeString(item.DocumentName) + "</a>")
+		if item.BookId != bookResult.BookId {
+			buf.WriteString(" - " + template.HTMLEscapeString(item.BookName))
+		}
+		buf.WriteString("</li>")
+	}
+	if buf.Len() == 0 {
+		return ""
+	}
+	return "<div class='wiki-referenced-by'><strong>" + i18n.Tr(c.Lang, "doc.referenced_by") + "</strong><ul>" + buf.String() + "</ul></div>"
+}
+
+// documentFieldsHtml 生成文档自定义字段的列表，字段值链接到按该字段筛选的搜索结果.
+func (c *DocumentController) documentFieldsHtml(fields []*models.DocumentFieldResult) string {
+	var buf strings.Builder
+
+	for _, field := range fields {
+		if field.Value == "" {
+			continue
+		}
+		searchURL := conf.URLFor("SearchController.Index") + "?field=" + url.QueryEscape(field.FieldName) + "&value=" + url.QueryEscape(field.Value)
+		buf.WriteString("<tr><th>" + template.HTMLEscapeString(field.FieldTitle) + "</th><td><a href='" + template.HTMLEscapeString(searchURL) + "'>" + template.HTMLEscapeString(field.Value) + "</a></td></tr>")
+	}
+	if buf.Len() == 0 {
+		return ""
+	}
+	return "<div class='wiki-fields'><table>" + buf.String() + "</table></div>"
+}
+
+// fieldErrorMessage 将自定义字段的校验错误转换为提示信息.
+func (c *DocumentController) fieldErrorMessage(err error) string {
+	if e, ok := err.(*models.FieldValueError); ok {
+		return i18n.Tr(c.Lang, "message.field_value_invalid", e.Field.FieldTitle, e.Value)
+	}
+	if err == models.ErrFrontMatterInvalid {
+		return i18n.Tr(c.Lang, "message.front_matter_invalid")
+	}
+	logs.Error("保存文档字段失败 ->", err)
+	return i18n.Tr(c.Lang, "message.failed")
+}
+
+// redirectRenamedDocument 文档标识变更或文档移动到其他项目后，当前用户可以阅读时将旧地址永久跳转到新地址.
+func (c *DocumentController) redirectRenamedDocument(bookId int, id string) {
+	doc, err := models.NewRedirect().FindDocument(bookId, id)
+	if err != nil {
+		return
+	}
+	book, err := models.NewBook().Find(doc.BookId)
+	if err != nil || !c.canReadDirectly(book, c.GetString("token")) {
+		return
+	}
+	docKey := doc.Identify
+	if docKey == "" {
+		docKey = strconv.Itoa(doc.DocumentId)
+	}
+	c.redirectPermanent(conf.URLFor("DocumentController.Read", ":key", book.Identify, ":id", docKey))
+}
+
 func promptUserToLogIn(c *DocumentController) {
 	logs.Info("Access " + c.Ctx.Request.URL.RequestURI() + " not permitted.")
 	logs.Info("  Access will be redirected to login page(SessionId: " + c.CruSession.SessionID(context.TODO()) + ").")
@@ -1485,3 +1958,33 @@ func promptUserToLogIn(c *DocumentController) {
 		c.Redirect(conf.URLFor("AccountController.Login")+"?url="+url.PathEscape(conf.BaseUrl+c.Ctx.Request.URL.RequestURI()), 302)
 	}
 }
+
+// editableBook 获取当前用户可以编辑的项目，没有权限时返回nil.
+func (c *DocumentController) editableBook(identify string) *models.Book {
+	if identify == "" {
+		return nil
+	}
+	book, err := models.NewBook().FindByFieldFirst("identify", identify)
+	if err != nil {
+		return nil
+	}
+	if c.Member.IsAdministrator() {
+		return book
+	}
+	roleId, err := models.NewBook().FindForRoleId(book.BookId, c.Member.MemberId)
+	if err != nil || roleId == conf.BookObserver {
+		return nil
+	}
+	return book
+}
+
+// documentAuditData 审计日志中记录的文档信息.
+func documentAuditData(bookIdentify string, doc *models.Document) map[string]interface{} {
+	return map[string]interface{}{
+		"book_identify": bookIdentify,
+		"doc_id":        doc.DocumentId,
+		"doc_name":      doc.DocumentName,
+		"identify":      doc.Identify,
+		"version":       doc.Version,
+	}
+}
@@ -66,6 +66,15 @@ func (c *HomeController) Index() {
 		}
 	}
 
+	// 获取可以继续阅读的文档
+	if memberId > 0 {
+		continueReading, err := models.NewBookReadHistory().FindContinueReading(memberId, 6)
+		if err != nil {
+			logs.Error("查询继续阅读的文档失败 ->", err)
+		}
+		c.Data["ContinueReading"] = continueReading
+	}
+
 	// 获取其他书籍
 	books, totalCount, err := models.NewBook().FindForHomeToPager(pageIndex, pageSize, memberId)
 	if err != nil {
@@ -1,10 +1,12 @@
 package controllers
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"html/template"
 	"regexp"
 	"strings"
+	"time"
 
 	"math"
 	"path/filepath"
@@ -91,8 +93,8 @@ func (c *ManagerController) CreateMember() {
 	if ok, err := regexp.MatchString(conf.RegexpAccount, account); account == "" || !ok || err != nil {
 		c.JsonResult(6001, i18n.Tr(c.Lang, "message.username_invalid_format"))
 	}
-	if l := strings.Count(password1, ""); password1 == "" || l > 50 || l < 6 {
-		c.JsonResult(6002, i18n.Tr(c.Lang, "message.pwd_length_tips"))
+	if policy := models.GetPasswordPolicy(); policy.Check(password1) != nil {
+		c.JsonResult(6002, policy.Tips(c.Lang))
 	}
 	if password1 != password2 {
 		c.JsonResult(6003, i18n.Tr(c.Lang, "message.wrong_confirm_pwd"))
@@ -157,12 +159,18 @@ func (c *ManagerController) UpdateMemberStatus() {
 	if member.Role == conf.MemberSuperRole {
 		c.JsonResult(6005, i18n.Tr(c.Lang, "message.cannot_change_super_status"))
 	}
+	originalStatus := member.Status
 	member.Status = status
 
 	if err := member.Update(); err != nil {
 		logs.Error("", err)
 		c.JsonResult(6003, i18n.Tr(c.Lang, "message.failed"))
 	}
+	c.addAuditLog(models.LoggerOperate, "member_status", member.Account, map[string]int{"status": originalStatus}, map[string]int{"status": status})
+	//禁用用户后注销其全部登录会话
+	if status == 1 {
+		_ = models.NewMemberSession().DeleteByMemberId(member.MemberId, "")
+	}
 	c.JsonResult(0, "ok", member)
 }
 
@@ -189,11 +197,13 @@ func (c *ManagerController) ChangeMemberRole() {
 	if member.Role == conf.MemberSuperRole {
 		c.JsonResult(6005, i18n.Tr(c.Lang, "message.cannot_change_super_priv"))
 	}
+	originalRole := member.Role
 	member.Role = conf.SystemRole(role)
 
 	if err := member.Update(); err != nil {
 		c.JsonResult(6003, i18n.Tr(c.Lang, "message.failed"))
 	}
+	c.addAuditLog(models.LoggerOperate, "member_role", member.Account, map[string]conf.SystemRole{"role": originalRole}, map[string]conf.SystemRole{"role": member.Role})
 	member.Lang = c.Lang
 	member.ResolveRoleName()
 	c.JsonResult(0, "ok", member)
@@ -221,6 +231,7 @@ func (c *ManagerController) EditMember() {
 		email := c.GetString("email")
 		phone := c.GetString("phone")
 		description := c.GetString("description")
+		original := map[string]string{"email": member.Email, "phone": member.Phone, "real_name": member.RealName}
 		member.Email = email
 		member.Phone = phone
 		member.Description = description
@@ -228,6 +239,9 @@ func (c *ManagerController) EditMember() {
 		if password1 != "" && password2 != password1 {
 			c.JsonResult(6001, i18n.Tr(c.Lang, "message.wrong_confirm_pwd"))
 		}
+		if policy := models.GetPasswordPolicy(); password1 != "" && member.AuthMethod != conf.AuthMethodLDAP && policy.Check(password1) != nil {
+			c.JsonResult(6002, policy.Tips(c.Lang))
+		}
 		if password1 != "" && member.AuthMethod != conf.AuthMethodLDAP {
 			member.Password = password1
 		}
@@ -241,14 +255,215 @@ func (c *ManagerController) EditMember() {
 				c.JsonResult(6003, i18n.Tr(c.Lang, "message.pwd_encrypt_failed"))
 			}
 			member.Password = password
+			member.PasswordUpdateTime = time.Now()
 		}
 		if err := member.Update(); err != nil {
 			c.JsonResult(6004, err.Error())
 		}
+		//重置密码后注销该用户的全部登录会话，管理员修改自己的密码时保留当前会话
+		if password1 != "" {
+			exceptToken := ""
+			if member.MemberId == c.Member.MemberId {
+				exceptToken = c.currentSessionToken()
+			}
+			_ = models.NewMemberSession().DeleteByMemberId(member.MemberId, exceptToken)
+		}
+		present := map[string]interface{}{"email": member.Email, "phone": member.Phone, "real_name": member.RealName, "password_reset": password1 != ""}
+		c.addAuditLog(models.LoggerOperate, "member_update", member.Account, original, present)
 		c.JsonResult(0, "ok")
 	}
 
+	sessions, _ := models.NewMemberSession().FindByMemberId(member.MemberId)
+
 	c.Data["Model"] = member
+	c.Data["TwoFactorEnabled"] = models.NewMemberTwoFactor().IsEnabled(member.MemberId)
+	c.Data["Sessions"] = sessions
+}
+
+// 强制用户下线，注销该用户的全部登录会话.
+func (c *ManagerController) LogoutMember() {
+	c.Prepare()
+
+	memberId, _ := c.GetInt("member_id", 0)
+	if memberId <= 0 {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.param_error"))
+	}
+	member, err := models.NewMember().Find(memberId)
+	if err != nil {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.user_not_existed"))
+	}
+	exceptToken := ""
+	if member.MemberId == c.Member.MemberId {
+		exceptToken = c.currentSessionToken()
+	}
+	if err := models.NewMemberSession().DeleteByMemberId(member.MemberId, exceptToken); err != nil {
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.failed"))
+	}
+	logs.Info("管理员强制用户下线 ->", c.Member.Account, member.Account)
+	c.addAuditLog(models.LoggerOperate, "member_logout", member.Account, nil, nil)
+	c.JsonResult(0, "ok")
+}
+
+// 重置用户的两步验证，用于用户丢失验证器且恢复码用尽的情况.
+func (c *ManagerController) ResetTwoFactor() {
+	c.Prepare()
+
+	memberId, _ := c.GetInt("member_id", 0)
+	if memberId <= 0 {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.param_error"))
+	}
+	member, err := models.NewMember().Find(memberId)
+	if err != nil {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.user_not_existed"))
+	}
+	if member.Role == conf.MemberSuperRole && c.Member.Role != conf.MemberSuperRole {
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.no_permission"))
+	}
+	if err := models.NewMemberTwoFactor().Delete(member.MemberId); err != nil {
+		c.JsonResult(6004, i18n.Tr(c.Lang, "message.failed"))
+	}
+	logs.Info("管理员重置了用户的两步验证 ->", c.Member.Account, member.Account)
+	c.addAuditLog(models.LoggerOperate, "two_factor_reset", member.Account, nil, nil)
+
+	c.JsonResult(0, "ok")
+}
+
+// 用户登录历史.
+func (c *ManagerController) LoginLogs() {
+	c.Prepare()
+	c.TplName = "manager/login_logs.tpl"
+	c.Data["Action"] = "login_logs"
+
+	pageIndex, _ := c.GetInt("page", 1)
+	account := strings.TrimSpace(c.GetString("account"))
+
+	loginLogs, totalCount, err := models.NewLoginLog().FindToPager(0, account, pageIndex, conf.PageSize)
+	if err != nil {
+		c.Abort("500")
+	}
+	if totalCount > 0 {
+		pager := pagination.NewPagination(c.Ctx.Request, totalCount, conf.PageSize, c.BaseUrl())
+		c.Data["PageHtml"] = pager.HtmlPages()
+	} else {
+		c.Data["PageHtml"] = ""
+	}
+	c.Data["Lists"] = loginLogs
+	c.Data["Account"] = account
+}
+
+// 审计日志中记录的操作类型.
+var auditLogActions = []string{
+	"login", "login_failed", "member_status", "member_role", "member_update", "member_delete", "member_logout", "two_factor_reset",
+	"team_delete", "book_member_role", "book_member_remove", "book_transfer", "book_privacy", "book_identify", "book_delete",
+	"document_delete", "document_move", "document_copy", "history_delete", "history_restore", "attachment_delete", "blog_delete", "comment_delete", "comment_approve", "comment_spam",
+	"recycle_restore", "recycle_purge", "setting_update", "log_export", "custom_field_save", "custom_field_delete",
+	"robot_save", "robot_delete", "mail_template_save", "mail_template_reset", "mail_retry",
+}
+
+// 审计日志.
+func (c *ManagerController) Logs() {
+	c.Prepare()
+	c.TplName = "manager/logs.tpl"
+	c.Data["Action"] = "logs"
+
+	pageIndex, _ := c.GetInt("page", 1)
+	filter := c.loggerFilter()
+
+	loggers, totalCount, err := models.NewLogger().FindToPager(filter, pageIndex, conf.PageSize)
+	if err != nil {
+		c.Abort("500")
+	}
+	if totalCount > 0 {
+		pager := pagination.NewPagination(c.Ctx.Request, totalCount, conf.PageSize, c.BaseUrl())
+		c.Data["PageHtml"] = pager.HtmlPages()
+	} else {
+		c.Data["PageHtml"] = ""
+	}
+	c.Data["Lists"] = loggers
+	c.Data["Filter"] = filter
+	c.Data["StartDate"] = c.GetString("start_date")
+	c.Data["EndDate"] = c.GetString("end_date")
+	c.Data["Categories"] = []string{models.LoggerOperate, models.LoggerDocument, models.LoggerSystem, models.LoggerException}
+	c.Data["AuditActions"] = auditLogActions
+	c.Data["Query"] = c.Ctx.Request.URL.RawQuery
+}
+
+// 导出审计日志，支持 csv 和 json 格式.
+func (c *ManagerController) ExportLogs() {
+	c.Prepare()
+
+	filter := c.loggerFilter()
+	format := c.GetString("format", "csv")
+	if format != "csv" && format != "json" {
+		c.Abort("404")
+	}
+	c.addAuditLog(models.LoggerSystem, "log_export", format, nil, filter)
+
+	filename := "audit-logs-" + time.Now().Format("20060102150405") + "." + format
+	w := c.Ctx.ResponseWriter
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+
+	var err error
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		first := true
+		_, _ = w.Write([]byte("["))
+		err = models.NewLogger().FindAll(filter, func(logger *models.Logger) error {
+			b, err := json.Marshal(logger)
+			if err != nil {
+				return err
+			}
+			if !first {
+				_, _ = w.Write([]byte(","))
+			}
+			first = false
+			_, err = w.Write(b)
+			return err
+		})
+		_, _ = w.Write([]byte("]"))
+	} else {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		//写入 BOM，避免 Excel 打开中文乱码
+		_, _ = w.Write([]byte("\xEF\xBB\xBF"))
+		writer := csv.NewWriter(w)
+		_ = writer.Write([]string{"log_id", "create_time", "category", "action", "member_id", "account", "content", "original_data", "present_data", "ip_address", "user_agent"})
+		err = models.NewLogger().FindAll(filter, func(logger *models.Logger) error {
+			return writer.Write([]string{
+				strconv.FormatInt(logger.LoggerId, 10),
+				logger.CreateTime.Format("2006-01-02 15:04:05"),
+				logger.Category,
+				logger.Action,
+				strconv.Itoa(logger.MemberId),
+				logger.Account,
+				logger.Content,
+				logger.OriginalData,
+				logger.PresentData,
+				logger.IPAddress,
+				logger.UserAgent,
+			})
+		})
+		writer.Flush()
+	}
+	if err != nil {
+		logs.Error("导出审计日志失败 ->", err)
+	}
+	c.StopRun()
+}
+
+// loggerFilter 从请求参数中解析日志查询条件.
+func (c *ManagerController) loggerFilter() models.LoggerFilter {
+	filter := models.LoggerFilter{
+		Category: strings.TrimSpace(c.GetString("category")),
+		Action:   strings.TrimSpace(c.GetString("action")),
+		Account:  strings.TrimSpace(c.GetString("account")),
+	}
+	if t, err := time.ParseInLocation("2006-01-02", c.GetString("start_date"), time.Local); err == nil {
+		filter.StartTime = t
+	}
+	if t, err := time.ParseInLocation("2006-01-02", c.GetString("end_date"), time.Local); err == nil {
+		filter.EndTime = t.AddDate(0, 0, 1)
+	}
+	return filter
 }
 
 // 删除一个用户，并将该用户的所有信息转移到超级管理员上.
@@ -280,6 +495,60 @@ func (c *ManagerController) DeleteMember() {
 		logs.Error(err)
 		c.JsonResult(5002, i18n.Tr(c.Lang, "message.failed"))
 	}
+	c.addAuditLog(models.LoggerOperate, "member_delete", member.Account, member, nil)
+	c.JsonResult(0, "ok")
+}
+
+// 回收站.
+func (c *ManagerController) Recycle() {
+	c.Prepare()
+	c.TplName = "manager/recycle.tpl"
+	c.Data["Action"] = "recycle"
+
+	pageIndex, _ := c.GetInt("page", 1)
+
+	list, totalCount, err := models.NewRecycleBin().FindToPager(0, pageIndex, conf.PageSize)
+	if err != nil {
+		c.Abort("500")
+	}
+	if totalCount > 0 {
+		pager := pagination.NewPagination(c.Ctx.Request, totalCount, conf.PageSize, c.BaseUrl())
+		c.Data["PageHtml"] = pager.HtmlPages()
+	} else {
+		c.Data["PageHtml"] = ""
+	}
+	c.Data["Lists"] = list
+}
+
+// 恢复回收站中的项目或文档.
+func (c *ManagerController) RecycleRestore() {
+	c.Prepare()
+
+	recycleId, _ := c.GetInt("recycle_id", 0)
+	item, err := models.NewRecycleBin().Find(recycleId)
+	if err != nil {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.param_error"))
+	}
+	if err := item.Restore(); err != nil {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.failed")+": "+err.Error())
+	}
+	c.addAuditLog(models.LoggerDocument, "recycle_restore", item.Title, nil, item)
+	c.JsonResult(0, "ok")
+}
+
+// 彻底删除回收站中的项目或文档.
+func (c *ManagerController) RecyclePurge() {
+	c.Prepare()
+
+	recycleId, _ := c.GetInt("recycle_id", 0)
+	item, err := models.NewRecycleBin().Find(recycleId)
+	if err != nil {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.param_error"))
+	}
+	if err := item.Purge(); err != nil {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.failed"))
+	}
+	c.addAuditLog(models.LoggerDocument, "recycle_purge", item.Title, item, nil)
 	c.JsonResult(0, "ok")
 }
 
@@ -305,12 +574,32 @@ func (c *ManagerController) Books() {
 	} else {
 		c.Data["PageHtml"] = ""
 	}
+	bookIds := make([]int, 0, len(books))
 	for i, book := range books {
 		books[i].Description = utils.StripTags(string(blackfriday.Run([]byte(book.Description))))
 		books[i].ModifyTime = book.ModifyTime.Local()
 		books[i].CreateTime = book.CreateTime.Local()
+		bookIds = append(bookIds, book.BookId)
+	}
+	linkChecks, err := models.NewLinkCheck().FindByBookIds(bookIds)
+	if err != nil {
+		logs.Error("查询链接检查结果失败 ->", err)
 	}
 	c.Data["Lists"] = books
+	c.Data["LinkChecks"] = linkChecks
+}
+
+// CheckLinks 在后台检查全部项目的失效链接.
+func (c *ManagerController) CheckLinks() {
+	c.Prepare()
+
+	if err := models.CheckAllBookLinks(); err == models.ErrLinkCheckRunning {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.link_check_running"))
+	} else if err != nil {
+		logs.Error("全站链接检查失败 ->", err)
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.failed"))
+	}
+	c.JsonResult(0, i18n.Tr(c.Lang, "message.link_check_started"))
 }
 
 // 编辑项目.
@@ -447,9 +736,12 @@ func (c *ManagerController) DeleteBook() {
 	if bookId <= 0 {
 		c.JsonResult(6001, i18n.Tr(c.Lang, "message.param_error"))
 	}
-	book := models.NewBook()
+	book, err := models.NewBook().Find(bookId)
 
-	err := book.ThoroughDeleteBook(bookId)
+	if err == orm.ErrNoRows {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.item_not_exist"))
+	}
+	err = models.NewRecycleBin().AddBook(bookId, c.Member.MemberId)
 
 	if err == orm.ErrNoRows {
 		c.JsonResult(6002, i18n.Tr(c.Lang, "message.item_not_exist"))
@@ -458,6 +750,7 @@ func (c *ManagerController) DeleteBook() {
 		logs.Error("删除失败 -> ", err)
 		c.JsonResult(6003, i18n.Tr(c.Lang, "message.failed"))
 	}
+	c.addAuditLog(models.LoggerDocument, "book_delete", book.Identify, book, nil)
 	c.JsonResult(0, "ok")
 }
 
@@ -503,10 +796,25 @@ func (c *ManagerController) Setting() {
 	options, err := models.NewOption().All()
 
 	if c.Ctx.Input.IsPost() {
+		original := make(map[string]string)
+		present := make(map[string]string)
 		for _, item := range options {
-			item.OptionValue = c.GetString(item.OptionName)
+			value := c.GetString(item.OptionName)
+			if value != item.OptionValue {
+				original[item.OptionName] = item.OptionValue
+				present[item.OptionName] = value
+			}
+			item.OptionValue = value
 			item.InsertOrUpdate()
 		}
+		if len(present) > 0 {
+			c.addAuditLog(models.LoggerSystem, "setting_update", "options", original, present)
+		}
+		if value, ok := original["PASSWORD_EXPIRE_DAYS"]; ok && models.NewPasswordPolicy("", "", value).ExpireDays == 0 && models.GetPasswordPolicy().ExpireDays > 0 {
+			if err := models.StartPasswordExpiry(); err != nil {
+				logs.Error("补写密码修改时间失败 ->", err)
+			}
+		}
 		c.JsonResult(0, "ok")
 	}
 
@@ -519,6 +827,11 @@ func (c *ManagerController) Setting() {
 		c.Data[item.OptionName] = item.OptionValue
 	}
 
+	c.Data["i18n_map"] = languageNames()
+}
+
+// languageNames 配置中支持的语言及其显示名称.
+func languageNames() map[string]string {
 	i18nMapStrs, err := web.AppConfig.String("i18n_map")
 	if err != nil {
 		logs.Error("web.AppConfig `i18n_map` not found")
@@ -530,7 +843,7 @@ func (c *ManagerController) Setting() {
 		logs.Error("json `i18nList` Unmarshal fail")
 		i18nMap = make(map[string]string)
 	}
-	c.Data["i18n_map"] = i18nMap
+	return i18nMap
 }
 
 // Transfer 转让项目.
@@ -577,6 +890,11 @@ func (c *ManagerController) Transfer() {
 		logs.Error("Transfer => ", err)
 		c.JsonResult(6008, err.Error())
 	}
+	original := map[string]interface{}{"member_id": rel.MemberId}
+	if founder, err := models.NewMember().Find(rel.MemberId); err == nil {
+		original["account"] = founder.Account
+	}
+	c.addAuditLog(models.LoggerDocument, "book_transfer", book.Identify, original, map[string]interface{}{"member_id": member.MemberId, "account": member.Account})
 	c.JsonResult(0, "ok")
 }
 
@@ -586,7 +904,37 @@ func (c *ManagerController) Comments() {
 	if !c.Member.IsAdministrator() {
 		c.Abort("403")
 	}
+	c.Data["Action"] = "comments"
+	pageIndex, _ := c.GetInt("page", 1)
+	approved, _ := c.GetInt("approved", models.CommentPending)
+
+	orphaned, _ := c.GetBool("orphaned", false)
+
+	list, totalCount, err := models.FindModerationComments(0, approved, pageIndex, conf.PageSize)
+	if orphaned {
+		list, totalCount, err = models.FindOrphanedComments(0, pageIndex, conf.PageSize)
+	}
+	if err != nil {
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	if totalCount > 0 {
+		pager := pagination.NewPagination(c.Ctx.Request, totalCount, conf.PageSize, c.BaseUrl())
+		c.Data["PageHtml"] = pager.HtmlPages()
+	} else {
+		c.Data["PageHtml"] = ""
+	}
+	c.Data["Lists"] = list
+	c.Data["Approved"] = approved
+	c.Data["Counts"] = models.CountModerationComments(0)
+	c.Data["Orphaned"] = orphaned
+	c.Data["OrphanedCount"] = models.CountOrphanedComments(0)
+	c.Data["ModerateUrl"] = conf.URLFor("ManagerController.ModerateComments")
+}
 
+// ModerateComments 批量审核全部项目中的评论.
+func (c *ManagerController) ModerateComments() {
+	c.Prepare()
+	commentModerator{&c.BaseController}.moderate(0)
 }
 
 // DeleteComment 标记评论为已删除
@@ -610,6 +958,7 @@ func (c *ManagerController) DeleteComment() {
 	if err := comment.Update("approved"); err != nil {
 		c.JsonResult(6003, "删除评论失败")
 	}
+	c.addAuditLog(models.LoggerDocument, "comment_delete", strconv.Itoa(comment.CommentId), comment, nil)
 	c.JsonResult(0, "ok", comment)
 }
 
@@ -638,6 +987,7 @@ func (c *ManagerController) PrivatelyOwned() {
 		c.JsonResult(6001, err.Error())
 	}
 
+	original := book.PrivatelyOwned
 	book.PrivatelyOwned = state
 
 	logs.Info("", state, status)
@@ -648,6 +998,7 @@ func (c *ManagerController) PrivatelyOwned() {
 		logs.Error("PrivatelyOwned => ", err)
 		c.JsonResult(6004, i18n.Tr(c.Lang, "message.failed"))
 	}
+	c.addAuditLog(models.LoggerDocument, "book_privacy", book.Identify, map[string]int{"privately_owned": original}, map[string]int{"privately_owned": state})
 	c.JsonResult(0, "ok")
 }
 
@@ -702,7 +1053,7 @@ func (c *ManagerController) AttachClean() {
 			searchList, err := models.NewDocumentSearchResult().SearchAllDocument(item.HttpPath)
 			if err != nil {
 				c.Abort("500")
-			} else if len(searchList) == 0 {
+			} else if len(searchList) == 0 && !models.NewRecycleBin().IsDocumentRecycled(item.DocumentId) {
 				logs.Info("delete file:", item.FilePath)
 				item.FilePath = p
 				if err := item.Delete(); err != nil {
@@ -760,12 +1111,14 @@ func (c *ManagerController) AttachDelete() {
 		logs.Error("AttachDelete => ", err)
 		c.JsonResult(6001, err.Error())
 	}
+	original := *attach
 	attach.FilePath = filepath.Join(conf.WorkingDirectory, attach.FilePath)
 
 	if err := attach.Delete(); err != nil {
 		logs.Error("AttachDelete => ", err)
 		c.JsonResult(6002, err.Error())
 	}
+	c.addAuditLog(models.LoggerDocument, "attachment_delete", original.FileName, original, nil)
 	c.JsonResult(0, "ok")
 }
 
@@ -935,8 +1288,12 @@ func (c *ManagerController) TeamDelete() {
 	if teamId <= 0 {
 		c.JsonResult(5002, i18n.Tr(c.Lang, "message.team_id_empty"))
 	}
-	err := models.NewTeam().Delete(teamId)
+	team, err := models.NewTeam().First(teamId)
+	c.CheckJsonError(5001, err)
+
+	err = models.NewTeam().Delete(teamId)
 	c.CheckJsonError(5001, err)
+	c.addAuditLog(models.LoggerOperate, "team_delete", team.TeamName, team, nil)
 
 	c.JsonResult(0, "OK")
 }
@@ -1243,3 +1600,233 @@ func (c *ManagerController) ItemsetsDelete() {
 	}
 	c.JsonResult(0, "OK")
 }
+
+// 项目空间的文档自定义字段.
+func (c *ManagerController) ItemsetsFields() {
+	c.Prepare()
+	c.TplName = "manager/itemsets_fields.tpl"
+	c.Data["Action"] = "itemsets"
+
+	itemId, _ := c.GetInt(":id", 0)
+	item, err := models.NewItemsets().First(itemId)
+	if err != nil {
+		c.ShowErrorPage(404, i18n.Tr(c.Lang, "message.project_space_not_exist"))
+	}
+	fields, err := models.NewCustomField().FindByItemId(item.ItemId)
+	if err != nil {
+		logs.Error("查询项目空间字段失败 ->", err)
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	c.Data["Model"] = item
+	c.Data["Lists"] = fields
+	c.Data["FieldTypes"] = models.FieldTypes
+}
+
+// 添加或修改项目空间的文档自定义字段.
+func (c *ManagerController) ItemsetsFieldSave() {
+	c.Prepare()
+	itemId, _ := c.GetInt(":id", 0)
+	item, err := models.NewItemsets().First(itemId)
+	if err != nil {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.project_space_not_exist"))
+	}
+
+	field := models.NewCustomField()
+	if fieldId, _ := c.GetInt("field_id", 0); fieldId > 0 {
+		if _, err := field.Find(fieldId); err != nil || field.ItemId != item.ItemId {
+			c.JsonResult(6002, i18n.Tr(c.Lang, "message.field_not_exist"))
+		}
+	}
+	field.ItemId = item.ItemId
+	customFieldHandler{&c.BaseController}.save(field)
+}
+
+// 删除项目空间的文档自定义字段.
+func (c *ManagerController) ItemsetsFieldDelete() {
+	c.Prepare()
+	itemId, _ := c.GetInt(":id", 0)
+	fieldId, _ := c.GetInt("field_id", 0)
+
+	field, err := models.NewCustomField().Find(fieldId)
+	if err != nil || field.ItemId != itemId || itemId <= 0 {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.field_not_exist"))
+	}
+	customFieldHandler{&c.BaseController}.delete(field)
+}
+
+// 发送测试邮件，直接使用当前的 SMTP 配置发送，不经过发送队列.
+func (c *ManagerController) SendTestMail() {
+	c.Prepare()
+	email := strings.TrimSpace(c.GetString("email", c.Member.Email))
+	if email == "" {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.email_empty"))
+	}
+	if !conf.GetMailConfig().EnableMail {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.mail_service_not_enable"))
+	}
+	content, err := models.RenderMailTemplate(models.MailTest, c.Lang, map[string]interface{}{"Member": c.Member})
+	if err != nil {
+		logs.Error("渲染测试邮件失败 ->", err)
+		c.JsonResult(6003, err.Error())
+	}
+	if err := models.SendMailContent(email, content); err != nil {
+		logs.Error("发送测试邮件失败 ->", email, err)
+		c.JsonResult(6004, i18n.Tr(c.Lang, "message.mail_test_failed", err.Error()))
+	}
+	c.JsonResult(0, i18n.Tr(c.Lang, "message.mail_test_sent", email))
+}
+
+// MailTemplates 邮件模板列表.
+func (c *ManagerController) MailTemplates() {
+	c.Prepare()
+	c.TplName = "manager/mail_templates.tpl"
+	c.Data["Action"] = "mail"
+
+	templates, err := models.NewMailTemplate().FindAll()
+	if err != nil {
+		logs.Error("查询邮件模板失败 ->", err)
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	customized := make(map[string]*models.MailTemplate, len(templates))
+	for _, item := range templates {
+		customized[item.Name+"/"+item.Lang] = item
+	}
+	c.Data["Lists"] = models.MailTemplateDefines
+	c.Data["Customized"] = customized
+	c.Data["Languages"] = languageNames()
+}
+
+// mailTemplate 获取请求中的邮件模板和语言.
+func (c *ManagerController) mailTemplate() (*models.MailTemplateDefine, *models.MailTemplate) {
+	define := models.FindMailTemplateDefine(c.GetString("name"))
+	lang := c.GetString("lang")
+	if define == nil || !i18n.IsExist(lang) {
+		if c.Ctx.Input.IsAjax() || c.Ctx.Input.IsPost() {
+			c.JsonResult(6001, i18n.Tr(c.Lang, "message.mail_template_not_exist"))
+		}
+		c.ShowErrorPage(404, i18n.Tr(c.Lang, "message.mail_template_not_exist"))
+	}
+	tpl, err := models.NewMailTemplate().Find(define.Name, lang)
+	if err != nil {
+		if err != orm.ErrNoRows {
+			logs.Error("查询邮件模板失败 ->", err)
+			c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+		}
+		tpl = &models.MailTemplate{Name: define.Name, Lang: lang}
+	}
+	return define, tpl
+}
+
+// MailTemplateEdit 修改邮件模板.
+func (c *ManagerController) MailTemplateEdit() {
+	c.Prepare()
+	define, tpl := c.mailTemplate()
+
+	if c.Ctx.Input.IsPost() {
+		original := *tpl
+		tpl.Subject = strings.TrimSpace(c.GetString("subject"))
+		tpl.Html = c.GetString("html")
+		tpl.Text = c.GetString("text")
+		tpl.ModifyAt = c.Member.MemberId
+		if err := tpl.Save(); err != nil {
+			c.JsonResult(6002, i18n.Tr(c.Lang, "message.mail_template_invalid", err.Error()))
+		}
+		c.addAuditLog(models.LoggerSystem, "mail_template_save", tpl.Name+"/"+tpl.Lang, original, tpl)
+		c.JsonResult(0, "ok")
+	}
+	c.TplName = "manager/mail_template_edit.tpl"
+	c.Data["Action"] = "mail"
+
+	source, err := ioutil.ReadFile(filepath.Join(web.BConfig.WebConfig.ViewsPath, define.View))
+	if err != nil {
+		logs.Error("读取默认邮件模板失败 ->", define.View, err)
+	}
+	c.Data["Define"] = define
+	c.Data["Model"] = tpl
+	c.Data["DefaultHtml"] = string(source)
+	c.Data["DefaultSubject"] = define.Subject(tpl.Lang, define.SampleData(tpl.Lang))
+	c.Data["Variables"] = define.Variables(tpl.Lang)
+	c.Data["LanguageName"] = languageNames()[tpl.Lang]
+}
+
+// MailTemplateReset 删除修改，恢复为默认模板.
+func (c *ManagerController) MailTemplateReset() {
+	c.Prepare()
+	_, tpl := c.mailTemplate()
+
+	if err := tpl.Delete(); err != nil {
+		logs.Error("恢复默认邮件模板失败 ->", err)
+		c.JsonResult(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	c.addAuditLog(models.LoggerSystem, "mail_template_reset", tpl.Name+"/"+tpl.Lang, tpl, nil)
+	c.JsonResult(0, "ok")
+}
+
+// MailTemplatePreview 使用示例数据预览邮件，POST 时预览尚未保存的修改.
+func (c *ManagerController) MailTemplatePreview() {
+	c.Prepare()
+	define, tpl := c.mailTemplate()
+
+	if c.Ctx.Input.IsPost() {
+		tpl.Subject = strings.TrimSpace(c.GetString("subject"))
+		tpl.Html = c.GetString("html")
+		tpl.Text = c.GetString("text")
+	}
+	content, err := tpl.Render(define.SampleData(tpl.Lang))
+	if err != nil {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.mail_template_invalid", err.Error()))
+	}
+	if c.Ctx.Input.IsPost() {
+		c.JsonResult(0, "ok", content)
+	}
+	c.Ctx.Output.Header("Content-Type", "text/html; charset=utf-8")
+	_ = c.Ctx.Output.Body([]byte(content.HTML))
+	c.StopRun()
+}
+
+// MailQueue 邮件发送队列.
+func (c *ManagerController) MailQueue() {
+	c.Prepare()
+	c.TplName = "manager/mail_queue.tpl"
+	c.Data["Action"] = "mail"
+
+	pageIndex, _ := c.GetInt("page", 1)
+	status, _ := c.GetInt("status", -1)
+
+	list, totalCount, err := models.NewMailQueue().FindToPager(status, pageIndex, conf.PageSize)
+	if err != nil {
+		logs.Error("查询邮件发送队列失败 ->", err)
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	if totalCount > 0 {
+		pager := pagination.NewPagination(c.Ctx.Request, totalCount, conf.PageSize, c.BaseUrl())
+		c.Data["PageHtml"] = pager.HtmlPages()
+	} else {
+		c.Data["PageHtml"] = ""
+	}
+	c.Data["Lists"] = list
+	c.Data["Status"] = status
+}
+
+// MailQueueRetry 立即重新发送邮件.
+func (c *ManagerController) MailQueueRetry() {
+	c.Prepare()
+	mailId, _ := c.GetInt("mail_id", 0)
+
+	item, err := models.NewMailQueue().Find(mailId)
+	if err != nil {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.mail_not_exist"))
+	}
+	if item.Status == models.MailStatusSent {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.mail_already_sent"))
+	}
+	if item.Status == models.MailStatusSending {
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.mail_sending"))
+	}
+	if err := item.Retry(); err != nil {
+		logs.Error("重新发送邮件失败 ->", mailId, err)
+		c.JsonResult(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	c.addAuditLog(models.LoggerSystem, "mail_retry", item.MailTo, nil, map[string]interface{}{"mail_id": item.MailId, "subject": item.Subject})
+	c.JsonResult(0, "ok")
+}
@@ -0,0 +1,113 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/i18n"
+	"github.com/mindoc-org/mindoc/conf"
+	"github.com/mindoc-org/mindoc/models"
+	"github.com/mindoc-org/mindoc/utils/pagination"
+)
+
+type NotificationController struct {
+	BaseController
+}
+
+// Index 站内通知列表.
+func (c *NotificationController) Index() {
+	c.TplName = "notification/index.tpl"
+
+	pageIndex, _ := c.GetInt("page", 1)
+	unread, _ := c.GetBool("unread", false)
+
+	list, totalCount, err := models.NewNotification().FindToPager(c.Member.MemberId, unread, pageIndex, conf.PageSize)
+	if err != nil {
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	for _, item := range list {
+		item.ResolveMessage(c.Lang)
+	}
+	if totalCount > 0 {
+		pager := pagination.NewPagination(c.Ctx.Request, totalCount, conf.PageSize, c.BaseUrl())
+		c.Data["PageHtml"] = pager.HtmlPages()
+	} else {
+		c.Data["PageHtml"] = ""
+	}
+	c.Data["Lists"] = list
+	c.Data["Unread"] = unread
+}
+
+// List 分页获取站内通知.
+func (c *NotificationController) List() {
+	pageIndex, _ := c.GetInt("page", 1)
+	pageSize, _ := c.GetInt("size", conf.PageSize)
+	unread, _ := c.GetBool("unread", false)
+
+	if pageIndex <= 0 {
+		pageIndex = 1
+	}
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = conf.PageSize
+	}
+	list, totalCount, err := models.NewNotification().FindToPager(c.Member.MemberId, unread, pageIndex, pageSize)
+	if err != nil {
+		c.JsonResult(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	for _, item := range list {
+		item.ResolveMessage(c.Lang)
+	}
+	c.JsonResult(0, "ok", map[string]interface{}{
+		"total":  totalCount,
+		"unread": models.NewNotification().CountUnread(c.Member.MemberId),
+		"list":   list,
+	})
+}
+
+// Unread 获取未读通知数量.
+func (c *NotificationController) Unread() {
+	c.JsonResult(0, "ok", map[string]interface{}{
+		"unread": models.NewNotification().CountUnread(c.Member.MemberId),
+	})
+}
+
+// Read 将通知标记为已读，没有指定通知时标记全部通知.
+func (c *NotificationController) Read() {
+	var ids []int
+	for _, value := range c.GetStrings("id") {
+		if id, err := strconv.Atoi(value); err == nil && id > 0 {
+			ids = append(ids, id)
+		}
+	}
+	all, _ := c.GetBool("all", false)
+	if len(ids) == 0 && !all {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.param_error"))
+	}
+	if _, err := models.NewNotification().MarkRead(c.Member.MemberId, ids); err != nil {
+		logs.Error("标记通知已读失败 ->", c.Member.MemberId, err)
+		c.JsonResult(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	c.JsonResult(0, "ok", map[string]interface{}{
+		"unread": models.NewNotification().CountUnread(c.Member.MemberId),
+	})
+}
+
+// Open 将通知标记为已读并跳转到通知的链接.
+func (c *NotificationController) Open() {
+	id, _ := c.GetInt(":id", 0)
+
+	notification, err := models.NewNotification().Find(id, c.Member.MemberId)
+	if err != nil {
+		c.ShowErrorPage(404, i18n.Tr(c.Lang, "message.page_not_existed"))
+	}
+	if notification.IsRead == 0 {
+		if _, err := notification.MarkRead(c.Member.MemberId, []int{notification.NotificationId}); err != nil {
+			logs.Error("标记通知已读失败 ->", notification.NotificationId, err)
+		}
+	}
+	if notification.Url == "" {
+		c.Redirect(conf.URLFor("NotificationController.Index"), 302)
+		return
+	}
+	c.Redirect(notification.Url, 302)
+}
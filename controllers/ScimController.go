@@ -0,0 +1,367 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+	"github.com/mindoc-org/mindoc/conf"
+	"github.com/mindoc-org/mindoc/models"
+)
+
+// scimAuditAccount 审计日志中 SCIM 客户端的操作人账号.
+const scimAuditAccount = "scim"
+
+// ScimController SCIM 2.0 用户同步接口，供身份平台自动创建、更新、禁用用户并同步团队.
+type ScimController struct {
+	web.Controller
+}
+
+func (c *ScimController) Prepare() {
+	c.EnableXSRF = false
+
+	scim := conf.GetScimConfig()
+	if !scim.Enable || scim.Token == "" {
+		c.scimError(http.StatusNotFound, "", "SCIM 接口未启用")
+	}
+	auth := c.Ctx.Input.Header("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[0:7], "Bearer ") {
+		c.scimError(http.StatusUnauthorized, "", "缺少认证令牌")
+	}
+	token := strings.TrimSpace(auth[7:])
+	if subtle.ConstantTimeCompare([]byte(token), []byte(scim.Token)) != 1 {
+		c.scimError(http.StatusUnauthorized, "", "认证令牌无效")
+	}
+}
+
+// Users 查询用户列表.
+func (c *ScimController) Users() {
+	attr, value, err := models.ParseScimFilter(c.GetString("filter"))
+	if err != nil {
+		c.scimError(http.StatusBadRequest, "invalidFilter", err.Error())
+	}
+	startIndex, count := c.pagination()
+
+	members, totalCount, err := models.FindScimUsers(attr, value, startIndex, count)
+	if err == models.ErrScimInvalidFilter {
+		c.scimError(http.StatusBadRequest, "invalidFilter", err.Error())
+	}
+	if err != nil {
+		logs.Error("SCIM 查询用户失败 ->", err)
+		c.scimError(http.StatusInternalServerError, "", err.Error())
+	}
+	resources := make([]interface{}, 0, len(members))
+	for _, member := range members {
+		resources = append(resources, models.NewScimUser(member))
+	}
+	c.scimResult(http.StatusOK, models.NewScimListResponse(totalCount, startIndex, resources))
+}
+
+// User 查询单个用户.
+func (c *ScimController) User() {
+	member := c.findMember()
+	c.scimResult(http.StatusOK, models.NewScimUser(member))
+}
+
+// CreateUser 创建用户.
+func (c *ScimController) CreateUser() {
+	var user models.ScimUser
+	c.decode(&user)
+
+	member, err := models.AddScimMember(&user)
+	if err == models.ErrMemberExist {
+		c.scimError(http.StatusConflict, "uniqueness", err.Error())
+	}
+	if err != nil {
+		c.scimError(http.StatusBadRequest, "invalidValue", err.Error())
+	}
+	logs.Info("SCIM 创建用户 ->", member.Account)
+	c.scimResult(http.StatusCreated, models.NewScimUser(member))
+}
+
+// ReplaceUser 使用完整的资源替换用户信息.
+func (c *ScimController) ReplaceUser() {
+	member := c.findMember()
+
+	var user models.ScimUser
+	c.decode(&user)
+
+	if user.UserName == "" {
+		user.UserName = member.Account
+	}
+	c.saveMember(member, &user)
+}
+
+// PatchUser 局部更新用户信息，身份平台通常使用 active=false 禁用用户.
+func (c *ScimController) PatchUser() {
+	member := c.findMember()
+
+	var patch models.ScimPatchRequest
+	c.decode(&patch)
+
+	user := models.NewScimUser(member)
+	if err := user.ApplyPatch(patch.Operations); err != nil {
+		c.scimError(http.StatusBadRequest, "invalidPath", err.Error())
+	}
+	c.saveMember(member, user)
+}
+
+// DeleteUser 删除用户.为保留用户创建的文档，仅将用户禁用.
+func (c *ScimController) DeleteUser() {
+	member := c.findMember()
+
+	if member.Role == conf.MemberSuperRole {
+		c.scimError(http.StatusBadRequest, "mutability", "不能禁用超级管理员")
+	}
+	originalStatus := member.Status
+	member.Status = 1
+
+	if err := member.Update("status"); err != nil {
+		c.scimError(http.StatusInternalServerError, "", err.Error())
+	}
+	_ = models.NewMemberSession().DeleteByMemberId(member.MemberId, "")
+	c.addAuditLog("member_status", member.Account, map[string]int{"status": originalStatus}, map[string]int{"status": member.Status})
+	logs.Info("SCIM 禁用用户 ->", member.Account)
+	c.Ctx.Output.SetStatus(http.StatusNoContent)
+	c.StopRun()
+}
+
+// Groups 查询团队列表.
+func (c *ScimController) Groups() {
+	attr, value, err := models.ParseScimFilter(c.GetString("filter"))
+	if err != nil {
+		c.scimError(http.StatusBadRequest, "invalidFilter", err.Error())
+	}
+	startIndex, count := c.pagination()
+
+	teams, totalCount, err := models.FindScimGroups(attr, value, startIndex, count)
+	if err == models.ErrScimInvalidFilter {
+		c.scimError(http.StatusBadRequest, "invalidFilter", err.Error())
+	}
+	if err != nil {
+		logs.Error("SCIM 查询团队失败 ->", err)
+		c.scimError(http.StatusInternalServerError, "", err.Error())
+	}
+	excludeMembers := strings.Contains(c.GetString("excludedAttributes"), "members")
+
+	resources := make([]interface{}, 0, len(teams))
+	for _, team := range teams {
+		var members []*models.TeamMember
+		if !excludeMembers {
+			members, _ = models.NewTeamMember().FindAllByTeamId(team.TeamId)
+		}
+		resources = append(resources, models.NewScimGroup(team, members))
+	}
+	c.scimResult(http.StatusOK, models.NewScimListResponse(totalCount, startIndex, resources))
+}
+
+// Group 查询单个团队.
+func (c *ScimController) Group() {
+	team := c.findTeam()
+	c.groupResult(http.StatusOK, team)
+}
+
+// CreateGroup 创建团队.
+func (c *ScimController) CreateGroup() {
+	var group models.ScimGroup
+	c.decode(&group)
+
+	founder, err := models.NewMember().FindByFieldFirst("role", int(conf.MemberSuperRole))
+	if err != nil {
+		logs.Error("SCIM 查询超级管理员失败 ->", err)
+		c.scimError(http.StatusInternalServerError, "", "未能找到超级管理员")
+	}
+	team := models.NewTeam()
+	team.TeamName = strings.TrimSpace(group.DisplayName)
+	team.MemberId = founder.MemberId
+
+	if err := team.Save(); err != nil {
+		c.scimError(http.StatusConflict, "uniqueness", err.Error())
+	}
+	if err := models.AddScimGroupMembers(team.TeamId, group.MemberIds()); err != nil {
+		c.scimError(http.StatusBadRequest, "invalidValue", err.Error())
+	}
+	logs.Info("SCIM 创建团队 ->", team.TeamName)
+	c.groupResult(http.StatusCreated, team)
+}
+
+// ReplaceGroup 使用完整的资源替换团队名称和成员.
+func (c *ScimController) ReplaceGroup() {
+	team := c.findTeam()
+
+	var group models.ScimGroup
+	c.decode(&group)
+
+	if name := strings.TrimSpace(group.DisplayName); name != "" && name != team.TeamName {
+		team.TeamName = name
+		if err := team.Save("team_name"); err != nil {
+			c.scimError(http.StatusBadRequest, "invalidValue", err.Error())
+		}
+	}
+	if err := models.SyncScimGroupMembers(team.TeamId, group.MemberIds()); err != nil {
+		c.scimError(http.StatusBadRequest, "invalidValue", err.Error())
+	}
+	c.groupResult(http.StatusOK, team)
+}
+
+// PatchGroup 局部更新团队，用于增加或移除成员.
+func (c *ScimController) PatchGroup() {
+	team := c.findTeam()
+
+	var patch models.ScimPatchRequest
+	c.decode(&patch)
+
+	if err := models.ApplyScimGroupPatch(team, patch.Operations); err != nil {
+		if err == models.ErrScimInvalidPath {
+			c.scimError(http.StatusBadRequest, "invalidPath", err.Error())
+		}
+		c.scimError(http.StatusBadRequest, "invalidValue", err.Error())
+	}
+	c.groupResult(http.StatusOK, team)
+}
+
+// DeleteGroup 删除团队.
+func (c *ScimController) DeleteGroup() {
+	team := c.findTeam()
+
+	if err := models.NewTeam().Delete(team.TeamId); err != nil {
+		c.scimError(http.StatusInternalServerError, "", err.Error())
+	}
+	logs.Info("SCIM 删除团队 ->", team.TeamName)
+	c.Ctx.Output.SetStatus(http.StatusNoContent)
+	c.StopRun()
+}
+
+func (c *ScimController) saveMember(member *models.Member, user *models.ScimUser) {
+	original := scimAuditData(member)
+	user.ToMember(member)
+
+	if member.Role == conf.MemberSuperRole && member.Status != 0 {
+		c.scimError(http.StatusBadRequest, "mutability", "不能禁用超级管理员")
+	}
+	if ok, err := regexp.MatchString(conf.RegexpAccount, member.Account); !ok || err != nil {
+		c.scimError(http.StatusBadRequest, "invalidValue", models.ErrMemberAccountFormatError.Error())
+	}
+	if err := member.Valid(true); err != nil {
+		c.scimError(http.StatusBadRequest, "invalidValue", err.Error())
+	}
+	if exist, err := models.NewMember().FindByAccount(member.Account); err == nil && exist.MemberId != member.MemberId {
+		c.scimError(http.StatusConflict, "uniqueness", models.ErrMemberExist.Error())
+	}
+	if err := member.Update("account", "real_name", "email", "phone", "status"); err != nil {
+		c.scimError(http.StatusBadRequest, "invalidValue", err.Error())
+	}
+	if member.Status != 0 {
+		_ = models.NewMemberSession().DeleteByMemberId(member.MemberId, "")
+	}
+	c.addAuditLog("member_update", member.Account, original, scimAuditData(member))
+	c.scimResult(http.StatusOK, models.NewScimUser(member))
+}
+
+// scimAuditData 审计日志中记录的 SCIM 可修改的用户信息.
+func scimAuditData(member *models.Member) map[string]interface{} {
+	return map[string]interface{}{
+		"account":   member.Account,
+		"real_name": member.RealName,
+		"email":     member.Email,
+		"phone":     member.Phone,
+		"status":    member.Status,
+	}
+}
+
+// addAuditLog 记录审计日志，操作人为 SCIM 客户端.
+func (c *ScimController) addAuditLog(action, content string, original, present interface{}) {
+	logger := models.NewLogger()
+	logger.Category = models.LoggerOperate
+	logger.Action = action
+	logger.Content = content
+	logger.Account = scimAuditAccount
+	logger.IPAddress = c.Ctx.Input.IP()
+	logger.UserAgent = c.Ctx.Input.UserAgent()
+	if err := logger.SetData(original, present).Add(); err != nil {
+		logs.Error("记录审计日志失败 ->", action, err)
+	}
+}
+
+func (c *ScimController) groupResult(status int, team *models.Team) {
+	members, err := models.NewTeamMember().FindAllByTeamId(team.TeamId)
+	if err != nil {
+		logs.Error("SCIM 查询团队成员失败 ->", err)
+	}
+	c.scimResult(status, models.NewScimGroup(team, members))
+}
+
+func (c *ScimController) findMember() *models.Member {
+	memberId, _ := strconv.Atoi(c.Ctx.Input.Param(":id"))
+	if memberId <= 0 {
+		c.scimError(http.StatusNotFound, "", models.ErrMemberNoExist.Error())
+	}
+	member, err := models.NewMember().Find(memberId)
+	if err == orm.ErrNoRows {
+		c.scimError(http.StatusNotFound, "", models.ErrMemberNoExist.Error())
+	}
+	if err != nil {
+		c.scimError(http.StatusInternalServerError, "", err.Error())
+	}
+	return member
+}
+
+func (c *ScimController) findTeam() *models.Team {
+	teamId, _ := strconv.Atoi(c.Ctx.Input.Param(":id"))
+	team, err := models.NewTeam().First(teamId)
+	if err == orm.ErrNoRows {
+		c.scimError(http.StatusNotFound, "", "团队不存在")
+	}
+	if err != nil {
+		c.scimError(http.StatusInternalServerError, "", err.Error())
+	}
+	return team
+}
+
+// SCIM 分页参数，startIndex 从1开始.
+func (c *ScimController) pagination() (startIndex, count int) {
+	startIndex, _ = c.GetInt("startIndex", 1)
+	count, _ = c.GetInt("count", 100)
+
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = 0
+	} else if count > 500 {
+		count = 500
+	}
+	return
+}
+
+func (c *ScimController) decode(v interface{}) {
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, v); err != nil {
+		c.scimError(http.StatusBadRequest, "invalidSyntax", err.Error())
+	}
+}
+
+func (c *ScimController) scimError(status int, scimType string, detail string) {
+	c.scimResult(status, models.NewScimError(status, scimType, detail))
+}
+
+// scimResult 响应 SCIM json 结果
+func (c *ScimController) scimResult(status int, data interface{}) {
+	returnJSON, err := json.Marshal(data)
+	if err != nil {
+		logs.Error(err)
+	}
+	c.Ctx.ResponseWriter.Header().Set("Content-Type", "application/scim+json; charset=utf-8")
+	c.Ctx.ResponseWriter.Header().Set("Cache-Control", "no-cache, no-store")
+	c.Ctx.ResponseWriter.WriteHeader(status)
+
+	if _, err := c.Ctx.ResponseWriter.Write(returnJSON); err != nil {
+		logs.Error(err)
+	}
+	c.StopRun()
+}
@@ -29,17 +29,33 @@ func (c *SearchController) Index() {
 	}
 
 	keyword := c.GetString("keyword")
+	field := strings.TrimSpace(c.GetString("field"))
+	fieldValue := strings.TrimSpace(c.GetString("value"))
 	pageIndex, _ := c.GetInt("page", 1)
 
 	c.Data["BaseUrl"] = c.BaseUrl()
+	c.Data["Field"] = field
+	c.Data["FieldValue"] = fieldValue
 
-	if keyword != "" {
+	if fields, err := models.NewCustomField().FindFieldNames(); err == nil {
+		c.Data["Fields"] = fields
+	}
+
+	if keyword != "" || (field != "" && fieldValue != "") {
 		c.Data["Keyword"] = keyword
 		memberId := 0
 		if c.Member != nil {
 			memberId = c.Member.MemberId
 		}
-		searchResult, totalCount, err := models.NewDocumentSearchResult().FindToPager(sqltil.EscapeLike(keyword), pageIndex, conf.PageSize, memberId)
+		var searchResult []*models.DocumentSearchResult
+		var totalCount int
+		var err error
+
+		if field != "" {
+			searchResult, totalCount, err = models.NewDocumentSearchResult().FindByFieldToPager(sqltil.EscapeLike(keyword), field, sqltil.EscapeLike(fieldValue), pageIndex, conf.PageSize, memberId)
+		} else {
+			searchResult, totalCount, err = models.NewDocumentSearchResult().FindToPager(sqltil.EscapeLike(keyword), pageIndex, conf.PageSize, memberId)
+		}
 
 		if err != nil {
 			logs.Error("搜索失败 ->", err)
@@ -51,6 +67,10 @@ func (c *SearchController) Index() {
 		} else {
 			c.Data["PageHtml"] = ""
 		}
+		// 按字段值搜索且没有关键字时，高亮显示字段值
+		if keyword == "" {
+			keyword = fieldValue
+		}
 		if len(searchResult) > 0 {
 			keywords := strings.Split(keyword, " ")
 
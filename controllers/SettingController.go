@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"fmt"
+	"image/png"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -10,10 +11,14 @@ import (
 
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/beego/i18n"
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
 	"github.com/mindoc-org/mindoc/conf"
 	"github.com/mindoc-org/mindoc/graphics"
 	"github.com/mindoc-org/mindoc/models"
 	"github.com/mindoc-org/mindoc/utils"
+	"github.com/mindoc-org/mindoc/utils/pagination"
+	"github.com/mindoc-org/mindoc/utils/totp"
 )
 
 type SettingController struct {
@@ -46,6 +51,9 @@ func (c *SettingController) Index() {
 
 func (c *SettingController) Password() {
 	c.TplName = "setting/password.tpl"
+	policy := models.GetPasswordPolicy()
+	c.Data["PasswordPolicyTips"] = policy.Tips(c.Lang)
+	c.Data["PasswordExpired"] = policy.IsExpired(c.Member)
 
 	if c.Ctx.Input.IsPost() {
 		if c.Member.AuthMethod == conf.AuthMethodLDAP {
@@ -62,8 +70,8 @@ func (c *SettingController) Password() {
 		if password2 == "" {
 			c.JsonResult(6004, i18n.Tr(c.Lang, "message.new_pwd_empty"))
 		}
-		if count := strings.Count(password2, ""); count < 6 || count > 18 {
-			c.JsonResult(6009, i18n.Tr(c.Lang, "message.pwd_length"))
+		if policy.Check(password2) != nil {
+			c.JsonResult(6009, policy.Tips(c.Lang))
 		}
 		if password2 != password3 {
 			c.JsonResult(6003, "确认密码不正确")
@@ -79,16 +87,87 @@ func (c *SettingController) Password() {
 			c.JsonResult(6007, i18n.Tr(c.Lang, "message.pwd_encrypt_failed"))
 		}
 		c.Member.Password = pwd
+		c.Member.PasswordUpdateTime = time.Now()
 		if c.Member.AuthMethod == "" {
 			c.Member.AuthMethod = "local"
 		}
 		if err := c.Member.Update(); err != nil {
 			c.JsonResult(6008, err.Error())
 		}
+		c.SetMember(*c.Member)
+		//修改密码后注销其他设备上的登录会话
+		_ = models.NewMemberSession().DeleteByMemberId(c.Member.MemberId, c.currentSessionToken())
 		c.JsonResult(0, "ok")
 	}
 }
 
+// LoginHistory 当前用户的登录历史
+func (c *SettingController) LoginHistory() {
+	c.TplName = "setting/login_history.tpl"
+
+	pageIndex, _ := c.GetInt("page", 1)
+
+	loginLogs, totalCount, err := models.NewLoginLog().FindToPager(c.Member.MemberId, "", pageIndex, conf.PageSize)
+	if err != nil {
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	if totalCount > 0 {
+		pager := pagination.NewPagination(c.Ctx.Request, totalCount, conf.PageSize, c.BaseUrl())
+		c.Data["PageHtml"] = pager.HtmlPages()
+	} else {
+		c.Data["PageHtml"] = ""
+	}
+	c.Data["Lists"] = loginLogs
+}
+
+// Sessions 当前用户的登录会话
+func (c *SettingController) Sessions() {
+	c.TplName = "setting/sessions.tpl"
+
+	sessions, err := models.NewMemberSession().FindByMemberId(c.Member.MemberId)
+	if err != nil {
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	c.Data["Lists"] = sessions
+	c.Data["CurrentToken"] = c.currentSessionToken()
+}
+
+// StaleDocuments 当前用户负责的超过复审周期未修改的文档
+func (c *SettingController) StaleDocuments() {
+	c.TplName = "setting/stale_documents.tpl"
+
+	docs, err := models.FindStaleDocumentsByMemberId(c.Member.MemberId)
+	if err != nil {
+		logs.Error("查询过期文档失败 ->", err)
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	c.Data["Lists"] = docs
+}
+
+// DeleteSession 注销指定的登录会话
+func (c *SettingController) DeleteSession() {
+	sessionId, _ := c.GetInt("session_id", 0)
+	if sessionId <= 0 {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.param_error"))
+	}
+	if err := models.NewMemberSession().Delete(c.Member.MemberId, sessionId); err != nil {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.failed"))
+	}
+	c.JsonResult(0, "ok")
+}
+
+// DeleteOtherSessions 注销除当前会话以外的全部登录会话
+func (c *SettingController) DeleteOtherSessions() {
+	token := c.currentSessionToken()
+	if token == "" {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.param_error"))
+	}
+	if err := models.NewMemberSession().DeleteByMemberId(c.Member.MemberId, token); err != nil {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.failed"))
+	}
+	c.JsonResult(0, "ok")
+}
+
 // Upload 上传图片
 func (c *SettingController) Upload() {
 	file, moreFile, err := c.GetFile("image-file")
@@ -173,3 +252,123 @@ func (c *SettingController) Upload() {
 
 	c.JsonResult(0, "ok", url)
 }
+
+// TwoFactor 两步验证设置
+func (c *SettingController) TwoFactor() {
+	c.TplName = "setting/two_factor.tpl"
+
+	twoFactor, err := models.NewMemberTwoFactor().FindByMemberId(c.Member.MemberId)
+	enabled := err == nil && twoFactor.TwoFactorId > 0
+
+	c.Data["TwoFactorEnabled"] = enabled
+	c.Data["TwoFactorRequired"] = models.IsTwoFactorRequired(c.Member)
+
+	if enabled {
+		c.Data["RecoveryCodeCount"] = twoFactor.RecoveryCodeCount()
+		return
+	}
+	secret, ok := c.GetSession(conf.TwoFactorSecretSessionName).(string)
+	if !ok || secret == "" {
+		secret, err = totp.GenerateSecret()
+		if err != nil {
+			logs.Error("生成两步验证密钥失败 ->", err)
+			c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+		}
+		c.SetSession(conf.TwoFactorSecretSessionName, secret)
+	}
+	c.Data["Secret"] = secret
+}
+
+// TwoFactorQrCode 生成验证器扫码使用的二维码
+func (c *SettingController) TwoFactorQrCode() {
+	secret, ok := c.GetSession(conf.TwoFactorSecretSessionName).(string)
+	if !ok || secret == "" {
+		c.ShowErrorPage(404, i18n.Tr(c.Lang, "message.two_factor_session_expired"))
+	}
+	issuer := c.Option["SITE_NAME"]
+	if issuer == "" {
+		issuer = "MinDoc"
+	}
+	code, err := qr.Encode(totp.URL(issuer, c.Member.Account, secret), qr.M, qr.Auto)
+	if err != nil {
+		logs.Error("生成二维码失败 ->", err)
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.gen_qrcode_failed"))
+	}
+	code, err = barcode.Scale(code, 200, 200)
+	if err != nil {
+		logs.Error("生成二维码失败 ->", err)
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.gen_qrcode_failed"))
+	}
+	c.Ctx.ResponseWriter.Header().Set("Content-Type", "image/png")
+	c.Ctx.ResponseWriter.Header().Set("Cache-Control", "no-store")
+
+	if err := png.Encode(c.Ctx.ResponseWriter, code); err != nil {
+		logs.Error("生成二维码失败 ->", err)
+	}
+}
+
+// TwoFactorEnable 校验验证码并启用两步验证
+func (c *SettingController) TwoFactorEnable() {
+	secret, ok := c.GetSession(conf.TwoFactorSecretSessionName).(string)
+	if !ok || secret == "" {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.two_factor_session_expired"))
+	}
+	code := strings.TrimSpace(c.GetString("code"))
+	if code == "" {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.two_factor_code_empty"))
+	}
+	codes, err := models.NewMemberTwoFactor().Enable(c.Member.MemberId, secret, code)
+	if err == models.ErrTwoFactorCodeInvalid {
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.two_factor_code_invalid"))
+	} else if err == models.ErrTwoFactorEnabled {
+		c.JsonResult(6004, i18n.Tr(c.Lang, "message.two_factor_enabled"))
+	} else if err != nil {
+		c.JsonResult(6005, i18n.Tr(c.Lang, "message.failed"))
+	}
+	c.DelSession(conf.TwoFactorSecretSessionName)
+	logs.Info("用户启用了两步验证 ->", c.Member.Account)
+
+	c.JsonResult(0, "ok", codes)
+}
+
+// TwoFactorDisable 关闭两步验证
+func (c *SettingController) TwoFactorDisable() {
+	if models.IsTwoFactorRequired(c.Member) {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.two_factor_required"))
+	}
+	twoFactor := c.verifyTwoFactorCode()
+
+	if err := twoFactor.Delete(c.Member.MemberId); err != nil {
+		c.JsonResult(6005, i18n.Tr(c.Lang, "message.failed"))
+	}
+	logs.Info("用户关闭了两步验证 ->", c.Member.Account)
+
+	c.JsonResult(0, "ok")
+}
+
+// TwoFactorRecoveryCodes 重新生成恢复码
+func (c *SettingController) TwoFactorRecoveryCodes() {
+	twoFactor := c.verifyTwoFactorCode()
+
+	codes, err := twoFactor.RegenerateRecoveryCodes()
+	if err != nil {
+		c.JsonResult(6005, i18n.Tr(c.Lang, "message.failed"))
+	}
+	c.JsonResult(0, "ok", codes)
+}
+
+// verifyTwoFactorCode 修改两步验证设置前需要校验当前的验证码.
+func (c *SettingController) verifyTwoFactorCode() *models.MemberTwoFactor {
+	twoFactor, err := models.NewMemberTwoFactor().FindByMemberId(c.Member.MemberId)
+	if err != nil {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.two_factor_not_enabled"))
+	}
+	code := strings.TrimSpace(c.GetString("code"))
+	if code == "" {
+		c.JsonResult(6003, i18n.Tr(c.Lang, "message.two_factor_code_empty"))
+	}
+	if !twoFactor.Verify(code) {
+		c.JsonResult(6004, i18n.Tr(c.Lang, "message.two_factor_code_invalid"))
+	}
+	return twoFactor
+}
@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"html/template"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/i18n"
+	"github.com/mindoc-org/mindoc/models"
+)
+
+type SubscriptionController struct {
+	BaseController
+}
+
+// Index 我的订阅.
+func (c *SubscriptionController) Index() {
+	c.TplName = "subscription/index.tpl"
+
+	list, err := models.NewSubscription().FindByMemberId(c.Member.MemberId)
+	if err != nil {
+		logs.Error("查询订阅失败 ->", c.Member.MemberId, err)
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	c.Data["Lists"] = list
+}
+
+// readableBook 获取当前用户可以阅读的项目和文档，doc_id 为 0 时只获取项目.
+func (c *SubscriptionController) readableBook() (*models.Book, int) {
+	identify := c.GetString("identify")
+	docId, _ := c.GetInt("doc_id", 0)
+
+	book, err := models.NewBook().FindByFieldFirst("identify", identify)
+	if err != nil {
+		c.JsonResult(6002, i18n.Tr(c.Lang, "message.item_not_exist"))
+	}
+	if !models.CanReadBook(book, c.Member) {
+		c.JsonResult(403, i18n.Tr(c.Lang, "message.no_permission"))
+	}
+	if docId > 0 {
+		doc, err := models.NewDocument().Find(docId)
+		if err != nil || doc.BookId != book.BookId {
+			c.JsonResult(6003, i18n.Tr(c.Lang, "message.doc_not_exist"))
+		}
+	} else if docId < 0 {
+		docId = 0
+	}
+	return book, docId
+}
+
+// Status 获取当前用户对项目和文档的订阅.
+func (c *SubscriptionController) Status() {
+	book, docId := c.readableBook()
+
+	data := map[string]interface{}{"book": "", "document": ""}
+	if subscription, err := models.NewSubscription().Find(c.Member.MemberId, book.BookId, 0); err == nil {
+		data["book"] = subscription.Frequency
+	}
+	if docId > 0 {
+		if subscription, err := models.NewSubscription().Find(c.Member.MemberId, book.BookId, docId); err == nil {
+			data["document"] = subscription.Frequency
+		}
+	}
+	c.JsonResult(0, "ok", data)
+}
+
+// Save 订阅项目或文档.
+func (c *SubscriptionController) Save() {
+	frequency := c.GetString("frequency", models.SubscriptionImmediate)
+	if !models.IsValidSubscriptionFrequency(frequency) {
+		c.JsonResult(6001, i18n.Tr(c.Lang, "message.param_error"))
+	}
+	book, docId := c.readableBook()
+
+	subscription, err := models.NewSubscription().Subscribe(c.Member.MemberId, book.BookId, docId, frequency)
+	if err != nil {
+		logs.Error("订阅失败 ->", c.Member.MemberId, book.BookId, docId, err)
+		c.JsonResult(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	c.JsonResult(0, "ok", subscription)
+}
+
+// Delete 取消订阅，可以指定订阅id或项目和文档.
+func (c *SubscriptionController) Delete() {
+	if subscriptionId, _ := c.GetInt("subscription_id", 0); subscriptionId > 0 {
+		_, err := orm.NewOrm().QueryTable(models.NewSubscription().TableNameWithPrefix()).
+			Filter("subscription_id", subscriptionId).
+			Filter("member_id", c.Member.MemberId).
+			Delete()
+		if err != nil {
+			logs.Error("取消订阅失败 ->", subscriptionId, err)
+			c.JsonResult(500, i18n.Tr(c.Lang, "message.system_error"))
+		}
+		c.JsonResult(0, "ok")
+	}
+	book, docId := c.readableBook()
+
+	if err := models.NewSubscription().Unsubscribe(c.Member.MemberId, book.BookId, docId); err != nil {
+		logs.Error("取消订阅失败 ->", c.Member.MemberId, book.BookId, docId, err)
+		c.JsonResult(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	c.JsonResult(0, "ok")
+}
+
+// Unsubscribe 通过邮件中的链接退订，不需要登录.
+// GET 请求只显示确认页面，避免邮件安全扫描和预加载访问链接时被退订，确认后通过 POST 请求退订.
+func (c *SubscriptionController) Unsubscribe() {
+	c.TplName = "subscription/unsubscribe.tpl"
+
+	subscription, err := models.NewSubscription().FindByToken(c.Ctx.Input.Param(":token"))
+	if err != nil {
+		if err != orm.ErrNoRows {
+			logs.Error("查询订阅失败 ->", err)
+		}
+		c.Data["Message"] = i18n.Tr(c.Lang, "message.subscription_not_exist")
+		return
+	}
+	name := ""
+	if book, err := models.NewBook().Find(subscription.BookId, "book_id", "book_name"); err == nil {
+		name = book.BookName
+	}
+	if subscription.DocumentId > 0 {
+		if doc, err := models.NewDocument().Find(subscription.DocumentId); err == nil {
+			name = doc.DocumentName
+		}
+	}
+	if !c.Ctx.Input.IsPost() {
+		c.Data["xsrfdata"] = template.HTML(c.XSRFFormHTML())
+		c.Data["Confirm"] = true
+		c.Data["Message"] = i18n.Tr(c.Lang, "message.unsubscribe_confirm", name)
+		return
+	}
+	if err := subscription.Delete(); err != nil {
+		logs.Error("退订失败 ->", subscription.SubscriptionId, err)
+		c.ShowErrorPage(500, i18n.Tr(c.Lang, "message.system_error"))
+	}
+	c.Data["Message"] = i18n.Tr(c.Lang, "message.unsubscribed", name)
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
+	"mime"
 	"net/mail"
 	"net/smtp"
 	"path"
@@ -122,7 +123,7 @@ func (c *SMTPClient) Send(m Mail) error {
 		message.WriteString(fmt.Sprintf("Return-Receipt-To: %s\r\n", m.RetReceipt))
 		message.WriteString(fmt.Sprintf("Disposition-Notification-To: %s\r\n", m.RetReceipt))
 	}
-	message.WriteString(fmt.Sprintf("From: %s <%s>\r\n", m.FromName, m.From))
+	message.WriteString(fmt.Sprintf("From: %s <%s>\r\n", mime.BEncoding.Encode(m.Charset, m.FromName), m.From))
 	if len(m.ReplyTo) > 0 {
 		message.WriteString(fmt.Sprintf("Return-Path: %s\r\n", m.ReplyTo))
 	}
@@ -150,7 +151,7 @@ func (c *SMTPClient) Send(m Mail) error {
 		}
 	}
 	message.WriteString("\r\n")
-	message.WriteString(fmt.Sprintf("Subject: %s\r\n", m.Subject))
+	message.WriteString(fmt.Sprintf("Subject: %s\r\n", mime.BEncoding.Encode(m.Charset, m.Subject)))
 	message.WriteString("MIME-Version: 1.0\r\n")
 	if m.Files != nil {
 		message.WriteString(fmt.Sprintf("Content-Type: multipart/mixed; boundary=\"%s\"\r\n\n--%s\r\n", boundary, boundary))
@@ -188,8 +189,17 @@ func (c *SMTPClient) Send(m Mail) error {
 				}
 			}
 		}
-		part := fmt.Sprintf("Content-Type: text/html\r\n\n%s\r\n\n", m.HTML)
-		message.WriteString(part)
+		if len(m.Text) > 0 {
+			//同时包含纯文本和 HTML 内容时，由邮件客户端选择显示的版本
+			alternative := boundary + "ALTERNATIVE"
+			message.WriteString(fmt.Sprintf("Content-Type: multipart/alternative; boundary=\"%s\"\r\n\r\n", alternative))
+			message.WriteString(fmt.Sprintf("--%s\r\nContent-Type: text/plain; charset=\"%s\"\r\n\r\n%s\r\n", alternative, m.Charset, m.Text))
+			message.WriteString(fmt.Sprintf("--%s\r\nContent-Type: text/html; charset=\"%s\"\r\n\r\n%s\r\n", alternative, m.Charset, m.HTML))
+			message.WriteString(fmt.Sprintf("--%s--\r\n\r\n", alternative))
+		} else {
+			part := fmt.Sprintf("Content-Type: text/html\r\n\n%s\r\n\n", m.HTML)
+			message.WriteString(part)
+		}
 		message.WriteString(embedImages)
 	} else {
 		part := fmt.Sprintf("Content-Type: text/plain\r\n\n%s\r\n\n", m.Text)
@@ -5,9 +5,11 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
 
 	_ "github.com/beego/beego/v2/server/web/session/memcache"
 	_ "github.com/beego/beego/v2/server/web/session/mysql"
@@ -61,6 +63,14 @@ func main() {
 			log.Fatal("启动程序失败 ->", err)
 		}
 	} else {
+		// 不通过服务运行时自行处理退出信号
+		go func() {
+			sig := make(chan os.Signal, 1)
+			signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+			<-sig
+			commands.Shutdown()
+			os.Exit(0)
+		}()
 		d.Run()
 	}
 
@@ -237,9 +237,16 @@ func (b *Blog) Save(cols ...string) error {
 
 	if b.BlogId > 0 {
 		b.Modified = time.Now()
+		old := NewBlog()
+		if redirectWatched(cols, "blog_identify") {
+			_ = o.QueryTable(b.TableNameWithPrefix()).Filter("blog_id", b.BlogId).One(old, "blog_identify")
+		}
 		_, err = o.Update(b, cols...)
 		key := fmt.Sprintf("blog-id-%d", b.BlogId)
 		_ = cache.Delete(key)
+		if err == nil && old.BlogIdentify != "" && old.BlogIdentify != b.BlogIdentify {
+			AddRedirect(RedirectBlog, b.BlogId, 0, old.BlogIdentify, b.BlogIdentify)
+		}
 
 	} else {
 
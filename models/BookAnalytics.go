@@ -0,0 +1,283 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+)
+
+// AnalyticsPeriods 统计页面支持的时间范围，单位天.
+var AnalyticsPeriods = []int{7, 30, 90}
+
+// analyticsTopLimit 排行列表的最大数量.
+const analyticsTopLimit = 20
+
+// AnalyticsPage 文档在统计时间范围内的阅读数据.
+type AnalyticsPage struct {
+	DocumentId    int    `json:"document_id"`
+	DocumentName  string `json:"document_name"`
+	Views         int    `json:"views"`
+	Readers       int    `json:"readers"`
+	Duration      int    `json:"duration"`
+	DurationViews int    `json:"duration_views"`
+	Exits         int    `json:"exits"`
+}
+
+// AverageDuration 平均阅读时长，单位秒.
+func (m *AnalyticsPage) AverageDuration() int {
+	if m.DurationViews == 0 {
+		return 0
+	}
+	return m.Duration / m.DurationViews
+}
+
+// ExitRate 离开率，百分比.
+func (m *AnalyticsPage) ExitRate() int {
+	if m.Views == 0 {
+		return 0
+	}
+	return m.Exits * 100 / m.Views
+}
+
+// AnalyticsTrend 项目每天的阅读数据，Percent 为相对最大阅读次数的百分比.
+type AnalyticsTrend struct {
+	Day     string `json:"day"`
+	Views   int    `json:"views"`
+	Readers int    `json:"readers"`
+	Percent int    `json:"percent"`
+}
+
+// AnalyticsReferer 外部来源站点.
+type AnalyticsReferer struct {
+	RefererHost string `json:"referer_host"`
+	Views       int    `json:"views"`
+}
+
+// AnalyticsSearch 项目内搜索的关键词.
+type AnalyticsSearch struct {
+	Keyword     string `json:"keyword"`
+	Searches    int    `json:"searches"`
+	ZeroResults int    `json:"zero_results"`
+}
+
+// BookAnalytics 项目在一段时间内的阅读统计.
+type BookAnalytics struct {
+	BookId   int    `json:"book_id"`
+	Days     int    `json:"days"`
+	StartDay string `json:"start_day"`
+	EndDay   string `json:"end_day"`
+
+	Views         int `json:"views"`
+	Readers       int `json:"readers"`
+	Duration      int `json:"duration"`
+	DurationViews int `json:"duration_views"`
+
+	Trend        []*AnalyticsTrend   `json:"trend"`
+	TopPages     []*AnalyticsPage    `json:"top_pages"`
+	ExitPages    []*AnalyticsPage    `json:"exit_pages"`
+	Referers     []*AnalyticsReferer `json:"referers"`
+	ZeroSearches []*AnalyticsSearch  `json:"zero_searches"`
+}
+
+// AverageDuration 平均阅读时长，单位秒.
+func (m *BookAnalytics) AverageDuration() int {
+	if m.DurationViews == 0 {
+		return 0
+	}
+	return m.Duration / m.DurationViews
+}
+
+// IsAnalyticsPeriod 判断是否是支持的统计时间范围.
+func IsAnalyticsPeriod(days int) bool {
+	for _, item := range AnalyticsPeriods {
+		if item == days {
+			return true
+		}
+	}
+	return false
+}
+
+// analyticsRange 获取最近 days 天的起止日期和起始时间，包含当天.
+func analyticsRange(days int) (startDay, endDay string, start time.Time) {
+	now := time.Now()
+	start = beginningOfDay(now).AddDate(0, 0, 1-days)
+	return start.Format(analyticsDayFormat), now.Format(analyticsDayFormat), start
+}
+
+// FindBookAnalytics 查询项目最近 days 天的阅读统计，当天的数据会先重新汇总.
+func FindBookAnalytics(bookId, days int) (*BookAnalytics, error) {
+	if !IsAnalyticsPeriod(days) {
+		days = AnalyticsPeriods[0]
+	}
+	if err := refreshBookAnalytics(bookId); err != nil {
+		return nil, err
+	}
+	startDay, endDay, start := analyticsRange(days)
+	result := &BookAnalytics{BookId: bookId, Days: days, StartDay: startDay, EndDay: endDay}
+
+	var err error
+	if result.Trend, err = FindBookAnalyticsTrend(bookId, days); err != nil {
+		return nil, err
+	}
+	for _, item := range result.Trend {
+		result.Views += item.Views
+	}
+
+	o := orm.NewOrm()
+	// 不同日期的读者可能是同一个人，读者人数从原始记录中去重统计
+	err = o.Raw("SELECT COUNT(DISTINCT visitor_id) FROM "+NewDocumentView().TableNameWithPrefix()+" WHERE book_id = ? AND create_time >= ?", bookId, start).
+		QueryRow(&result.Readers)
+	if err != nil {
+		return nil, err
+	}
+	err = o.Raw("SELECT COALESCE(SUM(duration), 0), COALESCE(SUM(duration_views), 0) FROM "+new(DocumentViewDaily).TableNameWithPrefix()+" WHERE book_id = ? AND document_id = 0 AND day >= ? AND day <= ?", bookId, startDay, endDay).
+		QueryRow(&result.Duration, &result.DurationViews)
+	if err != nil {
+		return nil, err
+	}
+	if result.TopPages, err = findAnalyticsPages(bookId, days, "views", analyticsTopLimit); err != nil {
+		return nil, err
+	}
+	if result.ExitPages, err = findAnalyticsPages(bookId, days, "exits", analyticsTopLimit); err != nil {
+		return nil, err
+	}
+	_, err = o.Raw("SELECT referer_host, COUNT(*) AS views FROM "+NewDocumentView().TableNameWithPrefix()+" WHERE book_id = ? AND create_time >= ? AND referer_host <> '' GROUP BY referer_host ORDER BY views DESC LIMIT ?", bookId, start, analyticsTopLimit).
+		QueryRows(&result.Referers)
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	if result.ZeroSearches, err = FindBookAnalyticsSearches(bookId, days, true, analyticsTopLimit); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// FindBookAnalyticsTrend 查询项目最近 days 天每天的阅读数据，没有阅读的日期也会列出.
+func FindBookAnalyticsTrend(bookId, days int) ([]*AnalyticsTrend, error) {
+	startDay, endDay, start := analyticsRange(days)
+
+	var list []*DocumentViewDaily
+	_, err := orm.NewOrm().QueryTable(new(DocumentViewDaily).TableNameWithPrefix()).
+		Filter("book_id", bookId).
+		Filter("document_id", 0).
+		Filter("day__gte", startDay).
+		Filter("day__lte", endDay).
+		All(&list, "day", "views", "readers")
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	daily := make(map[string]*DocumentViewDaily, len(list))
+	for _, item := range list {
+		daily[item.Day] = item
+	}
+	trend := make([]*AnalyticsTrend, 0, days)
+	maxViews := 0
+	for i := 0; i < days; i++ {
+		day := start.AddDate(0, 0, i).Format(analyticsDayFormat)
+		item := &AnalyticsTrend{Day: day}
+		if d, ok := daily[day]; ok {
+			item.Views = d.Views
+			item.Readers = d.Readers
+		}
+		if item.Views > maxViews {
+			maxViews = item.Views
+		}
+		trend = append(trend, item)
+	}
+	if maxViews > 0 {
+		for _, item := range trend {
+			item.Percent = item.Views * 100 / maxViews
+		}
+	}
+	return trend, nil
+}
+
+// FindBookAnalyticsPages 查询项目最近 days 天全部文档的阅读数据，按阅读次数排序.
+func FindBookAnalyticsPages(bookId, days int) ([]*AnalyticsPage, error) {
+	return findAnalyticsPages(bookId, days, "views", 0)
+}
+
+// findAnalyticsPages 按文档汇总阅读数据，orderBy 为 views 或 exits，limit 为 0 时不限制数量.
+func findAnalyticsPages(bookId, days int, orderBy string, limit int) ([]*AnalyticsPage, error) {
+	startDay, endDay, start := analyticsRange(days)
+
+	sql := "SELECT document_id, SUM(views) AS views, SUM(duration) AS duration, SUM(duration_views) AS duration_views, SUM(exits) AS exits FROM " +
+		new(DocumentViewDaily).TableNameWithPrefix() +
+		" WHERE book_id = ? AND document_id > 0 AND day >= ? AND day <= ? GROUP BY document_id"
+	if orderBy == "exits" {
+		sql += " HAVING SUM(exits) > 0 ORDER BY exits DESC, views DESC"
+	} else {
+		sql += " ORDER BY views DESC, document_id"
+	}
+	args := []interface{}{bookId, startDay, endDay}
+	if limit > 0 {
+		sql += " LIMIT ?"
+		args = append(args, limit)
+	}
+	var pages []*AnalyticsPage
+	if _, err := orm.NewOrm().Raw(sql, args...).QueryRows(&pages); err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return pages, nil
+	}
+
+	ids := make([]int, 0, len(pages))
+	for _, page := range pages {
+		ids = append(ids, page.DocumentId)
+	}
+	// 读者人数从原始记录中去重统计
+	var readers []orm.Params
+	_, err := orm.NewOrm().Raw("SELECT document_id, COUNT(DISTINCT visitor_id) AS readers FROM "+NewDocumentView().TableNameWithPrefix()+" WHERE book_id = ? AND create_time >= ? GROUP BY document_id", bookId, start).
+		Values(&readers)
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	counts := make(map[string]int, len(readers))
+	for _, row := range readers {
+		counts[fmt.Sprint(row["document_id"])], _ = strconv.Atoi(fmt.Sprint(row["readers"]))
+	}
+
+	var docs []*Document
+	_, err = orm.NewOrm().QueryTable(NewDocument().TableNameWithPrefix()).
+		Filter("document_id__in", ids).
+		All(&docs, "document_id", "document_name")
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	names := make(map[int]string, len(docs))
+	for _, doc := range docs {
+		names[doc.DocumentId] = doc.DocumentName
+	}
+	for _, page := range pages {
+		page.DocumentName = names[page.DocumentId]
+		page.Readers = counts[strconv.Itoa(page.DocumentId)]
+	}
+	return pages, nil
+}
+
+// FindBookAnalyticsSearches 查询项目最近 days 天的搜索关键词，zeroOnly 为 true 时只查询有过没有结果的关键词，limit 为 0 时不限制数量.
+func FindBookAnalyticsSearches(bookId, days int, zeroOnly bool, limit int) ([]*AnalyticsSearch, error) {
+	startDay, endDay, _ := analyticsRange(days)
+
+	sql := "SELECT keyword, SUM(searches) AS searches, SUM(zero_results) AS zero_results FROM " +
+		new(BookSearchTerm).TableNameWithPrefix() +
+		" WHERE book_id = ? AND day >= ? AND day <= ? GROUP BY keyword"
+	if zeroOnly {
+		sql += " HAVING SUM(zero_results) > 0 ORDER BY zero_results DESC, searches DESC"
+	} else {
+		sql += " ORDER BY searches DESC, keyword"
+	}
+	args := []interface{}{bookId, startDay, endDay}
+	if limit > 0 {
+		sql += " LIMIT ?"
+		args = append(args, limit)
+	}
+	var list []*AnalyticsSearch
+	if _, err := orm.NewOrm().Raw(sql, args...).QueryRows(&list); err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	return list, nil
+}
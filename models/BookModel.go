@@ -84,6 +84,10 @@ type Book struct {
 	//是否开启自动保存：0 否/1 是
 	AutoSave  int `orm:"column(auto_save);type(tinyint);default(0);description(是否开启自动保存：0 否/1 是)" json:"auto_save"`
 	PrintSate int `orm:"column(print_state);type(tinyint);default(1);description(启用打印：0 否/1 是)" json:"print_state"`
+	//文档复审周期天数，超过该天数未修改的文档视为过期，0 为不检查
+	ReviewDays int `orm:"column(review_days);type(int);default(0);description(文档复审周期天数，0 为不检查)" json:"review_days"`
+	//评论是否需要审核：0 否/1 是
+	CommentModerate int `orm:"column(comment_moderate);type(tinyint);default(0);description(评论是否需要审核：0 否/1 是)" json:"comment_moderate"`
 }
 
 func (book *Book) String() string {
@@ -185,6 +189,9 @@ func (book *Book) Update(cols ...string) error {
 	}
 
 	_, err := o.Update(book, cols...)
+	if err == nil && temp.Identify != book.Identify && redirectWatched(cols, "identify") {
+		AddRedirect(RedirectBook, book.BookId, 0, temp.Identify, book.Identify)
+	}
 	return err
 }
 
@@ -444,83 +451,6 @@ ORDER BY book.order_index, book.book_id DESC limit ? offset ?`
 	return
 }
 
-// 彻底删除项目.
-func (book *Book) ThoroughDeleteBook(id int) error {
-	if id <= 0 {
-		return ErrInvalidParameter
-	}
-	o := orm.NewOrm()
-
-	book, err := book.Find(id)
-	if err != nil {
-		return err
-	}
-	o.Begin()
-
-	//删除附件,这里没有删除实际物理文件
-	if err := o.DoTx(func(ctx context.Context, txOrm orm.TxOrmer) error {
-		_, err = txOrm.Raw("DELETE FROM "+NewAttachment().TableNameWithPrefix()+" WHERE book_id=?", book.BookId).Exec()
-		return err
-	}); err != nil {
-		return err
-	}
-
-	//删除文档
-	if err := o.DoTx(func(ctx context.Context, txOrm orm.TxOrmer) error {
-		_, err = txOrm.Raw("DELETE FROM "+NewDocument().TableNameWithPrefix()+" WHERE book_id = ?", book.BookId).Exec()
-		return err
-	}); err != nil {
-		return err
-	}
-	//删除项目
-	if err := o.DoTx(func(ctx context.Context, txOrm orm.TxOrmer) error {
-		_, err = txOrm.Raw("DELETE FROM "+book.TableNameWithPrefix()+" WHERE book_id = ?", book.BookId).Exec()
-		return err
-	}); err != nil {
-		return err
-	}
-
-	//删除关系
-
-	if err := o.DoTx(func(ctx context.Context, txOrm orm.TxOrmer) error {
-		_, err = txOrm.Raw("DELETE FROM "+NewRelationship().TableNameWithPrefix()+" WHERE book_id = ?", book.BookId).Exec()
-		return err
-	}); err != nil {
-		return err
-	}
-
-	if err := o.DoTx(func(ctx context.Context, txOrm orm.TxOrmer) error {
-		_, err = txOrm.Raw(fmt.Sprintf("DELETE FROM %s WHERE book_id=?", NewTeamRelationship().TableNameWithPrefix()), book.BookId).Exec()
-		return err
-	}); err != nil {
-		return err
-	}
-	//删除模板
-
-	if err := o.DoTx(func(ctx context.Context, txOrm orm.TxOrmer) error {
-		_, err = txOrm.Raw("DELETE FROM "+NewTemplate().TableNameWithPrefix()+" WHERE book_id = ?", book.BookId).Exec()
-		return err
-	}); err != nil {
-		return err
-	}
-
-	if book.Label != "" {
-		NewLabel().InsertOrUpdateMulti(book.Label)
-	}
-
-	//删除导出缓存
-	if err := os.RemoveAll(filepath.Join(conf.GetExportOutputPath(), strconv.Itoa(id))); err != nil {
-		logs.Error("删除项目缓存失败 ->", err)
-	}
-	//删除附件和图片
-	if err := os.RemoveAll(filepath.Join(conf.WorkingDirectory, "uploads", book.Identify)); err != nil {
-		logs.Error("删除项目附件和图片失败 ->", err)
-	}
-
-	return nil
-
-}
-
 // GetBooksByIds 根据图书ID列表获取图书信息
 func (book *Book) GetBooksByIds(ids []int) ([]*Book, error) {
 	if len(ids) == 0 {
@@ -662,11 +592,16 @@ func (book *Book) ReleaseContent(bookId int, lang string) {
 					logs.Error("发布失败 =>", bookId, err)
 					continue
 				}
+				var changed []*Document
 				for _, item := range docs {
 					item.BookId = bookId
 					item.Lang = lang
-					_ = item.ReleaseContent()
+					previous := item.Release
+					if err := item.ReleaseContent(); err == nil && item.Release != previous {
+						changed = append(changed, item)
+					}
 				}
+				NotifyRobotsBookRelease(bookId, changed)
 
 				//当文档发布后，需要删除已缓存的转换项目
 				outputPath := filepath.Join(conf.GetExportOutputPath(), strconv.Itoa(bookId))
@@ -1,9 +1,14 @@
 package models
 
 import (
+	"math"
+	"sync"
 	"time"
 
 	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/mindoc-org/mindoc/conf"
+	"github.com/mindoc-org/mindoc/utils/batchcounter"
 )
 
 // 用户阅读历史
@@ -14,6 +19,9 @@ type BookReadHistory struct {
 	CreateTime   time.Time `orm:"column(create_time);type(datetime);auto_now_add;description(创建时间)" json:"create_time"`
 	LastReadTime time.Time `orm:"column(last_read_time);type(datetime);auto_now;description(最近阅读时间)" json:"last_read_time"`
 	ReadCount    int       `orm:"column(read_count);type(int);description(阅读次数)" json:"read_count"`
+	// LastDocumentId 和 ScrollRatio 记录最后阅读的文档和滚动位置，用于继续阅读
+	LastDocumentId int     `orm:"column(last_document_id);type(int);default(0);description(最后阅读的文档ID)" json:"last_document_id"`
+	ScrollRatio    float64 `orm:"column(scroll_ratio);default(0);description(滚动位置占文档高度的比例)" json:"scroll_ratio"`
 }
 
 func NewBookReadHistory() *BookReadHistory {
@@ -90,3 +98,271 @@ func (m *BookReadHistory) FindToPager(memberId, pageIndex, pageSize int) ([]*Boo
 
 	return histories, int(totalCount), err
 }
+
+// Find 查询用户在项目中的阅读历史.
+func (m *BookReadHistory) Find(memberId, bookId int) (*BookReadHistory, error) {
+	err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("member_id", memberId).Filter("book_id", bookId).One(m)
+	return m, err
+}
+
+// ReadDocument 记录最后阅读的文档，切换到其他文档时滚动位置从头开始.
+func (m *BookReadHistory) ReadDocument(memberId, bookId, docId int) error {
+	if history, err := NewBookReadHistory().Find(memberId, bookId); err == nil && history.LastDocumentId == docId {
+		return nil
+	}
+	return m.SaveProgress(memberId, bookId, docId, 0)
+}
+
+// SaveProgress 保存文档的滚动位置，并将该文档作为最后阅读的文档.
+func (m *BookReadHistory) SaveProgress(memberId, bookId, docId int, ratio float64) error {
+	if ratio < 0 || math.IsNaN(ratio) {
+		ratio = 0
+	} else if ratio > 1 {
+		ratio = 1
+	}
+	history, err := NewBookReadHistory().Find(memberId, bookId)
+	if err == orm.ErrNoRows {
+		history.MemberId = memberId
+		history.BookId = bookId
+		history.ReadCount = 1
+		history.LastDocumentId = docId
+		history.ScrollRatio = ratio
+		return history.Add()
+	}
+	if err != nil {
+		return err
+	}
+	history.LastDocumentId = docId
+	history.ScrollRatio = ratio
+	return history.Update("last_document_id", "scroll_ratio", "last_read_time")
+}
+
+// ContinueReading 用户可以继续阅读的文档.
+type ContinueReading struct {
+	BookId       int       `json:"book_id"`
+	BookName     string    `json:"book_name"`
+	DocumentId   int       `json:"doc_id"`
+	DocumentName string    `json:"doc_name"`
+	Url          string    `json:"url"`
+	LastReadTime time.Time `json:"last_read_time"`
+}
+
+// FindContinueReading 按最近阅读时间查询用户可以继续阅读的文档，忽略已经没有权限的私有项目.
+func (m *BookReadHistory) FindContinueReading(memberId, limit int) ([]*ContinueReading, error) {
+	var histories []*BookReadHistory
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("member_id", memberId).
+		Filter("last_document_id__gt", 0).
+		OrderBy("-last_read_time").
+		Limit(limit * 2).
+		All(&histories)
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	list := make([]*ContinueReading, 0, limit)
+	for _, history := range histories {
+		if len(list) >= limit {
+			break
+		}
+		book, err := NewBook().Find(history.BookId, "book_id", "book_name", "identify", "privately_owned")
+		if err != nil {
+			continue
+		}
+		if book.PrivatelyOwned == 1 {
+			if _, err := book.FindForRoleId(book.BookId, memberId); err != nil {
+				continue
+			}
+		}
+		doc, err := NewDocument().Find(history.LastDocumentId)
+		if err != nil || doc.BookId != book.BookId {
+			continue
+		}
+		list = append(list, &ContinueReading{
+			BookId:       book.BookId,
+			BookName:     book.BookName,
+			DocumentId:   doc.DocumentId,
+			DocumentName: doc.DocumentName,
+			Url:          ContinueReadingUrl(book.Identify, doc),
+			LastReadTime: history.LastReadTime,
+		})
+	}
+	return list, nil
+}
+
+// ContinueReadingUrl 继续阅读的地址，打开时恢复上次的滚动位置.
+func ContinueReadingUrl(bookIdentify string, doc *Document) string {
+	docId := interface{}(doc.DocumentId)
+	if doc.Identify != "" {
+		docId = doc.Identify
+	}
+	return conf.URLFor("DocumentController.Read", ":key", bookIdentify, ":id", docId) + "?resume=1"
+}
+
+const (
+	// readProgressFlushInterval 缓存的阅读进度写入数据库的间隔
+	readProgressFlushInterval = 10 * time.Second
+	// readProgressMaxPending 缓存的阅读进度达到该数量时提前写入
+	readProgressMaxPending = 1000
+)
+
+// readProgressKey 用户在项目中的阅读进度
+type readProgressKey struct{ memberId, bookId int }
+
+// readStatusKey 用户已读的文档
+type readStatusKey struct{ memberId, docId int }
+
+// readProgressBuffer 缓存登录用户最后阅读的文档和已读的文档.
+type readProgressBuffer struct {
+	mu sync.Mutex
+	// history 每个项目只保留最后阅读的文档
+	history map[readProgressKey]int
+	// read 已读文档所属的项目
+	read map[readStatusKey]int
+
+	// flushMu 保证同一时间只有一个写入
+	flushMu sync.Mutex
+	writer  *batchcounter.Writer
+}
+
+// readProgress 缓存尚未写入数据库的阅读进度.
+var readProgress = newReadProgressBuffer()
+
+func newReadProgressBuffer() *readProgressBuffer {
+	b := &readProgressBuffer{
+		history: make(map[readProgressKey]int),
+		read:    make(map[readStatusKey]int),
+	}
+	b.writer = batchcounter.NewWriter(readProgressFlushInterval, b.flush)
+	return b
+}
+
+// RecordReadProgress 记录用户最后阅读的文档并标记为已读，先缓存在内存中，由 StartReadProgressWriter 定时批量写入数据库.
+func RecordReadProgress(memberId, bookId, docId int) {
+	b := readProgress
+	b.mu.Lock()
+	b.history[readProgressKey{memberId, bookId}] = docId
+	b.read[readStatusKey{memberId, docId}] = bookId
+	full := len(b.history)+len(b.read) >= readProgressMaxPending
+	b.mu.Unlock()
+
+	if full {
+		b.writer.Notify()
+	}
+}
+
+// StartReadProgressWriter 启动阅读进度的定时写入.
+func StartReadProgressWriter() {
+	readProgress.writer.Start(func(err error) {
+		logs.Error("写入阅读进度失败 ->", err)
+	})
+}
+
+// StopReadProgressWriter 停止定时写入，并写入尚未保存的阅读进度.
+func StopReadProgressWriter() error {
+	return readProgress.writer.Stop()
+}
+
+// pendingReadIds 获取用户在项目中尚未写入数据库的已读文档.
+func (b *readProgressBuffer) pendingReadIds(memberId, bookId int, read map[int]bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for key, id := range b.read {
+		if key.memberId == memberId && id == bookId {
+			read[key.docId] = true
+		}
+	}
+}
+
+// flush 写入缓存的阅读进度，已读文档按用户批量插入，写入失败的记录放回缓存.
+func (b *readProgressBuffer) flush() error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	if len(b.history) == 0 && len(b.read) == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	history, read := b.history, b.read
+	b.history = make(map[readProgressKey]int)
+	b.read = make(map[readStatusKey]int)
+	b.mu.Unlock()
+
+	var lastErr error
+	failedHistory := make(map[readProgressKey]int)
+	for key, docId := range history {
+		if err := NewBookReadHistory().ReadDocument(key.memberId, key.bookId, docId); err != nil {
+			failedHistory[key] = docId
+			lastErr = err
+		}
+	}
+
+	members := make(map[int][]*DocumentReadStatus)
+	for key, bookId := range read {
+		members[key.memberId] = append(members[key.memberId], &DocumentReadStatus{MemberId: key.memberId, BookId: bookId, DocumentId: key.docId, ReadTime: time.Now()})
+	}
+	failedRead := make(map[readStatusKey]int)
+	for memberId, list := range members {
+		if err := insertReadStatus(memberId, list); err != nil {
+			for _, status := range list {
+				failedRead[readStatusKey{memberId, status.DocumentId}] = status.BookId
+			}
+			lastErr = err
+		}
+	}
+
+	if len(failedHistory) > 0 || len(failedRead) > 0 {
+		b.mu.Lock()
+		// 数据库长时间不可用时丢弃，避免缓存无限增长
+		if len(b.history)+len(b.read)+len(failedHistory)+len(failedRead) > readProgressMaxPending*10 {
+			logs.Warn("丢弃未能写入的阅读进度 ->", len(failedHistory)+len(failedRead))
+		} else {
+			for key, docId := range failedHistory {
+				if _, ok := b.history[key]; !ok {
+					b.history[key] = docId
+				}
+			}
+			for key, bookId := range failedRead {
+				b.read[key] = bookId
+			}
+		}
+		b.mu.Unlock()
+	}
+	return lastErr
+}
+
+// insertReadStatus 批量插入用户尚未标记为已读的文档.
+func insertReadStatus(memberId int, list []*DocumentReadStatus) error {
+	o := orm.NewOrm()
+	table := NewDocumentReadStatus().TableNameWithPrefix()
+	docIds := make([]int, 0, len(list))
+	for _, status := range list {
+		docIds = append(docIds, status.DocumentId)
+	}
+	var exists []*DocumentReadStatus
+	if _, err := o.QueryTable(table).Filter("member_id", memberId).Filter("document_id__in", docIds).All(&exists, "document_id"); err != nil && err != orm.ErrNoRows {
+		return err
+	}
+	read := make(map[int]bool, len(exists))
+	for _, status := range exists {
+		read[status.DocumentId] = true
+	}
+	missing := make([]*DocumentReadStatus, 0, len(list))
+	for _, status := range list {
+		if !read[status.DocumentId] {
+			missing = append(missing, status)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if _, err := o.InsertMulti(100, missing); err != nil {
+		// 同时将项目标记为已读时批量插入会违反唯一约束，逐条重试
+		for _, status := range missing {
+			if err := status.MarkRead(status.MemberId, status.BookId, status.DocumentId); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -33,31 +33,33 @@ var (
 )
 
 type BookResult struct {
-	BookId         int       `json:"book_id"`
-	BookName       string    `json:"book_name"`
-	ItemId         int       `json:"item_id"`
-	ItemName       string    `json:"item_name"`
-	Identify       string    `json:"identify"`
-	OrderIndex     int       `json:"order_index"`
-	Description    string    `json:"description"`
-	Publisher      string    `json:"publisher"`
-	PrivatelyOwned int       `json:"privately_owned"`
-	PrivateToken   string    `json:"private_token"`
-	BookPassword   string    `json:"book_password"`
-	DocCount       int       `json:"doc_count"`
-	CommentStatus  string    `json:"comment_status"`
-	CommentCount   int       `json:"comment_count"`
-	CreateTime     time.Time `json:"create_time"`
-	CreateName     string    `json:"create_name"`
-	RealName       string    `json:"real_name"`
-	ModifyTime     time.Time `json:"modify_time"`
-	Cover          string    `json:"cover"`
-	Theme          string    `json:"theme"`
-	Label          string    `json:"label"`
-	MemberId       int       `json:"member_id"`
-	Editor         string    `json:"editor"`
-	AutoRelease    bool      `json:"auto_release"`
-	HistoryCount   int       `json:"history_count"`
+	BookId          int       `json:"book_id"`
+	BookName        string    `json:"book_name"`
+	ItemId          int       `json:"item_id"`
+	ItemName        string    `json:"item_name"`
+	Identify        string    `json:"identify"`
+	OrderIndex      int       `json:"order_index"`
+	Description     string    `json:"description"`
+	Publisher       string    `json:"publisher"`
+	PrivatelyOwned  int       `json:"privately_owned"`
+	PrivateToken    string    `json:"private_token"`
+	BookPassword    string    `json:"book_password"`
+	DocCount        int       `json:"doc_count"`
+	CommentStatus   string    `json:"comment_status"`
+	CommentCount    int       `json:"comment_count"`
+	CreateTime      time.Time `json:"create_time"`
+	CreateName      string    `json:"create_name"`
+	RealName        string    `json:"real_name"`
+	ModifyTime      time.Time `json:"modify_time"`
+	Cover           string    `json:"cover"`
+	Theme           string    `json:"theme"`
+	Label           string    `json:"label"`
+	MemberId        int       `json:"member_id"`
+	Editor          string    `json:"editor"`
+	AutoRelease     bool      `json:"auto_release"`
+	HistoryCount    int       `json:"history_count"`
+	ReviewDays      int       `json:"review_days"`
+	CommentModerate bool      `json:"comment_moderate"`
 
 	//RelationshipId     int           `json:"relationship_id"`
 	//TeamRelationshipId int           `json:"team_relationship_id"`
@@ -212,6 +214,8 @@ func (m *BookResult) ToBookResult(book Book) *BookResult {
 	m.IsUseFirstDocument = book.IsUseFirstDocument == 1
 	m.Publisher = book.Publisher
 	m.HistoryCount = book.HistoryCount
+	m.ReviewDays = book.ReviewDays
+	m.CommentModerate = book.CommentModerate == 1
 	m.IsDownload = book.IsDownload == 0
 	m.AutoSave = book.AutoSave == 1
 	m.PrintState = book.PrintSate == 1
@@ -430,6 +434,7 @@ func (m *BookResult) Converter(sessionId string) (ConvertBookResult, error) {
 		bufio := bytes.NewReader(buf.Bytes())
 
 		doc, err := goquery.NewDocumentFromReader(bufio)
+		renderExportMath(doc)
 		doc.Find("img").Each(func(i int, contentSelection *goquery.Selection) {
 			if src, ok := contentSelection.Attr("src"); ok {
 				//var encodeString string
@@ -619,8 +624,11 @@ func exportMarkdown(p string, parentId int, bookId int, baseDir string, bookUrl
 		if strings.TrimSpace(doc.Markdown) != "" {
 			re := regexp.MustCompile(`!\[(.*?)\]\((.*?)\)`)
 
+			//将图表代码块替换为渲染后的图片
+			markdown = renderMarkdownDiagrams(doc)
+
 			//处理文档中图片
-			markdown = re.ReplaceAllStringFunc(doc.Markdown, func(image string) string {
+			markdown = re.ReplaceAllStringFunc(markdown, func(image string) string {
 				images := re.FindAllSubmatch([]byte(image), -1)
 				if len(images) <= 0 || len(images[0]) < 3 {
 					return image
@@ -0,0 +1,337 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/i18n"
+	"github.com/mindoc-org/mindoc/conf"
+	"github.com/mindoc-org/mindoc/utils/dingtalk"
+	"github.com/mindoc-org/mindoc/utils/workweixin"
+)
+
+// 群机器人平台.
+const (
+	RobotDingTalk = "dingtalk"
+	RobotWeCom    = "wecom"
+)
+
+// 推送到群机器人的项目动态.
+const (
+	// RobotEventDocument 文档发布
+	RobotEventDocument = "document"
+	// RobotEventComment 文档有新评论
+	RobotEventComment = "comment"
+	// RobotEventBook 发布整个项目
+	RobotEventBook = "book"
+)
+
+// RobotPlatforms 支持的群机器人平台.
+var RobotPlatforms = []string{RobotDingTalk, RobotWeCom}
+
+// RobotEvents 支持推送的项目动态.
+var RobotEvents = []string{RobotEventDocument, RobotEventComment, RobotEventBook}
+
+// 项目发布消息中最多列出的文档数量.
+const robotMaxDocuments = 10
+
+var ErrRobotInvalid = errors.New("群机器人配置无效")
+
+// BookRobot 项目的钉钉或企业微信群机器人，项目有动态时推送 Markdown 消息.
+type BookRobot struct {
+	RobotId  int    `orm:"column(robot_id);pk;auto;unique" json:"robot_id"`
+	BookId   int    `orm:"column(book_id);type(int);index;description(项目id)" json:"book_id"`
+	Platform string `orm:"column(platform);size(20);description(平台 dingtalk/wecom)" json:"platform"`
+	Name     string `orm:"column(name);size(100);description(机器人名称)" json:"name"`
+	Webhook  string `orm:"column(webhook);size(1000);description(Webhook 地址)" json:"webhook"`
+	// Secret 钉钉机器人的加签密钥，企业微信机器人使用 Webhook 地址中的 key 验证
+	Secret string `orm:"column(secret);size(255);null;description(加签密钥)" json:"-"`
+	// Events 推送的项目动态，多个使用逗号分隔
+	Events       string    `orm:"column(events);size(255);description(推送的项目动态)" json:"events"`
+	Enabled      int       `orm:"column(enabled);type(int);default(1);description(是否启用 0：停用 1：启用)" json:"enabled"`
+	LastSendTime time.Time `orm:"type(datetime);column(last_send_time);null;description(上次推送时间)" json:"last_send_time"`
+	LastError    string    `orm:"column(last_error);size(1000);null;description(上次推送的错误信息)" json:"last_error"`
+	CreateTime   time.Time `orm:"type(datetime);column(create_time);auto_now_add" json:"create_time"`
+	ModifyTime   time.Time `orm:"type(datetime);column(modify_time);auto_now" json:"modify_time"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *BookRobot) TableName() string {
+	return "book_robots"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *BookRobot) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *BookRobot) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewBookRobot() *BookRobot {
+	return &BookRobot{}
+}
+
+// Find 查询群机器人.
+func (m *BookRobot) Find(robotId int) (*BookRobot, error) {
+	err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("robot_id", robotId).One(m)
+	return m, err
+}
+
+// FindByBookId 查询项目的全部群机器人.
+func (m *BookRobot) FindByBookId(bookId int) ([]*BookRobot, error) {
+	var robots []*BookRobot
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("book_id", bookId).
+		OrderBy("robot_id").
+		All(&robots)
+	if err == orm.ErrNoRows {
+		err = nil
+	}
+	return robots, err
+}
+
+// Save 添加或修改群机器人.
+func (m *BookRobot) Save() error {
+	m.Name = strings.TrimSpace(m.Name)
+	m.Webhook = strings.TrimSpace(m.Webhook)
+	m.Secret = strings.TrimSpace(m.Secret)
+
+	if m.BookId <= 0 || m.Name == "" || !isRobotPlatform(m.Platform) || len(m.EventList()) == 0 {
+		return ErrRobotInvalid
+	}
+	if !m.isAllowedWebhook() {
+		return ErrRobotInvalid
+	}
+	m.Events = strings.Join(m.EventList(), ",")
+	if m.Enabled != 0 {
+		m.Enabled = 1
+	}
+	var err error
+	if m.RobotId > 0 {
+		_, err = orm.NewOrm().Update(m, "platform", "name", "webhook", "secret", "events", "enabled", "modify_time")
+	} else {
+		_, err = orm.NewOrm().Insert(m)
+	}
+	return err
+}
+
+// Delete 删除群机器人.
+func (m *BookRobot) Delete() error {
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("robot_id", m.RobotId).Delete()
+	return err
+}
+
+// EventList 获取推送的项目动态，忽略不支持的动态.
+func (m *BookRobot) EventList() []string {
+	var events []string
+	for _, event := range RobotEvents {
+		if m.HasEvent(event) {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// HasEvent 判断是否推送指定的项目动态.
+func (m *BookRobot) HasEvent(event string) bool {
+	for _, item := range strings.Split(m.Events, ",") {
+		if strings.TrimSpace(item) == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Masked 隐藏 Webhook 地址中的凭据，用于记录日志.
+func (m *BookRobot) Masked() *BookRobot {
+	robot := *m
+	if u, err := url.Parse(robot.Webhook); err == nil {
+		u.RawQuery = ""
+		robot.Webhook = u.String()
+	}
+	return &robot
+}
+
+// Send 发送 Markdown 消息并记录推送结果.
+func (m *BookRobot) Send(title, text string) error {
+	var err error
+	switch {
+	case !m.isAllowedWebhook():
+		err = ErrRobotInvalid
+	case m.Platform == RobotDingTalk:
+		err = dingtalk.NewRobot(m.Webhook, m.Secret).SendMarkdown(title, text)
+	case m.Platform == RobotWeCom:
+		err = workweixin.SendRobotMarkdown(m.Webhook, text)
+	default:
+		err = ErrRobotInvalid
+	}
+	m.LastSendTime = time.Now()
+	m.LastError = ""
+	if err != nil {
+		m.LastError = truncateRunes(err.Error(), 1000)
+	}
+	if _, e := orm.NewOrm().Update(m, "last_send_time", "last_error"); e != nil {
+		logs.Error("更新群机器人推送状态失败 ->", m.RobotId, e)
+	}
+	return err
+}
+
+// isAllowedWebhook 判断 Webhook 是否为平台的官方地址或配置中额外允许的主机，避免向内网地址发起请求.
+func (m *BookRobot) isAllowedWebhook() bool {
+	u, err := url.Parse(m.Webhook)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.User != nil {
+		return false
+	}
+	host := strings.ToLower(u.Host)
+	for _, item := range conf.GetRobotWebhookHosts() {
+		if item == host {
+			return true
+		}
+	}
+	if u.Scheme != "https" || u.Port() != "" {
+		return false
+	}
+	for _, item := range conf.RobotWebhookHosts[m.Platform] {
+		if item == host {
+			return true
+		}
+	}
+	return false
+}
+
+func isRobotPlatform(platform string) bool {
+	for _, item := range RobotPlatforms {
+		if item == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifyBookRobots 将项目动态推送到项目中启用的群机器人.
+func NotifyBookRobots(bookId int, event, title, text string) {
+	var robots []*BookRobot
+	_, err := orm.NewOrm().QueryTable(NewBookRobot().TableNameWithPrefix()).
+		Filter("book_id", bookId).
+		Filter("enabled", 1).
+		All(&robots)
+	if err != nil {
+		if err != orm.ErrNoRows {
+			logs.Error("查询群机器人失败 ->", bookId, err)
+		}
+		return
+	}
+	for _, robot := range robots {
+		if !robot.HasEvent(event) {
+			continue
+		}
+		go func(robot *BookRobot) {
+			if err := robot.Send(title, text); err != nil {
+				logs.Error("群机器人推送失败 ->", robot.RobotId, robot.Name, err)
+			}
+		}(robot)
+	}
+}
+
+// robotLang 群机器人消息使用的语言.
+func robotLang() string {
+	lang, _ := web.AppConfig.String("default_lang")
+	return lang
+}
+
+// memberDisplayName 获取用户的显示名称.
+func memberDisplayName(memberId int) string {
+	member, err := NewMember().Find(memberId, "member_id", "account", "real_name")
+	if err != nil {
+		return ""
+	}
+	if member.RealName != "" {
+		return member.RealName
+	}
+	return member.Account
+}
+
+// robotMarkdownReplacer 转义 Markdown 控制字符，避免用户输入的内容被解析为链接、提及或格式.
+var robotMarkdownReplacer = strings.NewReplacer(
+	"\\", "\\\\", "`", "\\`", "*", "\\*", "_", "\\_", "~", "\\~",
+	"[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)", "<", "&lt;", ">", "&gt;",
+	"#", "\\#", "!", "\\!", "|", "\\|", "@", "\\@",
+)
+
+// escapeRobotMarkdown 转义群机器人消息中用户输入的内容.
+func escapeRobotMarkdown(s string) string {
+	return robotMarkdownReplacer.Replace(s)
+}
+
+// NotifyRobotsDocumentRelease 推送文档发布消息.
+func NotifyRobotsDocumentRelease(doc *Document) {
+	book, err := NewBook().Find(doc.BookId, "book_id", "book_name", "identify")
+	if err != nil {
+		return
+	}
+	lang := robotLang()
+	title := i18n.Tr(lang, "message.robot_document_title", doc.DocumentName)
+
+	var text strings.Builder
+	text.WriteString("#### " + title + "\n\n")
+	text.WriteString(i18n.Tr(lang, "message.robot_book", fmt.Sprintf("[%s](%s)", book.BookName, conf.URLFor("DocumentController.Index", ":key", book.Identify))) + "\n\n")
+	if editor := memberDisplayName(doc.ModifyAt); editor != "" {
+		text.WriteString(i18n.Tr(lang, "message.robot_editor", editor) + "\n\n")
+	}
+	text.WriteString(i18n.Tr(lang, "message.robot_time", doc.ModifyTime.Local().Format("2006-01-02 15:04")) + "\n\n")
+	text.WriteString(fmt.Sprintf("[%s](%s)", i18n.Tr(lang, "message.robot_view_document"), conf.URLFor("DocumentController.Read", ":key", book.Identify, ":id", doc.DocumentId)))
+
+	NotifyBookRobots(book.BookId, RobotEventDocument, title, text.String())
+}
+
+// NotifyRobotsBookRelease 推送项目发布消息，列出本次发布内容有变化的文档.
+func NotifyRobotsBookRelease(bookId int, docs []*Document) {
+	if len(docs) == 0 {
+		return
+	}
+	book, err := NewBook().Find(bookId, "book_id", "book_name", "identify")
+	if err != nil {
+		return
+	}
+	lang := robotLang()
+	title := i18n.Tr(lang, "message.robot_book_title", book.BookName)
+
+	var text strings.Builder
+	text.WriteString("#### " + title + "\n\n")
+	text.WriteString(i18n.Tr(lang, "message.robot_book_body", len(docs)) + "\n\n")
+	for i, doc := range docs {
+		if i >= robotMaxDocuments {
+			text.WriteString("- ...\n")
+			break
+		}
+		text.WriteString(fmt.Sprintf("- [%s](%s)\n", doc.DocumentName, conf.URLFor("DocumentController.Read", ":key", book.Identify, ":id", doc.DocumentId)))
+	}
+	text.WriteString(fmt.Sprintf("\n[%s](%s)", i18n.Tr(lang, "message.robot_view_book"), conf.URLFor("DocumentController.Index", ":key", book.Identify)))
+
+	NotifyBookRobots(book.BookId, RobotEventBook, title, text.String())
+}
+
+// NotifyRobotsComment 推送文档的新评论.
+func NotifyRobotsComment(comment *Comment, doc *Document, book *Book) {
+	lang := robotLang()
+	title := i18n.Tr(lang, "message.robot_comment_title", doc.DocumentName)
+
+	content := strings.Join(strings.Fields(comment.Content), " ")
+	if len([]rune(content)) > 200 {
+		content = truncateRunes(content, 200) + "..."
+	}
+	var text strings.Builder
+	text.WriteString("#### " + title + "\n\n")
+	text.WriteString(fmt.Sprintf("> **%s**: %s\n\n", escapeRobotMarkdown(comment.Author), escapeRobotMarkdown(content)))
+	text.WriteString(fmt.Sprintf("[%s](%s#articleComment)", i18n.Tr(lang, "blog.comment_view"), conf.URLFor("DocumentController.Read", ":key", book.Identify, ":id", doc.DocumentId)))
+
+	NotifyBookRobots(book.BookId, RobotEventComment, title, text.String())
+}
@@ -0,0 +1,156 @@
+package models
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/mindoc-org/mindoc/utils/textanchor"
+)
+
+// releaseHeading 标题在发布内容文字中的位置.
+type releaseHeading struct {
+	Offset int
+	Title  string
+}
+
+// releaseText 提取发布内容的文字，空白字符与 textanchor.Normalize 的处理方式一致，同时记录各级标题的位置.
+func releaseText(release string) (string, []releaseHeading) {
+	var buf strings.Builder
+	var headings []releaseHeading
+	length := 0
+	space := false
+
+	docQuery, err := goquery.NewDocumentFromReader(bytes.NewBufferString(release))
+	if err != nil {
+		return "", nil
+	}
+	var walk func(selection *goquery.Selection)
+	walk = func(selection *goquery.Selection) {
+		selection.Contents().Each(func(i int, node *goquery.Selection) {
+			switch name := goquery.NodeName(node); name {
+			case "#text":
+				for _, r := range node.Text() {
+					if unicode.IsSpace(r) {
+						space = length > 0
+						continue
+					}
+					if space {
+						buf.WriteByte(' ')
+						length++
+						space = false
+					}
+					buf.WriteRune(r)
+					length++
+				}
+			case "script", "style":
+			case "h1", "h2", "h3", "h4", "h5", "h6":
+				headings = append(headings, releaseHeading{Offset: length, Title: textanchor.Normalize(node.Text())})
+				walk(node)
+			default:
+				walk(node)
+			}
+		})
+	}
+	walk(docQuery.Find("body"))
+	return buf.String(), headings
+}
+
+// headingAt 获取文字位置所在章节的标题.
+func headingAt(headings []releaseHeading, offset int) string {
+	title := ""
+	for _, heading := range headings {
+		if heading.Offset > offset {
+			break
+		}
+		title = heading.Title
+	}
+	return title
+}
+
+// truncateRunes 按字符截断文字.
+func truncateRunes(text string, length int) string {
+	if runes := []rune(text); len(runes) > length {
+		return string(runes[:length])
+	}
+	return text
+}
+
+// SetAnchor 设置行内评论引用的原文，原文能在文档中找到时使用文档中的文字和上下文.
+func (m *Comment) SetAnchor(doc *Document, selector textanchor.Selector, heading string) {
+	selector.Exact = truncateRunes(textanchor.Normalize(selector.Exact), 500)
+	if selector.Exact == "" {
+		return
+	}
+	text, headings := releaseText(doc.Release)
+	if match, ok := textanchor.Find(text, selector, 1); ok {
+		selector = match.Selector
+		heading = headingAt(headings, match.Start)
+	}
+	m.AnchorText = selector.Exact
+	m.AnchorPrefix = truncateRunes(selector.Prefix, 100)
+	m.AnchorSuffix = truncateRunes(selector.Suffix, 100)
+	m.AnchorHeading = truncateRunes(textanchor.Normalize(heading), 255)
+}
+
+// ReanchorComments 文档发布后重新定位行内评论，找不到原文的评论标记为已失效.
+func ReanchorComments(doc *Document) {
+	o := orm.NewOrm()
+	var comments []*Comment
+	_, err := o.QueryTable(NewComment().TableNameWithPrefix()).
+		Filter("document_id", doc.DocumentId).
+		Filter("parent_id", 0).
+		Filter("anchor_text__isnull", false).
+		Exclude("anchor_text", "").
+		Exclude("approved", CommentDeleted).
+		All(&comments, "comment_id", "anchor_text", "anchor_prefix", "anchor_suffix", "anchor_heading", "orphaned")
+	if err != nil {
+		if err != orm.ErrNoRows {
+			logs.Error("查询行内评论失败 ->", doc.DocumentId, err)
+		}
+		return
+	}
+	if len(comments) == 0 {
+		return
+	}
+	text, headings := releaseText(doc.Release)
+	orphaned := 0
+
+	for _, comment := range comments {
+		selector := textanchor.Selector{Exact: comment.AnchorText, Prefix: comment.AnchorPrefix, Suffix: comment.AnchorSuffix}
+		if match, ok := textanchor.Find(text, selector, textanchor.DefaultThreshold); ok {
+			comment.AnchorText = match.Exact
+			comment.AnchorPrefix = truncateRunes(match.Prefix, 100)
+			comment.AnchorSuffix = truncateRunes(match.Suffix, 100)
+			comment.AnchorHeading = truncateRunes(headingAt(headings, match.Start), 255)
+			comment.Orphaned = 0
+		} else {
+			comment.Orphaned = 1
+			orphaned++
+		}
+		if _, err := o.Update(comment, "anchor_text", "anchor_prefix", "anchor_suffix", "anchor_heading", "orphaned"); err != nil {
+			logs.Error("更新行内评论位置失败 ->", comment.CommentId, err)
+		}
+	}
+	if orphaned > 0 {
+		logs.Info("文档发布后部分行内评论已失效 ->", doc.DocumentId, orphaned)
+	}
+}
+
+// Resolve 解决或重新打开行内评论.
+func (m *Comment) Resolve(memberId int, resolved bool) error {
+	if resolved {
+		m.Resolved = 1
+		m.ResolvedBy = memberId
+		m.ResolvedTime = time.Now()
+	} else {
+		m.Resolved = 0
+		m.ResolvedBy = 0
+	}
+	_, err := orm.NewOrm().Update(m, "resolved", "resolved_by", "resolved_time")
+	return err
+}
@@ -8,6 +8,14 @@ import (
 	"github.com/mindoc-org/mindoc/conf"
 )
 
+// 评论审核状态.
+const (
+	CommentPending  = 0
+	CommentApproved = 1
+	CommentSpam     = 2
+	CommentDeleted  = 3
+)
+
 // Comment struct
 type Comment struct {
 	CommentId int `orm:"pk;auto;unique;column(comment_id)" json:"comment_id"`
@@ -30,12 +38,33 @@ type Comment struct {
 	// UserAgent 评论者浏览器内容
 	UserAgent string `orm:"column(user_agent);size(500)" json:"user_agent"`
 	// Parent 评论所属父级
-	ParentId     int    `orm:"column(parent_id);type(int);default(0)" json:"parent_id"`
-	AgreeCount   int    `orm:"column(agree_count);type(int);default(0)" json:"agree_count"`
-	AgainstCount int    `orm:"column(against_count);type(int);default(0)" json:"against_count"`
-	Index        int    `orm:"-" json:"index"`
-	ShowDel      int    `orm:"-" json:"show_del"`
-	Avatar       string `orm:"-" json:"avatar"`
+	ParentId int `orm:"column(parent_id);type(int);default(0)" json:"parent_id"`
+	// AnchorText 行内评论引用的原文，AnchorPrefix 和 AnchorSuffix 为原文前后的文字，用于文档修改后重新定位.
+	AnchorText   string `orm:"column(anchor_text);size(500);null" json:"anchor_text"`
+	AnchorPrefix string `orm:"column(anchor_prefix);size(100);null" json:"anchor_prefix"`
+	AnchorSuffix string `orm:"column(anchor_suffix);size(100);null" json:"anchor_suffix"`
+	// AnchorHeading 原文所在章节的标题.
+	AnchorHeading string `orm:"column(anchor_heading);size(255);null" json:"anchor_heading"`
+	// Orphaned 原文已被删除，无法重新定位：0 否/1 是
+	Orphaned int `orm:"column(orphaned);type(int);default(0)" json:"orphaned"`
+	// Resolved 行内评论是否已解决：0 否/1 是
+	Resolved     int       `orm:"column(resolved);type(int);default(0)" json:"resolved"`
+	ResolvedBy   int       `orm:"column(resolved_by);type(int);default(0)" json:"resolved_by"`
+	ResolvedTime time.Time `orm:"type(datetime);column(resolved_time);null" json:"resolved_time"`
+	AgreeCount   int       `orm:"column(agree_count);type(int);default(0)" json:"agree_count"`
+	AgainstCount int       `orm:"column(against_count);type(int);default(0)" json:"against_count"`
+	Index        int       `orm:"-" json:"index"`
+	ShowDel      int       `orm:"-" json:"show_del"`
+	Avatar       string    `orm:"-" json:"avatar"`
+	// ShowResolve 当前用户是否可以解决或重新打开行内评论.
+	ShowResolve bool `orm:"-" json:"show_resolve"`
+	// HtmlContent 评论内容渲染后的 HTML.
+	HtmlContent string `orm:"-" json:"html_content"`
+	// Account 评论作者的账号，用于 @ 提及.
+	Account string `orm:"-" json:"account"`
+	// ReplyTo 回复的评论作者.
+	ReplyTo string     `orm:"-" json:"reply_to"`
+	Replies []*Comment `orm:"-" json:"replies"`
 }
 
 // TableName 获取对应数据库表名.
@@ -61,6 +90,11 @@ func (m *Comment) CanDelete(user_memberid int, user_bookrole conf.BookRole) bool
 	return user_memberid == m.MemberId || user_bookrole == conf.BookFounder || user_bookrole == conf.BookAdmin
 }
 
+// CanResolve 是否有权限解决或重新打开行内评论，评论者和项目的编辑者以上角色可以操作.
+func (m *Comment) CanResolve(memberId int, bookRole conf.BookRole) bool {
+	return memberId == m.MemberId || bookRole == conf.BookFounder || bookRole == conf.BookAdmin || bookRole == conf.BookEditor
+}
+
 // 根据文档id查询文档评论
 func (m *Comment) QueryCommentByDocumentId(doc_id, page, pagesize int, member *Member) (comments []*Comment, count int64, ret_page int) {
 	doc, err := NewDocument().Find(doc_id)
@@ -68,8 +102,23 @@ func (m *Comment) QueryCommentByDocumentId(doc_id, page, pagesize int, member *M
 		return
 	}
 
+	book, err := NewBook().Find(doc.BookId, "book_id", "comment_moderate")
+	if err != nil {
+		return
+	}
+	// 开启审核后待审核的评论只对评论者本人显示
+	cond := orm.NewCondition().And("approved", CommentApproved)
+	if book.CommentModerate == 0 {
+		cond = cond.Or("approved", CommentPending)
+	} else if member != nil {
+		cond = cond.OrCond(orm.NewCondition().And("approved", CommentPending).And("member_id", member.MemberId))
+	}
+	visible := orm.NewCondition().And("document_id", doc_id).AndCond(cond)
+	// 分页只计算顶层评论，回复跟随所属的评论一起显示
+	cond = orm.NewCondition().And("parent_id", 0).AndCond(visible)
+
 	o := orm.NewOrm()
-	count, _ = o.QueryTable(m.TableNameWithPrefix()).Filter("document_id", doc_id).Count()
+	count, _ = o.QueryTable(m.TableNameWithPrefix()).SetCond(cond).Count()
 	if -1 == page { // 请求最后一页
 		var total int = int(count)
 		if total%pagesize == 0 {
@@ -80,19 +129,34 @@ func (m *Comment) QueryCommentByDocumentId(doc_id, page, pagesize int, member *M
 	}
 	offset := (page - 1) * pagesize
 	ret_page = page
-	o.QueryTable(m.TableNameWithPrefix()).Filter("document_id", doc_id).OrderBy("comment_date").Offset(offset).Limit(pagesize).All(&comments)
+	o.QueryTable(m.TableNameWithPrefix()).SetCond(cond).OrderBy("comment_date").Offset(offset).Limit(pagesize).All(&comments)
+
+	replies := m.findReplies(comments, visible)
 
 	// 需要判断未登录的情况
 	var bookRole conf.BookRole
 	if member != nil {
 		bookRole, _ = NewRelationship().FindForRoleId(doc.BookId, member.MemberId)
 	}
+	all := append(append([]*Comment{}, comments...), replies...)
+	authors := commentMembers(all)
+	for _, comment := range all {
+		if member != nil && comment.CanDelete(member.MemberId, bookRole) {
+			comment.ShowDel = 1
+		}
+		if member != nil && comment.AnchorText != "" {
+			comment.ShowResolve = comment.CanResolve(member.MemberId, bookRole)
+		}
+		if author, ok := authors[comment.MemberId]; ok {
+			comment.Account = author.Account
+			comment.Avatar = author.Avatar
+		} else {
+			comment.Avatar = conf.GetDefaultAvatar()
+		}
+		comment.HtmlContent = RenderCommentContent(comment.Content)
+	}
 	for i := 0; i < len(comments); i++ {
 		comments[i].Index = (i + 1) + (page-1)*pagesize
-		if member != nil && comments[i].CanDelete(member.MemberId, bookRole) {
-			comments[i].ShowDel = 1
-			comments[i].Avatar = member.Avatar
-		}
 	}
 	return
 }
@@ -116,11 +180,14 @@ func (m *Comment) Insert() error {
 
 	o := orm.NewOrm()
 
-	if m.CommentId > 0 {
-		comment := NewComment()
+	if m.ParentId > 0 {
+		parent := NewComment()
 		//如果父评论不存在
-		if err := o.Read(comment); err != nil {
-			return err
+		if _, err := parent.Find(m.ParentId); err != nil {
+			return ErrCommentParentNotExist
+		}
+		if parent.DocumentId != m.DocumentId || parent.Approved == CommentSpam || parent.Approved == CommentDeleted {
+			return ErrCommentParentNotExist
 		}
 	}
 
@@ -171,10 +238,22 @@ func (m *Comment) Insert() error {
 	return err
 }
 
-// 删除一条评论
+// 删除一条评论及其全部回复
 func (m *Comment) Delete() error {
 	o := orm.NewOrm()
-	_, err := o.Delete(m)
+	ids := []int{m.CommentId}
+	for parents := ids; len(parents) > 0; {
+		var replies []*Comment
+		if _, err := o.QueryTable(m.TableNameWithPrefix()).Filter("parent_id__in", parents).All(&replies, "comment_id"); err != nil && err != orm.ErrNoRows {
+			return err
+		}
+		parents = make([]int, 0, len(replies))
+		for _, reply := range replies {
+			parents = append(parents, reply.CommentId)
+		}
+		ids = append(ids, parents...)
+	}
+	_, err := o.QueryTable(m.TableNameWithPrefix()).Filter("comment_id__in", ids).Delete()
 	return err
 }
 
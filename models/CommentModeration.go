@@ -0,0 +1,250 @@
+package models
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+	"github.com/mindoc-org/mindoc/conf"
+)
+
+var commentLinkRegexp = regexp.MustCompile(`(?i)(https?://|ftp://|www\.)`)
+
+// CommentModerationResult 审核队列中的评论.
+type CommentModerationResult struct {
+	*Comment
+	DocumentName string `json:"document_name"`
+	BookName     string `json:"book_name"`
+	BookIdentify string `json:"book_identify"`
+}
+
+// CommentApprovedStatus 根据项目的审核设置和垃圾评论规则获取新评论的审核状态.
+func CommentApprovedStatus(book *Book, content string, bookRole conf.BookRole) int {
+	// 项目创始人和管理员的评论不需要审核
+	if bookRole == conf.BookFounder || bookRole == conf.BookAdmin {
+		return CommentApproved
+	}
+	if IsSpamComment(content) {
+		return CommentSpam
+	}
+	if book.CommentModerate == 1 {
+		return CommentPending
+	}
+	return CommentApproved
+}
+
+// IsSpamComment 判断评论是否包含屏蔽关键词或者链接数量超过限制.
+func IsSpamComment(content string) bool {
+	lower := strings.ToLower(content)
+	for _, keyword := range strings.FieldsFunc(GetOptionValue("COMMENT_SPAM_KEYWORDS", ""), func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n' || r == '\r'
+	}) {
+		keyword = strings.ToLower(strings.TrimSpace(keyword))
+		if keyword != "" && strings.Contains(lower, keyword) {
+			return true
+		}
+	}
+	if maxLinks, err := strconv.Atoi(GetOptionValue("COMMENT_SPAM_MAX_LINKS", "2")); err == nil && maxLinks > 0 {
+		if len(commentLinkRegexp.FindAllStringIndex(content, -1)) > maxLinks {
+			return true
+		}
+	}
+	return false
+}
+
+// FindModerationComments 分页查询指定审核状态的评论，bookId 为 0 时查询全部项目.
+func FindModerationComments(bookId, approved, pageIndex, pageSize int) ([]*CommentModerationResult, int, error) {
+	qs := orm.NewOrm().QueryTable(NewComment().TableNameWithPrefix()).Filter("approved", approved)
+	if bookId > 0 {
+		qs = qs.Filter("book_id", bookId)
+	}
+	return findCommentResults(qs, pageIndex, pageSize)
+}
+
+// FindOrphanedComments 分页查询原文已被删除的行内评论，bookId 为 0 时查询全部项目.
+func FindOrphanedComments(bookId, pageIndex, pageSize int) ([]*CommentModerationResult, int, error) {
+	qs := orm.NewOrm().QueryTable(NewComment().TableNameWithPrefix()).
+		Filter("orphaned", 1).
+		Filter("parent_id", 0).
+		Exclude("approved", CommentDeleted)
+	if bookId > 0 {
+		qs = qs.Filter("book_id", bookId)
+	}
+	return findCommentResults(qs, pageIndex, pageSize)
+}
+
+// findCommentResults 分页查询评论并补充所属的文档和项目名称.
+func findCommentResults(qs orm.QuerySeter, pageIndex, pageSize int) (list []*CommentModerationResult, totalCount int, err error) {
+	offset := (pageIndex - 1) * pageSize
+
+	var comments []*Comment
+	_, err = qs.OrderBy("-comment_id").Offset(offset).Limit(pageSize).All(&comments)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			err = nil
+		} else {
+			logs.Error("查询审核评论失败 ->", err)
+		}
+		return
+	}
+	count, err := qs.Count()
+	if err != nil {
+		logs.Error("查询审核评论失败 ->", err)
+		return
+	}
+	totalCount = int(count)
+
+	docIds := make([]int, 0, len(comments))
+	bookIds := make([]int, 0, len(comments))
+	for _, comment := range comments {
+		docIds = append(docIds, comment.DocumentId)
+		bookIds = append(bookIds, comment.BookId)
+	}
+	docNames := make(map[int]string)
+	bookNames := make(map[int]*Book)
+	if len(comments) > 0 {
+		var docs []*Document
+		if _, err := orm.NewOrm().QueryTable(NewDocument().TableNameWithPrefix()).Filter("document_id__in", docIds).All(&docs, "document_id", "document_name"); err == nil {
+			for _, doc := range docs {
+				docNames[doc.DocumentId] = doc.DocumentName
+			}
+		}
+		var books []*Book
+		if _, err := orm.NewOrm().QueryTable(NewBook().TableNameWithPrefix()).Filter("book_id__in", bookIds).All(&books, "book_id", "book_name", "identify"); err == nil {
+			for _, book := range books {
+				bookNames[book.BookId] = book
+			}
+		}
+	}
+	for _, comment := range comments {
+		item := &CommentModerationResult{Comment: comment, DocumentName: docNames[comment.DocumentId]}
+		if book, ok := bookNames[comment.BookId]; ok {
+			item.BookName = book.BookName
+			item.BookIdentify = book.Identify
+		}
+		list = append(list, item)
+	}
+	return
+}
+
+// CountModerationComments 统计各审核状态的评论数量，bookId 为 0 时统计全部项目.
+func CountModerationComments(bookId int) map[int]int64 {
+	counts := make(map[int]int64)
+	for _, approved := range []int{CommentPending, CommentApproved, CommentSpam} {
+		qs := orm.NewOrm().QueryTable(NewComment().TableNameWithPrefix()).Filter("approved", approved)
+		if bookId > 0 {
+			qs = qs.Filter("book_id", bookId)
+		}
+		counts[approved], _ = qs.Count()
+	}
+	return counts
+}
+
+// CountOrphanedComments 统计原文已被删除的行内评论数量，bookId 为 0 时统计全部项目.
+func CountOrphanedComments(bookId int) int64 {
+	qs := orm.NewOrm().QueryTable(NewComment().TableNameWithPrefix()).
+		Filter("orphaned", 1).
+		Filter("parent_id", 0).
+		Exclude("approved", CommentDeleted)
+	if bookId > 0 {
+		qs = qs.Filter("book_id", bookId)
+	}
+	count, _ := qs.Count()
+	return count
+}
+
+// ModerateComments 批量修改评论的审核状态，bookId 大于 0 时只修改该项目的评论.
+func ModerateComments(bookId int, commentIds []int, approved int) (int64, error) {
+	if len(commentIds) == 0 {
+		return 0, nil
+	}
+	if approved < CommentPending || approved > CommentDeleted {
+		return 0, ErrInvalidParameter
+	}
+	qs := orm.NewOrm().QueryTable(NewComment().TableNameWithPrefix()).Filter("comment_id__in", commentIds)
+	if bookId > 0 {
+		qs = qs.Filter("book_id", bookId)
+	}
+	// 审核通过后才通知被回复和被提及的用户
+	var approving []*Comment
+	if approved == CommentApproved {
+		if _, err := qs.Exclude("approved", CommentApproved).All(&approving); err != nil && err != orm.ErrNoRows {
+			return 0, err
+		}
+	}
+	count, err := qs.Update(orm.Params{"approved": approved})
+	if err != nil {
+		return count, err
+	}
+	if len(approving) > 0 {
+		go func() {
+			for _, comment := range approving {
+				comment.Approved = CommentApproved
+				if err := NotifyCommentRecipients(comment); err != nil {
+					logs.Error("发送评论通知失败 ->", comment.CommentId, err)
+				}
+			}
+		}()
+	}
+	return count, nil
+}
+
+// NotifyCommentModerators 通过站内通知和邮件通知项目创始人和管理员有新的评论等待审核.
+func NotifyCommentModerators(comment *Comment) error {
+	mailConf := conf.GetMailConfig()
+	book, err := NewBook().Find(comment.BookId, "book_id", "book_name", "identify")
+	if err != nil {
+		return err
+	}
+	doc, err := NewDocument().Find(comment.DocumentId)
+	if err != nil {
+		return err
+	}
+	var relationships []*Relationship
+	_, err = orm.NewOrm().QueryTable(NewRelationship().TableNameWithPrefix()).
+		Filter("book_id", book.BookId).
+		Filter("role_id__in", conf.BookFounder, conf.BookAdmin).
+		All(&relationships, "member_id")
+	if err != nil {
+		return err
+	}
+	lang, _ := web.AppConfig.String("default_lang")
+
+	for _, relationship := range relationships {
+		if relationship.MemberId == comment.MemberId {
+			continue
+		}
+		member, err := NewMember().Find(relationship.MemberId, "member_id", "account", "real_name", "email", "status")
+		if err != nil || member.Status != 0 {
+			continue
+		}
+		_ = (&Notification{
+			MemberId:   member.MemberId,
+			SenderId:   comment.MemberId,
+			Type:       NotificationReviewComment,
+			Subject:    doc.DocumentName,
+			Content:    comment.Content,
+			Url:        web.URLFor("BookController.Comments", ":key", book.Identify),
+			BookId:     book.BookId,
+			DocumentId: doc.DocumentId,
+		}).Insert()
+
+		if !mailConf.EnableMail || member.Email == "" {
+			continue
+		}
+		data := map[string]interface{}{
+			"Member":       member,
+			"Comment":      comment,
+			"BookIdentify": book.Identify,
+			"BookName":     book.BookName,
+			"DocumentName": doc.DocumentName,
+		}
+		if err := SendTemplateMail(member.Email, MailCommentModeration, lang, data); err != nil {
+			logs.Error("发送评论审核通知失败 ->", member.Account, err)
+		}
+	}
+	return nil
+}
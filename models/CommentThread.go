@@ -0,0 +1,245 @@
+package models
+
+import (
+	"bytes"
+	"html/template"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+	"github.com/mindoc-org/mindoc/conf"
+	"github.com/mindoc-org/mindoc/utils"
+	"github.com/russross/blackfriday/v2"
+)
+
+// 回复的最大层级，超过后不再加载更深的回复.
+const maxCommentDepth = 10
+
+var commentMentionRegexp = regexp.MustCompile(`(^|[^a-zA-Z0-9.\-@])@([a-zA-Z0-9][a-zA-Z0-9.\-]{2,50})`)
+
+// findReplies 加载评论的全部可见回复，并挂载到所属的评论下.
+func (m *Comment) findReplies(comments []*Comment, cond *orm.Condition) []*Comment {
+	var replies []*Comment
+	nodes := make(map[int]*Comment, len(comments))
+	parents := make([]int, 0, len(comments))
+	for _, comment := range comments {
+		nodes[comment.CommentId] = comment
+		parents = append(parents, comment.CommentId)
+	}
+	o := orm.NewOrm()
+	for depth := 0; depth < maxCommentDepth && len(parents) > 0; depth++ {
+		var items []*Comment
+		_, err := o.QueryTable(m.TableNameWithPrefix()).
+			SetCond(orm.NewCondition().And("parent_id__in", parents).AndCond(cond)).
+			OrderBy("comment_date").
+			All(&items)
+		if err != nil {
+			if err != orm.ErrNoRows {
+				logs.Error("查询评论回复失败 ->", err)
+			}
+			break
+		}
+		parents = parents[:0:0]
+		for _, item := range items {
+			parent, ok := nodes[item.ParentId]
+			if !ok {
+				continue
+			}
+			item.ReplyTo = parent.Author
+			parent.Replies = append(parent.Replies, item)
+			nodes[item.CommentId] = item
+			parents = append(parents, item.CommentId)
+			replies = append(replies, item)
+		}
+	}
+	return replies
+}
+
+// commentMembers 查询评论作者的账号和头像.
+func commentMembers(comments []*Comment) map[int]*Member {
+	members := make(map[int]*Member)
+	memberIds := make([]int, 0, len(comments))
+	for _, comment := range comments {
+		if comment.MemberId > 0 {
+			memberIds = append(memberIds, comment.MemberId)
+		}
+	}
+	if len(memberIds) > 0 {
+		var items []*Member
+		_, err := orm.NewOrm().QueryTable(NewMember().TableNameWithPrefix()).Filter("member_id__in", memberIds).All(&items, "member_id", "account", "avatar")
+		if err != nil && err != orm.ErrNoRows {
+			logs.Error("查询评论用户失败 ->", err)
+		}
+		for _, member := range items {
+			if member.Avatar == "" {
+				member.Avatar = conf.GetDefaultAvatar()
+			}
+			members[member.MemberId] = member
+		}
+	}
+	return members
+}
+
+// ParseCommentMentions 解析评论中 @ 提及的账号，结果去重并保持出现顺序.
+func ParseCommentMentions(content string) []string {
+	var accounts []string
+	seen := make(map[string]bool)
+	for _, match := range commentMentionRegexp.FindAllStringSubmatch(content, -1) {
+		account := strings.TrimRight(match[2], ".-")
+		key := strings.ToLower(account)
+		if len(account) < 3 || seen[key] {
+			continue
+		}
+		seen[key] = true
+		accounts = append(accounts, account)
+	}
+	return accounts
+}
+
+// FindMentionedMembers 查询评论中提及的有效用户.
+func FindMentionedMembers(content string) ([]*Member, error) {
+	accounts := ParseCommentMentions(content)
+	if len(accounts) == 0 {
+		return nil, nil
+	}
+	var members []*Member
+	_, err := orm.NewOrm().QueryTable(NewMember().TableNameWithPrefix()).
+		Filter("account__in", accounts).
+		Filter("status", 0).
+		All(&members, "member_id", "account", "real_name", "email", "role", "status")
+	if err == orm.ErrNoRows {
+		err = nil
+	}
+	return members, err
+}
+
+// RenderCommentContent 将 Markdown 格式的评论渲染为安全的 HTML，并标记 @ 提及的用户.
+func RenderCommentContent(content string) string {
+	renderer := blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{
+		Flags: blackfriday.SkipHTML | blackfriday.Safelink | blackfriday.NofollowLinks | blackfriday.HrefTargetBlank,
+	})
+	html := utils.SafetyProcessor(string(blackfriday.Run([]byte(content), blackfriday.WithRenderer(renderer))))
+
+	if !strings.Contains(html, "@") {
+		return html
+	}
+	docQuery, err := goquery.NewDocumentFromReader(bytes.NewBufferString(html))
+	if err != nil {
+		return html
+	}
+	docQuery.Find("body, body *").Contents().Each(func(i int, selection *goquery.Selection) {
+		if goquery.NodeName(selection) != "#text" || !strings.Contains(selection.Text(), "@") {
+			return
+		}
+		if selection.ParentsFiltered("a, pre, code").Length() > 0 {
+			return
+		}
+		text := selection.Text()
+		var buf strings.Builder
+		last := 0
+		for _, match := range commentMentionRegexp.FindAllStringSubmatchIndex(text, -1) {
+			start, end := match[4]-1, match[5]
+			account := strings.TrimRight(text[match[4]:end], ".-")
+			end = match[4] + len(account)
+			buf.WriteString(template.HTMLEscapeString(text[last:start]))
+			buf.WriteString(`<span class="comment-mention">@` + template.HTMLEscapeString(account) + `</span>`)
+			last = end
+		}
+		if last == 0 {
+			return
+		}
+		buf.WriteString(template.HTMLEscapeString(text[last:]))
+		selection.ReplaceWithHtml(buf.String())
+	})
+	if body, err := docQuery.Find("body").Html(); err == nil {
+		return body
+	}
+	return html
+}
+
+// NotifyCommentRecipients 通过站内通知和邮件通知被回复的评论作者和评论中提及的用户，同时推送到项目的群机器人.
+func NotifyCommentRecipients(comment *Comment) error {
+	if comment.Approved != CommentApproved {
+		return nil
+	}
+	mailConf := conf.GetMailConfig()
+	book, err := NewBook().Find(comment.BookId, "book_id", "book_name", "identify", "privately_owned")
+	if err != nil {
+		return err
+	}
+	doc, err := NewDocument().Find(comment.DocumentId)
+	if err != nil {
+		return err
+	}
+	NotifyRobotsComment(comment, doc, book)
+
+	// 被回复的评论作者优先按回复通知
+	recipients := make(map[int]string)
+	var memberIds []int
+	if comment.ParentId > 0 {
+		if parent, err := NewComment().Find(comment.ParentId); err == nil && parent.MemberId > 0 {
+			recipients[parent.MemberId] = "reply"
+			memberIds = append(memberIds, parent.MemberId)
+		}
+	}
+	mentioned, err := FindMentionedMembers(comment.Content)
+	if err != nil {
+		return err
+	}
+	for _, member := range mentioned {
+		if _, ok := recipients[member.MemberId]; !ok {
+			recipients[member.MemberId] = "mention"
+			memberIds = append(memberIds, member.MemberId)
+		}
+	}
+	sort.Ints(memberIds)
+
+	lang, _ := web.AppConfig.String("default_lang")
+
+	for _, memberId := range memberIds {
+		if memberId == comment.MemberId {
+			continue
+		}
+		member, err := NewMember().Find(memberId, "member_id", "account", "real_name", "email", "role", "status")
+		if err != nil || member.Status != 0 {
+			continue
+		}
+		// 私有项目只通知有权限阅读的用户
+		if book.PrivatelyOwned == 1 && !member.IsAdministrator() {
+			if _, err := NewBook().FindForRoleId(book.BookId, member.MemberId); err != nil {
+				continue
+			}
+		}
+		reason := recipients[memberId]
+		_ = (&Notification{
+			MemberId:   memberId,
+			SenderId:   comment.MemberId,
+			Type:       reason,
+			Subject:    doc.DocumentName,
+			Content:    comment.Content,
+			Url:        documentPath(book.Identify, doc.DocumentId),
+			BookId:     book.BookId,
+			DocumentId: doc.DocumentId,
+		}).Insert()
+
+		if !mailConf.EnableMail || member.Email == "" {
+			continue
+		}
+		data := map[string]interface{}{
+			"Member":       member,
+			"Comment":      comment,
+			"Reason":       reason,
+			"BookIdentify": book.Identify,
+			"DocumentId":   doc.DocumentId,
+			"DocumentName": doc.DocumentName,
+		}
+		if err := SendTemplateMail(member.Email, MailCommentNotify, lang, data); err != nil {
+			logs.Error("发送评论通知失败 ->", member.Account, err)
+		}
+	}
+	return nil
+}
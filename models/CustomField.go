@@ -0,0 +1,542 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/mindoc-org/mindoc/conf"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FieldTypeText   = "text"
+	FieldTypeNumber = "number"
+	FieldTypeDate   = "date"
+	FieldTypeSelect = "select"
+	FieldTypeMember = "member"
+)
+
+// FieldTypes 支持的自定义字段类型.
+var FieldTypes = []string{FieldTypeText, FieldTypeNumber, FieldTypeDate, FieldTypeSelect, FieldTypeMember}
+
+// maxFieldValueLength 字段值允许的最大长度.
+const maxFieldValueLength = 500
+
+var fieldNameRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]{0,49}$`)
+
+// frontMatterRegexp 匹配 Markdown 开头的 YAML 元数据.
+var frontMatterRegexp = regexp.MustCompile(`^---[ \t]*\r?\n((?s:.*?))\r?\n?---[ \t]*(\r?\n|$)`)
+
+// CustomField 项目或项目空间中定义的文档自定义字段.
+type CustomField struct {
+	FieldId int `orm:"column(field_id);pk;auto;unique" json:"field_id"`
+	// BookId 字段所属项目，项目空间的字段为0.
+	BookId int `orm:"column(book_id);type(int);default(0);index" json:"book_id"`
+	// ItemId 字段所属项目空间，项目的字段为0.
+	ItemId     int    `orm:"column(item_id);type(int);default(0);index" json:"item_id"`
+	FieldName  string `orm:"column(field_name);size(50)" json:"field_name"`
+	FieldTitle string `orm:"column(field_title);size(100)" json:"field_title"`
+	FieldType  string `orm:"column(field_type);size(20);default(text)" json:"field_type"`
+	// Options 下拉字段的可选值，每行一个.
+	Options    string    `orm:"column(options);size(2000);null" json:"options"`
+	OrderSort  int       `orm:"column(order_sort);type(int);default(0)" json:"order_sort"`
+	CreateTime time.Time `orm:"column(create_time);type(datetime);auto_now_add" json:"create_time"`
+	ModifyTime time.Time `orm:"column(modify_time);type(datetime);auto_now" json:"modify_time"`
+}
+
+// DocumentField 文档自定义字段的值.
+type DocumentField struct {
+	Id         int       `orm:"column(id);pk;auto;unique" json:"-"`
+	DocumentId int       `orm:"column(document_id);type(int);index" json:"doc_id"`
+	BookId     int       `orm:"column(book_id);type(int);index" json:"book_id"`
+	FieldName  string    `orm:"column(field_name);size(50);index" json:"field_name"`
+	FieldValue string    `orm:"column(field_value);size(500)" json:"field_value"`
+	ModifyTime time.Time `orm:"column(modify_time);type(datetime);auto_now" json:"modify_time"`
+}
+
+// DocumentFieldResult 文档的字段定义及对应的值.
+type DocumentFieldResult struct {
+	FieldName  string   `json:"field_name"`
+	FieldTitle string   `json:"field_title"`
+	FieldType  string   `json:"field_type"`
+	Options    []string `json:"options,omitempty"`
+	Value      string   `json:"value"`
+}
+
+// FieldValueError 字段值不符合字段类型.
+type FieldValueError struct {
+	Field *CustomField
+	Value string
+}
+
+func (e *FieldValueError) Error() string {
+	return fmt.Sprintf("字段 %s 的值 %s 无效", e.Field.FieldTitle, e.Value)
+}
+
+// TableName 获取对应数据库表名.
+func (m *CustomField) TableName() string {
+	return "custom_fields"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *CustomField) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *CustomField) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewCustomField() *CustomField {
+	return &CustomField{}
+}
+
+// 多字段唯一键
+func (m *DocumentField) TableUnique() [][]string {
+	return [][]string{{"document_id", "field_name"}}
+}
+
+// TableName 获取对应数据库表名.
+func (m *DocumentField) TableName() string {
+	return "document_fields"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *DocumentField) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *DocumentField) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewDocumentField() *DocumentField {
+	return &DocumentField{}
+}
+
+// Find 查询指定的字段定义.
+func (m *CustomField) Find(fieldId int) (*CustomField, error) {
+	err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("field_id", fieldId).One(m)
+	return m, err
+}
+
+// FindByBookId 查询项目自身定义的字段.
+func (m *CustomField) FindByBookId(bookId int) ([]*CustomField, error) {
+	var fields []*CustomField
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("book_id", bookId).
+		Filter("item_id", 0).
+		OrderBy("order_sort", "field_id").
+		All(&fields)
+	if err == orm.ErrNoRows {
+		err = nil
+	}
+	return fields, err
+}
+
+// FindByItemId 查询项目空间定义的字段.
+func (m *CustomField) FindByItemId(itemId int) ([]*CustomField, error) {
+	var fields []*CustomField
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("item_id", itemId).
+		Filter("book_id", 0).
+		OrderBy("order_sort", "field_id").
+		All(&fields)
+	if err == orm.ErrNoRows {
+		err = nil
+	}
+	return fields, err
+}
+
+// FindForBook 查询项目中文档可用的字段，项目中定义的字段会覆盖项目空间中的同名字段.
+func (m *CustomField) FindForBook(bookId int) ([]*CustomField, error) {
+	book, err := NewBook().Find(bookId, "book_id", "item_id")
+	if err != nil {
+		return nil, err
+	}
+	itemFields, err := m.FindByItemId(book.ItemId)
+	if err != nil {
+		return nil, err
+	}
+	bookFields, err := m.FindByBookId(bookId)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]*CustomField, 0, len(itemFields)+len(bookFields))
+	index := make(map[string]int, len(itemFields))
+
+	for _, field := range itemFields {
+		index[field.FieldName] = len(fields)
+		fields = append(fields, field)
+	}
+	for _, field := range bookFields {
+		if i, ok := index[field.FieldName]; ok {
+			fields[i] = field
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// FindFieldNames 查询全部已定义的字段，用于搜索时筛选，同名字段只返回一个.
+func (m *CustomField) FindFieldNames() ([]*CustomField, error) {
+	var fields []*CustomField
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).OrderBy("order_sort", "field_id").All(&fields)
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	results := make([]*CustomField, 0, len(fields))
+	seen := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		if seen[field.FieldName] {
+			continue
+		}
+		seen[field.FieldName] = true
+		results = append(results, field)
+	}
+	return results, nil
+}
+
+// Save 添加或更新字段定义.
+func (m *CustomField) Save() error {
+	m.FieldName = strings.TrimSpace(m.FieldName)
+	m.FieldTitle = strings.TrimSpace(m.FieldTitle)
+
+	if !fieldNameRegexp.MatchString(m.FieldName) || m.FieldTitle == "" || utf8.RuneCountInString(m.FieldTitle) > 100 {
+		return ErrInvalidParameter
+	}
+	if !m.validType() {
+		return ErrInvalidParameter
+	}
+	if m.FieldType == FieldTypeSelect && len(m.OptionList()) == 0 {
+		return ErrInvalidParameter
+	}
+	o := orm.NewOrm()
+
+	exist, err := o.QueryTable(m.TableNameWithPrefix()).
+		Filter("book_id", m.BookId).
+		Filter("item_id", m.ItemId).
+		Filter("field_name", m.FieldName).
+		Exclude("field_id", m.FieldId).
+		Count()
+	if err != nil {
+		return err
+	}
+	if exist > 0 {
+		return ErrCustomFieldExist
+	}
+	if m.FieldId > 0 {
+		_, err = o.Update(m)
+	} else {
+		_, err = o.Insert(m)
+	}
+	return err
+}
+
+// Delete 删除字段定义及文档中对应的值，项目中其他同名字段仍然生效时会保留文档中的值.
+func (m *CustomField) Delete() error {
+	o := orm.NewOrm()
+
+	if _, err := o.Delete(m); err != nil {
+		return err
+	}
+	var bookIds []int
+	if m.BookId > 0 {
+		bookIds = []int{m.BookId}
+	} else {
+		var books []*Book
+		_, err := o.QueryTable(NewBook().TableNameWithPrefix()).Filter("item_id", m.ItemId).All(&books, "book_id")
+		if err != nil && err != orm.ErrNoRows {
+			return err
+		}
+		for _, book := range books {
+			bookIds = append(bookIds, book.BookId)
+		}
+	}
+	for _, bookId := range bookIds {
+		fields, err := m.FindForBook(bookId)
+		if err != nil {
+			continue
+		}
+		defined := false
+		for _, field := range fields {
+			if field.FieldName == m.FieldName {
+				defined = true
+				break
+			}
+		}
+		if defined {
+			continue
+		}
+		_, err = o.QueryTable(NewDocumentField().TableNameWithPrefix()).
+			Filter("book_id", bookId).
+			Filter("field_name", m.FieldName).
+			Delete()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *CustomField) validType() bool {
+	for _, fieldType := range FieldTypes {
+		if fieldType == m.FieldType {
+			return true
+		}
+	}
+	return false
+}
+
+// OptionList 下拉字段的可选值.
+func (m *CustomField) OptionList() []string {
+	options := make([]string, 0)
+	for _, option := range strings.Split(m.Options, "\n") {
+		if option = strings.TrimSpace(option); option != "" {
+			options = append(options, option)
+		}
+	}
+	return options
+}
+
+// Normalize 校验字段值并转换为统一的格式，日期保存为 2006-01-02，数字去掉多余的零.
+func (m *CustomField) Normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	if utf8.RuneCountInString(value) > maxFieldValueLength {
+		return "", &FieldValueError{Field: m, Value: value}
+	}
+	switch m.FieldType {
+	case FieldTypeNumber:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", &FieldValueError{Field: m, Value: value}
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case FieldTypeDate:
+		for _, layout := range []string{"2006-1-2", "2006/1/2", time.RFC3339, "2006-1-2 15:04:05"} {
+			if date, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+				return date.Format("2006-01-02"), nil
+			}
+		}
+		return "", &FieldValueError{Field: m, Value: value}
+	case FieldTypeSelect:
+		for _, option := range m.OptionList() {
+			if strings.EqualFold(option, value) {
+				return option, nil
+			}
+		}
+		return "", &FieldValueError{Field: m, Value: value}
+	case FieldTypeMember:
+		member, err := NewMember().FindByAccount(value)
+		if err != nil || member.MemberId <= 0 {
+			return "", &FieldValueError{Field: m, Value: value}
+		}
+		return member.Account, nil
+	}
+	return value, nil
+}
+
+// FindByDocumentId 查询文档的字段及其值，未定义的字段不会返回.
+func (m *DocumentField) FindByDocumentId(bookId, docId int) ([]*DocumentFieldResult, error) {
+	fields, err := NewCustomField().FindForBook(bookId)
+	if err != nil {
+		return nil, err
+	}
+	values, err := m.findValues(docId)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*DocumentFieldResult, 0, len(fields))
+	for _, field := range fields {
+		result := &DocumentFieldResult{
+			FieldName:  field.FieldName,
+			FieldTitle: field.FieldTitle,
+			FieldType:  field.FieldType,
+			Value:      values[field.FieldName],
+		}
+		if field.FieldType == FieldTypeSelect {
+			result.Options = field.OptionList()
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (m *DocumentField) findValues(docId int) (map[string]string, error) {
+	var items []*DocumentField
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("document_id", docId).All(&items)
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	values := make(map[string]string, len(items))
+	for _, item := range items {
+		values[item.FieldName] = item.FieldValue
+	}
+	return values, nil
+}
+
+// Save 保存文档的全部字段值，values 中未包含的字段会被清空，未定义的字段会被忽略.
+func (m *DocumentField) Save(doc *Document, values map[string]string) error {
+	items, err := m.normalize(doc, values)
+	if err != nil {
+		return err
+	}
+
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.QueryTable(m.TableNameWithPrefix()).Filter("document_id", doc.DocumentId).Delete(); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	for _, item := range items {
+		if _, err := tx.Insert(item); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Check 校验文档的自定义字段值，不保存.
+func (m *DocumentField) Check(doc *Document, values map[string]string) error {
+	_, err := m.normalize(doc, values)
+	return err
+}
+
+// normalize 按项目的字段定义格式化字段值，忽略空值.
+func (m *DocumentField) normalize(doc *Document, values map[string]string) ([]*DocumentField, error) {
+	fields, err := NewCustomField().FindForBook(doc.BookId)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*DocumentField, 0, len(fields))
+	for _, field := range fields {
+		value, err := field.Normalize(values[field.FieldName])
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			continue
+		}
+		items = append(items, &DocumentField{
+			DocumentId: doc.DocumentId,
+			BookId:     doc.BookId,
+			FieldName:  field.FieldName,
+			FieldValue: value,
+		})
+	}
+	return items, nil
+}
+
+// ParseFrontMatter 解析 Markdown 开头的 YAML 元数据，没有元数据时 ok 为 false.
+func ParseFrontMatter(markdown string) (values map[string]string, ok bool, err error) {
+	match := frontMatterRegexp.FindStringSubmatch(strings.TrimPrefix(markdown, "\ufeff"))
+	if match == nil {
+		return nil, false, nil
+	}
+	var data map[string]interface{}
+	if err := yaml.Unmarshal([]byte(match[1]), &data); err != nil {
+		return nil, true, ErrFrontMatterInvalid
+	}
+	values = make(map[string]string, len(data))
+	for key, value := range data {
+		values[key] = frontMatterValue(value)
+	}
+	return values, true, nil
+}
+
+func frontMatterValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format("2006-01-02")
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, frontMatterValue(item))
+		}
+		return strings.Join(items, ", ")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(keys))
+		for _, key := range keys {
+			items = append(items, key+": "+frontMatterValue(v[key]))
+		}
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprint(value)
+}
+
+// StripFrontMatterHtml 删除编辑器预览时由元数据生成的分割线和段落，只有内容与元数据一致时才会删除.
+func StripFrontMatterHtml(markdown, content string) string {
+	match := frontMatterRegexp.FindStringSubmatch(strings.TrimPrefix(markdown, "\ufeff"))
+	if match == nil {
+		return content
+	}
+	docQuery, err := goquery.NewDocumentFromReader(bytes.NewBufferString(content))
+	if err != nil {
+		return content
+	}
+	first := docQuery.Find("body").Children().Filter("hr").First()
+	if first.Length() == 0 || frontMatterText(first.PrevAll().Text()) != "" {
+		return content
+	}
+	frontMatter := frontMatterText(match[1])
+	text := ""
+	selection := first
+	next := first.Next()
+
+	for text != frontMatter && next.Length() > 0 {
+		text += frontMatterText(next.Text())
+		if !strings.HasPrefix(frontMatter, text) {
+			return content
+		}
+		selection = selection.AddSelection(next)
+		next = next.Next()
+	}
+	if text != frontMatter {
+		return content
+	}
+	if next.Is("hr") {
+		selection = selection.AddSelection(next)
+	}
+	selection.Remove()
+
+	if html, err := docQuery.Find("body").Html(); err == nil {
+		return strings.TrimSpace(html)
+	}
+	return content
+}
+
+// frontMatterText 只保留文字和数字，用于比较元数据和编辑器生成的内容.
+func frontMatterText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
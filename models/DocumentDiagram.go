@@ -0,0 +1,354 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/mindoc-org/mindoc/conf"
+	"github.com/mindoc-org/mindoc/utils/diagram"
+	"github.com/mindoc-org/mindoc/utils/filetil"
+)
+
+// diagramSelector 发布内容中可能包含图表源码的元素.
+const diagramSelector = "pre, div.lang-mermaid, div[data-lang]"
+
+var fenceRegexp = regexp.MustCompile("^[ \t]{0,3}(`{3,}|~{3,})[ \t]*([^`\\s]*)")
+
+// diagramBlock Markdown 中的图表代码块.
+type diagramBlock struct {
+	Lang   string
+	Source string
+	// Start 和 End 为代码块在 Markdown 中的起止位置，包括围栏.
+	Start int
+	End   int
+}
+
+// diagramType 获取代码块语言对应的图表类型，不支持服务端渲染时返回空字符串.
+func diagramType(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if _, ok := diagram.Lookup(lang); !ok {
+		return ""
+	}
+	return conf.DiagramLanguages[lang]
+}
+
+// markdownDiagramBlocks 查找 Markdown 中可以在服务端渲染的图表代码块.
+func markdownDiagramBlocks(markdown string) []diagramBlock {
+	var blocks []diagramBlock
+	var current *diagramBlock
+	var fence string
+	var source strings.Builder
+
+	offset := 0
+	for _, line := range strings.SplitAfter(markdown, "\n") {
+		start := offset
+		offset += len(line)
+		text := strings.TrimRight(line, "\r\n")
+
+		if fence == "" {
+			match := fenceRegexp.FindStringSubmatch(text)
+			if match == nil {
+				continue
+			}
+			fence = match[1]
+			current = &diagramBlock{Lang: match[2], Start: start}
+			source.Reset()
+			continue
+		}
+		trimmed := strings.TrimSpace(text)
+		if strings.HasPrefix(trimmed, fence[:1]) && len(trimmed) >= len(fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			if diagramType(current.Lang) != "" {
+				current.Source = strings.TrimSuffix(source.String(), "\n")
+				current.End = offset
+				blocks = append(blocks, *current)
+			}
+			fence = ""
+			current = nil
+			continue
+		}
+		source.WriteString(text + "\n")
+	}
+	return blocks
+}
+
+// elementDiagramLanguage 获取发布内容中元素的代码块语言.
+func elementDiagramLanguage(selection *goquery.Selection) string {
+	if lang, ok := selection.Attr("data-lang"); ok {
+		return lang
+	}
+	classes := selection.AttrOr("class", "") + " " + selection.ChildrenFiltered("code").AttrOr("class", "")
+	for _, class := range strings.Fields(classes) {
+		if strings.HasPrefix(class, "language-") {
+			return strings.TrimPrefix(class, "language-")
+		}
+		if strings.HasPrefix(class, "lang-") {
+			return strings.TrimPrefix(class, "lang-")
+		}
+	}
+	return ""
+}
+
+// elementDiagramSource 从发布内容中获取图表源码，已经由浏览器渲染过的图表无法获取.
+func elementDiagramSource(selection *goquery.Selection) (string, bool) {
+	if selection.Find("svg, img").Length() > 0 {
+		return "", false
+	}
+	if lines := selection.Find("ol.linenums > li"); lines.Length() > 0 {
+		source := make([]string, 0, lines.Length())
+		lines.Each(func(i int, line *goquery.Selection) {
+			source = append(source, line.Text())
+		})
+		return strings.Join(source, "\n"), true
+	}
+	return strings.TrimSpace(selection.Text()), true
+}
+
+// errDiagramNotRendered 图表尚未渲染，发布时不等待渲染.
+var errDiagramNotRendered = errors.New("diagram not rendered")
+
+var (
+	diagramQueue     = make(chan int, 500)
+	diagramQueueOnce sync.Once
+	diagramLocker    sync.Mutex
+	diagramPending   = make(map[int]bool)
+)
+
+// renderDiagram 获取图表渲染后的图片地址，图片作为文档附件保存，同一文档中相同内容的图表只会渲染一次.
+// render 为 false 时只查找已渲染的图片，未渲染时返回 errDiagramNotRendered.
+func renderDiagram(doc *Document, book *Book, lang, source string, render bool) (string, error) {
+	renderer, ok := diagram.Lookup(lang)
+	if !ok {
+		return "", diagram.ErrEmptyCommand
+	}
+	format := renderer.Format()
+	hash := sha256.Sum256([]byte(conf.DiagramLanguages[strings.ToLower(lang)] + "\n" + format + "\n" + source))
+	name := hex.EncodeToString(hash[:]) + "." + format
+
+	// 图片按文档分目录保存，删除文档时清理附件不会影响其他文档
+	docId := strconv.Itoa(doc.DocumentId)
+	filePath := filepath.Join(conf.WorkingDirectory, "uploads", book.Identify, "diagrams", docId, name)
+	httpPath := "/uploads/" + book.Identify + "/diagrams/" + docId + "/" + name
+
+	attach := NewAttachment()
+	err := orm.NewOrm().QueryTable(attach.TableNameWithPrefix()).Filter("book_id", book.BookId).Filter("http_path", httpPath).One(attach)
+	if err != nil && err != orm.ErrNoRows {
+		return "", err
+	}
+	if attach.AttachmentId > 0 && filetil.FileExists(filePath) {
+		return httpPath, nil
+	}
+	if !render {
+		return "", errDiagramNotRendered
+	}
+	body, err := renderer.Render(context.Background(), []byte(source))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return "", err
+	}
+	// 先写入临时文件，避免同时读取到不完整的图片
+	tempFile := filePath + ".tmp"
+	if err := os.WriteFile(tempFile, body, 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tempFile, filePath); err != nil {
+		_ = os.Remove(tempFile)
+		return "", err
+	}
+	if attach.AttachmentId == 0 {
+		attach.BookId = book.BookId
+		attach.DocumentId = doc.DocumentId
+		attach.FileName = name
+		attach.FilePath = strings.TrimPrefix(filePath, conf.WorkingDirectory)
+		attach.FileSize = float64(len(body))
+		attach.HttpPath = httpPath
+		attach.FileExt = "." + format
+		attach.CreateAt = doc.ModifyAt
+		if err := attach.Insert(); err != nil {
+			return "", err
+		}
+	}
+	return httpPath, nil
+}
+
+// queueDiagramRender 将文档加入后台渲染队列，渲染完成后更新文档的发布内容.
+func queueDiagramRender(docId int) {
+	diagramLocker.Lock()
+	if diagramPending[docId] {
+		diagramLocker.Unlock()
+		return
+	}
+	diagramPending[docId] = true
+	diagramLocker.Unlock()
+
+	diagramQueueOnce.Do(func() {
+		go func() {
+			defer func() {
+				if err := recover(); err != nil {
+					logs.Error("协程崩溃 ->", err)
+				}
+			}()
+			for docId := range diagramQueue {
+				diagramLocker.Lock()
+				delete(diagramPending, docId)
+				diagramLocker.Unlock()
+
+				renderDocumentDiagrams(docId)
+			}
+		}()
+	})
+	select {
+	case diagramQueue <- docId:
+	default:
+		logs.Warn("图表渲染队列已满 ->", docId)
+		diagramLocker.Lock()
+		delete(diagramPending, docId)
+		diagramLocker.Unlock()
+	}
+}
+
+// renderDocumentDiagrams 渲染文档发布内容中尚未渲染的图表，发布内容在渲染期间被修改时放弃本次结果.
+func renderDocumentDiagrams(docId int) {
+	doc, err := NewDocument().Find(docId)
+	if err != nil {
+		logs.Error("查询文档失败 ->", docId, err)
+		return
+	}
+	release, _ := doc.replaceReleaseDiagrams(doc.Release, true)
+	if release == doc.Release {
+		return
+	}
+	n, err := orm.NewOrm().QueryTable(doc.TableNameWithPrefix()).
+		Filter("document_id", doc.DocumentId).
+		Filter("release", doc.Release).
+		Update(orm.Params{"release": release})
+	if err != nil {
+		logs.Error("保存图表渲染结果失败 ->", docId, err)
+		return
+	}
+	if n > 0 {
+		doc.RemoveCache()
+		if err := os.RemoveAll(filepath.Join(conf.WorkingDirectory, "uploads", "books", strconv.Itoa(doc.BookId))); err != nil {
+			logs.Error("删除已缓存的文档目录失败 ->", err)
+		}
+		_ = os.RemoveAll(filepath.Join(conf.GetExportOutputPath(), strconv.Itoa(doc.BookId)))
+	}
+}
+
+// renderReleaseDiagrams 将发布内容中已渲染过的图表代码块替换为图片，尚未渲染的图表在后台渲染，
+// 渲染完成前保留代码块由浏览器渲染.
+func (item *Document) renderReleaseDiagrams(content string) string {
+	content, pending := item.replaceReleaseDiagrams(content, false)
+	if pending {
+		queueDiagramRender(item.DocumentId)
+	}
+	return content
+}
+
+// replaceReleaseDiagrams 将发布内容中的图表代码块替换为服务端渲染的图片，渲染失败的代码块保持不变.
+// Markdown 文档优先使用源码中的代码块，避免浏览器渲染后的内容无法还原.
+// render 为 false 时只使用已渲染的图片，返回值 pending 表示是否有尚未渲染的图表.
+func (item *Document) replaceReleaseDiagrams(content string, render bool) (string, bool) {
+	if !diagram.Enabled() || strings.TrimSpace(content) == "" {
+		return content, false
+	}
+	docQuery, err := goquery.NewDocumentFromReader(bytes.NewBufferString(content))
+	if err != nil {
+		return content, false
+	}
+	book, err := NewBook().Find(item.BookId, "book_id", "identify")
+	if err != nil {
+		logs.Error("查询项目失败 ->", item.BookId, err)
+		return content, false
+	}
+	blocks := markdownDiagramBlocks(item.Markdown)
+	next := 0
+	found, pending := false, false
+
+	docQuery.Find(diagramSelector).Each(func(i int, selection *goquery.Selection) {
+		// 嵌入的其他文档内容不在当前文档的 Markdown 中，跳过以免与代码块错位
+		if selection.ParentsFiltered(diagramSelector+", div.doc-include").Length() > 0 {
+			return
+		}
+		lang := elementDiagramLanguage(selection)
+		name := diagramType(lang)
+		if name == "" {
+			return
+		}
+		var source string
+		if next < len(blocks) && diagramType(blocks[next].Lang) == name {
+			source = blocks[next].Source
+			next++
+		} else if text, ok := elementDiagramSource(selection); ok {
+			source = text
+		} else {
+			return
+		}
+		if strings.TrimSpace(source) == "" {
+			return
+		}
+		src, err := renderDiagram(item, book, lang, source, render)
+		if err == errDiagramNotRendered {
+			pending = true
+			return
+		}
+		if err != nil {
+			logs.Error("渲染图表失败 ->", item.DocumentId, name, err)
+			return
+		}
+		found = true
+		selection.ReplaceWithHtml("<p class=\"diagram\" data-diagram=\"" + template.HTMLEscapeString(name) + "\"><img src=\"" + src + "\" alt=\"" + template.HTMLEscapeString(name) + "\"></p>")
+	})
+	if !found {
+		return content, pending
+	}
+	if html, err := docQuery.Find("body").Html(); err == nil {
+		return html, pending
+	}
+	return content, pending
+}
+
+// renderMarkdownDiagrams 将 Markdown 中的图表代码块替换为服务端渲染的图片，用于导出 Markdown.
+func renderMarkdownDiagrams(doc *Document) string {
+	markdown := doc.Markdown
+	if !diagram.Enabled() {
+		return markdown
+	}
+	blocks := markdownDiagramBlocks(markdown)
+	if len(blocks) == 0 {
+		return markdown
+	}
+	book, err := NewBook().Find(doc.BookId, "book_id", "identify")
+	if err != nil {
+		logs.Error("查询项目失败 ->", doc.BookId, err)
+		return markdown
+	}
+	var buf strings.Builder
+	last := 0
+	for _, block := range blocks {
+		src, err := renderDiagram(doc, book, block.Lang, block.Source, true)
+		if err != nil {
+			logs.Error("渲染图表失败 ->", block.Lang, err)
+			continue
+		}
+		buf.WriteString(markdown[last:block.Start])
+		buf.WriteString("![" + diagramType(block.Lang) + "](" + src + ")\n")
+		last = block.End
+	}
+	buf.WriteString(markdown[last:])
+	return buf.String()
+}
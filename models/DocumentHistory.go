@@ -113,8 +113,9 @@ func (m *DocumentHistory) Restore(historyId, docId, uid int) error {
 	doc.Version = time.Now().Unix()
 	doc.IsOpen = m.IsOpen
 
-	_, err = o.Update(doc)
-
+	if _, err = o.Update(doc); err == nil {
+		RefreshDocumentReferences(doc)
+	}
 	return err
 }
 
@@ -0,0 +1,327 @@
+package models
+
+import (
+	"bytes"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/i18n"
+	"github.com/mindoc-org/mindoc/conf"
+)
+
+// maxIncludeDepth 文档引用允许嵌套的最大层数.
+const maxIncludeDepth = 5
+
+// includeRegexp 匹配单独成段的引用指令，例如 {{include book/doc#heading}}.
+var includeRegexp = regexp.MustCompile(`^\{\{\s*include\s+([^\s{}<>]+)\s*\}\}$`)
+
+var headingSlugRegexp = regexp.MustCompile(`[^\w]+`)
+
+// includeResolver 在发布文档时展开文档中引用的其他文档.
+type includeResolver struct {
+	*linkResolver
+	doc  *Document
+	book *Book
+	// includes 当前文档直接引用的文档.
+	includes []int
+}
+
+func newIncludeResolver(doc *Document) *includeResolver {
+	resolver := &includeResolver{
+		linkResolver: newLinkResolver(),
+		doc:          doc,
+	}
+	if book, err := NewBook().Find(doc.BookId); err == nil {
+		resolver.book = book
+		resolver.books[book.Identify] = book
+	}
+	return resolver
+}
+
+// resolve 展开内容中的全部引用指令.
+func (r *includeResolver) resolve(content string) string {
+	return r.expand(content, []int{r.doc.DocumentId})
+}
+
+// expand 替换内容中单独成段的引用指令，和其他文字混排的指令不会被处理.
+func (r *includeResolver) expand(content string, stack []int) string {
+	if !strings.Contains(content, "{{") {
+		return content
+	}
+	docQuery, err := goquery.NewDocumentFromReader(bytes.NewBufferString(content))
+	if err != nil {
+		return content
+	}
+	found := false
+
+	docQuery.Find("p, div, li, td").Each(func(i int, selection *goquery.Selection) {
+		if selection.Children().Length() > 0 {
+			return
+		}
+		match := includeRegexp.FindStringSubmatch(strings.TrimSpace(selection.Text()))
+		if match == nil {
+			return
+		}
+		found = true
+		if goquery.NodeName(selection) == "p" {
+			selection.ReplaceWithHtml(r.include(match[1], stack))
+		} else {
+			selection.SetHtml(r.include(match[1], stack))
+		}
+	})
+	if !found {
+		return content
+	}
+	if html, err := docQuery.Find("body").Html(); err == nil {
+		return html
+	}
+	return content
+}
+
+// include 生成引用指令对应的内容，引用失败时返回错误提示.
+func (r *includeResolver) include(target string, stack []int) string {
+	path, heading := target, ""
+	if i := strings.Index(target, "#"); i >= 0 {
+		path, heading = target[:i], target[i+1:]
+	}
+	var book *Book
+	var identify string
+
+	if i := strings.Index(path, "/"); i >= 0 {
+		book = r.findBook(path[:i])
+		identify = path[i+1:]
+	} else {
+		book = r.book
+		identify = path
+	}
+	if book == nil || identify == "" {
+		return r.includeError(target, "include_not_found")
+	}
+	if !r.canInclude(book) {
+		return r.includeError(target, "include_no_permission")
+	}
+	docId := r.findDocumentId(book, identify)
+	if docId <= 0 {
+		return r.includeError(target, "include_not_found")
+	}
+	for _, id := range stack {
+		if id == docId {
+			return r.includeError(target, "include_cycle")
+		}
+	}
+	if len(stack) > maxIncludeDepth {
+		return r.includeError(target, "include_too_deep")
+	}
+	source, err := NewDocument().Find(docId)
+	if err != nil {
+		return r.includeError(target, "include_not_found")
+	}
+	if len(stack) == 1 {
+		r.includes = append(r.includes, docId)
+	}
+	content, ok := r.sourceContent(source, append(stack, docId))
+	if !ok {
+		return r.includeError(target, "include_not_released")
+	}
+
+	if heading != "" {
+		section, ok := includeSection(content, heading)
+		if !ok {
+			return r.includeError(target, "include_heading_not_found")
+		}
+		content = section
+	}
+	return "<div class=\"doc-include\" data-include=\"" + template.HTMLEscapeString(target) + "\">" + content + "</div>"
+}
+
+// sourceContent 获取被引用文档已发布的正文，其中嵌套的引用会重新生成，草稿不会被引用.
+func (r *includeResolver) sourceContent(source *Document, stack []int) (string, bool) {
+	if strings.TrimSpace(source.Release) == "" {
+		return "", false
+	}
+	docQuery, err := goquery.NewDocumentFromReader(bytes.NewBufferString(source.Release))
+	if err != nil {
+		return "", false
+	}
+	docQuery.Find("div.wiki-bottom, div.attach-list, div.markdown-toc").Remove()
+	r.replaceIncludes(docQuery, stack)
+
+	selection := docQuery.Find("div.markdown-article").First()
+	if selection.Length() == 0 {
+		selection = docQuery.Find("div.whole-article-wrap").First()
+	}
+	if selection.Length() == 0 {
+		selection = docQuery.Find("body")
+	}
+	html, err := selection.Html()
+	return html, err == nil
+}
+
+// replaceIncludes 重新生成已发布内容中最外层的引用部分.
+func (r *includeResolver) replaceIncludes(docQuery *goquery.Document, stack []int) {
+	docQuery.Find("div.doc-include[data-include]").Each(func(i int, selection *goquery.Selection) {
+		if selection.ParentsFiltered("div.doc-include").Length() > 0 {
+			return
+		}
+		target, _ := selection.Attr("data-include")
+		selection.ReplaceWithHtml(r.include(target, stack))
+	})
+}
+
+// canInclude 只能引用同一项目或公开项目中的文档.
+// 引用的内容会展示给当前项目的全部读者，私有项目的内容即使编辑者有权限阅读也不能引用.
+func (r *includeResolver) canInclude(book *Book) bool {
+	if r.book != nil && book.BookId == r.book.BookId {
+		return true
+	}
+	return book.PrivatelyOwned == 0
+}
+
+func (r *includeResolver) includeError(target, reason string) string {
+	return "<div class=\"doc-include doc-include-error\" data-include=\"" + template.HTMLEscapeString(target) + "\">" +
+		i18n.Tr(r.doc.Lang, "doc."+reason, template.HTMLEscapeString(target)) + "</div>"
+}
+
+// includeSection 截取标题及其下级内容，标题可以是标题的文字、锚点或 id.
+func includeSection(content, heading string) (string, bool) {
+	docQuery, err := goquery.NewDocumentFromReader(bytes.NewBufferString(content))
+	if err != nil {
+		return "", false
+	}
+	heading = strings.ToLower(strings.TrimSpace(heading))
+	headings := "h1, h2, h3, h4, h5, h6"
+
+	var found *goquery.Selection
+	docQuery.Find(headings).EachWithBreak(func(i int, selection *goquery.Selection) bool {
+		text := strings.ToLower(strings.TrimSpace(selection.Text()))
+		id, _ := selection.Attr("id")
+		name, _ := selection.Find("a[name]").Attr("name")
+
+		if text == heading || strings.Trim(headingSlugRegexp.ReplaceAllString(text, "-"), "-") == heading ||
+			(id != "" && strings.ToLower(id) == heading) || (name != "" && strings.ToLower(name) == heading) {
+			found = selection
+			return false
+		}
+		return true
+	})
+	if found == nil {
+		return "", false
+	}
+	level := goquery.NodeName(found)
+
+	var buf bytes.Buffer
+	if html, err := goquery.OuterHtml(found); err == nil {
+		buf.WriteString(html)
+	}
+	for next := found.Next(); next.Length() > 0; next = next.Next() {
+		if next.Is(headings) && goquery.NodeName(next) <= level {
+			break
+		}
+		if html, err := goquery.OuterHtml(next); err == nil {
+			buf.WriteString(html)
+		}
+	}
+	return buf.String(), true
+}
+
+// refreshIncludes 被引用的文档重新发布后，重新生成已发布内容中引用的部分，文档的草稿不会被发布.
+func (item *Document) refreshIncludes() ([]int, error) {
+	docQuery, err := goquery.NewDocumentFromReader(bytes.NewBufferString(item.Release))
+	if err != nil {
+		return nil, err
+	}
+	resolver := newIncludeResolver(item)
+	resolver.replaceIncludes(docQuery, []int{item.DocumentId})
+
+	html, err := docQuery.Find("body").Html()
+	if err != nil {
+		return nil, err
+	}
+	item.Release = html
+	return resolver.includes, nil
+}
+
+// dependentRelease 等待重新发布引用方的文档.
+type dependentRelease struct {
+	docId int
+	lang  string
+}
+
+var dependentQueue = make(chan dependentRelease, 500)
+var dependentOnce = sync.Once{}
+
+// queueDependents 将引用了当前文档的文档加入后台队列重新发布，避免在发布过程中递归发布.
+func (item *Document) queueDependents() {
+	dependentOnce.Do(func() {
+		go func() {
+			for job := range dependentQueue {
+				releaseDependents(job)
+			}
+		}()
+	})
+	dependentQueue <- dependentRelease{docId: item.DocumentId, lang: item.Lang}
+}
+
+// releaseDependents 逐层重新发布引用了文档的文档，只有内容发生变化时才继续发布引用了它的文档.
+func releaseDependents(job dependentRelease) {
+	defer func() {
+		if err := recover(); err != nil {
+			logs.Error("重新发布引用文档时崩溃 ->", job.docId, err)
+		}
+	}()
+	released := map[int]bool{job.docId: true}
+	current := []int{job.docId}
+
+	for depth := 0; depth <= maxIncludeDepth && len(current) > 0; depth++ {
+		var next []int
+		for _, sourceId := range current {
+			for _, docId := range NewDocumentReference().FindIncludedBy(sourceId) {
+				if released[docId] {
+					continue
+				}
+				released[docId] = true
+
+				doc, err := NewDocument().Find(docId)
+				if err != nil {
+					continue
+				}
+				doc.Lang = job.lang
+				previous := doc.Release
+				includes, err := doc.refreshIncludes()
+				if err != nil {
+					logs.Error("更新文档引用内容失败 ->", docId, err)
+					continue
+				}
+				if err := doc.Processor().InsertOrUpdate("release"); err != nil {
+					logs.Error("重新发布文档失败 ->", docId, err)
+					continue
+				}
+				doc.RemoveCache()
+				doc.afterRelease(includes)
+				if err := os.RemoveAll(filepath.Join(conf.WorkingDirectory, "uploads", "books", strconv.Itoa(doc.BookId))); err != nil {
+					logs.Error("删除已缓存的文档目录失败 ->", err)
+				}
+				if doc.Release != previous {
+					next = append(next, docId)
+				}
+			}
+		}
+		current = next
+	}
+}
+
+// afterRelease 发布后更新文档的链接、引用关系和行内评论的位置.
+func (item *Document) afterRelease(includes []int) {
+	RefreshDocumentReferences(item)
+	ReanchorComments(item)
+	if err := NewDocumentReference().SaveIncludes(item, includes); err != nil {
+		logs.Error("保存文档引用关系失败 ->", item.DocumentId, err)
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"context"
 	"time"
 
 	"github.com/beego/i18n"
@@ -20,6 +21,7 @@ import (
 	"github.com/mindoc-org/mindoc/cache"
 	"github.com/mindoc-org/mindoc/conf"
 	"github.com/mindoc-org/mindoc/utils"
+	"github.com/mindoc-org/mindoc/utils/batchcounter"
 )
 
 // Document struct.
@@ -42,6 +44,10 @@ type Document struct {
 	IsOpen        int           `orm:"column(is_open);type(int);default(0);description(是否展开子目录 0：阅读时关闭节点 1：阅读时展开节点 2：空目录 单击时会展开下级节点)" json:"is_open"` //是否展开子目录：0 否/1 是 /2 空间节点，单击时展开下一级
 	ViewCount     int           `orm:"column(view_count);type(int);description(浏览量)" json:"view_count"`
 	AttachList    []*Attachment `orm:"-" json:"attach"`
+	// Fields 文档的自定义字段.
+	Fields []*DocumentFieldResult `orm:"-" json:"fields,omitempty"`
+	// ContentTime 内容最后修改的时间，发布文档只会更新 ModifyTime
+	ContentTime time.Time `orm:"column(content_time);type(datetime);null;description(内容修改时间)" json:"-"`
 	//i18n
 	Lang string `orm:"-"`
 }
@@ -94,7 +100,15 @@ func (item *Document) InsertOrUpdate(cols ...string) error {
 	item.DocumentName = utils.StripTags(item.DocumentName)
 	var err error
 	if item.DocumentId > 0 {
+		old := NewDocument()
+		if redirectWatched(cols, "identify") {
+			_ = o.QueryTable(item.TableNameWithPrefix()).Filter("document_id", item.DocumentId).One(old, "identify")
+		}
+		cols = item.touchContent(cols)
 		_, err = o.Update(item, cols...)
+		if err == nil && old.Identify != "" && old.Identify != item.Identify {
+			AddRedirect(RedirectDocument, item.DocumentId, item.BookId, old.Identify, item.Identify)
+		}
 	} else {
 		if item.Identify == "" {
 			book := NewBook()
@@ -110,6 +124,7 @@ func (item *Document) InsertOrUpdate(cols ...string) error {
 			sort, _ := o.QueryTable(item.TableNameWithPrefix()).Filter("book_id", item.BookId).Filter("parent_id", item.ParentId).Count()
 			item.OrderSort = int(sort) + 1
 		}
+		item.ContentTime = time.Now()
 		_, err = o.Insert(item)
 		NewBook().ResetDocumentNumber(item.BookId)
 	}
@@ -120,6 +135,33 @@ func (item *Document) InsertOrUpdate(cols ...string) error {
 	return nil
 }
 
+// touchContent 保存的内容与数据库中不同时更新内容修改时间，返回需要更新的字段.
+func (item *Document) touchContent(cols []string) []string {
+	if !redirectWatched(cols, "markdown") && !redirectWatched(cols, "content") {
+		return cols
+	}
+	old := NewDocument()
+	err := orm.NewOrm().QueryTable(item.TableNameWithPrefix()).Filter("document_id", item.DocumentId).One(old, "markdown", "content", "content_time")
+	if err == nil && old.Markdown == item.Markdown && old.Content == item.Content {
+		// 更新全部字段时保留原来的修改时间
+		item.ContentTime = old.ContentTime
+		return cols
+	}
+	item.ContentTime = time.Now()
+	if len(cols) > 0 {
+		cols = append(cols, "content_time")
+	}
+	return cols
+}
+
+// InitDocumentContentTime 为没有内容修改时间的文档补写时间，优先使用最近一次文档历史的时间.
+func InitDocumentContentTime() error {
+	table := NewDocument().TableNameWithPrefix()
+	_, err := orm.NewOrm().Raw("UPDATE " + table + " SET content_time = COALESCE((SELECT MAX(h.modify_time) FROM " +
+		NewDocumentHistory().TableNameWithPrefix() + " h WHERE h.document_id = " + table + ".document_id), modify_time) WHERE content_time IS NULL").Exec()
+	return err
+}
+
 // 根据文档识别编号和项目id获取一篇文档
 func (item *Document) FindByIdentityFirst(identify string, bookId int) (*Document, error) {
 	o := orm.NewOrm()
@@ -129,34 +171,6 @@ func (item *Document) FindByIdentityFirst(identify string, bookId int) (*Documen
 	return item, err
 }
 
-// 递归删除一个文档.
-func (item *Document) RecursiveDocument(docId int) error {
-
-	o := orm.NewOrm()
-
-	if doc, err := item.Find(docId); err == nil {
-		o.Delete(doc)
-		NewDocumentHistory().Clear(doc.DocumentId)
-	}
-	var maps []orm.Params
-
-	_, err := o.Raw("SELECT document_id FROM " + item.TableNameWithPrefix() + " WHERE parent_id=" + strconv.Itoa(docId)).Values(&maps)
-	if err != nil {
-		logs.Error("RecursiveDocument => ", err)
-		return err
-	}
-
-	for _, param := range maps {
-		if docId, ok := param["document_id"].(string); ok {
-			id, _ := strconv.Atoi(docId)
-			o.QueryTable(item.TableNameWithPrefix()).Filter("document_id", id).Delete()
-			item.RecursiveDocument(id)
-		}
-	}
-
-	return nil
-}
-
 // 将文档写入缓存
 func (item *Document) PutToCache() {
 	go func(m Document) {
@@ -178,10 +192,10 @@ func (item *Document) PutToCache() {
 // 清除缓存
 func (item *Document) RemoveCache() {
 	go func(m Document) {
-		cache.Put("Document.Id."+strconv.Itoa(m.DocumentId), m, time.Second*3600)
+		cache.Delete("Document.Id." + strconv.Itoa(m.DocumentId))
 
 		if m.Identify != "" {
-			cache.Put(fmt.Sprintf("Document.BookId.%d.Identify.%s", m.BookId, m.Identify), m, time.Second*3600)
+			cache.Delete(fmt.Sprintf("Document.BookId.%d.Identify.%s", m.BookId, m.Identify))
 		}
 	}(*item)
 }
@@ -239,10 +253,13 @@ func (item *Document) IsExist(documentId int) bool {
 	return o.QueryTable(item.TableNameWithPrefix()).Filter("document_id", documentId).Exist()
 }
 
-// 发布单篇文档
+// 发布单篇文档，内容有变化时在后台重新发布引用了该文档的文档
 func (item *Document) ReleaseContent() error {
+	previous := item.Release
 
-	item.Release = strings.TrimSpace(item.Content)
+	resolver := newIncludeResolver(item)
+	item.Release = resolver.resolve(strings.TrimSpace(item.Content))
+	item.Release = item.renderReleaseDiagrams(item.Release)
 
 	err := item.Processor().InsertOrUpdate("release")
 
@@ -252,11 +269,19 @@ func (item *Document) ReleaseContent() error {
 	}
 	//当文档发布后，需要清除已缓存的转换文档和文档缓存
 	item.RemoveCache()
+	item.afterRelease(resolver.includes)
+	if item.Release != previous {
+		notified := NotifyDocumentSubscribers(item)
+		NotifyDocumentChange(item, notified)
+	}
 
 	if err := os.RemoveAll(filepath.Join(conf.WorkingDirectory, "uploads", "books", strconv.Itoa(item.BookId))); err != nil {
 		logs.Error("删除已缓存的文档目录失败 -> ", filepath.Join(conf.WorkingDirectory, "uploads", "books", strconv.Itoa(item.BookId)))
 		return err
 	}
+	if item.Release != previous {
+		item.queueDependents()
+	}
 
 	return nil
 }
@@ -394,10 +419,50 @@ func (item *Document) Processor() *Document {
 	return item
 }
 
-// 增加阅读次数
+// documentViewCounter 缓冲文档的阅读次数，定时批量写入数据库.
+var documentViewCounter = batchcounter.NewCounter(30*time.Second, 1000, flushDocumentViewCounts)
+
+// 增加阅读次数，阅读次数先在内存中累加，由 StartDocumentViewCounter 定时写入数据库
 func (item *Document) IncrViewCount(id int) {
-	o := orm.NewOrm()
-	o.QueryTable(item.TableNameWithPrefix()).Filter("document_id", id).Update(orm.Params{
-		"view_count": orm.ColValue(orm.ColAdd, 1),
+	documentViewCounter.Incr(id)
+}
+
+// StartDocumentViewCounter 启动阅读次数的定时写入.
+func StartDocumentViewCounter() {
+	documentViewCounter.Start(func(err error) {
+		logs.Error("写入文档阅读次数失败 ->", err)
+	})
+}
+
+// StopDocumentViewCounter 停止定时写入，并写入尚未保存的阅读次数.
+func StopDocumentViewCounter() error {
+	return documentViewCounter.Stop()
+}
+
+// flushDocumentViewCounts 批量写入阅读次数，增量相同的文档使用同一条语句更新.
+func flushDocumentViewCounts(deltas map[int]int) error {
+	const batchSize = 500
+	groups := make(map[int][]int)
+	for id, delta := range deltas {
+		groups[delta] = append(groups[delta], id)
+	}
+	table := NewDocument().TableNameWithPrefix()
+
+	return orm.NewOrm().DoTx(func(ctx context.Context, txOrm orm.TxOrmer) error {
+		for delta, ids := range groups {
+			for start := 0; start < len(ids); start += batchSize {
+				end := start + batchSize
+				if end > len(ids) {
+					end = len(ids)
+				}
+				_, err := txOrm.QueryTable(table).Filter("document_id__in", ids[start:end]).Update(orm.Params{
+					"view_count": orm.ColValue(orm.ColAdd, delta),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
 	})
 }
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/mindoc-org/mindoc/conf"
+)
+
+// DocumentReadStatus 用户已读的文档，没有记录的文档为未读.
+type DocumentReadStatus struct {
+	StatusId   int       `orm:"column(status_id);pk;auto;unique" json:"status_id"`
+	MemberId   int       `orm:"column(member_id);type(int);description(用户id)" json:"member_id"`
+	BookId     int       `orm:"column(book_id);type(int);description(项目id)" json:"book_id"`
+	DocumentId int       `orm:"column(document_id);type(int);description(文档id)" json:"document_id"`
+	ReadTime   time.Time `orm:"type(datetime);column(read_time);auto_now_add;description(阅读时间)" json:"read_time"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *DocumentReadStatus) TableName() string {
+	return "document_read_status"
+}
+
+// TableUnique 每个用户的每篇文档只有一条记录.
+func (m *DocumentReadStatus) TableUnique() [][]string {
+	return [][]string{{"member_id", "document_id"}}
+}
+
+// TableIndex 按用户和项目查询已读文档.
+func (m *DocumentReadStatus) TableIndex() [][]string {
+	return [][]string{{"member_id", "book_id"}}
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *DocumentReadStatus) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *DocumentReadStatus) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewDocumentReadStatus() *DocumentReadStatus {
+	return &DocumentReadStatus{}
+}
+
+// MarkRead 将文档标记为已读.
+func (m *DocumentReadStatus) MarkRead(memberId, bookId, docId int) error {
+	o := orm.NewOrm()
+	exist := o.QueryTable(m.TableNameWithPrefix()).Filter("member_id", memberId).Filter("document_id", docId).Exist()
+	if exist {
+		return nil
+	}
+	status := &DocumentReadStatus{MemberId: memberId, BookId: bookId, DocumentId: docId}
+	if _, err := o.Insert(status); err != nil {
+		// 同时打开同一篇文档时插入会违反唯一约束，此时文档已经是已读
+		if o.QueryTable(m.TableNameWithPrefix()).Filter("member_id", memberId).Filter("document_id", docId).Exist() {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// MarkBookRead 将项目中的全部文档标记为已读，返回新标记的文档数量.
+func (m *DocumentReadStatus) MarkBookRead(memberId, bookId int) (int, error) {
+	var docs []*Document
+	_, err := orm.NewOrm().QueryTable(NewDocument().TableNameWithPrefix()).Filter("book_id", bookId).All(&docs, "document_id")
+	if err != nil && err != orm.ErrNoRows {
+		return 0, err
+	}
+	read, err := m.FindReadIds(memberId, bookId)
+	if err != nil {
+		return 0, err
+	}
+	var list []*DocumentReadStatus
+	for _, doc := range docs {
+		if !read[doc.DocumentId] {
+			list = append(list, &DocumentReadStatus{MemberId: memberId, BookId: bookId, DocumentId: doc.DocumentId, ReadTime: time.Now()})
+		}
+	}
+	if len(list) == 0 {
+		return 0, nil
+	}
+	_, err = orm.NewOrm().InsertMulti(100, list)
+	return len(list), err
+}
+
+// FindReadIds 查询用户在项目中已读的文档.
+func (m *DocumentReadStatus) FindReadIds(memberId, bookId int) (map[int]bool, error) {
+	var list []*DocumentReadStatus
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("member_id", memberId).
+		Filter("book_id", bookId).
+		All(&list, "document_id")
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	read := make(map[int]bool, len(list))
+	for _, item := range list {
+		read[item.DocumentId] = true
+	}
+	// 刚阅读的文档可能还没有写入数据库
+	readProgress.pendingReadIds(memberId, bookId, read)
+	return read, nil
+}
@@ -0,0 +1,287 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/mindoc-org/mindoc/conf"
+)
+
+const (
+	ReferenceDocument   = "document"
+	ReferenceAttachment = "attachment"
+	ReferenceInclude    = "include"
+)
+
+// DocumentReference 文档发布内容中对其他文档或附件的引用.
+type DocumentReference struct {
+	ReferenceId int `orm:"column(reference_id);pk;auto;unique" json:"reference_id"`
+	// BookId 引用方文档所属项目.
+	BookId     int       `orm:"column(book_id);type(int);index" json:"book_id"`
+	DocumentId int       `orm:"column(document_id);type(int);index" json:"document_id"`
+	TargetType string    `orm:"column(target_type);size(20)" json:"target_type"`
+	TargetId   int       `orm:"column(target_id);type(int);index" json:"target_id"`
+	CreateTime time.Time `orm:"column(create_time);type(datetime);auto_now_add" json:"create_time"`
+}
+
+// DocumentReferenceResult 引用了指定文档的文档.
+type DocumentReferenceResult struct {
+	DocumentId   int    `json:"doc_id"`
+	DocumentName string `json:"doc_name"`
+	Identify     string `json:"identify"`
+	BookId       int    `json:"book_id"`
+	BookName     string `json:"book_name"`
+	BookIdentify string `json:"book_identify"`
+	// PrivatelyOwned 引用方文档所属项目是否为私有项目.
+	PrivatelyOwned int `json:"privately_owned"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *DocumentReference) TableName() string {
+	return "document_references"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *DocumentReference) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *DocumentReference) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewDocumentReference() *DocumentReference {
+	return &DocumentReference{}
+}
+
+// Refresh 根据文档的发布内容重新生成文档的引用记录.
+func (m *DocumentReference) Refresh(doc *Document) error {
+	resolver := newLinkResolver()
+	targets := make(map[string]bool)
+	var references []DocumentReference
+
+	for _, link := range releaseLinks(doc.Release) {
+		targetType, targetId := resolver.resolveReference(link)
+		if targetId <= 0 || (targetType == ReferenceDocument && targetId == doc.DocumentId) {
+			continue
+		}
+		key := targetType + ":" + strconv.Itoa(targetId)
+		if targets[key] {
+			continue
+		}
+		targets[key] = true
+		references = append(references, DocumentReference{
+			BookId:     doc.BookId,
+			DocumentId: doc.DocumentId,
+			TargetType: targetType,
+			TargetId:   targetId,
+			CreateTime: time.Now(),
+		})
+	}
+
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.QueryTable(m.TableNameWithPrefix()).
+		Filter("document_id", doc.DocumentId).
+		Filter("target_type__in", ReferenceDocument, ReferenceAttachment).
+		Delete()
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if len(references) > 0 {
+		if _, err := tx.InsertMulti(len(references), references); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// SaveIncludes 保存文档通过引用指令嵌入的文档.
+func (m *DocumentReference) SaveIncludes(doc *Document, docIds []int) error {
+	o := orm.NewOrm()
+
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.QueryTable(m.TableNameWithPrefix()).
+		Filter("document_id", doc.DocumentId).
+		Filter("target_type", ReferenceInclude).
+		Delete()
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	saved := make(map[int]bool, len(docIds))
+	for _, docId := range docIds {
+		if saved[docId] {
+			continue
+		}
+		saved[docId] = true
+		reference := &DocumentReference{
+			BookId:     doc.BookId,
+			DocumentId: doc.DocumentId,
+			TargetType: ReferenceInclude,
+			TargetId:   docId,
+		}
+		if _, err := tx.Insert(reference); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// FindIncludedBy 查询通过引用指令嵌入了指定文档的文档id.
+func (m *DocumentReference) FindIncludedBy(docId int) []int {
+	var references []*DocumentReference
+
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("target_type", ReferenceInclude).
+		Filter("target_id", docId).
+		All(&references, "document_id")
+	if err != nil && err != orm.ErrNoRows {
+		logs.Error("查询文档引用失败 ->", err)
+	}
+	docIds := make([]int, 0, len(references))
+	for _, reference := range references {
+		docIds = append(docIds, reference.DocumentId)
+	}
+	return docIds
+}
+
+// RefreshDocumentReferences 刷新文档的引用记录，失败时只记录日志.
+func RefreshDocumentReferences(doc *Document) {
+	if err := NewDocumentReference().Refresh(doc); err != nil {
+		logs.Error("更新文档引用失败 ->", doc.DocumentId, err)
+	}
+}
+
+// FindReferencedBy 查询引用了指定文档的其他文档.
+func (m *DocumentReference) FindReferencedBy(docId int) ([]*DocumentReferenceResult, error) {
+	return m.FindReferencedByIds([]int{docId})
+}
+
+// FindReferencedBySubtree 查询引用了指定文档或其子文档的其他文档，用于删除文档前的提示.
+func (m *DocumentReference) FindReferencedBySubtree(bookId, docId int) ([]*DocumentReferenceResult, error) {
+	docIds, err := documentSubtreeIds(bookId, docId)
+	if err != nil {
+		return nil, err
+	}
+	return m.FindReferencedByIds(docIds)
+}
+
+// FindReferencedByIds 查询引用了指定文档集合中任一文档的其他文档，集合内部的相互引用不计入.
+func (m *DocumentReference) FindReferencedByIds(docIds []int) ([]*DocumentReferenceResult, error) {
+	if len(docIds) == 0 {
+		return nil, nil
+	}
+	var references []*DocumentReference
+
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("target_type", ReferenceDocument).
+		Filter("target_id__in", docIds).
+		Exclude("document_id__in", docIds).
+		OrderBy("reference_id").
+		All(&references, "document_id")
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	sourceIds := make([]int, 0, len(references))
+	for _, reference := range references {
+		sourceIds = append(sourceIds, reference.DocumentId)
+	}
+	return findReferenceDocuments(sourceIds)
+}
+
+// findReferenceDocuments 查询引用方文档及其所属项目，已删除的文档会被忽略.
+func findReferenceDocuments(docIds []int) ([]*DocumentReferenceResult, error) {
+	results := make([]*DocumentReferenceResult, 0, len(docIds))
+	if len(docIds) == 0 {
+		return results, nil
+	}
+	o := orm.NewOrm()
+
+	var docs []*Document
+	_, err := o.QueryTable(NewDocument().TableNameWithPrefix()).
+		Filter("document_id__in", docIds).
+		All(&docs, "document_id", "document_name", "identify", "book_id")
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	index := make(map[int]*Document, len(docs))
+	bookIds := make([]int, 0, len(docs))
+	for _, doc := range docs {
+		index[doc.DocumentId] = doc
+		bookIds = append(bookIds, doc.BookId)
+	}
+	var books []*Book
+	if len(bookIds) > 0 {
+		_, err = o.QueryTable(NewBook().TableNameWithPrefix()).
+			Filter("book_id__in", bookIds).
+			All(&books, "book_id", "book_name", "identify", "privately_owned")
+		if err != nil && err != orm.ErrNoRows {
+			return nil, err
+		}
+	}
+	bookIndex := make(map[int]*Book, len(books))
+	for _, book := range books {
+		bookIndex[book.BookId] = book
+	}
+
+	seen := make(map[int]bool, len(docIds))
+	for _, docId := range docIds {
+		doc, ok := index[docId]
+		if !ok || seen[docId] {
+			continue
+		}
+		book, ok := bookIndex[doc.BookId]
+		if !ok {
+			continue
+		}
+		seen[docId] = true
+		results = append(results, &DocumentReferenceResult{
+			DocumentId:     doc.DocumentId,
+			DocumentName:   doc.DocumentName,
+			Identify:       doc.Identify,
+			BookId:         book.BookId,
+			BookName:       book.BookName,
+			BookIdentify:   book.Identify,
+			PrivatelyOwned: book.PrivatelyOwned,
+		})
+	}
+	return results, nil
+}
+
+// resolveReference 解析站内链接指向的文档或附件.
+func (c *linkResolver) resolveReference(link string) (string, int) {
+	u, segments := c.parse(link)
+	if u == nil || segments == nil {
+		return "", 0
+	}
+	switch {
+	case segments[0] == "docs" && len(segments) == 3:
+		if book := c.findBook(segments[1]); book != nil {
+			return ReferenceDocument, c.findDocumentId(book, segments[2])
+		}
+	case segments[0] == "attach_files" && len(segments) == 3:
+		attachId, _ := strconv.Atoi(segments[2])
+		if attach, err := NewAttachment().Find(attachId); err == nil {
+			return ReferenceAttachment, attach.AttachmentId
+		}
+	case segments[0] == "uploads":
+		attach := NewAttachment()
+		err := orm.NewOrm().QueryTable(attach.TableNameWithPrefix()).Filter("http_path", u.Path).One(attach, "attachment_id")
+		if err == nil {
+			return ReferenceAttachment, attach.AttachmentId
+		}
+	}
+	return "", 0
+}
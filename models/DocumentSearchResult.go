@@ -338,3 +338,76 @@ func (m *DocumentSearchResult) SearchAllDocument(keyword string) (docs []*Docume
 
 	return
 }
+
+// FindByFieldToPager 按文档的自定义字段分页搜索文档，关键字为空时只按字段筛选.
+func (m *DocumentSearchResult) FindByFieldToPager(keyword, fieldName, fieldValue string, pageIndex, pageSize, memberId int) (searchResult []*DocumentSearchResult, totalCount int, err error) {
+	o := orm.NewOrm()
+
+	offset := (pageIndex - 1) * pageSize
+
+	keyword = "%" + strings.Replace(keyword, " ", "%", -1) + "%"
+	fieldValue = "%" + fieldValue + "%"
+
+	_need_escape := need_escape(keyword) || need_escape(fieldValue)
+	escape_sql := func(sql string) string {
+		if _need_escape {
+			return escape_re.ReplaceAllString(sql, escape_replace)
+		}
+		return sql
+	}
+
+	from := `FROM md_documents AS doc
+  LEFT JOIN md_books AS book ON doc.book_id = book.book_id
+  LEFT JOIN md_relationship AS rel ON book.book_id = rel.book_id AND rel.role_id = 0
+  LEFT JOIN md_members AS mdmb ON rel.member_id = mdmb.member_id `
+	where := `WHERE book.privately_owned = 0 `
+	args := make([]interface{}, 0)
+
+	if memberId > 0 {
+		from += `LEFT JOIN md_relationship AS rel1 ON doc.book_id = rel1.book_id AND rel1.member_id = ?
+  LEFT JOIN (SELECT *
+             FROM (SELECT
+                     book_id,
+                     team_member_id,
+                     role_id
+                   FROM md_team_relationship AS mtr
+                     LEFT JOIN md_team_member AS mtm ON mtm.team_id = mtr.team_id AND mtm.member_id = ?
+                   ORDER BY role_id DESC) AS t
+             GROUP BY t.role_id, t.team_member_id, t.book_id) AS team
+    ON team.book_id = book.book_id `
+		where = `WHERE (book.privately_owned = 0 OR rel1.relationship_id > 0 OR team.team_member_id > 0) `
+		args = append(args, memberId, memberId)
+	}
+	where += `AND doc.document_id IN (SELECT document_id FROM md_document_fields WHERE field_name = ? AND field_value LIKE ?)
+  AND (doc.document_name LIKE ? OR doc.release LIKE ?) `
+	args = append(args, fieldName, fieldValue, keyword, keyword)
+
+	sql1 := `SELECT count(doc.document_id) AS total_count ` + from + where
+
+	err = o.Raw(escape_sql(sql1), args...).QueryRow(&totalCount)
+	if err != nil {
+		logs.Error("查询搜索结果失败 -> ", err)
+		return
+	}
+
+	sql2 := `SELECT
+  doc.document_id,
+  doc.modify_time,
+  doc.create_time,
+  doc.document_name,
+  doc.identify,
+  doc.release    AS description,
+  book.identify  AS book_identify,
+  book.book_name,
+  rel.member_id,
+  mdmb.account AS author,
+  'document'     AS search_type ` + from + where + `
+ORDER BY doc.modify_time DESC
+LIMIT ? OFFSET ?;`
+
+	_, err = o.Raw(escape_sql(sql2), append(args, pageSize, offset)...).QueryRows(&searchResult)
+	if err != nil {
+		logs.Error("查询搜索结果失败 -> ", err)
+	}
+	return
+}
@@ -0,0 +1,518 @@
+package models
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/mindoc-org/mindoc/conf"
+	"github.com/mindoc-org/mindoc/utils/filetil"
+)
+
+// DocumentTransfer 将文档及其子文档移动或复制到另一个项目.
+type DocumentTransfer struct {
+	Source *Book
+	Target *Book
+	// DocumentId 要移动或复制的文档.
+	DocumentId int
+	// ParentId 目标项目中的父文档，为0时放在根目录.
+	ParentId int
+	// IsCopy 为true时复制，否则移动.
+	IsCopy bool
+	// WithHistory 复制时是否同时复制文档历史.
+	WithHistory bool
+	MemberId    int
+
+	docs     []*Document
+	attaches []*Attachment
+	// docKeys 旧的文档标识或id到新值的映射，用于改写文档链接.
+	docKeys map[string]string
+	// paths 旧的附件路径到新路径的映射.
+	paths map[string]string
+	// attachIds 旧的附件id到新id的映射.
+	attachIds map[string]string
+	// moved 移动的文档id到原标识的映射.
+	moved map[int]string
+	// files 本次复制出的附件文件，失败时清理.
+	files []string
+	// sources 移动前的附件文件，移动成功后删除.
+	sources    []string
+	docLink    *regexp.Regexp
+	attachLink *regexp.Regexp
+}
+
+func NewDocumentTransfer(source, target *Book) *DocumentTransfer {
+	return &DocumentTransfer{
+		Source:     source,
+		Target:     target,
+		docKeys:    make(map[string]string),
+		moved:      make(map[int]string),
+		paths:      make(map[string]string),
+		attachIds:  make(map[string]string),
+		docLink:    regexp.MustCompile(`/docs/` + regexp.QuoteMeta(source.Identify) + `/([A-Za-z0-9_.\-]+)`),
+		attachLink: regexp.MustCompile(`/attach_files/` + regexp.QuoteMeta(source.Identify) + `/([0-9]+)`),
+	}
+}
+
+// Transfer 执行移动或复制，返回目标项目中的根文档.
+func (m *DocumentTransfer) Transfer() (*Document, error) {
+	if m.Source == nil || m.Target == nil || m.DocumentId <= 0 {
+		return nil, ErrInvalidParameter
+	}
+	if !m.IsCopy && m.Source.BookId == m.Target.BookId {
+		return nil, ErrTransferSameBook
+	}
+	root, err := NewDocument().Find(m.DocumentId)
+	if err != nil {
+		return nil, err
+	}
+	if root.BookId != m.Source.BookId {
+		return nil, ErrDataNotExist
+	}
+	if m.ParentId > 0 {
+		parent, err := NewDocument().Find(m.ParentId)
+		if err != nil || parent.BookId != m.Target.BookId {
+			return nil, ErrTransferParentInvalid
+		}
+	}
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if m.IsCopy {
+		err = m.copyDocuments(tx)
+	} else {
+		err = m.moveDocuments(tx)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		m.removeFiles()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		m.removeFiles()
+		return nil, err
+	}
+	for _, file := range m.sources {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			logs.Error("删除已移动的附件文件失败 ->", file, err)
+		}
+	}
+
+	if !m.IsCopy {
+		//记录移动前的地址，旧链接可以跳转到新的项目
+		for _, doc := range m.docs {
+			oldIdentify := m.moved[doc.DocumentId]
+			if oldIdentify == "" {
+				oldIdentify = strconv.Itoa(doc.DocumentId)
+			}
+			AddRedirect(RedirectDocument, doc.DocumentId, m.Source.BookId, oldIdentify, doc.Identify)
+		}
+	}
+	for _, doc := range m.docs {
+		RefreshDocumentReferences(doc)
+	}
+	for _, bookId := range []int{m.Source.BookId, m.Target.BookId} {
+		NewBook().ResetDocumentNumber(bookId)
+		//删除导出缓存
+		if err := os.RemoveAll(filepath.Join(conf.GetExportOutputPath(), strconv.Itoa(bookId))); err != nil {
+			logs.Error("删除项目缓存失败 ->", err)
+		}
+	}
+
+	doc, err := NewDocument().Find(m.docs[0].DocumentId)
+	if err != nil {
+		return nil, err
+	}
+	m.addHistory(root, doc)
+	return doc, nil
+}
+
+// load 读取要处理的文档和附件，父文档总是排在子文档之前.
+func (m *DocumentTransfer) load() error {
+	ids, err := documentSubtreeIds(m.Source.BookId, m.DocumentId)
+	if err != nil {
+		return err
+	}
+	o := orm.NewOrm()
+
+	var docs []*Document
+	if _, err := o.QueryTable(NewDocument().TableNameWithPrefix()).Filter("document_id__in", ids).All(&docs); err != nil {
+		return err
+	}
+	index := make(map[int]*Document, len(docs))
+	for _, doc := range docs {
+		index[doc.DocumentId] = doc
+	}
+	m.docs = m.docs[:0]
+	for _, id := range ids {
+		if doc, ok := index[id]; ok {
+			m.docs = append(m.docs, doc)
+		}
+	}
+	if len(m.docs) == 0 {
+		return ErrDataNotExist
+	}
+	_, err = o.QueryTable(NewAttachment().TableNameWithPrefix()).Filter("document_id__in", ids).All(&m.attaches)
+	if err != nil && err != orm.ErrNoRows {
+		return err
+	}
+	return nil
+}
+
+// moveDocuments 移动文档，文档id保持不变，标识冲突时重新生成.
+func (m *DocumentTransfer) moveDocuments(tx orm.TxOrmer) error {
+	table := NewDocument().TableNameWithPrefix()
+	used := make(map[string]bool)
+
+	for i, doc := range m.docs {
+		identify, err := m.uniqueIdentify(tx, doc.Identify, used)
+		if err != nil {
+			return err
+		}
+		id := strconv.Itoa(doc.DocumentId)
+		m.docKeys[id] = id
+		m.moved[doc.DocumentId] = doc.Identify
+		if doc.Identify != "" {
+			m.docKeys[doc.Identify] = identify
+		}
+		//先清除旧项目下的缓存
+		doc.RemoveCache()
+		doc.BookId = m.Target.BookId
+		doc.Identify = identify
+		if i == 0 {
+			doc.ParentId = m.ParentId
+			doc.OrderSort = m.nextOrderSort(tx)
+		}
+	}
+	for _, attach := range m.attaches {
+		if err := m.transferFile(attach); err != nil {
+			return err
+		}
+		id := strconv.Itoa(attach.AttachmentId)
+		m.attachIds[id] = id
+	}
+	for _, attach := range m.attaches {
+		_, err := tx.QueryTable(attach.TableNameWithPrefix()).Filter("attachment_id", attach.AttachmentId).Update(orm.Params{
+			"book_id":   m.Target.BookId,
+			"file_path": attach.FilePath,
+			"http_path": m.rewrite(attach.HttpPath),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	ids := make([]int, len(m.docs))
+	for i, doc := range m.docs {
+		ids[i] = doc.DocumentId
+	}
+	_, err := tx.QueryTable(NewComment().TableNameWithPrefix()).Filter("document_id__in", ids).Update(orm.Params{"book_id": m.Target.BookId})
+	if err != nil {
+		return err
+	}
+	//原附件文件会被删除，文档历史中的附件地址也需要改写
+	if len(m.paths) > 0 {
+		var histories []*DocumentHistory
+		_, err := tx.QueryTable(NewDocumentHistory().TableNameWithPrefix()).Filter("document_id__in", ids).All(&histories, "history_id", "markdown", "content")
+		if err != nil && err != orm.ErrNoRows {
+			return err
+		}
+		for _, history := range histories {
+			markdown, content := m.rewrite(history.Markdown), m.rewrite(history.Content)
+			if markdown == history.Markdown && content == history.Content {
+				continue
+			}
+			_, err := tx.QueryTable(NewDocumentHistory().TableNameWithPrefix()).Filter("history_id", history.HistoryId).Update(orm.Params{
+				"markdown": markdown,
+				"content":  content,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, doc := range m.docs {
+		identify := interface{}(nil)
+		if doc.Identify != "" {
+			identify = doc.Identify
+		}
+		_, err := tx.QueryTable(table).Filter("document_id", doc.DocumentId).Update(orm.Params{
+			"book_id":    doc.BookId,
+			"parent_id":  doc.ParentId,
+			"order_sort": doc.OrderSort,
+			"identify":   identify,
+			"markdown":   m.rewrite(doc.Markdown),
+			"content":    m.rewrite(doc.Content),
+			"release":    m.rewrite(doc.Release),
+			"version":    time.Now().Unix(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return m.rewriteSourceLinks(tx)
+}
+
+// copyDocuments 复制文档、附件以及可选的文档历史.
+func (m *DocumentTransfer) copyDocuments(tx orm.TxOrmer) error {
+	used := make(map[string]bool)
+	parents := make(map[int]int, len(m.docs))
+	sourceIds := make([]int, len(m.docs))
+
+	for i, doc := range m.docs {
+		oldIdentify := doc.Identify
+		if doc.Identify == "" {
+			doc.Identify = fmt.Sprintf("%s-%s", m.Target.Identify, strconv.FormatInt(time.Now().UnixNano(), 32))
+		}
+		identify, err := m.uniqueIdentify(tx, doc.Identify, used)
+		if err != nil {
+			return err
+		}
+		sourceIds[i] = doc.DocumentId
+
+		if i == 0 {
+			doc.ParentId = m.ParentId
+			doc.OrderSort = m.nextOrderSort(tx)
+		} else {
+			doc.ParentId = parents[doc.ParentId]
+		}
+		doc.DocumentId = 0
+		doc.BookId = m.Target.BookId
+		doc.Identify = identify
+		doc.MemberId = m.MemberId
+		doc.ModifyAt = m.MemberId
+		doc.ViewCount = 0
+		doc.Version = time.Now().Unix()
+
+		if _, err := tx.Insert(doc); err != nil {
+			return err
+		}
+		parents[sourceIds[i]] = doc.DocumentId
+		m.docKeys[strconv.Itoa(sourceIds[i])] = strconv.Itoa(doc.DocumentId)
+		if oldIdentify != "" {
+			m.docKeys[oldIdentify] = identify
+		}
+	}
+	for _, attach := range m.attaches {
+		if err := m.transferFile(attach); err != nil {
+			return err
+		}
+		oldId := attach.AttachmentId
+		attach.AttachmentId = 0
+		attach.BookId = m.Target.BookId
+		attach.DocumentId = parents[attach.DocumentId]
+		attach.CreateAt = m.MemberId
+		if _, err := tx.Insert(attach); err != nil {
+			return err
+		}
+		m.attachIds[strconv.Itoa(oldId)] = strconv.Itoa(attach.AttachmentId)
+	}
+	for _, attach := range m.attaches {
+		attach.HttpPath = m.rewrite(attach.HttpPath)
+		if _, err := tx.Update(attach, "http_path"); err != nil {
+			return err
+		}
+	}
+	for _, doc := range m.docs {
+		doc.Markdown = m.rewrite(doc.Markdown)
+		doc.Content = m.rewrite(doc.Content)
+		doc.Release = m.rewrite(doc.Release)
+		if _, err := tx.Update(doc, "markdown", "content", "release"); err != nil {
+			return err
+		}
+	}
+	if !m.WithHistory {
+		return nil
+	}
+	var histories []*DocumentHistory
+	_, err := tx.QueryTable(NewDocumentHistory().TableNameWithPrefix()).Filter("document_id__in", sourceIds).OrderBy("history_id").All(&histories)
+	if err != nil && err != orm.ErrNoRows {
+		return err
+	}
+	for _, history := range histories {
+		history.HistoryId = 0
+		history.DocumentId = parents[history.DocumentId]
+		if parentId, ok := parents[history.ParentId]; ok {
+			history.ParentId = parentId
+		} else {
+			history.ParentId = m.ParentId
+		}
+		history.Markdown = m.rewrite(history.Markdown)
+		history.Content = m.rewrite(history.Content)
+		if _, err := tx.Insert(history); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uniqueIdentify 如果标识在目标项目中已存在则生成一个新的标识.
+func (m *DocumentTransfer) uniqueIdentify(tx orm.TxOrmer, identify string, used map[string]bool) (string, error) {
+	if identify == "" {
+		return "", nil
+	}
+	candidate := identify
+	for {
+		if !used[candidate] {
+			qs := tx.QueryTable(NewDocument().TableNameWithPrefix()).Filter("book_id", m.Target.BookId).Filter("identify", candidate)
+			if !m.IsCopy && m.Source.BookId == m.Target.BookId {
+				qs = qs.Exclude("document_id", m.DocumentId)
+			}
+			exist, err := qs.Count()
+			if err != nil {
+				return "", err
+			}
+			if exist == 0 {
+				used[candidate] = true
+				return candidate, nil
+			}
+		}
+		prefix := identify
+		if len(prefix) > 80 {
+			prefix = prefix[:80]
+		}
+		candidate = fmt.Sprintf("%s-%s", prefix, strconv.FormatInt(time.Now().UnixNano(), 32))
+	}
+}
+
+// nextOrderSort 将文档排在目标父文档的最后.
+func (m *DocumentTransfer) nextOrderSort(tx orm.TxOrmer) int {
+	var sort int
+	err := tx.Raw("SELECT COALESCE(MAX(order_sort),0) FROM "+NewDocument().TableNameWithPrefix()+" WHERE book_id = ? AND parent_id = ?", m.Target.BookId, m.ParentId).QueryRow(&sort)
+	if err != nil {
+		logs.Error("查询文档排序失败 ->", err)
+	}
+	return sort + 1
+}
+
+// transferFile 将附件文件复制到目标项目的上传目录，并记录路径映射，移动时原文件在提交后删除.
+func (m *DocumentTransfer) transferFile(attach *Attachment) error {
+	oldPath := strings.Replace(attach.FilePath, "\\", "/", -1)
+	prefix := "/uploads/" + m.Source.Identify + "/"
+	if !strings.HasPrefix(oldPath, prefix) {
+		return nil
+	}
+	newPath := "/uploads/" + m.Target.Identify + "/" + strings.TrimPrefix(oldPath, prefix)
+
+	source := filepath.Join(conf.WorkingDirectory, attach.FilePath)
+	if !filetil.FileExists(source) {
+		logs.Warn("附件文件不存在 ->", source)
+		return nil
+	}
+	dst := filepath.Join(conf.WorkingDirectory, filepath.FromSlash(newPath))
+	if filetil.FileExists(dst) {
+		//目标文件已存在时使用新的文件名
+		ext := filepath.Ext(newPath)
+		newPath = strings.TrimSuffix(newPath, ext) + "_" + strconv.FormatInt(time.Now().UnixNano(), 32) + ext
+		dst = filepath.Join(conf.WorkingDirectory, filepath.FromSlash(newPath))
+	}
+	if err := filetil.CopyFile(source, dst); err != nil {
+		return err
+	}
+	m.files = append(m.files, dst)
+	if !m.IsCopy {
+		m.sources = append(m.sources, source)
+	}
+	m.paths[oldPath] = newPath
+	attach.FilePath = filepath.FromSlash(newPath)
+	return nil
+}
+
+func (m *DocumentTransfer) removeFiles() {
+	for _, file := range m.files {
+		_ = os.Remove(file)
+	}
+}
+
+// rewrite 改写文档内容中指向已移动文档和附件的链接.
+func (m *DocumentTransfer) rewrite(text string) string {
+	if text == "" {
+		return text
+	}
+	for oldPath, newPath := range m.paths {
+		text = strings.Replace(text, oldPath, newPath, -1)
+	}
+	text = m.docLink.ReplaceAllStringFunc(text, func(s string) string {
+		key := m.docLink.FindStringSubmatch(s)[1]
+		if v, ok := m.docKeys[key]; ok {
+			return "/docs/" + m.Target.Identify + "/" + v
+		}
+		return s
+	})
+	text = m.attachLink.ReplaceAllStringFunc(text, func(s string) string {
+		key := m.attachLink.FindStringSubmatch(s)[1]
+		if v, ok := m.attachIds[key]; ok {
+			return "/attach_files/" + m.Target.Identify + "/" + v
+		}
+		return s
+	})
+	return text
+}
+
+// rewriteSourceLinks 移动后改写原项目中其他文档指向已移动文档的链接.
+func (m *DocumentTransfer) rewriteSourceLinks(tx orm.TxOrmer) error {
+	var docs []*Document
+	_, err := tx.QueryTable(NewDocument().TableNameWithPrefix()).
+		Filter("book_id", m.Source.BookId).
+		All(&docs, "document_id", "markdown", "content", "release")
+	if err != nil && err != orm.ErrNoRows {
+		return err
+	}
+	for _, doc := range docs {
+		markdown, content, release := m.rewrite(doc.Markdown), m.rewrite(doc.Content), m.rewrite(doc.Release)
+		if markdown == doc.Markdown && content == doc.Content && release == doc.Release {
+			continue
+		}
+		_, err := tx.QueryTable(NewDocument().TableNameWithPrefix()).Filter("document_id", doc.DocumentId).Update(orm.Params{
+			"markdown": markdown,
+			"content":  content,
+			"release":  release,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addHistory 在文档历史中记录移动或复制操作.
+func (m *DocumentTransfer) addHistory(source, target *Document) {
+	newHistory := func(doc *Document, action, actionName string) {
+		history := NewDocumentHistory()
+		history.DocumentId = doc.DocumentId
+		history.DocumentName = doc.DocumentName
+		history.ParentId = doc.ParentId
+		history.Markdown = doc.Markdown
+		history.Content = doc.Content
+		history.MemberId = doc.MemberId
+		history.ModifyAt = m.MemberId
+		history.Version = time.Now().Unix()
+		history.IsOpen = doc.IsOpen
+		history.Action = action
+		history.ActionName = actionName
+		if _, err := history.InsertOrUpdate(); err != nil {
+			logs.Error("写入文档历史失败 ->", err)
+		}
+	}
+	if m.IsCopy {
+		newHistory(source, "copy_to", fmt.Sprintf("复制到《%s》", m.Target.BookName))
+		newHistory(target, "copy_from", fmt.Sprintf("从《%s》复制", m.Source.BookName))
+	} else {
+		newHistory(source, "move_out", fmt.Sprintf("移动到《%s》", m.Target.BookName))
+		newHistory(target, "move_in", fmt.Sprintf("从《%s》移入", m.Source.BookName))
+	}
+	for _, doc := range m.docs {
+		doc.RemoveCache()
+	}
+}
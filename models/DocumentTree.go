@@ -19,6 +19,8 @@ type DocumentTree struct {
 	Identify     string                 `json:"identify"`
 	BookIdentify string                 `json:"-"`
 	Version      int64                  `json:"version"`
+	Stale        bool                   `json:"-"`
+	Unread       bool                   `json:"-"`
 	State        *DocumentSelected      `json:"-"`
 	AAttrs       map[string]interface{} `json:"a_attr"`
 	Children     []*DocumentTree        `json:"children"`
@@ -49,7 +51,7 @@ func (item *Document) FindDocumentTree(bookId int) ([]*DocumentTree, error) {
 	count, err := o.QueryTable(item).Filter("book_id", bookId).
 		OrderBy("order_sort", "document_id").
 		Limit(math.MaxInt32).
-		All(&docs, "document_id", "version", "document_name", "parent_id", "identify", "is_open")
+		All(&docs, "document_id", "version", "document_name", "parent_id", "identify", "is_open", "modify_time", "content_time")
 
 	if err != nil {
 		return trees, err
@@ -73,6 +75,10 @@ func (item *Document) FindDocumentTree(bookId int) ([]*DocumentTree, error) {
 			tree.State = &DocumentSelected{Selected: false, Opened: false, Disabled: true}
 			tree.AAttrs = map[string]interface{}{"disabled": true, "opened": 2}
 		}
+		if item.IsStale(book.ReviewDays) {
+			tree.Stale = true
+			tree.AAttrs["data-stale"] = "true"
+		}
 		tree.DocumentId = item.DocumentId
 		tree.Identify = item.Identify
 		tree.Version = item.Version
@@ -144,11 +150,22 @@ func (item *Document) FindDocumentTree2(bookId int) ([]*DocumentTree, error) {
 	return trees, nil
 }
 
-func (item *Document) CreateDocumentTreeForHtml(bookId, selectedId int) (string, error) {
+// 生成项目文档树的 HTML，memberId 大于 0 时标记用户未读的文档
+func (item *Document) CreateDocumentTreeForHtml(bookId, selectedId, memberId int) (string, error) {
 	trees, err := item.FindDocumentTree(bookId)
 	if err != nil {
 		return "", err
 	}
+	// 登录用户标记未读的文档
+	if memberId > 0 {
+		read, err := NewDocumentReadStatus().FindReadIds(memberId, bookId)
+		if err != nil {
+			return "", err
+		}
+		for _, tree := range trees {
+			tree.Unread = !read[tree.DocumentId]
+		}
+	}
 	parentId := getSelectedNode(trees, selectedId)
 
 	buf := bytes.NewBufferString("")
@@ -203,6 +220,12 @@ func getDocumentTree(array []*DocumentTree, parentId int, selectedId int, select
 			if item.State != nil && item.State.Disabled {
 				buf.WriteString(" disabled=\"true\"")
 			}
+			if item.Stale {
+				buf.WriteString(" data-stale=\"true\"")
+			}
+			if item.Unread {
+				buf.WriteString(" data-unread=\"true\"")
+			}
 			buf.WriteString(fmt.Sprintf(" data-version=\"%d\"%s>%s</a>", item.Version, selected, template.HTMLEscapeString(item.DocumentName)))
 
 			for _, sub := range array {
@@ -0,0 +1,522 @@
+package models
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/mindoc-org/mindoc/conf"
+	"github.com/mindoc-org/mindoc/utils"
+	"github.com/mindoc-org/mindoc/utils/batchcounter"
+)
+
+const (
+	// documentViewKeepDays 原始阅读记录的保留天数，统计页面最多查询这么多天
+	documentViewKeepDays = 90
+	// documentViewMaxDuration 单次阅读时长的上限，超过时按上限计算
+	documentViewMaxDuration = 4 * 60 * 60
+	// analyticsDayFormat 按天汇总时的日期格式
+	analyticsDayFormat = "2006-01-02"
+	// documentViewFlushInterval 缓存的阅读记录写入数据库的间隔
+	documentViewFlushInterval = 10 * time.Second
+	// documentViewMaxPending 缓存的阅读记录和时长上报达到该数量时提前写入
+	documentViewMaxPending = 1000
+	// documentViewExitWindow 离开项目后在该时间内又打开了同一项目的文档，说明是刷新页面或站内跳转，不算离开
+	documentViewExitWindow = 30 * time.Second
+	// analyticsRefreshInterval 统计页面重新汇总当天数据的最短间隔
+	analyticsRefreshInterval = 5 * time.Minute
+)
+
+// DocumentView 文档的一次阅读记录，按天汇总到 DocumentViewDaily.
+type DocumentView struct {
+	ViewId int `orm:"column(view_id);pk;auto;unique" json:"view_id"`
+	// ViewKey 阅读记录的随机标识，阅读记录异步写入，离开文档时使用它上报阅读时长
+	ViewKey    string `orm:"column(view_key);size(32);description(阅读记录标识)" json:"-"`
+	BookId     int    `orm:"column(book_id);type(int);description(项目id)" json:"book_id"`
+	DocumentId int    `orm:"column(document_id);type(int);description(文档id)" json:"document_id"`
+	// MemberId 匿名用户为 0
+	MemberId int `orm:"column(member_id);type(int);default(0);description(阅读用户id)" json:"member_id"`
+	// VisitorId 区分读者，登录用户使用用户id，匿名用户使用 Cookie 中的随机标识
+	VisitorId   string `orm:"column(visitor_id);size(64);description(读者标识)" json:"-"`
+	Referer     string `orm:"column(referer);size(500);null;description(来源地址)" json:"referer"`
+	RefererHost string `orm:"column(referer_host);size(255);null;description(来源站点)" json:"referer_host"`
+	// Duration 阅读时长，由阅读页面离开时上报
+	Duration int `orm:"column(duration);type(int);default(0);description(阅读时长，单位秒)" json:"duration"`
+	IsExit   int `orm:"column(is_exit);type(int);default(0);description(是否从该文档离开项目 0：否 1：是)" json:"is_exit"`
+	// CreateTime 由 Record 设置为阅读的时间，而不是写入数据库的时间
+	CreateTime time.Time `orm:"type(datetime);column(create_time)" json:"create_time"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *DocumentView) TableName() string {
+	return "document_views"
+}
+
+// TableIndex 按项目和时间查询阅读记录.
+func (m *DocumentView) TableIndex() [][]string {
+	return [][]string{{"book_id", "create_time"}, {"view_key"}}
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *DocumentView) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *DocumentView) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewDocumentView() *DocumentView {
+	return &DocumentView{}
+}
+
+// Record 记录一次阅读，阅读记录先缓存在内存中，由 StartDocumentViewWriter 定时批量写入数据库.
+// 返回阅读记录的标识，离开文档时通过 LeaveDocumentView 上报阅读时长.
+func (m *DocumentView) Record() string {
+	m.ViewKey = string(utils.Krand(32, utils.KC_RAND_KIND_ALL))
+	m.Referer = truncateRunes(m.Referer, 500)
+	m.RefererHost = truncateRunes(m.RefererHost, 255)
+	m.CreateTime = time.Now()
+	documentViews.add(m)
+	return m.ViewKey
+}
+
+// LeaveDocumentView 记录离开文档时的阅读时长，只接受同一项目同一读者的第一次上报.
+func LeaveDocumentView(viewKey string, bookId int, visitorId string, duration int, exit bool) {
+	if duration < 0 {
+		duration = 0
+	} else if duration > documentViewMaxDuration {
+		duration = documentViewMaxDuration
+	}
+	leave := documentViewLeave{bookId: bookId, visitorId: visitorId, duration: duration}
+	if exit {
+		leave.isExit = 1
+	}
+	documentViews.leave(viewKey, leave)
+}
+
+// StartDocumentViewWriter 启动阅读记录的定时写入.
+func StartDocumentViewWriter() {
+	documentViews.writer.Start(func(err error) {
+		logs.Error("写入文档阅读记录失败 ->", err)
+	})
+}
+
+// StopDocumentViewWriter 停止定时写入，并写入尚未保存的阅读记录.
+func StopDocumentViewWriter() error {
+	return documentViews.writer.Stop()
+}
+
+// documentViewLeave 尚未写入的阅读时长上报.
+type documentViewLeave struct {
+	bookId    int
+	visitorId string
+	duration  int
+	isExit    int
+}
+
+// documentViewExit 最近一次离开项目的上报.
+type documentViewExit struct {
+	viewKey string
+	time    time.Time
+}
+
+// documentViewBuffer 缓存阅读记录和阅读时长上报.
+type documentViewBuffer struct {
+	mu     sync.Mutex
+	views  []*DocumentView
+	keys   map[string]*DocumentView
+	leaves map[string]documentViewLeave
+	// exits 按项目和读者记录最近离开项目的阅读记录，cancels 为已写入数据库、需要取消离开标记的阅读记录
+	exits   map[string]documentViewExit
+	cancels []string
+
+	// flushMu 保证同一时间只有一个写入
+	flushMu sync.Mutex
+	writer  *batchcounter.Writer
+}
+
+// documentViews 缓存尚未写入数据库的阅读记录.
+var documentViews = newDocumentViewBuffer()
+
+func newDocumentViewBuffer() *documentViewBuffer {
+	b := &documentViewBuffer{
+		keys:   make(map[string]*DocumentView),
+		leaves: make(map[string]documentViewLeave),
+		exits:  make(map[string]documentViewExit),
+	}
+	b.writer = batchcounter.NewWriter(documentViewFlushInterval, b.flush)
+	return b
+}
+
+// add 缓存新的阅读记录，同一读者刚离开项目又打开了项目中的文档时取消上一次的离开标记.
+func (b *documentViewBuffer) add(view *DocumentView) {
+	b.mu.Lock()
+	b.views = append(b.views, view)
+	b.keys[view.ViewKey] = view
+	exitKey := strconv.Itoa(view.BookId) + ":" + view.VisitorId
+	if exit, ok := b.exits[exitKey]; ok {
+		delete(b.exits, exitKey)
+		if view.CreateTime.Sub(exit.time) < documentViewExitWindow {
+			b.cancelExit(exit.viewKey)
+		}
+	}
+	b.mu.Unlock()
+	b.notify()
+}
+
+// cancelExit 取消阅读记录的离开标记，调用时需要持有 mu.
+func (b *documentViewBuffer) cancelExit(viewKey string) {
+	if view, ok := b.keys[viewKey]; ok {
+		view.IsExit = 0
+	} else if leave, ok := b.leaves[viewKey]; ok {
+		leave.isExit = 0
+		b.leaves[viewKey] = leave
+	} else {
+		b.cancels = append(b.cancels, viewKey)
+	}
+}
+
+// leave 阅读记录还没有写入时直接修改缓存的记录，否则等写入时更新数据库.
+func (b *documentViewBuffer) leave(viewKey string, leave documentViewLeave) {
+	b.mu.Lock()
+	if leave.isExit == 1 {
+		b.exits[strconv.Itoa(leave.bookId)+":"+leave.visitorId] = documentViewExit{viewKey: viewKey, time: time.Now()}
+	}
+	if view, ok := b.keys[viewKey]; ok {
+		if view.BookId == leave.bookId && view.VisitorId == leave.visitorId && view.Duration == 0 && view.IsExit == 0 {
+			view.Duration = leave.duration
+			view.IsExit = leave.isExit
+		}
+		b.mu.Unlock()
+		return
+	}
+	if _, ok := b.leaves[viewKey]; !ok {
+		b.leaves[viewKey] = leave
+	}
+	b.mu.Unlock()
+	b.notify()
+}
+
+// notify 缓存的数量达到上限时提前写入.
+func (b *documentViewBuffer) notify() {
+	b.mu.Lock()
+	full := len(b.views)+len(b.leaves) >= documentViewMaxPending
+	b.mu.Unlock()
+
+	if full {
+		b.writer.Notify()
+	}
+}
+
+// flush 在一个事务中写入缓存的阅读记录，再更新已写入记录的阅读时长，写入失败时放回缓存.
+func (b *documentViewBuffer) flush() error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	for key, exit := range b.exits {
+		if time.Since(exit.time) >= documentViewExitWindow {
+			delete(b.exits, key)
+		}
+	}
+	if len(b.views) == 0 && len(b.leaves) == 0 && len(b.cancels) == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	views, leaves, cancels := b.views, b.leaves, b.cancels
+	b.views = nil
+	b.keys = make(map[string]*DocumentView)
+	b.leaves = make(map[string]documentViewLeave)
+	b.cancels = nil
+	b.mu.Unlock()
+
+	table := NewDocumentView().TableNameWithPrefix()
+	err := orm.NewOrm().DoTx(func(ctx context.Context, txOrm orm.TxOrmer) error {
+		if len(views) > 0 {
+			if _, err := txOrm.InsertMulti(100, views); err != nil {
+				return err
+			}
+		}
+		for key, leave := range leaves {
+			_, err := txOrm.QueryTable(table).
+				Filter("view_key", key).
+				Filter("book_id", leave.bookId).
+				Filter("visitor_id", leave.visitorId).
+				Filter("duration", 0).
+				Filter("is_exit", 0).
+				Update(orm.Params{"duration": leave.duration, "is_exit": leave.isExit})
+			if err != nil {
+				return err
+			}
+		}
+		if len(cancels) > 0 {
+			if _, err := txOrm.QueryTable(table).Filter("view_key__in", cancels).Update(orm.Params{"is_exit": 0}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		b.mu.Lock()
+		// 数据库长时间不可用时丢弃最早的阅读记录，避免缓存无限增长
+		if over := len(views) + len(b.views) - documentViewMaxPending*10; over > 0 {
+			if over > len(views) {
+				over = len(views)
+			}
+			logs.Warn("丢弃未能写入的文档阅读记录 ->", over)
+			views = views[over:]
+		}
+		b.views = append(views, b.views...)
+		for _, view := range views {
+			b.keys[view.ViewKey] = view
+		}
+		for key, leave := range leaves {
+			if _, ok := b.leaves[key]; !ok {
+				b.leaves[key] = leave
+			}
+		}
+		b.cancels = append(cancels, b.cancels...)
+		b.mu.Unlock()
+	}
+	return err
+}
+
+// eachDocumentView 按批次遍历项目在时间范围内的阅读记录，bookId 为 0 时遍历全部项目.
+func eachDocumentView(bookId int, start, end time.Time, fn func(*DocumentView), cols ...string) error {
+	const batchSize = 1000
+	lastId := 0
+	cols = append(cols, "view_id")
+
+	for {
+		qs := orm.NewOrm().QueryTable(NewDocumentView().TableNameWithPrefix()).
+			Filter("create_time__gte", start).
+			Filter("create_time__lt", end).
+			Filter("view_id__gt", lastId)
+		if bookId > 0 {
+			qs = qs.Filter("book_id", bookId)
+		}
+		var list []*DocumentView
+		if _, err := qs.OrderBy("view_id").Limit(batchSize).All(&list, cols...); err != nil && err != orm.ErrNoRows {
+			return err
+		}
+		for _, item := range list {
+			fn(item)
+			lastId = item.ViewId
+		}
+		if len(list) < batchSize {
+			return nil
+		}
+	}
+}
+
+// DocumentViewDaily 文档每天的阅读统计，DocumentId 为 0 的记录是整个项目的统计.
+type DocumentViewDaily struct {
+	DailyId    int    `orm:"column(daily_id);pk;auto;unique" json:"daily_id"`
+	BookId     int    `orm:"column(book_id);type(int);description(项目id)" json:"book_id"`
+	DocumentId int    `orm:"column(document_id);type(int);description(文档id，0 表示整个项目)" json:"document_id"`
+	Day        string `orm:"column(day);size(10);description(日期)" json:"day"`
+	Views      int    `orm:"column(views);type(int);default(0);description(阅读次数)" json:"views"`
+	Readers    int    `orm:"column(readers);type(int);default(0);description(读者人数)" json:"readers"`
+	// Duration 和 DurationViews 为上报了阅读时长的阅读次数及其总时长，用于计算平均阅读时长
+	Duration      int `orm:"column(duration);type(int);default(0);description(总阅读时长，单位秒)" json:"duration"`
+	DurationViews int `orm:"column(duration_views);type(int);default(0);description(上报了阅读时长的阅读次数)" json:"duration_views"`
+	Exits         int `orm:"column(exits);type(int);default(0);description(从该文档离开项目的次数)" json:"exits"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *DocumentViewDaily) TableName() string {
+	return "document_view_daily"
+}
+
+// TableUnique 每个文档每天只有一条统计.
+func (m *DocumentViewDaily) TableUnique() [][]string {
+	return [][]string{{"book_id", "document_id", "day"}}
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *DocumentViewDaily) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *DocumentViewDaily) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+// AverageDuration 平均阅读时长，单位秒.
+func (m *DocumentViewDaily) AverageDuration() int {
+	if m.DurationViews == 0 {
+		return 0
+	}
+	return m.Duration / m.DurationViews
+}
+
+// beginningOfDay 获取当天零点.
+func beginningOfDay(t time.Time) time.Time {
+	y, mon, d := t.Date()
+	return time.Date(y, mon, d, 0, 0, 0, 0, t.Location())
+}
+
+var (
+	// aggregateMu 汇总时先删除再写入统计结果，同一时间只允许一个汇总，避免写入时违反唯一索引
+	aggregateMu sync.Mutex
+	// aggregatedBooks 统计页面最后一次汇总项目当天数据的时间
+	aggregatedBooks   = make(map[int]time.Time)
+	aggregatedBooksMu sync.Mutex
+)
+
+// AggregateDocumentViews 重新汇总指定日期已写入数据库的阅读记录，bookId 为 0 时汇总全部项目.
+func AggregateDocumentViews(day time.Time, bookId int) error {
+	aggregateMu.Lock()
+	defer aggregateMu.Unlock()
+
+	start := beginningOfDay(day)
+	dayStr := start.Format(analyticsDayFormat)
+
+	type dailyKey struct{ bookId, documentId int }
+	stats := make(map[dailyKey]*DocumentViewDaily)
+	readers := make(map[dailyKey]map[string]bool)
+
+	add := func(key dailyKey, view *DocumentView) {
+		daily, ok := stats[key]
+		if !ok {
+			daily = &DocumentViewDaily{BookId: key.bookId, DocumentId: key.documentId, Day: dayStr}
+			stats[key] = daily
+			readers[key] = make(map[string]bool)
+		}
+		daily.Views++
+		if view.Duration > 0 {
+			daily.Duration += view.Duration
+			daily.DurationViews++
+		}
+		daily.Exits += view.IsExit
+		readers[key][view.VisitorId] = true
+	}
+	err := eachDocumentView(bookId, start, start.AddDate(0, 0, 1), func(view *DocumentView) {
+		add(dailyKey{view.BookId, view.DocumentId}, view)
+		add(dailyKey{view.BookId, 0}, view)
+	}, "book_id", "document_id", "visitor_id", "duration", "is_exit")
+	if err != nil {
+		return err
+	}
+
+	list := make([]*DocumentViewDaily, 0, len(stats))
+	for key, daily := range stats {
+		daily.Readers = len(readers[key])
+		list = append(list, daily)
+	}
+	table := new(DocumentViewDaily).TableNameWithPrefix()
+
+	return orm.NewOrm().DoTx(func(ctx context.Context, txOrm orm.TxOrmer) error {
+		qs := txOrm.QueryTable(table).Filter("day", dayStr)
+		if bookId > 0 {
+			qs = qs.Filter("book_id", bookId)
+		}
+		if _, err := qs.Delete(); err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return nil
+		}
+		_, err := txOrm.InsertMulti(100, list)
+		return err
+	})
+}
+
+// refreshBookAnalytics 统计页面打开时汇总项目当天的阅读记录，间隔小于 analyticsRefreshInterval 时使用已有的结果.
+func refreshBookAnalytics(bookId int) error {
+	aggregatedBooksMu.Lock()
+	if time.Since(aggregatedBooks[bookId]) < analyticsRefreshInterval {
+		aggregatedBooksMu.Unlock()
+		return nil
+	}
+	aggregatedBooks[bookId] = time.Now()
+	aggregatedBooksMu.Unlock()
+
+	return AggregateDocumentViews(time.Now(), bookId)
+}
+
+// AggregateAllDocumentViews 汇总前一天和当天的阅读记录，并删除超过保留天数的原始记录.
+func AggregateAllDocumentViews() {
+	// 先写入缓存的阅读记录，写入失败时仍然汇总已有的记录
+	if err := documentViews.flush(); err != nil {
+		logs.Error("写入文档阅读记录失败 ->", err)
+	}
+	now := time.Now()
+	for _, day := range []time.Time{now.AddDate(0, 0, -1), now} {
+		if err := AggregateDocumentViews(day, 0); err != nil {
+			logs.Error("汇总文档阅读统计失败 ->", day.Format(analyticsDayFormat), err)
+		}
+	}
+	before := beginningOfDay(now).AddDate(0, 0, -documentViewKeepDays)
+	if _, err := orm.NewOrm().QueryTable(NewDocumentView().TableNameWithPrefix()).Filter("create_time__lt", before).Delete(); err != nil {
+		logs.Error("清理文档阅读记录失败 ->", err)
+	}
+}
+
+// BookSearchTerm 项目内搜索的关键词，按天统计搜索次数和没有结果的次数.
+type BookSearchTerm struct {
+	TermId      int    `orm:"column(term_id);pk;auto;unique" json:"term_id"`
+	BookId      int    `orm:"column(book_id);type(int);description(项目id)" json:"book_id"`
+	Keyword     string `orm:"column(keyword);size(100);description(搜索关键词)" json:"keyword"`
+	Day         string `orm:"column(day);size(10);description(日期)" json:"day"`
+	Searches    int    `orm:"column(searches);type(int);default(0);description(搜索次数)" json:"searches"`
+	ZeroResults int    `orm:"column(zero_results);type(int);default(0);description(没有结果的次数)" json:"zero_results"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *BookSearchTerm) TableName() string {
+	return "book_search_terms"
+}
+
+// TableUnique 每个项目的每个关键词每天只有一条统计.
+func (m *BookSearchTerm) TableUnique() [][]string {
+	return [][]string{{"book_id", "keyword", "day"}}
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *BookSearchTerm) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *BookSearchTerm) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+// RecordBookSearch 记录一次项目内搜索.
+func RecordBookSearch(bookId int, keyword string, results int) {
+	keyword = strings.ToLower(strings.Join(strings.Fields(keyword), " "))
+	if keyword == "" {
+		return
+	}
+	keyword = truncateRunes(keyword, 100)
+	day := time.Now().Format(analyticsDayFormat)
+	zero := 0
+	if results == 0 {
+		zero = 1
+	}
+	o := orm.NewOrm()
+	qs := o.QueryTable(new(BookSearchTerm).TableNameWithPrefix()).
+		Filter("book_id", bookId).
+		Filter("keyword", keyword).
+		Filter("day", day)
+
+	params := orm.Params{
+		"searches":     orm.ColValue(orm.ColAdd, 1),
+		"zero_results": orm.ColValue(orm.ColAdd, zero),
+	}
+	if n, err := qs.Update(params); err == nil && n > 0 {
+		return
+	}
+	term := &BookSearchTerm{BookId: bookId, Keyword: keyword, Day: day, Searches: 1, ZeroResults: zero}
+	if _, err := o.Insert(term); err != nil {
+		// 同时有相同的搜索时插入会违反唯一约束，改为累加
+		if _, err := qs.Update(params); err != nil {
+			logs.Error("记录项目搜索失败 ->", bookId, keyword, err)
+		}
+	}
+}
@@ -40,6 +40,34 @@ var (
 
 	ErrCommentClosed          = errors.New("评论已关闭")
 	ErrCommentContentNotEmpty = errors.New("评论内容不能为空")
+	ErrCommentParentNotExist  = errors.New("回复的评论不存在")
+
+	// ErrTwoFactorCodeInvalid 两步验证码错误.
+	ErrTwoFactorCodeInvalid = errors.New("验证码不正确")
+	ErrTwoFactorEnabled     = errors.New("已启用两步验证")
+
+	// ErrPasswordPolicy 密码不符合系统的密码策略.
+	ErrPasswordPolicy = errors.New("密码不符合密码策略")
+	// ErrLoginLocked 登录失败次数过多，账号或IP被临时锁定.
+	ErrLoginLocked = errors.New("登录失败次数过多，请稍后再试")
+
+	// ErrRecycleConflict 要恢复的数据与现有数据冲突.
+	ErrRecycleConflict = errors.New("原数据已被占用，无法恢复")
+	// ErrRecycleBookDeleted 文档所属的项目已被删除.
+	ErrRecycleBookDeleted = errors.New("文档所属项目不存在，请先恢复项目")
+
+	// ErrTransferSameBook 不能将文档移动到所在的项目.
+	ErrTransferSameBook = errors.New("目标项目与当前项目相同")
+	// ErrTransferParentInvalid 目标父文档不存在或不属于目标项目.
+	ErrTransferParentInvalid = errors.New("目标父文档不存在")
+
+	// ErrLinkCheckRunning 链接检查正在进行中.
+	ErrLinkCheckRunning = errors.New("链接检查正在进行中")
+
+	// ErrCustomFieldExist 字段标识已被使用.
+	ErrCustomFieldExist = errors.New("字段标识已存在")
+	// ErrFrontMatterInvalid 文档元数据不是合法的 YAML.
+	ErrFrontMatterInvalid = errors.New("文档元数据格式不正确")
 )
 
 type Error struct {
@@ -0,0 +1,163 @@
+package models
+
+import (
+	"html"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/mindoc-org/mindoc/utils/mathml"
+)
+
+// mathSkipSelector 不处理其中公式的元素.
+const mathSkipSelector = "pre, code, script, style, textarea, math, .katex"
+
+// mathSegment 文字中的一段普通文字或公式.
+type mathSegment struct {
+	Text    string
+	Math    bool
+	Display bool
+}
+
+// renderExportMath 将导出内容中的公式转换为 MathML，导出的电子书中无法执行阅读页面中的公式脚本.
+func renderExportMath(docQuery *goquery.Document) {
+	// 编辑器已经渲染过的公式直接使用 KaTeX 生成的 MathML
+	docQuery.Find(".katex").Each(func(i int, selection *goquery.Selection) {
+		math := selection.Find("math").First()
+		if math.Length() == 0 {
+			return
+		}
+		target := selection
+		if display := selection.ParentsFiltered(".katex-display").First(); display.Length() > 0 {
+			math.SetAttr("display", "block")
+			target = display
+		}
+		if content, err := goquery.OuterHtml(math); err == nil {
+			target.ReplaceWithHtml(content)
+		}
+	})
+	// Editor.md 未渲染的公式
+	docQuery.Find(".editormd-tex").Each(func(i int, selection *goquery.Selection) {
+		if selection.Find("math").Length() > 0 {
+			return
+		}
+		tex := strings.TrimSpace(selection.Text())
+		if tex == "" {
+			return
+		}
+		content, err := mathml.Convert(tex, goquery.NodeName(selection) != "span")
+		if err != nil {
+			logs.Warn("转换公式失败 ->", tex, err)
+			return
+		}
+		selection.SetHtml(content)
+	})
+
+	docQuery.Find("body, body *").Contents().Each(func(i int, selection *goquery.Selection) {
+		if goquery.NodeName(selection) != "#text" || !strings.Contains(selection.Text(), "$") {
+			return
+		}
+		if selection.ParentsFiltered(mathSkipSelector).Length() > 0 {
+			return
+		}
+		text := selection.Text()
+		segments := splitMath(text)
+		if len(segments) == 1 && !segments[0].Math && segments[0].Text == text {
+			return
+		}
+		var buf strings.Builder
+		for _, segment := range segments {
+			if !segment.Math {
+				buf.WriteString(html.EscapeString(segment.Text))
+				continue
+			}
+			content, err := mathml.Convert(segment.Text, segment.Display)
+			if err != nil {
+				logs.Warn("转换公式失败 ->", segment.Text, err)
+				if segment.Display {
+					buf.WriteString(html.EscapeString("$$" + segment.Text + "$$"))
+				} else {
+					buf.WriteString(html.EscapeString("$" + segment.Text + "$"))
+				}
+				continue
+			}
+			buf.WriteString(content)
+		}
+		selection.ReplaceWithHtml(buf.String())
+	})
+}
+
+// splitMath 拆分文字中 $...$ 和 $$...$$ 包围的公式，\$ 表示普通的美元符号.
+// 行内公式的 $ 内侧不能是空白，并且结束的 $ 后不能紧跟数字，避免把金额识别为公式.
+func splitMath(text string) []mathSegment {
+	var segments []mathSegment
+	var plain strings.Builder
+
+	flush := func() {
+		if plain.Len() > 0 {
+			segments = append(segments, mathSegment{Text: plain.String()})
+			plain.Reset()
+		}
+	}
+	for i := 0; i < len(text); {
+		if strings.HasPrefix(text[i:], "\\$") {
+			plain.WriteString("$")
+			i += 2
+			continue
+		}
+		if text[i] != '$' {
+			plain.WriteByte(text[i])
+			i++
+			continue
+		}
+		if strings.HasPrefix(text[i:], "$$") {
+			if end := strings.Index(text[i+2:], "$$"); end > 0 {
+				flush()
+				segments = append(segments, mathSegment{Text: text[i+2 : i+2+end], Math: true, Display: true})
+				i += end + 4
+				continue
+			}
+			plain.WriteString("$$")
+			i += 2
+			continue
+		}
+		if end := inlineMathEnd(text, i+1); end > 0 {
+			flush()
+			segments = append(segments, mathSegment{Text: text[i+1 : end], Math: true})
+			i = end + 1
+			continue
+		}
+		plain.WriteByte('$')
+		i++
+	}
+	flush()
+	return segments
+}
+
+// inlineMathEnd 查找行内公式结束的 $ 的位置，没有找到时返回 -1.
+func inlineMathEnd(text string, start int) int {
+	if start >= len(text) || isMathSpace(text[start]) {
+		return -1
+	}
+	for i := start; i < len(text); i++ {
+		switch text[i] {
+		case '\n':
+			return -1
+		case '\\':
+			i++
+		case '$':
+			if i == start || isMathSpace(text[i-1]) {
+				return -1
+			}
+			if i+1 < len(text) && text[i+1] >= '0' && text[i+1] <= '9' {
+				return -1
+			}
+			return i
+		}
+	}
+	return -1
+}
+
+func isMathSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
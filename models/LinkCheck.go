@@ -0,0 +1,566 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+	"github.com/mindoc-org/mindoc/conf"
+	"github.com/mindoc-org/mindoc/utils/filetil"
+)
+
+const (
+	LinkTypeDocument   = "document"
+	LinkTypeAttachment = "attachment"
+	LinkTypeBook       = "book"
+	LinkTypeExternal   = "external"
+)
+
+// BrokenLink 链接检查中发现的失效链接.
+type BrokenLink struct {
+	LinkId       int       `orm:"column(link_id);pk;auto;unique" json:"link_id"`
+	BookId       int       `orm:"column(book_id);type(int);index" json:"book_id"`
+	DocumentId   int       `orm:"column(document_id);type(int);index" json:"document_id"`
+	DocumentName string    `orm:"column(document_name);size(500)" json:"document_name"`
+	LinkType     string    `orm:"column(link_type);size(20)" json:"link_type"`
+	Url          string    `orm:"column(url);size(2000)" json:"url"`
+	Reason       string    `orm:"column(reason);size(500);null" json:"reason"`
+	CheckTime    time.Time `orm:"column(check_time);type(datetime)" json:"check_time"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *BrokenLink) TableName() string {
+	return "broken_links"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *BrokenLink) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *BrokenLink) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewBrokenLink() *BrokenLink {
+	return &BrokenLink{}
+}
+
+// FindByBookId 查询项目最近一次检查发现的失效链接.
+func (m *BrokenLink) FindByBookId(bookId int) ([]*BrokenLink, error) {
+	var list []*BrokenLink
+
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("book_id", bookId).
+		OrderBy("document_id", "link_id").
+		All(&list)
+	if err == orm.ErrNoRows {
+		err = nil
+	}
+	return list, err
+}
+
+// LinkCheck 项目最近一次链接检查的结果汇总.
+type LinkCheck struct {
+	CheckId     int       `orm:"column(check_id);pk;auto;unique" json:"check_id"`
+	BookId      int       `orm:"column(book_id);type(int);unique" json:"book_id"`
+	LinkCount   int       `orm:"column(link_count);type(int);default(0)" json:"link_count"`
+	BrokenCount int       `orm:"column(broken_count);type(int);default(0)" json:"broken_count"`
+	CheckTime   time.Time `orm:"column(check_time);type(datetime)" json:"check_time"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *LinkCheck) TableName() string {
+	return "link_checks"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *LinkCheck) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *LinkCheck) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewLinkCheck() *LinkCheck {
+	return &LinkCheck{}
+}
+
+// FindByBookId 查询项目最近一次链接检查的汇总.
+func (m *LinkCheck) FindByBookId(bookId int) (*LinkCheck, error) {
+	err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("book_id", bookId).One(m)
+	return m, err
+}
+
+// FindByBookIds 批量查询项目的链接检查汇总.
+func (m *LinkCheck) FindByBookIds(bookIds []int) (map[int]*LinkCheck, error) {
+	checks := make(map[int]*LinkCheck, len(bookIds))
+	if len(bookIds) == 0 {
+		return checks, nil
+	}
+	var list []*LinkCheck
+
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("book_id__in", bookIds).All(&list)
+	if err != nil && err != orm.ErrNoRows {
+		return checks, err
+	}
+	for _, check := range list {
+		checks[check.BookId] = check
+	}
+	return checks, nil
+}
+
+var (
+	linkCheckLocker  sync.Mutex
+	linkCheckRunning = make(map[int]bool)
+	siteCheckRunning bool
+)
+
+// linkResolver 解析已发布内容中的站内链接，并缓存查询到的项目和文档.
+type linkResolver struct {
+	hosts map[string]bool
+	books map[string]*Book
+	docs  map[string]int
+}
+
+// linkChecker 检查一个项目中已发布文档里的链接.
+type linkChecker struct {
+	*linkResolver
+	book         *Book
+	allowedHosts []string
+	client       *http.Client
+	external     map[string]string
+	linkCount    int
+	broken       []*BrokenLink
+}
+
+// CheckBookLinks 检查项目已发布内容中的链接并保存失效链接，同一项目同时只会执行一次检查.
+func CheckBookLinks(bookId int) (*LinkCheck, error) {
+	if !lockBookLinkCheck(bookId) {
+		return nil, ErrLinkCheckRunning
+	}
+	defer unlockBookLinkCheck(bookId)
+
+	return checkBookLinks(bookId)
+}
+
+// StartBookLinkCheck 在后台检查项目的链接，结果保存后可在项目概要中查看.
+func StartBookLinkCheck(bookId int) error {
+	if !lockBookLinkCheck(bookId) {
+		return ErrLinkCheckRunning
+	}
+	go func() {
+		defer unlockBookLinkCheck(bookId)
+
+		if _, err := checkBookLinks(bookId); err != nil {
+			logs.Error("检查项目链接失败 ->", bookId, err)
+		}
+	}()
+	return nil
+}
+
+// IsBookLinkCheckRunning 项目是否正在检查链接.
+func IsBookLinkCheckRunning(bookId int) bool {
+	linkCheckLocker.Lock()
+	defer linkCheckLocker.Unlock()
+	return linkCheckRunning[bookId]
+}
+
+func lockBookLinkCheck(bookId int) bool {
+	linkCheckLocker.Lock()
+	defer linkCheckLocker.Unlock()
+	if linkCheckRunning[bookId] {
+		return false
+	}
+	linkCheckRunning[bookId] = true
+	return true
+}
+
+func unlockBookLinkCheck(bookId int) {
+	linkCheckLocker.Lock()
+	delete(linkCheckRunning, bookId)
+	linkCheckLocker.Unlock()
+}
+
+func checkBookLinks(bookId int) (*LinkCheck, error) {
+	book, err := NewBook().Find(bookId)
+	if err != nil {
+		return nil, err
+	}
+	checker := newLinkChecker(book)
+
+	var docs []*Document
+	_, err = orm.NewOrm().QueryTable(NewDocument().TableNameWithPrefix()).
+		Filter("book_id", bookId).
+		All(&docs, "document_id", "document_name", "release")
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	for _, doc := range docs {
+		checker.checkDocument(doc)
+	}
+	return checker.save()
+}
+
+// CheckAllBookLinks 在后台依次检查全部项目的链接.
+func CheckAllBookLinks() error {
+	linkCheckLocker.Lock()
+	if siteCheckRunning {
+		linkCheckLocker.Unlock()
+		return ErrLinkCheckRunning
+	}
+	siteCheckRunning = true
+	linkCheckLocker.Unlock()
+
+	var books []*Book
+	_, err := orm.NewOrm().QueryTable(NewBook().TableNameWithPrefix()).All(&books, "book_id")
+	if err != nil && err != orm.ErrNoRows {
+		linkCheckLocker.Lock()
+		siteCheckRunning = false
+		linkCheckLocker.Unlock()
+		return err
+	}
+	go func() {
+		defer func() {
+			linkCheckLocker.Lock()
+			siteCheckRunning = false
+			linkCheckLocker.Unlock()
+		}()
+		for _, book := range books {
+			if _, err := CheckBookLinks(book.BookId); err != nil && err != ErrLinkCheckRunning {
+				logs.Error("检查项目链接失败 ->", book.BookId, err)
+			}
+		}
+		logs.Info("全站链接检查完成 ->", len(books))
+	}()
+	return nil
+}
+
+func newLinkResolver() *linkResolver {
+	resolver := &linkResolver{
+		hosts: make(map[string]bool),
+		books: make(map[string]*Book),
+		docs:  make(map[string]int),
+	}
+	for _, key := range []string{"baseurl", "cdnimg"} {
+		if u, err := url.Parse(web.AppConfig.DefaultString(key, "")); err == nil && u.Host != "" {
+			resolver.hosts[strings.ToLower(u.Host)] = true
+		}
+	}
+	return resolver
+}
+
+func newLinkChecker(book *Book) *linkChecker {
+	checker := &linkChecker{
+		linkResolver: newLinkResolver(),
+		book:         book,
+		external:     make(map[string]string),
+	}
+	checker.books[book.Identify] = book
+	for _, host := range strings.FieldsFunc(GetOptionValue("LINK_CHECK_ALLOWED_HOSTS", ""), func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n' || r == '\r' || r == ' '
+	}) {
+		checker.allowedHosts = append(checker.allowedHosts, strings.ToLower(strings.TrimPrefix(host, "*.")))
+	}
+	checker.client = checker.newClient()
+	return checker
+}
+
+// newClient 创建检查站外链接的客户端，只访问允许的站点，并且不会连接内网地址.
+func (c *linkChecker) newClient() *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			// 连接时检查解析后的地址，避免域名解析到内网地址
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				conn, err := dialer.DialContext(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+				if ip, ok := conn.RemoteAddr().(*net.TCPAddr); ok && isInternalIP(ip.IP) {
+					conn.Close()
+					return nil, errLinkCheckInternalAddress
+				}
+				return conn, nil
+			},
+		},
+		// 每次跳转都按同样的规则检查，避免允许的站点跳转到内网地址
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if scheme := strings.ToLower(req.URL.Scheme); scheme != "http" && scheme != "https" {
+				return errLinkCheckRedirect
+			}
+			if !c.isAllowedHost(req.URL.Hostname()) {
+				return errLinkCheckRedirect
+			}
+			if ip := net.ParseIP(req.URL.Hostname()); ip != nil && isInternalIP(ip) {
+				return errLinkCheckInternalAddress
+			}
+			return nil
+		},
+	}
+}
+
+var (
+	errLinkCheckRedirect        = errors.New("redirect to a host that is not allowed")
+	errLinkCheckInternalAddress = errors.New("internal address is not allowed")
+)
+
+// isInternalIP 判断是否为本机、内网或保留地址，链接检查不会访问这些地址.
+func isInternalIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return true
+	}
+	// 100.64.0.0/10 运营商级 NAT 地址
+	if ip4 := ip.To4(); ip4 != nil && ip4[0] == 100 && ip4[1]&0xc0 == 64 {
+		return true
+	}
+	return false
+}
+
+// releaseLinks 提取已发布内容中的全部链接和图片地址.
+func releaseLinks(release string) []string {
+	var links []string
+	if strings.TrimSpace(release) == "" {
+		return links
+	}
+	docQuery, err := goquery.NewDocumentFromReader(bytes.NewBufferString(release))
+	if err != nil {
+		logs.Error("解析文档内容失败 ->", err)
+		return links
+	}
+	checked := make(map[string]bool)
+
+	docQuery.Find("a[href], img[src]").Each(func(i int, selection *goquery.Selection) {
+		link, ok := selection.Attr("href")
+		if !ok {
+			link, _ = selection.Attr("src")
+		}
+		link = strings.TrimSpace(link)
+		if link != "" && !checked[link] {
+			checked[link] = true
+			links = append(links, link)
+		}
+	})
+	return links
+}
+
+func (c *linkChecker) checkDocument(doc *Document) {
+	for _, link := range releaseLinks(doc.Release) {
+		if linkType, reason := c.checkLink(link); reason != "" {
+			c.broken = append(c.broken, &BrokenLink{
+				BookId:       c.book.BookId,
+				DocumentId:   doc.DocumentId,
+				DocumentName: doc.DocumentName,
+				LinkType:     linkType,
+				Url:          link,
+				Reason:       reason,
+			})
+		}
+	}
+}
+
+// checkLink 判断链接类型，链接失效时返回失效原因，不需要检查的链接返回空的类型.
+func (c *linkChecker) checkLink(link string) (string, string) {
+	u, segments := c.parse(link)
+	if u == nil {
+		return "", ""
+	}
+	if segments == nil {
+		if !c.isAllowedHost(u.Hostname()) {
+			return "", ""
+		}
+		c.linkCount++
+		return LinkTypeExternal, c.checkExternal(link)
+	}
+
+	switch {
+	case segments[0] == "docs" && len(segments) == 2:
+		c.linkCount++
+		if c.findBook(segments[1]) == nil {
+			return LinkTypeBook, "book_not_exist"
+		}
+		return LinkTypeBook, ""
+	case segments[0] == "docs" && len(segments) == 3:
+		c.linkCount++
+		book := c.findBook(segments[1])
+		if book == nil {
+			return LinkTypeDocument, "book_not_exist"
+		}
+		if c.findDocumentId(book, segments[2]) == 0 {
+			return LinkTypeDocument, "document_not_exist"
+		}
+		return LinkTypeDocument, ""
+	case segments[0] == "attach_files" && len(segments) == 3:
+		c.linkCount++
+		attachId, _ := strconv.Atoi(segments[2])
+		attach, err := NewAttachment().Find(attachId)
+		if err != nil {
+			return LinkTypeAttachment, "attachment_not_exist"
+		}
+		if book := c.findBook(segments[1]); book == nil || book.BookId != attach.BookId {
+			return LinkTypeAttachment, "attachment_not_exist"
+		}
+		if !filetil.FileExists(filepath.Join(conf.WorkingDirectory, attach.FilePath)) {
+			return LinkTypeAttachment, "file_not_exist"
+		}
+		return LinkTypeAttachment, ""
+	case segments[0] == "uploads":
+		c.linkCount++
+		if !filetil.FileExists(filepath.Join(conf.WorkingDirectory, u.Path)) {
+			return LinkTypeAttachment, "file_not_exist"
+		}
+		return LinkTypeAttachment, ""
+	}
+	return "", ""
+}
+
+func (c *linkChecker) isAllowedHost(host string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range c.allowedHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// parse 解析链接，站内链接返回路径的各个部分，站外的 http 链接返回 nil，无法处理的链接返回空的 url.
+func (c *linkResolver) parse(link string) (*url.URL, []string) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, nil
+	}
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Host)
+
+	if scheme != "" && scheme != "http" && scheme != "https" {
+		return nil, nil
+	}
+	if host != "" && !c.hosts[host] {
+		return u, nil
+	}
+	if host == "" && !strings.HasPrefix(u.Path, "/") {
+		return nil, nil
+	}
+	return u, strings.Split(strings.Trim(u.Path, "/"), "/")
+}
+
+// findBook 根据标识查找项目，项目标识变更后旧标识仍然有效.
+func (c *linkResolver) findBook(identify string) *Book {
+	if book, ok := c.books[identify]; ok {
+		return book
+	}
+	book, err := NewBook().FindByIdentify(identify, "book_id", "identify", "privately_owned")
+	if err != nil {
+		book, err = NewRedirect().FindBook(identify)
+	}
+	if err != nil {
+		book = nil
+	}
+	c.books[identify] = book
+	return book
+}
+
+// findDocumentId 根据文档id或标识查找项目中的文档，文档不存在时返回 0.
+func (c *linkResolver) findDocumentId(book *Book, identify string) int {
+	key := strconv.Itoa(book.BookId) + "/" + identify
+	if docId, ok := c.docs[key]; ok {
+		return docId
+	}
+	doc := NewDocument()
+	qs := orm.NewOrm().QueryTable(doc.TableNameWithPrefix()).Filter("book_id", book.BookId)
+	if docId, err := strconv.Atoi(identify); err == nil {
+		qs = qs.Filter("document_id", docId)
+	} else {
+		qs = qs.Filter("identify", identify)
+	}
+	if err := qs.One(doc, "document_id"); err != nil {
+		if redirect, err := NewRedirect().FindDocument(book.BookId, identify); err == nil {
+			doc = redirect
+		}
+	}
+	c.docs[key] = doc.DocumentId
+	return doc.DocumentId
+}
+
+func (c *linkChecker) checkExternal(link string) string {
+	if reason, ok := c.external[link]; ok {
+		return reason
+	}
+	reason := ""
+	resp, err := c.client.Head(link)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		resp, err = c.client.Get(link)
+	}
+	if err != nil {
+		reason = "request_failed"
+	} else {
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			reason = "HTTP " + strconv.Itoa(resp.StatusCode)
+		}
+	}
+	c.external[link] = reason
+	return reason
+}
+
+// save 保存检查结果，覆盖项目上一次的检查结果.
+func (c *linkChecker) save() (*LinkCheck, error) {
+	o := orm.NewOrm()
+	now := time.Now()
+
+	tx, err := o.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.QueryTable(NewBrokenLink().TableNameWithPrefix()).Filter("book_id", c.book.BookId).Delete(); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	for _, link := range c.broken {
+		link.CheckTime = now
+		if _, err := tx.Insert(link); err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+	check := NewLinkCheck()
+	if err := tx.QueryTable(check.TableNameWithPrefix()).Filter("book_id", c.book.BookId).One(check); err != nil && err != orm.ErrNoRows {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	check.BookId = c.book.BookId
+	check.LinkCount = c.linkCount
+	check.BrokenCount = len(c.broken)
+	check.CheckTime = now
+
+	if check.CheckId > 0 {
+		_, err = tx.Update(check)
+	} else {
+		_, err = tx.Insert(check)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return check, tx.Commit()
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/mindoc-org/mindoc/conf"
+)
+
+// 锁定时长的上限.
+const maxLoginLockoutDuration = 24 * time.Hour
+
+// 同一个IP允许的失败次数是单个账号的倍数.
+const loginLockoutIPMultiple = 4
+
+// LoginLockout 记录账号或IP的登录失败次数和锁定状态.
+type LoginLockout struct {
+	LockoutId int `orm:"column(lockout_id);pk;auto;unique" json:"lockout_id"`
+	//锁定对象，格式为 account:<账号> 或 ip:<IP地址>
+	LockKey string `orm:"column(lock_key);size(255);unique;description(锁定对象)" json:"lock_key"`
+	//当前连续失败次数
+	FailedCount int `orm:"column(failed_count);type(int);default(0);description(连续失败次数)" json:"failed_count"`
+	//累计锁定次数，每次锁定时长翻倍
+	LockoutCount   int       `orm:"column(lockout_count);type(int);default(0);description(累计锁定次数)" json:"lockout_count"`
+	LockedUntil    time.Time `orm:"column(locked_until);type(datetime);null;description(锁定截止时间)" json:"locked_until"`
+	LastFailedTime time.Time `orm:"column(last_failed_time);type(datetime);null;description(最后失败时间)" json:"last_failed_time"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *LoginLockout) TableName() string {
+	return "login_lockout"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *LoginLockout) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *LoginLockout) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewLoginLockout() *LoginLockout {
+	return &LoginLockout{}
+}
+
+// LoginLockoutPolicy 登录锁定策略.
+type LoginLockoutPolicy struct {
+	//单个账号连续失败多少次后锁定，0 表示不锁定
+	Threshold int
+	//首次锁定的时长
+	Duration time.Duration
+}
+
+// GetLoginLockoutPolicy 读取当前的登录锁定策略.
+func GetLoginLockoutPolicy() LoginLockoutPolicy {
+	policy := LoginLockoutPolicy{Threshold: 5, Duration: 15 * time.Minute}
+
+	if v, err := strconv.Atoi(GetOptionValue("LOGIN_LOCKOUT_THRESHOLD", "5")); err == nil && v >= 0 {
+		policy.Threshold = v
+	}
+	if v, err := strconv.Atoi(GetOptionValue("LOGIN_LOCKOUT_MINUTES", "15")); err == nil && v > 0 {
+		policy.Duration = time.Duration(v) * time.Minute
+	}
+	return policy
+}
+
+// accountLockKey 使用邮箱登录时按对应的账号计算失败次数.
+func accountLockKey(account string) string {
+	account = strings.TrimSpace(account)
+	if strings.Contains(account, "@") {
+		if member, err := NewMember().FindByFieldFirst("email", account); err == nil && member.MemberId > 0 {
+			account = member.Account
+		}
+	}
+	return "account:" + strings.ToLower(account)
+}
+
+func ipLockKey(ip string) string {
+	return "ip:" + ip
+}
+
+// IsLoginLocked 判断账号或IP是否被锁定，锁定时返回解锁时间.
+func IsLoginLocked(account, ip string) (time.Time, bool) {
+	if GetLoginLockoutPolicy().Threshold <= 0 {
+		return time.Time{}, false
+	}
+	var list []*LoginLockout
+
+	_, err := orm.NewOrm().QueryTable(NewLoginLockout().TableNameWithPrefix()).
+		Filter("lock_key__in", accountLockKey(account), ipLockKey(ip)).
+		Filter("locked_until__gt", time.Now()).
+		All(&list)
+	if err != nil && err != orm.ErrNoRows {
+		logs.Error("查询登录锁定状态失败 ->", err)
+		return time.Time{}, false
+	}
+	var until time.Time
+	for _, item := range list {
+		if item.LockedUntil.After(until) {
+			until = item.LockedUntil
+		}
+	}
+	return until, !until.IsZero()
+}
+
+// RecordLoginFailure 记录一次登录失败，达到阈值时锁定账号或IP.
+func RecordLoginFailure(account, ip string) {
+	policy := GetLoginLockoutPolicy()
+	if policy.Threshold <= 0 {
+		return
+	}
+	if account != "" {
+		recordLockKeyFailure(accountLockKey(account), policy.Threshold, policy.Duration)
+	}
+	if ip != "" {
+		recordLockKeyFailure(ipLockKey(ip), policy.Threshold*loginLockoutIPMultiple, policy.Duration)
+	}
+}
+
+// ResetLoginFailure 登录成功后清除账号的失败记录，IP的失败记录不清除，避免通过一个可用账号绕过IP锁定.
+func ResetLoginFailure(account string) {
+	_, err := orm.NewOrm().QueryTable(NewLoginLockout().TableNameWithPrefix()).Filter("lock_key", accountLockKey(account)).Delete()
+	if err != nil {
+		logs.Error("清除登录失败记录失败 ->", account, err)
+	}
+}
+
+func recordLockKeyFailure(key string, threshold int, duration time.Duration) {
+	o := orm.NewOrm()
+	now := time.Now()
+
+	lockout := NewLoginLockout()
+	err := o.QueryTable(lockout.TableNameWithPrefix()).Filter("lock_key", key).One(lockout)
+	if err != nil && err != orm.ErrNoRows {
+		logs.Error("查询登录锁定状态失败 ->", key, err)
+		return
+	}
+	if lockout.LockoutId > 0 {
+		//长时间没有失败记录后重新计算
+		if now.Sub(lockout.LastFailedTime) > duration {
+			lockout.FailedCount = 0
+		}
+		if now.Sub(lockout.LastFailedTime) > maxLoginLockoutDuration {
+			lockout.LockoutCount = 0
+		}
+	}
+	lockout.LockKey = key
+	lockout.FailedCount++
+	lockout.LastFailedTime = now
+
+	if lockout.FailedCount >= threshold {
+		lockout.LockoutCount++
+		lockout.FailedCount = 0
+
+		d := duration
+		for i := 1; i < lockout.LockoutCount && d < maxLoginLockoutDuration; i++ {
+			d *= 2
+		}
+		if d > maxLoginLockoutDuration {
+			d = maxLoginLockoutDuration
+		}
+		lockout.LockedUntil = now.Add(d)
+		logs.Warn("登录失败次数过多，已锁定 ->", key, lockout.LockedUntil)
+	}
+	if lockout.LockoutId > 0 {
+		_, err = o.Update(lockout)
+	} else {
+		_, err = o.Insert(lockout)
+	}
+	if err != nil {
+		logs.Error("保存登录失败记录失败 ->", key, err)
+	}
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/mindoc-org/mindoc/conf"
+)
+
+// 登录失败原因.
+const (
+	LoginFailedPassword  = "password"
+	LoginFailedLocked    = "locked"
+	LoginFailedTwoFactor = "two_factor"
+)
+
+// LoginLog 用户登录历史.
+type LoginLog struct {
+	LoginLogId int    `orm:"column(login_log_id);pk;auto;unique" json:"login_log_id"`
+	MemberId   int    `orm:"column(member_id);type(int);index;default(0);description(用户id)" json:"member_id"`
+	Account    string `orm:"column(account);size(255);index;description(登录账号)" json:"account"`
+	//登录方式：local/ldap/http/auth2
+	AuthMethod string `orm:"column(auth_method);size(50);description(登录方式)" json:"auth_method"`
+	//登录状态：0 成功/1 失败
+	Status     int       `orm:"column(status);type(int);default(0);description(状态 0：成功 1：失败)" json:"status"`
+	Reason     string    `orm:"column(reason);size(50);null;description(失败原因)" json:"reason"`
+	IPAddress  string    `orm:"column(ip_address);size(100);description(IP地址)" json:"ip_address"`
+	UserAgent  string    `orm:"column(user_agent);size(500);null;description(浏览器标识)" json:"user_agent"`
+	CreateTime time.Time `orm:"type(datetime);column(create_time);auto_now_add;index;description(登录时间)" json:"create_time"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *LoginLog) TableName() string {
+	return "login_log"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *LoginLog) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *LoginLog) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewLoginLog() *LoginLog {
+	return &LoginLog{}
+}
+
+// Insert 保存登录记录.
+func (m *LoginLog) Insert() error {
+	if len(m.UserAgent) > 500 {
+		m.UserAgent = m.UserAgent[:500]
+	}
+	if len(m.Account) > 255 {
+		m.Account = m.Account[:255]
+	}
+	if m.AuthMethod == "" {
+		m.AuthMethod = "local"
+	}
+	if _, err := orm.NewOrm().Insert(m); err != nil {
+		logs.Error("保存登录记录失败 ->", m.Account, err)
+		return err
+	}
+	return nil
+}
+
+// FindToPager 分页查询登录记录，memberId 大于 0 时只查询该用户的记录.
+func (m *LoginLog) FindToPager(memberId int, account string, pageIndex, pageSize int) (list []*LoginLog, totalCount int, err error) {
+	offset := (pageIndex - 1) * pageSize
+
+	qs := orm.NewOrm().QueryTable(m.TableNameWithPrefix())
+	if memberId > 0 {
+		qs = qs.Filter("member_id", memberId)
+	}
+	if account != "" {
+		qs = qs.Filter("account", account)
+	}
+	_, err = qs.OrderBy("-login_log_id").Offset(offset).Limit(pageSize).All(&list)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			err = nil
+		} else {
+			logs.Error("查询登录记录失败 ->", err)
+		}
+		return
+	}
+	count, err := qs.Count()
+	if err != nil {
+		logs.Error("查询登录记录失败 ->", err)
+		return
+	}
+	totalCount = int(count)
+	return
+}
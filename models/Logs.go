@@ -1,14 +1,28 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
+	"strconv"
 	"sync/atomic"
 	"time"
 
 	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
 	"github.com/mindoc-org/mindoc/conf"
 )
 
+// 日志类别.
+const (
+	LoggerOperate   = "operate"
+	LoggerSystem    = "system"
+	LoggerException = "exception"
+	LoggerDocument  = "document"
+)
+
+// 过期日志的清理间隔.
+const loggerCleanInterval = time.Hour
+
 var loggerQueue = &logQueue{channel: make(chan *Logger, 100), isRuning: 0}
 
 type logQueue struct {
@@ -21,11 +35,15 @@ type Logger struct {
 	LoggerId int64 `orm:"pk;auto;unique;column(log_id)" json:"log_id"`
 	MemberId int   `orm:"column(member_id);type(int)" json:"member_id"`
 	// 日志类别：operate 操作日志/ system 系统日志/ exception 异常日志 / document 文档操作日志
-	Category     string    `orm:"column(category);size(255);default(operate)" json:"category"`
+	Category string `orm:"column(category);size(255);default(operate)" json:"category"`
+	// 操作类型，例如 login/book_delete/book_transfer
+	Action string `orm:"column(action);size(100);null;index" json:"action"`
+	// 操作人账号，用户被删除后仍可追溯
+	Account      string    `orm:"column(account);size(255);null;index" json:"account"`
 	Content      string    `orm:"column(content);type(text)" json:"content"`
 	OriginalData string    `orm:"column(original_data);type(text)" json:"original_data"`
 	PresentData  string    `orm:"column(present_data);type(text)" json:"present_data"`
-	CreateTime   time.Time `orm:"type(datetime);column(create_time);auto_now_add" json:"create_time"`
+	CreateTime   time.Time `orm:"type(datetime);column(create_time);auto_now_add;index" json:"create_time"`
 	UserAgent    string    `orm:"column(user_agent);size(500)" json:"user_agent"`
 	IPAddress    string    `orm:"column(ip_address);size(255)" json:"ip_address"`
 }
@@ -48,7 +66,7 @@ func NewLogger() *Logger {
 }
 
 func (m *Logger) Add() error {
-	if m.MemberId <= 0 {
+	if m.MemberId <= 0 && m.Account == "" {
 		return errors.New("用户ID不能为空")
 	}
 	if m.Category == "" {
@@ -57,6 +75,9 @@ func (m *Logger) Add() error {
 	if m.Content == "" {
 		return errors.New("日志内容不能为空")
 	}
+	if len(m.UserAgent) > 500 {
+		m.UserAgent = m.UserAgent[:500]
+	}
 	loggerQueue.channel <- m
 	if atomic.LoadInt32(&(loggerQueue.isRuning)) <= 0 {
 		atomic.AddInt32(&(loggerQueue.isRuning), 1)
@@ -65,13 +86,142 @@ func (m *Logger) Add() error {
 	return nil
 }
 
+// SetData 以 JSON 格式保存变更前后的数据.
+func (m *Logger) SetData(original, present interface{}) *Logger {
+	m.OriginalData = loggerDataString(original)
+	m.PresentData = loggerDataString(present)
+	return m
+}
+
+func loggerDataString(v interface{}) string {
+	switch data := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return data
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		logs.Error("序列化日志数据失败 ->", err)
+		return ""
+	}
+	return string(b)
+}
+
+// LoggerFilter 日志查询条件.
+type LoggerFilter struct {
+	Category  string
+	Action    string
+	Account   string
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+func (m *Logger) filter(filter LoggerFilter) orm.QuerySeter {
+	qs := orm.NewOrm().QueryTable(m.TableNameWithPrefix())
+	if filter.Category != "" {
+		qs = qs.Filter("category", filter.Category)
+	}
+	if filter.Action != "" {
+		qs = qs.Filter("action", filter.Action)
+	}
+	if filter.Account != "" {
+		qs = qs.Filter("account", filter.Account)
+	}
+	if !filter.StartTime.IsZero() {
+		qs = qs.Filter("create_time__gte", filter.StartTime)
+	}
+	if !filter.EndTime.IsZero() {
+		qs = qs.Filter("create_time__lt", filter.EndTime)
+	}
+	return qs
+}
+
+// FindToPager 分页查询日志.
+func (m *Logger) FindToPager(filter LoggerFilter, pageIndex, pageSize int) (list []*Logger, totalCount int, err error) {
+	offset := (pageIndex - 1) * pageSize
+
+	qs := m.filter(filter)
+
+	_, err = qs.OrderBy("-log_id").Offset(offset).Limit(pageSize).All(&list)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			err = nil
+		} else {
+			logs.Error("查询日志失败 ->", err)
+		}
+		return
+	}
+	count, err := qs.Count()
+	if err != nil {
+		logs.Error("查询日志失败 ->", err)
+		return
+	}
+	totalCount = int(count)
+	return
+}
+
+// FindAll 按批次遍历符合条件的日志，用于导出.
+func (m *Logger) FindAll(filter LoggerFilter, fn func(*Logger) error) error {
+	const batchSize = 500
+	var lastId int64
+
+	for {
+		var list []*Logger
+
+		qs := m.filter(filter)
+		if lastId > 0 {
+			qs = qs.Filter("log_id__lt", lastId)
+		}
+		_, err := qs.OrderBy("-log_id").Limit(batchSize).All(&list)
+		if err != nil && err != orm.ErrNoRows {
+			logs.Error("查询日志失败 ->", err)
+			return err
+		}
+		for _, item := range list {
+			if err := fn(item); err != nil {
+				return err
+			}
+			lastId = item.LoggerId
+		}
+		if len(list) < batchSize {
+			return nil
+		}
+	}
+}
+
+// DeleteBefore 删除指定时间之前的日志.
+func (m *Logger) DeleteBefore(t time.Time) (int64, error) {
+	return orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("create_time__lt", t).Delete()
+}
+
+// cleanExpiredLoggers 根据保留天数清理过期日志，保留天数为 0 时不清理.
+func cleanExpiredLoggers() {
+	days, err := strconv.Atoi(GetOptionValue("AUDIT_LOG_RETENTION_DAYS", "180"))
+	if err != nil || days <= 0 {
+		return
+	}
+	if count, err := NewLogger().DeleteBefore(time.Now().AddDate(0, 0, -days)); err != nil {
+		logs.Error("清理过期日志失败 ->", err)
+	} else if count > 0 {
+		logs.Info("已清理过期日志 ->", count)
+	}
+}
+
 func addLoggerAsync() {
 	defer atomic.AddInt32(&(loggerQueue.isRuning), -1)
 	o := orm.NewOrm()
+	var lastClean time.Time
 
 	for {
 		logger := <-loggerQueue.channel
 
-		o.Insert(logger)
+		if _, err := o.Insert(logger); err != nil {
+			logs.Error("保存日志失败 ->", logger.Action, err)
+		}
+		if time.Since(lastClean) > loggerCleanInterval {
+			lastClean = time.Now()
+			cleanExpiredLoggers()
+		}
 	}
 }
@@ -0,0 +1,318 @@
+package models
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/mindoc-org/mindoc/conf"
+	"github.com/mindoc-org/mindoc/mail"
+)
+
+// 邮件的发送状态.
+const (
+	MailStatusPending = 0
+	MailStatusSent    = 1
+	MailStatusFailed  = 2
+	MailStatusSending = 3
+)
+
+// 发送失败后的重试间隔，重试次数用完后不再发送.
+var mailRetryIntervals = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour, 6 * time.Hour}
+
+const (
+	// mailQueueBatch 每次从队列中取出的邮件数量
+	mailQueueBatch = 20
+	// mailQueueKeepDays 发送成功的邮件保留天数
+	mailQueueKeepDays = 30
+	// mailSendingTimeout 邮件被取出发送后超过该时间仍未完成时，认为发送进程已退出，重新发送
+	mailSendingTimeout = 10 * time.Minute
+)
+
+var (
+	ErrMailDisabled = errors.New("邮件功能未启用")
+	ErrMailExpired  = errors.New("邮件中的链接已过期")
+)
+
+// MailQueue 待发送的邮件，发送失败时按间隔重试.
+type MailQueue struct {
+	MailId    int       `orm:"column(mail_id);pk;auto;unique" json:"mail_id"`
+	Template  string    `orm:"column(template);size(50);description(邮件模板名称)" json:"template"`
+	MailTo    string    `orm:"column(mail_to);size(255);description(收件人)" json:"mail_to"`
+	Subject   string    `orm:"column(subject);size(500);description(邮件标题)" json:"subject"`
+	Html      string    `orm:"column(html);type(text);description(HTML 正文)" json:"-"`
+	Text      string    `orm:"column(text);type(text);null;description(纯文本正文)" json:"-"`
+	Status    int       `orm:"column(status);type(int);default(0);index;description(状态 0：待发送 1：已发送 2：发送失败)" json:"status"`
+	Attempts  int       `orm:"column(attempts);type(int);default(0);description(已尝试发送次数)" json:"attempts"`
+	NextTime  time.Time `orm:"type(datetime);column(next_time);index;description(下次发送时间)" json:"next_time"`
+	LastError string    `orm:"column(last_error);size(1000);null;description(上次发送的错误信息)" json:"last_error"`
+	SendTime  time.Time `orm:"type(datetime);column(send_time);null;description(发送成功时间)" json:"send_time"`
+	// ExpireTime 邮件中链接的过期时间，过期后不再发送；设置了过期时间的邮件发送成功后立即删除
+	ExpireTime time.Time `orm:"type(datetime);column(expire_time);null;description(过期时间)" json:"expire_time"`
+	// CreateTime 加入队列的时间
+	CreateTime time.Time `orm:"type(datetime);column(create_time);auto_now_add" json:"create_time"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *MailQueue) TableName() string {
+	return "mail_queue"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *MailQueue) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *MailQueue) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewMailQueue() *MailQueue {
+	return &MailQueue{}
+}
+
+var (
+	mailQueueOnce   sync.Once
+	mailQueueSignal = make(chan struct{}, 1)
+)
+
+// Push 将渲染后的邮件加入发送队列.
+func (m *MailQueue) Push(to, template string, content *MailContent) error {
+	m.MailTo = to
+	m.Template = template
+	m.Subject = truncateRunes(content.Subject, 500)
+	m.Html = content.HTML
+	m.Text = content.Text
+	m.Status = MailStatusPending
+	m.NextTime = time.Now()
+	m.ExpireTime = mailExpireTime(template)
+	if _, err := orm.NewOrm().Insert(m); err != nil {
+		return err
+	}
+	wakeMailQueue()
+	return nil
+}
+
+// mailExpireTime 获取邮件中链接的过期时间，找回密码邮件的链接在 MailExpired 分钟后失效.
+func mailExpireTime(template string) time.Time {
+	if template == MailFindPassword {
+		return time.Now().Add(time.Duration(conf.GetMailConfig().MailExpired) * time.Minute)
+	}
+	return time.Time{}
+}
+
+// isExpired 判断邮件中的链接是否已经过期.
+func (m *MailQueue) isExpired(t time.Time) bool {
+	return !m.ExpireTime.IsZero() && t.After(m.ExpireTime)
+}
+
+// Find 查询队列中的邮件.
+func (m *MailQueue) Find(mailId int) (*MailQueue, error) {
+	err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("mail_id", mailId).One(m)
+	return m, err
+}
+
+// FindToPager 分页查询队列中的邮件，status 小于 0 时查询全部.
+func (m *MailQueue) FindToPager(status, pageIndex, pageSize int) (list []*MailQueue, totalCount int, err error) {
+	offset := (pageIndex - 1) * pageSize
+
+	qs := orm.NewOrm().QueryTable(m.TableNameWithPrefix())
+	if status >= 0 {
+		qs = qs.Filter("status", status)
+	}
+	_, err = qs.OrderBy("-mail_id").Offset(offset).Limit(pageSize).All(&list, "mail_id", "template", "mail_to", "subject", "status", "attempts", "next_time", "last_error", "send_time", "create_time")
+	if err != nil {
+		if err == orm.ErrNoRows {
+			err = nil
+		}
+		return
+	}
+	count, err := qs.Count()
+	totalCount = int(count)
+	return
+}
+
+// Retry 重新发送邮件，重置已尝试的次数.
+func (m *MailQueue) Retry() error {
+	m.Status = MailStatusPending
+	m.Attempts = 0
+	m.NextTime = time.Now()
+	if _, err := orm.NewOrm().Update(m, "status", "attempts", "next_time"); err != nil {
+		return err
+	}
+	wakeMailQueue()
+	return nil
+}
+
+// Send 立即发送邮件并记录发送结果，失败时根据已尝试的次数安排重试.
+// 设置了过期时间的邮件发送成功或链接过期后删除，避免链接长期保存在数据库中.
+func (m *MailQueue) Send() error {
+	if m.isExpired(time.Now()) {
+		m.delete()
+		return ErrMailExpired
+	}
+	err := SendMailContent(m.MailTo, &MailContent{Subject: m.Subject, HTML: m.Html, Text: m.Text})
+
+	m.Attempts++
+	if err == nil {
+		m.Status = MailStatusSent
+		m.SendTime = time.Now()
+		m.LastError = ""
+		if !m.ExpireTime.IsZero() {
+			m.delete()
+			return nil
+		}
+	} else {
+		m.LastError = truncateRunes(err.Error(), 1000)
+		if m.Attempts > len(mailRetryIntervals) {
+			m.Status = MailStatusFailed
+		} else {
+			m.Status = MailStatusPending
+			m.NextTime = time.Now().Add(mailRetryIntervals[m.Attempts-1])
+		}
+		// 不再重试或下次重试时链接已经过期
+		if !m.ExpireTime.IsZero() && (m.Status == MailStatusFailed || m.isExpired(m.NextTime)) {
+			m.delete()
+			return err
+		}
+	}
+	if _, e := orm.NewOrm().Update(m, "status", "attempts", "next_time", "last_error", "send_time"); e != nil {
+		logs.Error("更新邮件发送状态失败 ->", m.MailId, e)
+	}
+	return err
+}
+
+// delete 从队列中删除邮件.
+func (m *MailQueue) delete() {
+	if _, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("mail_id", m.MailId).Delete(); err != nil {
+		logs.Error("删除邮件失败 ->", m.MailId, err)
+	}
+}
+
+// claim 将待发送的邮件标记为发送中，多个实例同时发送时只有一个能取得邮件.
+func (m *MailQueue) claim() bool {
+	n, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("mail_id", m.MailId).
+		Filter("status", MailStatusPending).
+		Update(orm.Params{"status": MailStatusSending, "next_time": time.Now().Add(mailSendingTimeout)})
+	if err != nil {
+		logs.Error("取出待发送邮件失败 ->", m.MailId, err)
+		return false
+	}
+	return n > 0
+}
+
+// SendMailContent 使用系统配置的 SMTP 服务器发送邮件，发件人名称为站点名称.
+func SendMailContent(to string, content *MailContent) error {
+	mailConf := conf.GetMailConfig()
+	if !mailConf.EnableMail {
+		return ErrMailDisabled
+	}
+	client := mail.NewSMTPClient(&mail.SMTPConfig{
+		Username: mailConf.SmtpUserName,
+		Password: mailConf.SmtpPassword,
+		Host:     mailConf.SmtpHost,
+		Port:     mailConf.SmtpPort,
+		Secure:   mailConf.Secure,
+		Identity: "",
+	})
+	m := mail.NewMail()
+
+	if err := m.AddFrom(mailConf.FormUserName); err != nil {
+		return err
+	}
+	m.AddFromName(GetOptionValue("SITE_NAME", "MinDoc"))
+	m.AddSubject(content.Subject)
+	m.AddHTML(content.HTML)
+	m.AddText(content.Text)
+	if err := m.AddTo(to); err != nil {
+		return err
+	}
+	return client.Send(m)
+}
+
+// StartMailQueue 启动发送队列，队列中有到期的邮件时依次发送.
+func StartMailQueue() {
+	mailQueueOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(time.Minute)
+			defer ticker.Stop()
+			lastPurge := time.Time{}
+
+			for {
+				if conf.GetMailConfig().EnableMail {
+					sendPendingMails()
+				}
+				if time.Since(lastPurge) >= 24*time.Hour {
+					lastPurge = time.Now()
+					purgeSentMails()
+				}
+				select {
+				case <-ticker.C:
+				case <-mailQueueSignal:
+				}
+			}
+		}()
+	})
+}
+
+// wakeMailQueue 有新邮件时通知队列立即发送.
+func wakeMailQueue() {
+	select {
+	case mailQueueSignal <- struct{}{}:
+	default:
+	}
+}
+
+// sendPendingMails 发送一批到期的邮件，还有剩余时继续通知队列发送.
+func sendPendingMails() {
+	o := orm.NewOrm()
+	table := NewMailQueue().TableNameWithPrefix()
+	// 发送进程中途退出时，发送中的邮件超时后重新发送
+	if _, err := o.QueryTable(table).Filter("status", MailStatusSending).Filter("next_time__lt", time.Now()).Update(orm.Params{"status": MailStatusPending}); err != nil {
+		logs.Error("恢复发送超时的邮件失败 ->", err)
+	}
+	if _, err := o.QueryTable(table).Filter("status__in", MailStatusPending, MailStatusFailed).Filter("expire_time__lt", time.Now()).Delete(); err != nil {
+		logs.Error("删除已过期的邮件失败 ->", err)
+	}
+	var list []*MailQueue
+	// SQLite 按字符串比较保存了纳秒的时间，留出一秒避免刚加入队列的邮件被跳过
+	_, err := o.QueryTable(table).
+		Filter("status", MailStatusPending).
+		Filter("next_time__lte", time.Now().Add(time.Second)).
+		OrderBy("next_time", "mail_id").
+		Limit(mailQueueBatch).
+		All(&list)
+	if err != nil {
+		if err != orm.ErrNoRows {
+			logs.Error("查询待发送邮件失败 ->", err)
+		}
+		return
+	}
+	for _, item := range list {
+		if !item.claim() {
+			continue
+		}
+		if err := item.Send(); err != nil {
+			logs.Error("发送邮件失败 ->", item.MailId, item.MailTo, err)
+		} else {
+			logs.Info("邮件发送成功 ->", item.MailId, item.MailTo)
+		}
+	}
+	if len(list) == mailQueueBatch {
+		wakeMailQueue()
+	}
+}
+
+// purgeSentMails 删除超过保留天数的已发送邮件.
+func purgeSentMails() {
+	_, err := orm.NewOrm().QueryTable(NewMailQueue().TableNameWithPrefix()).
+		Filter("status", MailStatusSent).
+		Filter("send_time__lt", time.Now().AddDate(0, 0, -mailQueueKeepDays)).
+		Delete()
+	if err != nil {
+		logs.Error("清理已发送邮件失败 ->", err)
+	}
+}
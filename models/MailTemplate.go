@@ -0,0 +1,437 @@
+package models
+
+import (
+	"bytes"
+	"errors"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/i18n"
+	"github.com/mindoc-org/mindoc/conf"
+)
+
+// 系统发送的邮件模板.
+const (
+	MailFindPassword      = "find_password"
+	MailCommentNotify     = "comment_notify"
+	MailCommentModeration = "comment_moderation"
+	MailStaleDocuments    = "stale_documents"
+	MailSubscription      = "subscription"
+	MailTest              = "test"
+)
+
+var ErrMailTemplateNotExist = errors.New("邮件模板不存在")
+
+// MailTemplateDefine 内置的邮件模板，默认正文使用 views 中的模板文件，默认标题使用语言包.
+type MailTemplateDefine struct {
+	Name string
+	// View 默认的 HTML 正文模板文件
+	View string
+	// Subject 根据邮件数据生成默认标题
+	Subject func(lang string, data map[string]interface{}) string
+	// Sample 生成预览和校验模板时使用的示例数据
+	Sample func(lang string) map[string]interface{}
+}
+
+// MailTemplateDefines 全部内置的邮件模板.
+var MailTemplateDefines = []*MailTemplateDefine{
+	{
+		Name: MailFindPassword,
+		View: "account/mail_template.tpl",
+		Subject: func(lang string, data map[string]interface{}) string {
+			return i18n.Tr(lang, "message.find_password_subject", data["SITE_NAME"])
+		},
+		Sample: func(lang string) map[string]interface{} {
+			return map[string]interface{}{
+				"url": conf.URLFor("AccountController.FindPassword", "token", "sample", "mail", "admin@example.com"),
+			}
+		},
+	},
+	{
+		Name: MailCommentNotify,
+		View: "comment/notify_mail.tpl",
+		Subject: func(lang string, data map[string]interface{}) string {
+			comment, _ := data["Comment"].(*Comment)
+			if comment == nil {
+				comment = &Comment{}
+			}
+			reason, _ := data["Reason"].(string)
+			return i18n.Tr(lang, "message.comment_"+reason+"_subject", data["SITE_NAME"], comment.Author)
+		},
+		Sample: func(lang string) map[string]interface{} {
+			return map[string]interface{}{
+				"Member":       sampleMailMember(),
+				"Comment":      sampleMailComment(lang),
+				"Reason":       "reply",
+				"BookIdentify": "mindoc",
+				"DocumentId":   1,
+				"DocumentName": i18n.Tr(lang, "message.mail_sample_document"),
+			}
+		},
+	},
+	{
+		Name: MailCommentModeration,
+		View: "comment/moderation_mail.tpl",
+		Subject: func(lang string, data map[string]interface{}) string {
+			return i18n.Tr(lang, "message.comment_moderation_subject", data["SITE_NAME"], data["DocumentName"])
+		},
+		Sample: func(lang string) map[string]interface{} {
+			return map[string]interface{}{
+				"Member":       sampleMailMember(),
+				"Comment":      sampleMailComment(lang),
+				"BookIdentify": "mindoc",
+				"BookName":     i18n.Tr(lang, "message.mail_sample_book"),
+				"DocumentName": i18n.Tr(lang, "message.mail_sample_document"),
+			}
+		},
+	},
+	{
+		Name: MailStaleDocuments,
+		View: "book/stale_mail.tpl",
+		Subject: func(lang string, data map[string]interface{}) string {
+			docs, _ := data["Lists"].([]*StaleDocumentResult)
+			return i18n.Tr(lang, "message.stale_mail_subject", data["SITE_NAME"], len(docs))
+		},
+		Sample: func(lang string) map[string]interface{} {
+			return map[string]interface{}{
+				"Member": sampleMailMember(),
+				"Lists": []*StaleDocumentResult{{
+					DocumentId:   1,
+					DocumentName: i18n.Tr(lang, "message.mail_sample_document"),
+					BookName:     i18n.Tr(lang, "message.mail_sample_book"),
+					BookIdentify: "mindoc",
+					ModifyTime:   time.Now().AddDate(0, 0, -120),
+					StaleDays:    120,
+				}},
+			}
+		},
+	},
+	{
+		Name: MailSubscription,
+		View: "subscription/mail.tpl",
+		Subject: func(lang string, data map[string]interface{}) string {
+			changes, _ := data["Changes"].([]*SubscriptionChange)
+			if len(changes) == 1 {
+				return i18n.Tr(lang, "message.subscription_change_subject", data["SITE_NAME"], changes[0].DocumentName)
+			}
+			frequency, _ := data["Frequency"].(string)
+			return i18n.Tr(lang, "message.subscription_"+frequency+"_subject", data["SITE_NAME"], len(changes))
+		},
+		Sample: func(lang string) map[string]interface{} {
+			return map[string]interface{}{
+				"Member": sampleMailMember(),
+				"Changes": []*SubscriptionChange{{
+					DocumentId:   1,
+					DocumentName: i18n.Tr(lang, "message.mail_sample_document"),
+					BookIdentify: "mindoc",
+					BookName:     i18n.Tr(lang, "message.mail_sample_book"),
+					Editors:      []string{"admin"},
+					ModifyTime:   time.Now(),
+					Added:        12,
+					Removed:      3,
+					HasDiff:      true,
+				}},
+				"Subscriptions": []*Subscription{{
+					BookId:   1,
+					Token:    "sample",
+					BookName: i18n.Tr(lang, "message.mail_sample_book"),
+				}},
+				"Frequency": SubscriptionDaily,
+			}
+		},
+	},
+	{
+		Name: MailTest,
+		View: "manager/test_mail.tpl",
+		Subject: func(lang string, data map[string]interface{}) string {
+			return i18n.Tr(lang, "message.mail_test_subject", data["SITE_NAME"])
+		},
+		Sample: func(lang string) map[string]interface{} {
+			return map[string]interface{}{
+				"Member": sampleMailMember(),
+			}
+		},
+	},
+}
+
+func sampleMailMember() *Member {
+	return &Member{MemberId: 1, Account: "admin", Email: "admin@example.com"}
+}
+
+func sampleMailComment(lang string) *Comment {
+	return &Comment{Author: "admin", Content: i18n.Tr(lang, "message.mail_sample_comment")}
+}
+
+// FindMailTemplateDefine 根据名称查询内置的邮件模板.
+func FindMailTemplateDefine(name string) *MailTemplateDefine {
+	for _, define := range MailTemplateDefines {
+		if define.Name == name {
+			return define
+		}
+	}
+	return nil
+}
+
+// SampleData 示例数据，包含渲染邮件时自动添加的公共数据.
+func (d *MailTemplateDefine) SampleData(lang string) map[string]interface{} {
+	return mailTemplateData(lang, d.Sample(lang))
+}
+
+// Variables 模板中可以使用的变量名称.
+func (d *MailTemplateDefine) Variables(lang string) []string {
+	data := d.SampleData(lang)
+	variables := make([]string, 0, len(data))
+	for key := range data {
+		variables = append(variables, "."+key)
+	}
+	sort.Strings(variables)
+	return variables
+}
+
+// MailTemplate 管理员修改后的邮件模板，按模板名称和语言保存，为空的部分使用默认模板.
+type MailTemplate struct {
+	TemplateId int    `orm:"column(template_id);pk;auto;unique" json:"template_id"`
+	Name       string `orm:"column(name);size(50);description(模板名称)" json:"name"`
+	Lang       string `orm:"column(lang);size(20);description(语言)" json:"lang"`
+	Subject    string `orm:"column(subject);size(500);null;description(邮件标题模板)" json:"subject"`
+	Html       string `orm:"column(html);type(text);null;description(HTML 正文模板)" json:"html"`
+	// Text 纯文本正文模板，为空时从 HTML 正文中提取
+	Text       string    `orm:"column(text);type(text);null;description(纯文本正文模板)" json:"text"`
+	ModifyAt   int       `orm:"column(modify_at);type(int);description(最后修改人)" json:"modify_at"`
+	CreateTime time.Time `orm:"type(datetime);column(create_time);auto_now_add" json:"create_time"`
+	ModifyTime time.Time `orm:"type(datetime);column(modify_time);auto_now" json:"modify_time"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *MailTemplate) TableName() string {
+	return "mail_templates"
+}
+
+// TableUnique 每个模板的每种语言只保存一份.
+func (m *MailTemplate) TableUnique() [][]string {
+	return [][]string{{"name", "lang"}}
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *MailTemplate) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *MailTemplate) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewMailTemplate() *MailTemplate {
+	return &MailTemplate{}
+}
+
+// Find 查询模板在指定语言下的修改，没有修改时返回 orm.ErrNoRows.
+func (m *MailTemplate) Find(name, lang string) (*MailTemplate, error) {
+	err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("name", name).
+		Filter("lang", lang).
+		One(m)
+	return m, err
+}
+
+// FindAll 查询全部修改过的模板.
+func (m *MailTemplate) FindAll() ([]*MailTemplate, error) {
+	var templates []*MailTemplate
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).OrderBy("name", "lang").All(&templates)
+	if err == orm.ErrNoRows {
+		err = nil
+	}
+	return templates, err
+}
+
+// IsCustomized 是否修改了模板的任一部分.
+func (m *MailTemplate) IsCustomized() bool {
+	return strings.TrimSpace(m.Subject) != "" || strings.TrimSpace(m.Html) != "" || strings.TrimSpace(m.Text) != ""
+}
+
+// Save 校验并保存模板，三部分都为空时删除修改恢复为默认模板.
+func (m *MailTemplate) Save() error {
+	define := FindMailTemplateDefine(m.Name)
+	if define == nil || !i18n.IsExist(m.Lang) {
+		return ErrMailTemplateNotExist
+	}
+	if !m.IsCustomized() {
+		return m.Delete()
+	}
+	if _, err := m.Render(define.SampleData(m.Lang)); err != nil {
+		return err
+	}
+	o := orm.NewOrm()
+	var err error
+	if old, e := NewMailTemplate().Find(m.Name, m.Lang); e == nil {
+		m.TemplateId = old.TemplateId
+		_, err = o.Update(m, "subject", "html", "text", "modify_at", "modify_time")
+	} else {
+		_, err = o.Insert(m)
+	}
+	return err
+}
+
+// Delete 删除修改，恢复为默认模板.
+func (m *MailTemplate) Delete() error {
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("name", m.Name).
+		Filter("lang", m.Lang).
+		Delete()
+	return err
+}
+
+// MailContent 渲染后的邮件内容.
+type MailContent struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// mailTemplateFuncs 修改后的模板中可以使用的函数，与页面模板中的同名函数一致.
+var mailTemplateFuncs = map[string]interface{}{
+	"i18n":     i18n.Tr,
+	"urlfor":   conf.URLFor,
+	"config":   GetOptionValue,
+	"date":     web.Date,
+	"str2html": web.Str2html,
+	"date_format": func(t time.Time, format string) string {
+		return t.Local().Format(format)
+	},
+}
+
+// mailTemplateData 添加每封邮件都可以使用的公共数据.
+func mailTemplateData(lang string, data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["Lang"] = lang
+	if _, ok := data["SITE_NAME"]; !ok {
+		data["SITE_NAME"] = GetOptionValue("SITE_NAME", "MinDoc")
+	}
+	if _, ok := data["BaseUrl"]; !ok {
+		data["BaseUrl"] = strings.TrimSuffix(conf.URLFor("HomeController.Index"), "/")
+	}
+	return data
+}
+
+// RenderMailTemplate 使用管理员修改后的模板或默认模板渲染邮件.
+func RenderMailTemplate(name, lang string, data map[string]interface{}) (*MailContent, error) {
+	if FindMailTemplateDefine(name) == nil {
+		return nil, ErrMailTemplateNotExist
+	}
+	m, err := NewMailTemplate().Find(name, lang)
+	if err != nil {
+		if err != orm.ErrNoRows {
+			return nil, err
+		}
+		m = &MailTemplate{Name: name, Lang: lang}
+	}
+	return m.Render(mailTemplateData(lang, data))
+}
+
+// Render 渲染邮件，未修改的部分使用默认模板.
+func (m *MailTemplate) Render(data map[string]interface{}) (*MailContent, error) {
+	define := FindMailTemplateDefine(m.Name)
+	if define == nil {
+		return nil, ErrMailTemplateNotExist
+	}
+	data = mailTemplateData(m.Lang, data)
+	content := &MailContent{}
+
+	if strings.TrimSpace(m.Subject) != "" {
+		tpl, err := texttemplate.New("subject").Funcs(mailTemplateFuncs).Parse(m.Subject)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		content.Subject = buf.String()
+	} else {
+		content.Subject = define.Subject(m.Lang, data)
+	}
+	// 标题中不能包含换行，避免写入额外的邮件头
+	content.Subject = strings.Join(strings.Fields(content.Subject), " ")
+
+	var buf bytes.Buffer
+	if strings.TrimSpace(m.Html) != "" {
+		tpl, err := htmltemplate.New("html").Funcs(mailTemplateFuncs).Parse(m.Html)
+		if err != nil {
+			return nil, err
+		}
+		if err := tpl.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+	} else if err := web.ExecuteViewPathTemplate(&buf, define.View, web.BConfig.WebConfig.ViewsPath, data); err != nil {
+		return nil, err
+	}
+	content.HTML = buf.String()
+
+	if strings.TrimSpace(m.Text) != "" {
+		tpl, err := texttemplate.New("text").Funcs(mailTemplateFuncs).Parse(m.Text)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+		content.Text = buf.String()
+	} else {
+		content.Text = mailText(content.HTML)
+	}
+	return content, nil
+}
+
+// mailText 从 HTML 正文中提取纯文本，链接地址写在链接文字后面.
+func mailText(html string) string {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		return ""
+	}
+	doc.Find("head,style,script").Remove()
+	doc.Find("br").ReplaceWithHtml("\n")
+	doc.Find("a").Each(func(i int, selection *goquery.Selection) {
+		href, _ := selection.Attr("href")
+		text := strings.TrimSpace(selection.Text())
+		if href != "" && href != text && !strings.HasPrefix(href, "#") {
+			selection.SetText(text + " (" + href + ")")
+		}
+	})
+	doc.Find("p,div,li,tr,h1,h2,h3,h4,blockquote").Each(func(i int, selection *goquery.Selection) {
+		selection.AppendHtml("\n")
+	})
+
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			if !blank && len(lines) > 0 {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		lines = append(lines, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// SendTemplateMail 渲染邮件模板并加入发送队列.
+func SendTemplateMail(to, name, lang string, data map[string]interface{}) error {
+	content, err := RenderMailTemplate(name, lang, data)
+	if err != nil {
+		return err
+	}
+	return NewMailQueue().Push(to, name, content)
+}
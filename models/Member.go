@@ -49,6 +49,8 @@ type Member struct {
 	CreateTime    time.Time       `orm:"type(datetime);column(create_time);auto_now_add;description(创建时间)" json:"create_time"`
 	CreateAt      int             `orm:"type(int);column(create_at);description(创建人id)" json:"create_at"`
 	LastLoginTime time.Time       `orm:"type(datetime);column(last_login_time);null;description(最后登录时间)" json:"last_login_time"`
+	//密码最后修改时间，用于判断密码是否过期
+	PasswordUpdateTime time.Time `orm:"type(datetime);column(password_update_time);null;description(密码修改时间)" json:"-"`
 	//i18n
 	Lang string `orm:"-"`
 }
@@ -342,6 +344,7 @@ func (m *Member) Add() error {
 	}
 
 	m.Password = hash
+	m.PasswordUpdateTime = time.Now()
 	if m.AuthMethod == "" {
 		m.AuthMethod = "local"
 	}
@@ -538,6 +541,11 @@ func (m *Member) Delete(oldId int, newId int) error {
 		o.Rollback()
 		return err
 	}
+	_, err = o.Raw("DELETE FROM md_member_session WHERE member_id = ?", oldId).Exec()
+	if err != nil {
+		o.Rollback()
+		return err
+	}
 
 	_, err = o.Raw("DELETE FROM md_members WHERE member_id = ?", oldId).Exec()
 	if err != nil {
@@ -601,6 +609,11 @@ func (m *Member) Delete(oldId int, newId int) error {
 	}
 	_, err = o.QueryTable(NewTeamMember()).Filter("member_id", oldId).Delete()
 
+	if err != nil {
+		o.Rollback()
+		return err
+	}
+	_, err = o.QueryTable(NewDocumentReadStatus()).Filter("member_id", oldId).Delete()
 	if err != nil {
 		o.Rollback()
 		return err
@@ -0,0 +1,184 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+	"github.com/mindoc-org/mindoc/conf"
+)
+
+// 最后访问时间的更新间隔，避免每个请求都写数据库.
+const memberSessionTouchInterval = time.Minute
+
+// MemberSession 用户的登录会话，每次登录生成一条记录，删除记录即可使对应的会话下线.
+type MemberSession struct {
+	SessionId int `orm:"column(session_id);pk;auto;unique" json:"session_id"`
+	MemberId  int `orm:"column(member_id);type(int);index;description(用户id)" json:"member_id"`
+	//会话令牌，保存在 Session 和“记住登录”的 Cookie 中
+	Token     string `orm:"column(token);size(100);unique;description(会话令牌)" json:"-"`
+	IPAddress string `orm:"column(ip_address);size(100);description(IP地址)" json:"ip_address"`
+	UserAgent string `orm:"column(user_agent);size(500);null;description(浏览器标识)" json:"user_agent"`
+	//是否记住登录
+	Remember     bool      `orm:"column(remember);default(false);description(是否记住登录)" json:"remember"`
+	CreateTime   time.Time `orm:"type(datetime);column(create_time);auto_now_add;description(登录时间)" json:"create_time"`
+	LastSeenTime time.Time `orm:"type(datetime);column(last_seen_time);null;description(最后访问时间)" json:"last_seen_time"`
+	ExpireTime   time.Time `orm:"type(datetime);column(expire_time);index;description(过期时间)" json:"expire_time"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *MemberSession) TableName() string {
+	return "member_session"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *MemberSession) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *MemberSession) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewMemberSession() *MemberSession {
+	return &MemberSession{}
+}
+
+// sessionLifetime 未记住登录时会话的有效期，与 Session 的回收时间一致.
+func sessionLifetime() time.Duration {
+	if v := web.BConfig.WebConfig.Session.SessionGCMaxLifetime; v > 0 {
+		return time.Duration(v) * time.Second
+	}
+	return time.Hour
+}
+
+// Create 为用户创建一个新的登录会话.
+func (m *MemberSession) Create(memberId int, ip, userAgent string) (*MemberSession, error) {
+	if memberId <= 0 {
+		return nil, ErrInvalidParameter
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	if len(userAgent) > 500 {
+		userAgent = userAgent[:500]
+	}
+	now := time.Now()
+	m.MemberId = memberId
+	m.Token = hex.EncodeToString(b)
+	m.IPAddress = ip
+	m.UserAgent = userAgent
+	m.LastSeenTime = now
+	m.ExpireTime = now.Add(sessionLifetime())
+
+	o := orm.NewOrm()
+	if _, err := o.Insert(m); err != nil {
+		logs.Error("保存登录会话失败 ->", memberId, err)
+		return nil, err
+	}
+	//顺便清理已过期的会话
+	if _, err := o.QueryTable(m.TableNameWithPrefix()).Filter("expire_time__lt", now).Delete(); err != nil {
+		logs.Error("清理过期登录会话失败 ->", err)
+	}
+	return m, nil
+}
+
+// FindByToken 根据令牌查询未过期的会话.
+func (m *MemberSession) FindByToken(token string) (*MemberSession, error) {
+	if token == "" {
+		return m, ErrInvalidParameter
+	}
+	err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("token", token).Filter("expire_time__gt", time.Now()).One(m)
+
+	return m, err
+}
+
+// FindByMemberId 查询用户全部未过期的会话.
+func (m *MemberSession) FindByMemberId(memberId int) ([]*MemberSession, error) {
+	var list []*MemberSession
+
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("member_id", memberId).
+		Filter("expire_time__gt", time.Now()).
+		OrderBy("-last_seen_time").
+		All(&list)
+	if err != nil && err != orm.ErrNoRows {
+		logs.Error("查询登录会话失败 ->", memberId, err)
+		return nil, err
+	}
+	return list, nil
+}
+
+// Touch 更新会话的最后访问时间和IP，间隔不足一分钟时不更新.
+func (m *MemberSession) Touch(ip string) {
+	now := time.Now()
+	if now.Sub(m.LastSeenTime) < memberSessionTouchInterval && m.IPAddress == ip {
+		return
+	}
+	m.LastSeenTime = now
+	m.IPAddress = ip
+	cols := []string{"last_seen_time", "ip_address"}
+
+	if expire := now.Add(sessionLifetime()); expire.After(m.ExpireTime) {
+		m.ExpireTime = expire
+		cols = append(cols, "expire_time")
+	}
+	if _, err := orm.NewOrm().Update(m, cols...); err != nil {
+		logs.Error("更新登录会话失败 ->", m.SessionId, err)
+	}
+}
+
+// SetRemember 标记会话为记住登录，有效期与 Cookie 一致.
+func (m *MemberSession) SetRemember(expire time.Time) error {
+	m.Remember = true
+	m.ExpireTime = expire
+	_, err := orm.NewOrm().Update(m, "remember", "expire_time")
+	if err != nil {
+		logs.Error("更新登录会话失败 ->", m.SessionId, err)
+	}
+	return err
+}
+
+// DeleteByToken 删除令牌对应的会话.
+func (m *MemberSession) DeleteByToken(token string) error {
+	if token == "" {
+		return ErrInvalidParameter
+	}
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("token", token).Delete()
+	if err != nil {
+		logs.Error("删除登录会话失败 ->", err)
+	}
+	return err
+}
+
+// Delete 删除用户的指定会话.
+func (m *MemberSession) Delete(memberId, sessionId int) error {
+	if memberId <= 0 || sessionId <= 0 {
+		return ErrInvalidParameter
+	}
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("member_id", memberId).Filter("session_id", sessionId).Delete()
+	if err != nil {
+		logs.Error("删除登录会话失败 ->", memberId, sessionId, err)
+	}
+	return err
+}
+
+// DeleteByMemberId 删除用户的全部会话，exceptToken 不为空时保留该会话.
+func (m *MemberSession) DeleteByMemberId(memberId int, exceptToken string) error {
+	if memberId <= 0 {
+		return ErrInvalidParameter
+	}
+	qs := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("member_id", memberId)
+	if exceptToken != "" {
+		qs = qs.Exclude("token", exceptToken)
+	}
+	if _, err := qs.Delete(); err != nil {
+		logs.Error("删除登录会话失败 ->", memberId, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/mindoc-org/mindoc/conf"
+	"github.com/mindoc-org/mindoc/utils/totp"
+)
+
+// 恢复码数量.
+const TwoFactorRecoveryCodeCount = 10
+
+var recoveryCodeChars = []byte("abcdefghjkmnpqrstuvwxyz23456789")
+
+// MemberTwoFactor 用户两步验证信息.
+type MemberTwoFactor struct {
+	TwoFactorId int    `orm:"column(two_factor_id);pk;auto;unique" json:"two_factor_id"`
+	MemberId    int    `orm:"column(member_id);type(int);unique;description(用户id)" json:"member_id"`
+	Secret      string `orm:"column(secret);size(100);description(TOTP密钥)" json:"-"`
+	//恢复码的 sha256 值，JSON 数组格式，使用后移除
+	RecoveryCodes string    `orm:"column(recovery_codes);type(text);null;description(恢复码)" json:"-"`
+	LastUsedStep  int64     `orm:"column(last_used_step);default(0);description(最后使用的时间步)" json:"-"`
+	CreateTime    time.Time `orm:"column(create_time);type(datetime);auto_now_add;description(启用时间)" json:"create_time"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *MemberTwoFactor) TableName() string {
+	return "member_two_factor"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *MemberTwoFactor) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *MemberTwoFactor) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewMemberTwoFactor() *MemberTwoFactor {
+	return &MemberTwoFactor{}
+}
+
+// FindByMemberId 查询用户的两步验证信息.
+func (m *MemberTwoFactor) FindByMemberId(memberId int) (*MemberTwoFactor, error) {
+	if memberId <= 0 {
+		return m, ErrInvalidParameter
+	}
+	err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("member_id", memberId).One(m)
+
+	return m, err
+}
+
+// IsEnabled 用户是否已启用两步验证.
+func (m *MemberTwoFactor) IsEnabled(memberId int) bool {
+	if memberId <= 0 {
+		return false
+	}
+	return orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("member_id", memberId).Exist()
+}
+
+// Enable 校验验证码后为用户启用两步验证，返回明文恢复码.
+func (m *MemberTwoFactor) Enable(memberId int, secret string, code string) ([]string, error) {
+	if memberId <= 0 || secret == "" {
+		return nil, ErrInvalidParameter
+	}
+	step, ok := totp.Validate(secret, code, time.Now())
+	if !ok {
+		return nil, ErrTwoFactorCodeInvalid
+	}
+	if m.IsEnabled(memberId) {
+		return nil, ErrTwoFactorEnabled
+	}
+	m.MemberId = memberId
+	m.Secret = secret
+	m.LastUsedStep = step
+
+	codes, err := m.resetRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := orm.NewOrm().Insert(m); err != nil {
+		logs.Error("启用两步验证失败 ->", memberId, err)
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Verify 校验验证码或恢复码，恢复码使用一次后失效.
+func (m *MemberTwoFactor) Verify(code string) bool {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" || m.TwoFactorId <= 0 {
+		return false
+	}
+	o := orm.NewOrm()
+
+	//同一个验证码不能重复使用
+	if step, ok := totp.ValidateAfter(m.Secret, code, time.Now(), m.LastUsedStep); ok {
+		m.LastUsedStep = step
+		if _, err := o.Update(m, "last_used_step"); err != nil {
+			logs.Error("更新两步验证时间步失败 ->", m.MemberId, err)
+		}
+		return true
+	}
+
+	var hashes []string
+	if err := json.Unmarshal([]byte(m.RecoveryCodes), &hashes); err != nil {
+		return false
+	}
+	hash := hashRecoveryCode(code)
+	for i, item := range hashes {
+		if item == hash {
+			hashes = append(hashes[:i], hashes[i+1:]...)
+			b, _ := json.Marshal(hashes)
+			m.RecoveryCodes = string(b)
+			if _, err := o.Update(m, "recovery_codes"); err != nil {
+				logs.Error("更新两步验证恢复码失败 ->", m.MemberId, err)
+				return false
+			}
+			logs.Info("用户使用了两步验证恢复码 ->", m.MemberId)
+			return true
+		}
+	}
+	return false
+}
+
+// RecoveryCodeCount 剩余可用的恢复码数量.
+func (m *MemberTwoFactor) RecoveryCodeCount() int {
+	var hashes []string
+	if err := json.Unmarshal([]byte(m.RecoveryCodes), &hashes); err != nil {
+		return 0
+	}
+	return len(hashes)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，旧的恢复码全部失效.
+func (m *MemberTwoFactor) RegenerateRecoveryCodes() ([]string, error) {
+	codes, err := m.resetRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := orm.NewOrm().Update(m, "recovery_codes"); err != nil {
+		logs.Error("更新两步验证恢复码失败 ->", m.MemberId, err)
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Delete 关闭或重置用户的两步验证.
+func (m *MemberTwoFactor) Delete(memberId int) error {
+	if memberId <= 0 {
+		return ErrInvalidParameter
+	}
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("member_id", memberId).Delete()
+	if err != nil {
+		logs.Error("删除两步验证失败 ->", memberId, err)
+	}
+	return err
+}
+
+func (m *MemberTwoFactor) resetRecoveryCodes() ([]string, error) {
+	codes := make([]string, TwoFactorRecoveryCodeCount)
+	hashes := make([]string, TwoFactorRecoveryCodeCount)
+
+	for i := 0; i < TwoFactorRecoveryCodeCount; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		for j := range b {
+			b[j] = recoveryCodeChars[int(b[j])%len(recoveryCodeChars)]
+		}
+		codes[i] = string(b[:5]) + "-" + string(b[5:])
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+	s, err := json.Marshal(hashes)
+	if err != nil {
+		return nil, err
+	}
+	m.RecoveryCodes = string(s)
+	return codes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	h := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(code))))
+	return hex.EncodeToString(h[:])
+}
+
+// IsTwoFactorRequired 系统是否要求该用户启用两步验证.
+func IsTwoFactorRequired(member *Member) bool {
+	if member == nil || !member.IsAdministrator() {
+		return false
+	}
+	return strings.EqualFold(GetOptionValue("REQUIRE_ADMIN_TWO_FACTOR", "false"), "true")
+}
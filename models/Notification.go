@@ -0,0 +1,254 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+	"github.com/beego/i18n"
+	"github.com/mindoc-org/mindoc/conf"
+)
+
+// 站内通知类型.
+const (
+	NotificationMention        = "mention"
+	NotificationReply          = "reply"
+	NotificationReviewComment  = "review_comment"
+	NotificationReviewDocument = "review_document"
+	NotificationMemberAdd      = "member_add"
+	NotificationMemberRole     = "member_role"
+	NotificationMemberRemove   = "member_remove"
+	NotificationDocumentChange = "document_change"
+	NotificationSubscription   = "subscription"
+)
+
+// Notification 站内通知.
+type Notification struct {
+	NotificationId int `orm:"column(notification_id);pk;auto;unique" json:"notification_id"`
+	// MemberId 接收通知的用户
+	MemberId int `orm:"column(member_id);type(int);index;description(接收通知的用户id)" json:"member_id"`
+	// SenderId 触发通知的用户，系统发送的通知为 0
+	SenderId int    `orm:"column(sender_id);type(int);default(0);description(触发通知的用户id)" json:"sender_id"`
+	Type     string `orm:"column(type);size(50);description(通知类型)" json:"type"`
+	// Subject 通知涉及的项目或文档名称
+	Subject string `orm:"column(subject);size(255);null;description(项目或文档名称)" json:"subject"`
+	// Content 评论摘要等附加内容，项目成员变更通知中为角色id
+	Content    string    `orm:"column(content);size(1000);null;description(通知内容)" json:"content"`
+	Url        string    `orm:"column(url);size(2000);null;description(通知链接)" json:"url"`
+	BookId     int       `orm:"column(book_id);type(int);default(0)" json:"book_id"`
+	DocumentId int       `orm:"column(document_id);type(int);default(0)" json:"document_id"`
+	IsRead     int       `orm:"column(is_read);type(int);default(0);description(是否已读 0：未读 1：已读)" json:"is_read"`
+	CreateTime time.Time `orm:"type(datetime);column(create_time);auto_now_add;description(通知时间)" json:"create_time"`
+	ReadTime   time.Time `orm:"type(datetime);column(read_time);null;description(阅读时间)" json:"read_time"`
+
+	SenderName string `orm:"-" json:"sender_name"`
+	Message    string `orm:"-" json:"message"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *Notification) TableName() string {
+	return "notifications"
+}
+
+// TableIndex 未读通知按用户和状态查询.
+func (m *Notification) TableIndex() [][]string {
+	return [][]string{
+		{"member_id", "is_read"},
+	}
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *Notification) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *Notification) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewNotification() *Notification {
+	return &Notification{}
+}
+
+// Insert 发送通知，不会通知触发通知的用户本人.
+func (m *Notification) Insert() error {
+	if m.MemberId <= 0 || m.MemberId == m.SenderId {
+		return nil
+	}
+	m.Subject = truncateRunes(m.Subject, 255)
+	m.Content = truncateRunes(m.Content, 1000)
+	if _, err := orm.NewOrm().Insert(m); err != nil {
+		logs.Error("保存通知失败 ->", m.MemberId, m.Type, err)
+		return err
+	}
+	return nil
+}
+
+// Find 查询用户的通知.
+func (m *Notification) Find(id, memberId int) (*Notification, error) {
+	err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("notification_id", id).
+		Filter("member_id", memberId).
+		One(m)
+	return m, err
+}
+
+// FindToPager 分页查询用户的通知，unread 为 true 时只查询未读通知.
+func (m *Notification) FindToPager(memberId int, unread bool, pageIndex, pageSize int) (list []*Notification, totalCount int, err error) {
+	offset := (pageIndex - 1) * pageSize
+
+	qs := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("member_id", memberId)
+	if unread {
+		qs = qs.Filter("is_read", 0)
+	}
+	_, err = qs.OrderBy("-notification_id").Offset(offset).Limit(pageSize).All(&list)
+	if err != nil {
+		if err == orm.ErrNoRows {
+			err = nil
+		} else {
+			logs.Error("查询通知失败 ->", err)
+		}
+		return
+	}
+	count, err := qs.Count()
+	if err != nil {
+		logs.Error("查询通知失败 ->", err)
+		return
+	}
+	totalCount = int(count)
+
+	senders := make(map[int]string)
+	for _, item := range list {
+		if item.SenderId <= 0 {
+			continue
+		}
+		if name, ok := senders[item.SenderId]; ok {
+			item.SenderName = name
+			continue
+		}
+		if member, err := NewMember().Find(item.SenderId, "member_id", "account", "real_name"); err == nil {
+			senders[item.SenderId] = member.Account
+			if member.RealName != "" {
+				senders[item.SenderId] = member.RealName
+			}
+			item.SenderName = senders[item.SenderId]
+		}
+	}
+	return
+}
+
+// CountUnread 统计用户的未读通知数量.
+func (m *Notification) CountUnread(memberId int) int64 {
+	count, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("member_id", memberId).
+		Filter("is_read", 0).
+		Count()
+	if err != nil {
+		logs.Error("统计未读通知失败 ->", memberId, err)
+	}
+	return count
+}
+
+// MarkRead 将用户的通知标记为已读，ids 为空时标记全部通知.
+func (m *Notification) MarkRead(memberId int, ids []int) (int64, error) {
+	qs := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("member_id", memberId).
+		Filter("is_read", 0)
+	if len(ids) > 0 {
+		qs = qs.Filter("notification_id__in", ids)
+	}
+	return qs.Update(orm.Params{
+		"is_read":   1,
+		"read_time": time.Now(),
+	})
+}
+
+// ResolveMessage 生成通知的文字.
+func (m *Notification) ResolveMessage(lang string) *Notification {
+	sender := m.SenderName
+	if sender == "" {
+		sender = i18n.Tr(lang, "uc.notification_system")
+	}
+	switch m.Type {
+	case NotificationMemberAdd, NotificationMemberRole:
+		roleId, _ := strconv.Atoi(m.Content)
+		m.Message = i18n.Tr(lang, "uc.notification_"+m.Type, sender, m.Subject, bookRoleName(lang, conf.BookRole(roleId)))
+	case NotificationReviewDocument:
+		m.Message = i18n.Tr(lang, "uc.notification_"+m.Type, m.Subject, m.Content)
+	default:
+		m.Message = i18n.Tr(lang, "uc.notification_"+m.Type, sender, m.Subject)
+	}
+	return m
+}
+
+// bookRoleName 获取项目角色名称.
+func bookRoleName(lang string, roleId conf.BookRole) string {
+	switch roleId {
+	case conf.BookFounder:
+		return i18n.Tr(lang, "common.creator")
+	case conf.BookAdmin:
+		return i18n.Tr(lang, "common.administrator")
+	case conf.BookEditor:
+		return i18n.Tr(lang, "common.editor")
+	default:
+		return i18n.Tr(lang, "common.observer")
+	}
+}
+
+// documentPath 文档阅读页面的地址，通知中保存不含域名的地址.
+func documentPath(bookIdentify string, documentId int) string {
+	return web.URLFor("DocumentController.Read", ":key", bookIdentify, ":id", documentId)
+}
+
+// NotifyBookMember 通知用户在项目中的角色变更.
+func NotifyBookMember(book *BookResult, memberId, senderId int, notificationType string, roleId conf.BookRole) {
+	notification := &Notification{
+		MemberId: memberId,
+		SenderId: senderId,
+		Type:     notificationType,
+		Subject:  book.BookName,
+		Content:  strconv.Itoa(int(roleId)),
+		BookId:   book.BookId,
+	}
+	if notificationType != NotificationMemberRemove {
+		notification.Url = web.URLFor("DocumentController.Index", ":key", book.Identify)
+	}
+	_ = notification.Insert()
+}
+
+// NotifyDocumentChange 通知文档的创建者文档已被其他人修改并发布.
+// notified 为已经通过订阅收到本次发布通知的用户，创建者没有项目阅读权限时也不通知.
+func NotifyDocumentChange(doc *Document, notified map[int]bool) {
+	if doc.ModifyAt <= 0 || doc.ModifyAt == doc.MemberId || notified[doc.MemberId] {
+		return
+	}
+	book, err := NewBook().Find(doc.BookId, "book_id", "identify", "privately_owned")
+	if err != nil {
+		return
+	}
+	member, err := NewMember().Find(doc.MemberId, "member_id", "role", "status")
+	if err != nil || member.Status != 0 || !CanReadBook(book, member) {
+		return
+	}
+	// 同一篇文档未读的修改通知只保留一条
+	exist := orm.NewOrm().QueryTable(NewNotification().TableNameWithPrefix()).
+		Filter("member_id", doc.MemberId).
+		Filter("type", NotificationDocumentChange).
+		Filter("document_id", doc.DocumentId).
+		Filter("is_read", 0).
+		Exist()
+	if exist {
+		return
+	}
+	_ = (&Notification{
+		MemberId:   doc.MemberId,
+		SenderId:   doc.ModifyAt,
+		Type:       NotificationDocumentChange,
+		Subject:    doc.DocumentName,
+		Url:        documentPath(book.Identify, doc.DocumentId),
+		BookId:     doc.BookId,
+		DocumentId: doc.DocumentId,
+	}).Insert()
+}
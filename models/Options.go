@@ -47,7 +47,7 @@ func (p *Option) FindByKey(key string) (*Option, error) {
 	o := orm.NewOrm()
 
 	p.OptionName = key
-	if err := o.Read(p); err != nil {
+	if err := o.Read(p, "option_name"); err != nil {
 		return p, err
 	}
 	return p, nil
@@ -174,6 +174,135 @@ func (m *Option) Init() error {
 		}
 	}
 
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "REQUIRE_ADMIN_TWO_FACTOR").Exist() {
+		option := NewOption()
+		option.OptionValue = "false"
+		option.OptionName = "REQUIRE_ADMIN_TWO_FACTOR"
+		option.OptionTitle = "管理员必须启用两步验证"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "PASSWORD_MIN_LENGTH").Exist() {
+		option := NewOption()
+		option.OptionValue = "6"
+		option.OptionName = "PASSWORD_MIN_LENGTH"
+		option.OptionTitle = "密码最小长度"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "PASSWORD_COMPLEXITY").Exist() {
+		option := NewOption()
+		option.OptionValue = "1"
+		option.OptionName = "PASSWORD_COMPLEXITY"
+		option.OptionTitle = "密码至少包含的字符种类"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "PASSWORD_EXPIRE_DAYS").Exist() {
+		option := NewOption()
+		option.OptionValue = "0"
+		option.OptionName = "PASSWORD_EXPIRE_DAYS"
+		option.OptionTitle = "密码有效期(天)"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "LOGIN_LOCKOUT_THRESHOLD").Exist() {
+		option := NewOption()
+		option.OptionValue = "5"
+		option.OptionName = "LOGIN_LOCKOUT_THRESHOLD"
+		option.OptionTitle = "登录失败锁定次数"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "LOGIN_LOCKOUT_MINUTES").Exist() {
+		option := NewOption()
+		option.OptionValue = "15"
+		option.OptionName = "LOGIN_LOCKOUT_MINUTES"
+		option.OptionTitle = "登录失败锁定时长(分钟)"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "AUDIT_LOG_RETENTION_DAYS").Exist() {
+		option := NewOption()
+		option.OptionValue = "180"
+		option.OptionName = "AUDIT_LOG_RETENTION_DAYS"
+		option.OptionTitle = "审计日志保留天数"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "RECYCLE_BIN_RETENTION_DAYS").Exist() {
+		option := NewOption()
+		option.OptionValue = "30"
+		option.OptionName = "RECYCLE_BIN_RETENTION_DAYS"
+		option.OptionTitle = "回收站保留天数"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "LINK_CHECK_INTERVAL_HOURS").Exist() {
+		option := NewOption()
+		option.OptionValue = "24"
+		option.OptionName = "LINK_CHECK_INTERVAL_HOURS"
+		option.OptionTitle = "链接检查间隔(小时)"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "LINK_CHECK_ALLOWED_HOSTS").Exist() {
+		option := NewOption()
+		option.OptionValue = ""
+		option.OptionName = "LINK_CHECK_ALLOWED_HOSTS"
+		option.OptionTitle = "检查的外部链接域名"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "STALE_REMIND_INTERVAL_DAYS").Exist() {
+		option := NewOption()
+		option.OptionValue = "7"
+		option.OptionName = "STALE_REMIND_INTERVAL_DAYS"
+		option.OptionTitle = "过期文档提醒间隔天数"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "COMMENT_SPAM_KEYWORDS").Exist() {
+		option := NewOption()
+		option.OptionValue = ""
+		option.OptionName = "COMMENT_SPAM_KEYWORDS"
+		option.OptionTitle = "垃圾评论关键词"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "COMMENT_SPAM_MAX_LINKS").Exist() {
+		option := NewOption()
+		option.OptionValue = "2"
+		option.OptionName = "COMMENT_SPAM_MAX_LINKS"
+		option.OptionTitle = "评论最多链接数量"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -189,5 +318,122 @@ func (m *Option) Update() error {
 			return err
 		}
 	}
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "REQUIRE_ADMIN_TWO_FACTOR").Exist() {
+		option := NewOption()
+		option.OptionValue = "false"
+		option.OptionName = "REQUIRE_ADMIN_TWO_FACTOR"
+		option.OptionTitle = "管理员必须启用两步验证"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "PASSWORD_MIN_LENGTH").Exist() {
+		option := NewOption()
+		option.OptionValue = "6"
+		option.OptionName = "PASSWORD_MIN_LENGTH"
+		option.OptionTitle = "密码最小长度"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "PASSWORD_COMPLEXITY").Exist() {
+		option := NewOption()
+		option.OptionValue = "1"
+		option.OptionName = "PASSWORD_COMPLEXITY"
+		option.OptionTitle = "密码至少包含的字符种类"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "PASSWORD_EXPIRE_DAYS").Exist() {
+		option := NewOption()
+		option.OptionValue = "0"
+		option.OptionName = "PASSWORD_EXPIRE_DAYS"
+		option.OptionTitle = "密码有效期(天)"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "LOGIN_LOCKOUT_THRESHOLD").Exist() {
+		option := NewOption()
+		option.OptionValue = "5"
+		option.OptionName = "LOGIN_LOCKOUT_THRESHOLD"
+		option.OptionTitle = "登录失败锁定次数"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "LOGIN_LOCKOUT_MINUTES").Exist() {
+		option := NewOption()
+		option.OptionValue = "15"
+		option.OptionName = "LOGIN_LOCKOUT_MINUTES"
+		option.OptionTitle = "登录失败锁定时长(分钟)"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "AUDIT_LOG_RETENTION_DAYS").Exist() {
+		option := NewOption()
+		option.OptionValue = "180"
+		option.OptionName = "AUDIT_LOG_RETENTION_DAYS"
+		option.OptionTitle = "审计日志保留天数"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "RECYCLE_BIN_RETENTION_DAYS").Exist() {
+		option := NewOption()
+		option.OptionValue = "30"
+		option.OptionName = "RECYCLE_BIN_RETENTION_DAYS"
+		option.OptionTitle = "回收站保留天数"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "LINK_CHECK_INTERVAL_HOURS").Exist() {
+		option := NewOption()
+		option.OptionValue = "24"
+		option.OptionName = "LINK_CHECK_INTERVAL_HOURS"
+		option.OptionTitle = "链接检查间隔(小时)"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "LINK_CHECK_ALLOWED_HOSTS").Exist() {
+		option := NewOption()
+		option.OptionValue = ""
+		option.OptionName = "LINK_CHECK_ALLOWED_HOSTS"
+		option.OptionTitle = "检查的外部链接域名"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "STALE_REMIND_INTERVAL_DAYS").Exist() {
+		option := NewOption()
+		option.OptionValue = "7"
+		option.OptionName = "STALE_REMIND_INTERVAL_DAYS"
+		option.OptionTitle = "过期文档提醒间隔天数"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "COMMENT_SPAM_KEYWORDS").Exist() {
+		option := NewOption()
+		option.OptionValue = ""
+		option.OptionName = "COMMENT_SPAM_KEYWORDS"
+		option.OptionTitle = "垃圾评论关键词"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
+	if !o.QueryTable(m.TableNameWithPrefix()).Filter("option_name", "COMMENT_SPAM_MAX_LINKS").Exist() {
+		option := NewOption()
+		option.OptionValue = "2"
+		option.OptionName = "COMMENT_SPAM_MAX_LINKS"
+		option.OptionTitle = "评论最多链接数量"
+		if _, err := o.Insert(option); err != nil {
+			return err
+		}
+	}
 	return nil
 }
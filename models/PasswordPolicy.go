@@ -0,0 +1,96 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/i18n"
+)
+
+// PasswordPolicy 系统密码策略，通过后台配置项设置.
+type PasswordPolicy struct {
+	//密码最小长度
+	MinLength int
+	//至少需要包含的字符种类数：大写字母、小写字母、数字、符号
+	Complexity int
+	//密码有效期，单位天，0 表示永不过期
+	ExpireDays int
+}
+
+// GetPasswordPolicy 读取当前的密码策略.
+func GetPasswordPolicy() PasswordPolicy {
+	return NewPasswordPolicy(GetOptionValue("PASSWORD_MIN_LENGTH", "6"),
+		GetOptionValue("PASSWORD_COMPLEXITY", "1"),
+		GetOptionValue("PASSWORD_EXPIRE_DAYS", "0"))
+}
+
+// NewPasswordPolicy 根据配置项的值创建密码策略，非法的值使用默认值.
+func NewPasswordPolicy(minLength, complexity, expireDays string) PasswordPolicy {
+	policy := PasswordPolicy{MinLength: 6, Complexity: 1}
+
+	if v, err := strconv.Atoi(minLength); err == nil && v >= 6 && v <= 50 {
+		policy.MinLength = v
+	}
+	if v, err := strconv.Atoi(complexity); err == nil && v >= 1 && v <= 4 {
+		policy.Complexity = v
+	}
+	if v, err := strconv.Atoi(expireDays); err == nil && v > 0 {
+		policy.ExpireDays = v
+	}
+	return policy
+}
+
+// Check 校验密码是否符合策略.
+func (p PasswordPolicy) Check(password string) error {
+	if l := strings.Count(password, "") - 1; l < p.MinLength || l > 50 {
+		return ErrPasswordPolicy
+	}
+	var upper, lower, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+	if upper+lower+digit+symbol < p.Complexity {
+		return ErrPasswordPolicy
+	}
+	return nil
+}
+
+// Tips 密码策略的说明文字.
+func (p PasswordPolicy) Tips(lang string) string {
+	if p.Complexity > 1 {
+		return i18n.Tr(lang, "message.password_policy_complexity", p.MinLength, p.Complexity)
+	}
+	return i18n.Tr(lang, "message.password_policy_length", p.MinLength)
+}
+
+// IsExpired 判断用户的密码是否已过期，只有本地用户的密码会过期.
+func (p PasswordPolicy) IsExpired(member *Member) bool {
+	if p.ExpireDays <= 0 || member == nil || member.AuthMethod != "local" {
+		return false
+	}
+	//没有修改时间的旧账号在开启有效期时才补写，此前不视为过期
+	if member.PasswordUpdateTime.IsZero() {
+		return false
+	}
+	return time.Since(member.PasswordUpdateTime) > time.Duration(p.ExpireDays)*24*time.Hour
+}
+
+// StartPasswordExpiry 开启密码有效期时调用，为没有密码修改时间的用户补写当前时间，使有效期从开启时开始计算.
+func StartPasswordExpiry() error {
+	_, err := orm.NewOrm().QueryTable(NewMember().TableNameWithPrefix()).
+		Filter("password_update_time__isnull", true).
+		Update(orm.Params{"password_update_time": time.Now()})
+	return err
+}
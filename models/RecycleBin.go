@@ -0,0 +1,520 @@
+package models
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/mindoc-org/mindoc/conf"
+)
+
+// 回收站中的对象类型.
+const (
+	RecycleDocument = "document"
+	RecycleBook     = "book"
+)
+
+// RecycleBin 回收站.删除文档或项目时将相关数据行原样保存，恢复时按原主键写回，从而恢复到原来的位置.
+type RecycleBin struct {
+	RecycleId  int    `orm:"column(recycle_id);pk;auto;unique" json:"recycle_id"`
+	ObjectType string `orm:"column(object_type);size(50);index;description(对象类型 document/book)" json:"object_type"`
+	ObjectId   int    `orm:"column(object_id);type(int);description(文档或项目id)" json:"object_id"`
+	BookId     int    `orm:"column(book_id);type(int);index;description(所属项目id)" json:"book_id"`
+	//删除前的父级文档
+	ParentId      int    `orm:"column(parent_id);type(int);default(0);description(父级文档)" json:"parent_id"`
+	Title         string `orm:"column(title);size(500);description(文档或项目名称)" json:"title"`
+	Identify      string `orm:"column(identify);size(100);null;description(唯一标识)" json:"identify"`
+	DocumentCount int    `orm:"column(document_count);type(int);default(0);description(包含的文档数)" json:"document_count"`
+	//被删除的数据行，JSON格式，键为表名
+	Data       string    `orm:"column(data);type(text);description(被删除的数据)" json:"-"`
+	MemberId   int       `orm:"column(member_id);type(int);description(删除人)" json:"member_id"`
+	CreateTime time.Time `orm:"type(datetime);column(create_time);auto_now_add;index;description(删除时间)" json:"create_time"`
+
+	Account string `orm:"-" json:"account"`
+}
+
+// RecycleBinDocument 回收站中的文档，用于判断附件所属的文档是否在回收站中.
+type RecycleBinDocument struct {
+	Id         int `orm:"column(id);pk;auto;unique" json:"id"`
+	RecycleId  int `orm:"column(recycle_id);type(int);index;description(回收站id)" json:"recycle_id"`
+	DocumentId int `orm:"column(document_id);type(int);index;description(文档id)" json:"document_id"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *RecycleBinDocument) TableName() string {
+	return "recycle_bin_documents"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *RecycleBinDocument) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *RecycleBinDocument) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+// recycleData 被删除的数据行，键为不带前缀的表名.
+type recycleData map[string][]orm.Params
+
+// TableName 获取对应数据库表名.
+func (m *RecycleBin) TableName() string {
+	return "recycle_bin"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *RecycleBin) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *RecycleBin) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewRecycleBin() *RecycleBin {
+	return &RecycleBin{}
+}
+
+// recycleBookTables 删除项目时需要保存的表及关联字段，按恢复时写入的顺序排列.
+func recycleBookTables() []string {
+	return []string{
+		NewBook().TableName(),
+		NewDocument().TableName(),
+		NewAttachment().TableName(),
+		NewRelationship().TableName(),
+		NewTeamRelationship().TableName(),
+		NewTemplate().TableName(),
+	}
+}
+
+func (m *RecycleBin) Find(id int) (*RecycleBin, error) {
+	if id <= 0 {
+		return m, ErrInvalidParameter
+	}
+	err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("recycle_id", id).One(m)
+
+	return m, err
+}
+
+// AddDocument 将文档及其全部子文档移入回收站.
+func (m *RecycleBin) AddDocument(doc *Document, memberId int) error {
+	ids, err := documentSubtreeIds(doc.BookId, doc.DocumentId)
+	if err != nil {
+		return err
+	}
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+	rows, err := recycleSelectRows(tx, doc.TableName(), "document_id", ids)
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	m.ObjectType = RecycleDocument
+	m.ObjectId = doc.DocumentId
+	m.BookId = doc.BookId
+	m.ParentId = doc.ParentId
+	m.Title = doc.DocumentName
+	m.Identify = doc.Identify
+	m.DocumentCount = len(ids)
+	m.MemberId = memberId
+
+	if err := m.insert(tx, recycleData{doc.TableName(): rows}); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	docs := make([]*RecycleBinDocument, len(ids))
+	for i, id := range ids {
+		docs[i] = &RecycleBinDocument{RecycleId: m.RecycleId, DocumentId: id}
+	}
+	if _, err := tx.InsertMulti(100, docs); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := recycleDeleteRows(tx, doc.TableName(), "document_id", ids); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		(&Document{DocumentId: id}).RemoveCache()
+	}
+	doc.RemoveCache()
+	return nil
+}
+
+// AddBook 将项目及其文档、附件、成员关系和模板移入回收站，附件文件保留到彻底删除时.
+func (m *RecycleBin) AddBook(bookId, memberId int) error {
+	book, err := NewBook().Find(bookId)
+	if err != nil {
+		return err
+	}
+	o := orm.NewOrm()
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+	data := make(recycleData)
+	for _, table := range recycleBookTables() {
+		rows, err := recycleSelectRows(tx, table, "book_id", []int{bookId})
+		if err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+		data[table] = rows
+	}
+	m.ObjectType = RecycleBook
+	m.ObjectId = book.BookId
+	m.BookId = book.BookId
+	m.Title = book.BookName
+	m.Identify = book.Identify
+	m.DocumentCount = len(data[NewDocument().TableName()])
+	m.MemberId = memberId
+
+	if err := m.insert(tx, data); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	for _, table := range recycleBookTables() {
+		if err := recycleDeleteRows(tx, table, "book_id", []int{bookId}); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	if book.Label != "" {
+		NewLabel().InsertOrUpdateMulti(book.Label)
+	}
+	//删除导出缓存
+	if err := os.RemoveAll(filepath.Join(conf.GetExportOutputPath(), strconv.Itoa(bookId))); err != nil {
+		logs.Error("删除项目缓存失败 ->", err)
+	}
+	return nil
+}
+
+func (m *RecycleBin) insert(tx orm.TxOrmer, data recycleData) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	m.Data = string(b)
+	_, err = tx.Insert(m)
+	return err
+}
+
+func (m *RecycleBin) data() (recycleData, error) {
+	data := make(recycleData)
+	err := json.Unmarshal([]byte(m.Data), &data)
+	return data, err
+}
+
+// IsDocumentRecycled 判断文档是否在回收站中，清理附件时需保留回收站中的文档的附件.
+func (m *RecycleBin) IsDocumentRecycled(docId int) bool {
+	if docId <= 0 {
+		return false
+	}
+	return orm.NewOrm().QueryTable(new(RecycleBinDocument).TableNameWithPrefix()).Filter("document_id", docId).Exist()
+}
+
+// Restore 将回收站中的数据恢复到原来的位置.
+func (m *RecycleBin) Restore() error {
+	data, err := m.data()
+	if err != nil {
+		logs.Error("解析回收站数据失败 ->", m.RecycleId, err)
+		return err
+	}
+	o := orm.NewOrm()
+	docTable := NewDocument().TableName()
+
+	if m.ObjectType == RecycleBook {
+		if o.QueryTable(NewBook().TableNameWithPrefix()).Filter("book_id", m.BookId).Exist() ||
+			o.QueryTable(NewBook().TableNameWithPrefix()).Filter("identify", m.Identify).Exist() {
+			return ErrRecycleConflict
+		}
+	} else {
+		if !o.QueryTable(NewBook().TableNameWithPrefix()).Filter("book_id", m.BookId).Exist() {
+			return ErrRecycleBookDeleted
+		}
+		for _, row := range data[docTable] {
+			//原父级文档已不存在时恢复到根目录
+			if id, _ := strconv.Atoi(recycleString(row["document_id"])); id == m.ObjectId && m.ParentId > 0 &&
+				!o.QueryTable(NewDocument().TableNameWithPrefix()).Filter("document_id", m.ParentId).Filter("book_id", m.BookId).Exist() {
+				row["parent_id"] = "0"
+			}
+			//文档标识已被其他文档使用时清空标识
+			if identify := recycleString(row["identify"]); identify != "" &&
+				o.QueryTable(NewDocument().TableNameWithPrefix()).Filter("book_id", m.BookId).Filter("identify", identify).Exist() {
+				row["identify"] = nil
+			}
+		}
+	}
+	ids := recycleIds(data[docTable], "document_id")
+	if len(ids) > 0 && o.QueryTable(NewDocument().TableNameWithPrefix()).Filter("document_id__in", ids).Exist() {
+		return ErrRecycleConflict
+	}
+
+	tx, err := o.Begin()
+	if err != nil {
+		return err
+	}
+	tables := []string{docTable}
+	if m.ObjectType == RecycleBook {
+		tables = recycleBookTables()
+	}
+	for _, table := range tables {
+		if err := recycleInsertRows(tx, table, data[table]); err != nil {
+			logs.Error("恢复回收站数据失败 ->", table, err)
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.QueryTable(m.TableNameWithPrefix()).Filter("recycle_id", m.RecycleId).Delete(); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.QueryTable(new(RecycleBinDocument).TableNameWithPrefix()).Filter("recycle_id", m.RecycleId).Delete(); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	NewBook().ResetDocumentNumber(m.BookId)
+
+	if m.ObjectType == RecycleBook {
+		if book, err := NewBook().Find(m.BookId); err == nil && book.Label != "" {
+			NewLabel().InsertOrUpdateMulti(book.Label)
+		}
+	}
+	return nil
+}
+
+// Purge 彻底删除回收站中的数据，同时删除文档历史、阅读状态、文档的附件和项目的附件文件.
+func (m *RecycleBin) Purge() error {
+	data, err := m.data()
+	if err != nil {
+		logs.Error("解析回收站数据失败 ->", m.RecycleId, err)
+	}
+	o := orm.NewOrm()
+
+	ids := recycleIds(data[NewDocument().TableName()], "document_id")
+	if len(ids) > 0 {
+		if _, err := o.QueryTable(NewDocumentHistory().TableNameWithPrefix()).Filter("document_id__in", ids).Delete(); err != nil {
+			logs.Error("删除文档历史失败 ->", err)
+		}
+		if _, err := o.QueryTable(NewDocumentReadStatus().TableNameWithPrefix()).Filter("document_id__in", ids).Delete(); err != nil {
+			logs.Error("删除文档阅读状态失败 ->", err)
+		}
+	}
+	//项目的附件数据已保存在回收站中，文档的附件仍在附件表中
+	if m.ObjectType == RecycleDocument && len(ids) > 0 {
+		m.purgeAttachments(ids)
+	}
+	if _, err := o.QueryTable(m.TableNameWithPrefix()).Filter("recycle_id", m.RecycleId).Delete(); err != nil {
+		return err
+	}
+	if _, err := o.QueryTable(new(RecycleBinDocument).TableNameWithPrefix()).Filter("recycle_id", m.RecycleId).Delete(); err != nil {
+		logs.Error("删除回收站文档失败 ->", m.RecycleId, err)
+	}
+	//同名项目已重新创建时不能删除附件目录
+	if m.ObjectType == RecycleBook && m.Identify != "" && !o.QueryTable(NewBook().TableNameWithPrefix()).Filter("identify", m.Identify).Exist() {
+		if err := os.RemoveAll(filepath.Join(conf.WorkingDirectory, "uploads", m.Identify)); err != nil {
+			logs.Error("删除项目附件和图片失败 ->", err)
+		}
+	}
+	return nil
+}
+
+// purgeAttachments 删除文档的附件记录和文件.
+func (m *RecycleBin) purgeAttachments(ids []int) {
+	var attaches []*Attachment
+	_, err := orm.NewOrm().QueryTable(NewAttachment().TableNameWithPrefix()).
+		Filter("book_id", m.BookId).
+		Filter("document_id__in", ids).
+		All(&attaches)
+	if err != nil && err != orm.ErrNoRows {
+		logs.Error("查询文档附件失败 ->", err)
+		return
+	}
+	for _, attach := range attaches {
+		attach.FilePath = filepath.Join(conf.WorkingDirectory, attach.FilePath)
+		if err := attach.Delete(); err != nil {
+			logs.Error("删除文档附件失败 ->", attach.AttachmentId, err)
+		}
+	}
+}
+
+// FindToPager 分页查询回收站，bookId 大于 0 时只查询该项目中被删除的文档.
+func (m *RecycleBin) FindToPager(bookId int, pageIndex, pageSize int) (list []*RecycleBin, totalCount int, err error) {
+	offset := (pageIndex - 1) * pageSize
+
+	qs := orm.NewOrm().QueryTable(m.TableNameWithPrefix())
+	if bookId > 0 {
+		qs = qs.Filter("book_id", bookId).Filter("object_type", RecycleDocument)
+	}
+	_, err = qs.OrderBy("-recycle_id").Offset(offset).Limit(pageSize).All(&list, "recycle_id", "object_type", "object_id", "book_id", "parent_id", "title", "identify", "document_count", "member_id", "create_time")
+	if err != nil {
+		if err == orm.ErrNoRows {
+			err = nil
+		} else {
+			logs.Error("查询回收站失败 ->", err)
+		}
+		return
+	}
+	count, err := qs.Count()
+	if err != nil {
+		logs.Error("查询回收站失败 ->", err)
+		return
+	}
+	totalCount = int(count)
+
+	accounts := make(map[int]string)
+	for _, item := range list {
+		account, ok := accounts[item.MemberId]
+		if !ok {
+			if member, err := NewMember().Find(item.MemberId, "account"); err == nil {
+				account = member.Account
+			}
+			accounts[item.MemberId] = account
+		}
+		item.Account = account
+	}
+	return
+}
+
+// PurgeExpiredRecycleBin 彻底删除超过保留天数的回收站数据，保留天数为 0 时不清理.
+func PurgeExpiredRecycleBin() {
+	days, err := strconv.Atoi(GetOptionValue("RECYCLE_BIN_RETENTION_DAYS", "30"))
+	if err != nil || days <= 0 {
+		return
+	}
+	var list []*RecycleBin
+
+	_, err = orm.NewOrm().QueryTable(NewRecycleBin().TableNameWithPrefix()).
+		Filter("create_time__lt", time.Now().AddDate(0, 0, -days)).
+		All(&list)
+	if err != nil && err != orm.ErrNoRows {
+		logs.Error("查询过期回收站数据失败 ->", err)
+		return
+	}
+	for _, item := range list {
+		if err := item.Purge(); err != nil {
+			logs.Error("清理回收站失败 ->", item.RecycleId, err)
+		} else {
+			logs.Info("已清理回收站数据 ->", item.ObjectType, item.Title)
+		}
+	}
+}
+
+// documentSubtreeIds 获取文档及其全部子文档的id.
+func documentSubtreeIds(bookId, docId int) ([]int, error) {
+	o := orm.NewOrm()
+	ids := []int{docId}
+	parents := []int{docId}
+
+	for len(parents) > 0 {
+		var docs []*Document
+		_, err := o.QueryTable(NewDocument().TableNameWithPrefix()).
+			Filter("book_id", bookId).
+			Filter("parent_id__in", parents).
+			All(&docs, "document_id")
+		if err != nil && err != orm.ErrNoRows {
+			return nil, err
+		}
+		parents = parents[:0]
+		for _, doc := range docs {
+			ids = append(ids, doc.DocumentId)
+			parents = append(parents, doc.DocumentId)
+		}
+	}
+	return ids, nil
+}
+
+// recycleQuote 根据数据库类型转义列名.
+func recycleQuote(tx orm.TxOrmer, name string) string {
+	if tx.Driver().Type() == orm.DRPostgres {
+		return `"` + name + `"`
+	}
+	return "`" + name + "`"
+}
+
+func recycleWhereIn(column string, ids []int) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return column + " IN (" + strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",") + ")", args
+}
+
+func recycleSelectRows(tx orm.TxOrmer, table, column string, ids []int) ([]orm.Params, error) {
+	var rows []orm.Params
+
+	where, args := recycleWhereIn(column, ids)
+	_, err := tx.Raw("SELECT * FROM "+conf.GetDatabasePrefix()+table+" WHERE "+where, args...).Values(&rows)
+	if err == orm.ErrNoRows {
+		err = nil
+	}
+	return rows, err
+}
+
+func recycleDeleteRows(tx orm.TxOrmer, table, column string, ids []int) error {
+	where, args := recycleWhereIn(column, ids)
+	_, err := tx.Raw("DELETE FROM "+conf.GetDatabasePrefix()+table+" WHERE "+where, args...).Exec()
+	return err
+}
+
+func recycleInsertRows(tx orm.TxOrmer, table string, rows []orm.Params) error {
+	for _, row := range rows {
+		columns := make([]string, 0, len(row))
+		for column := range row {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+
+		names := make([]string, len(columns))
+		args := make([]interface{}, len(columns))
+		for i, column := range columns {
+			names[i] = recycleQuote(tx, column)
+			args[i] = row[column]
+		}
+		sql := "INSERT INTO " + conf.GetDatabasePrefix() + table + " (" + strings.Join(names, ",") + ") VALUES (" + strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",") + ")"
+		if _, err := tx.Raw(sql, args...).Exec(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recycleIds(rows []orm.Params, column string) []int {
+	ids := make([]int, 0, len(rows))
+	for _, row := range rows {
+		if id, err := strconv.Atoi(recycleString(row[column])); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func recycleString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		b, _ := json.Marshal(value)
+		return strings.Trim(string(b), `"`)
+	}
+}
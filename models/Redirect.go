@@ -0,0 +1,132 @@
+package models
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/mindoc-org/mindoc/conf"
+)
+
+const (
+	RedirectBook     = "book"
+	RedirectDocument = "document"
+	RedirectBlog     = "blog"
+)
+
+// Redirect 记录项目、文档和文章标识的变更，旧链接通过它跳转到新的地址.
+type Redirect struct {
+	RedirectId int    `orm:"column(redirect_id);pk;auto;unique" json:"redirect_id"`
+	ObjectType string `orm:"column(object_type);size(20);index" json:"object_type"`
+	ObjectId   int    `orm:"column(object_id);type(int);index" json:"object_id"`
+	// BookId 文档变更前所属的项目.
+	BookId      int       `orm:"column(book_id);type(int);default(0);index" json:"book_id"`
+	OldIdentify string    `orm:"column(old_identify);size(100);index" json:"old_identify"`
+	NewIdentify string    `orm:"column(new_identify);size(100);null" json:"new_identify"`
+	CreateTime  time.Time `orm:"column(create_time);type(datetime);auto_now_add" json:"create_time"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *Redirect) TableName() string {
+	return "redirect"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *Redirect) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *Redirect) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewRedirect() *Redirect {
+	return &Redirect{}
+}
+
+// AddRedirect 记录一次标识变更，同一个旧标识只保留最新的记录.
+func AddRedirect(objectType string, objectId, bookId int, oldIdentify, newIdentify string) {
+	if oldIdentify == "" || objectId <= 0 {
+		return
+	}
+	o := orm.NewOrm()
+	m := NewRedirect()
+
+	_, err := o.QueryTable(m.TableNameWithPrefix()).
+		Filter("object_type", objectType).
+		Filter("book_id", bookId).
+		Filter("old_identify", oldIdentify).
+		Delete()
+	if err != nil {
+		logs.Error("删除跳转记录失败 ->", err)
+	}
+
+	m.ObjectType = objectType
+	m.ObjectId = objectId
+	m.BookId = bookId
+	m.OldIdentify = oldIdentify
+	m.NewIdentify = newIdentify
+	if _, err := o.Insert(m); err != nil {
+		logs.Error("写入跳转记录失败 ->", err)
+	}
+}
+
+func (m *Redirect) find(objectType string, bookId int, identify string) (*Redirect, error) {
+	o := orm.NewOrm()
+
+	err := o.QueryTable(m.TableNameWithPrefix()).
+		Filter("object_type", objectType).
+		Filter("book_id", bookId).
+		Filter("old_identify", identify).
+		OrderBy("-redirect_id").
+		One(m)
+	return m, err
+}
+
+// FindBook 根据项目的旧标识查找项目.
+func (m *Redirect) FindBook(identify string) (*Book, error) {
+	if _, err := m.find(RedirectBook, 0, identify); err != nil {
+		return nil, err
+	}
+	return NewBook().Find(m.ObjectId)
+}
+
+// FindDocument 根据文档在原项目中的旧标识或id查找文档.
+func (m *Redirect) FindDocument(bookId int, identify string) (*Document, error) {
+	if docId, err := strconv.Atoi(identify); err == nil {
+		err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+			Filter("object_type", RedirectDocument).
+			Filter("book_id", bookId).
+			Filter("object_id", docId).
+			OrderBy("-redirect_id").
+			One(m)
+		if err != nil {
+			return nil, err
+		}
+	} else if _, err := m.find(RedirectDocument, bookId, identify); err != nil {
+		return nil, err
+	}
+	return NewDocument().Find(m.ObjectId)
+}
+
+// FindBlog 根据文章的旧标识查找文章.
+func (m *Redirect) FindBlog(identify string) (*Blog, error) {
+	if _, err := m.find(RedirectBlog, 0, identify); err != nil {
+		return nil, err
+	}
+	return NewBlog().Find(m.ObjectId)
+}
+
+// redirectWatched 判断本次更新是否可能修改了标识字段.
+func redirectWatched(cols []string, field string) bool {
+	if len(cols) == 0 {
+		return true
+	}
+	for _, col := range cols {
+		if col == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,580 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/mindoc-org/mindoc/conf"
+	"github.com/mindoc-org/mindoc/utils"
+)
+
+const (
+	ScimSchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ScimSchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ScimSchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ScimSchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	ScimSchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+)
+
+var (
+	ErrScimInvalidFilter = errors.New("不支持的过滤条件，仅支持 eq 运算")
+	ErrScimInvalidPath   = errors.New("不支持的属性路径")
+	ErrScimInvalidValue  = errors.New("属性值格式不正确")
+)
+
+// SCIM 过滤条件，仅支持 attr eq "value" 形式.
+var scimFilterRegexp = regexp.MustCompile(`(?i)^\s*([a-zA-Z.]+)\s+eq\s+"((?:[^"\\]|\\.)*)"\s*$`)
+
+// SCIM 成员路径过滤，例如：members[value eq "2"].
+var scimMemberPathRegexp = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+"([^"]*)"\s*\]$`)
+
+type ScimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Location     string `json:"location,omitempty"`
+}
+
+type ScimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type ScimMultiValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// ScimUser SCIM 用户资源，对应 Member.
+type ScimUser struct {
+	Schemas      []string         `json:"schemas"`
+	Id           string           `json:"id,omitempty"`
+	ExternalId   string           `json:"externalId,omitempty"`
+	UserName     string           `json:"userName"`
+	Name         *ScimName        `json:"name,omitempty"`
+	DisplayName  string           `json:"displayName,omitempty"`
+	Emails       []ScimMultiValue `json:"emails,omitempty"`
+	PhoneNumbers []ScimMultiValue `json:"phoneNumbers,omitempty"`
+	Password     string           `json:"password,omitempty"`
+	Active       *bool            `json:"active,omitempty"`
+	Meta         *ScimMeta        `json:"meta,omitempty"`
+}
+
+// ScimGroup SCIM 用户组资源，对应 Team.
+type ScimGroup struct {
+	Schemas     []string         `json:"schemas"`
+	Id          string           `json:"id,omitempty"`
+	ExternalId  string           `json:"externalId,omitempty"`
+	DisplayName string           `json:"displayName"`
+	Members     []ScimMultiValue `json:"members"`
+	Meta        *ScimMeta        `json:"meta,omitempty"`
+}
+
+type ScimListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+type ScimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+type ScimPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+type ScimPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []ScimPatchOperation `json:"Operations"`
+}
+
+func NewScimListResponse(total, startIndex int, resources []interface{}) *ScimListResponse {
+	return &ScimListResponse{
+		Schemas:      []string{ScimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+func NewScimError(status int, scimType string, detail string) *ScimError {
+	return &ScimError{
+		Schemas:  []string{ScimSchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+// ParseScimFilter 解析 SCIM 过滤条件，返回属性名和值.
+func ParseScimFilter(filter string) (attr string, value string, err error) {
+	if strings.TrimSpace(filter) == "" {
+		return "", "", nil
+	}
+	matches := scimFilterRegexp.FindStringSubmatch(filter)
+	if len(matches) != 3 {
+		return "", "", ErrScimInvalidFilter
+	}
+	return strings.ToLower(matches[1]), strings.Replace(matches[2], `\"`, `"`, -1), nil
+}
+
+// NewScimUser 将用户转换为 SCIM 用户资源.
+func NewScimUser(member *Member) *ScimUser {
+	active := member.Status == 0
+	user := &ScimUser{
+		Schemas:     []string{ScimSchemaUser},
+		Id:          strconv.Itoa(member.MemberId),
+		UserName:    member.Account,
+		DisplayName: member.RealName,
+		Active:      &active,
+		Meta: &ScimMeta{
+			ResourceType: "User",
+			Created:      member.CreateTime.Format(time.RFC3339),
+			Location:     conf.URLFor("ScimController.User", ":id", member.MemberId),
+		},
+	}
+	if member.RealName != "" {
+		user.Name = &ScimName{Formatted: member.RealName}
+	}
+	if member.Email != "" {
+		user.Emails = []ScimMultiValue{{Value: member.Email, Type: "work", Primary: true}}
+	}
+	if member.Phone != "" {
+		user.PhoneNumbers = []ScimMultiValue{{Value: member.Phone, Type: "work"}}
+	}
+	return user
+}
+
+// ToMember 将 SCIM 用户资源中的属性写入到用户中.
+func (u *ScimUser) ToMember(member *Member) {
+	member.Account = strings.TrimSpace(u.UserName)
+
+	if u.DisplayName != "" {
+		member.RealName = u.DisplayName
+	} else if u.Name != nil {
+		if u.Name.Formatted != "" {
+			member.RealName = u.Name.Formatted
+		} else if name := strings.TrimSpace(u.Name.FamilyName + u.Name.GivenName); name != "" {
+			member.RealName = name
+		}
+	}
+	if email := scimPrimaryValue(u.Emails); email != "" {
+		member.Email = email
+	}
+	if phone := scimPrimaryValue(u.PhoneNumbers); phone != "" {
+		member.Phone = phone
+	}
+	if u.Active != nil {
+		if *u.Active {
+			member.Status = 0
+		} else {
+			member.Status = 1
+		}
+	}
+}
+
+// ApplyPatch 将 PATCH 操作应用到 SCIM 用户资源上.
+func (u *ScimUser) ApplyPatch(operations []ScimPatchOperation) error {
+	for _, op := range operations {
+		if strings.EqualFold(op.Op, "remove") {
+			switch strings.ToLower(op.Path) {
+			case "displayname":
+				u.DisplayName = ""
+			case "name", "name.formatted":
+				u.Name = nil
+			case "phonenumbers":
+				u.PhoneNumbers = nil
+			case "externalid":
+				u.ExternalId = ""
+			default:
+				return ErrScimInvalidPath
+			}
+			continue
+		}
+		if !strings.EqualFold(op.Op, "add") && !strings.EqualFold(op.Op, "replace") {
+			return ErrScimInvalidValue
+		}
+		//没有指定路径时，value 为包含属性的对象
+		if op.Path == "" {
+			var values map[string]json.RawMessage
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return ErrScimInvalidValue
+			}
+			for path, value := range values {
+				if err := u.setAttribute(path, value); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := u.setAttribute(op.Path, op.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *ScimUser) setAttribute(path string, value json.RawMessage) error {
+	path = strings.ToLower(path)
+
+	switch {
+	case path == "active":
+		active, err := scimBool(value)
+		if err != nil {
+			return err
+		}
+		u.Active = &active
+	case path == "username":
+		return json.Unmarshal(value, &u.UserName)
+	case path == "displayname":
+		return json.Unmarshal(value, &u.DisplayName)
+	case path == "externalid":
+		return json.Unmarshal(value, &u.ExternalId)
+	case path == "name":
+		return json.Unmarshal(value, &u.Name)
+	case strings.HasPrefix(path, "name."):
+		if u.Name == nil {
+			u.Name = &ScimName{}
+		}
+		switch strings.TrimPrefix(path, "name.") {
+		case "formatted":
+			return json.Unmarshal(value, &u.Name.Formatted)
+		case "givenname":
+			return json.Unmarshal(value, &u.Name.GivenName)
+		case "familyname":
+			return json.Unmarshal(value, &u.Name.FamilyName)
+		}
+		return ErrScimInvalidPath
+	case path == "emails":
+		return json.Unmarshal(value, &u.Emails)
+	case strings.HasPrefix(path, "emails["):
+		var email string
+		if err := json.Unmarshal(value, &email); err != nil {
+			return ErrScimInvalidValue
+		}
+		u.Emails = []ScimMultiValue{{Value: email, Type: "work", Primary: true}}
+	case path == "phonenumbers":
+		return json.Unmarshal(value, &u.PhoneNumbers)
+	case strings.HasPrefix(path, "phonenumbers["):
+		var phone string
+		if err := json.Unmarshal(value, &phone); err != nil {
+			return ErrScimInvalidValue
+		}
+		u.PhoneNumbers = []ScimMultiValue{{Value: phone, Type: "work"}}
+	case strings.HasPrefix(path, "urn:"):
+		//忽略扩展属性
+	default:
+		return ErrScimInvalidPath
+	}
+	return nil
+}
+
+// FindScimUsers 根据 SCIM 过滤条件分页查询用户，startIndex 从1开始.
+func FindScimUsers(attr, value string, startIndex, count int) ([]*Member, int, error) {
+	o := orm.NewOrm()
+
+	qs := o.QueryTable(NewMember().TableNameWithPrefix())
+
+	switch attr {
+	case "":
+	case "id":
+		qs = qs.Filter("member_id", value)
+	case "username":
+		qs = qs.Filter("account", value)
+	case "emails", "emails.value":
+		qs = qs.Filter("email", value)
+	case "displayname":
+		qs = qs.Filter("real_name", value)
+	default:
+		return nil, 0, ErrScimInvalidFilter
+	}
+
+	totalCount, err := qs.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+	var members []*Member
+
+	_, err = qs.OrderBy("member_id").Offset(startIndex - 1).Limit(count).All(&members)
+	if err != nil && err != orm.ErrNoRows {
+		return nil, 0, err
+	}
+	return members, int(totalCount), nil
+}
+
+// AddScimMember 通过 SCIM 创建用户，未提供密码时生成随机密码.
+func AddScimMember(user *ScimUser) (*Member, error) {
+	member := NewMember()
+	user.ToMember(member)
+
+	if _, err := NewMember().FindByAccount(member.Account); err == nil {
+		return nil, ErrMemberExist
+	}
+	if user.Password != "" {
+		if err := GetPasswordPolicy().Check(user.Password); err != nil {
+			return nil, err
+		}
+		member.Password = user.Password
+	} else {
+		member.Password = string(utils.Krand(32, utils.KC_RAND_KIND_ALL))
+	}
+	member.AuthMethod = conf.AuthMethodLocal
+	member.Role = conf.GetScimConfig().UserRole
+	member.Avatar = conf.GetDefaultAvatar()
+	member.CreateTime = time.Now()
+
+	if err := member.Add(); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// NewScimGroup 将团队及其成员转换为 SCIM 用户组资源.
+func NewScimGroup(team *Team, members []*TeamMember) *ScimGroup {
+	group := &ScimGroup{
+		Schemas:     []string{ScimSchemaGroup},
+		Id:          strconv.Itoa(team.TeamId),
+		DisplayName: team.TeamName,
+		Members:     make([]ScimMultiValue, 0, len(members)),
+		Meta: &ScimMeta{
+			ResourceType: "Group",
+			Created:      team.CreateTime.Format(time.RFC3339),
+			Location:     conf.URLFor("ScimController.Group", ":id", team.TeamId),
+		},
+	}
+	for _, item := range members {
+		group.Members = append(group.Members, ScimMultiValue{
+			Value:   strconv.Itoa(item.MemberId),
+			Display: item.Account,
+			Ref:     conf.URLFor("ScimController.User", ":id", item.MemberId),
+		})
+	}
+	return group
+}
+
+// MemberIds 获取用户组中的用户ID.
+func (g *ScimGroup) MemberIds() []int {
+	memberIds := make([]int, 0, len(g.Members))
+	for _, item := range g.Members {
+		if id, err := strconv.Atoi(item.Value); err == nil && id > 0 {
+			memberIds = append(memberIds, id)
+		}
+	}
+	return memberIds
+}
+
+// FindScimGroups 根据 SCIM 过滤条件分页查询团队，startIndex 从1开始.
+func FindScimGroups(attr, value string, startIndex, count int) ([]*Team, int, error) {
+	o := orm.NewOrm()
+
+	qs := o.QueryTable(NewTeam().TableNameWithPrefix())
+
+	switch attr {
+	case "":
+	case "id":
+		qs = qs.Filter("team_id", value)
+	case "displayname":
+		qs = qs.Filter("team_name", value)
+	default:
+		return nil, 0, ErrScimInvalidFilter
+	}
+
+	totalCount, err := qs.Count()
+	if err != nil {
+		return nil, 0, err
+	}
+	var teams []*Team
+
+	_, err = qs.OrderBy("team_id").Offset(startIndex - 1).Limit(count).All(&teams)
+	if err != nil && err != orm.ErrNoRows {
+		return nil, 0, err
+	}
+	return teams, int(totalCount), nil
+}
+
+// SyncScimGroupMembers 将团队成员同步为指定的用户列表.
+func SyncScimGroupMembers(teamId int, memberIds []int) error {
+	members, err := NewTeamMember().FindAllByTeamId(teamId)
+	if err != nil && err != orm.ErrNoRows {
+		return err
+	}
+	exists := make(map[int]bool, len(memberIds))
+	for _, id := range memberIds {
+		exists[id] = true
+	}
+	removeIds := make([]int, 0)
+	for _, item := range members {
+		if !exists[item.MemberId] {
+			removeIds = append(removeIds, item.MemberId)
+		}
+	}
+	if err := RemoveScimGroupMembers(teamId, removeIds); err != nil {
+		return err
+	}
+	return AddScimGroupMembers(teamId, memberIds)
+}
+
+// AddScimGroupMembers 将用户加入团队，已存在的用户和已禁用的用户将被忽略.
+func AddScimGroupMembers(teamId int, memberIds []int) error {
+	o := orm.NewOrm()
+	role := conf.GetScimConfig().TeamRole
+
+	for _, memberId := range memberIds {
+		if o.QueryTable(NewTeamMember().TableNameWithPrefix()).Filter("team_id", teamId).Filter("member_id", memberId).Exist() {
+			continue
+		}
+		if o.QueryTable(NewMember().TableNameWithPrefix()).Filter("member_id", memberId).Filter("status", 1).Exist() {
+			logs.Warn("SCIM 跳过已禁用的团队成员 ->", teamId, memberId)
+			continue
+		}
+		teamMember := NewTeamMember()
+		teamMember.TeamId = teamId
+		teamMember.MemberId = memberId
+		teamMember.RoleId = role
+
+		if err := teamMember.Save(); err != nil {
+			logs.Error("SCIM 添加团队成员失败 ->", teamId, memberId, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveScimGroupMembers 将用户从团队中移除.
+func RemoveScimGroupMembers(teamId int, memberIds []int) error {
+	if len(memberIds) == 0 {
+		return nil
+	}
+	_, err := orm.NewOrm().QueryTable(NewTeamMember().TableNameWithPrefix()).Filter("team_id", teamId).Filter("member_id__in", memberIds).Delete()
+	if err != nil {
+		logs.Error("SCIM 移除团队成员失败 ->", teamId, memberIds, err)
+	}
+	return err
+}
+
+// ApplyScimGroupPatch 将 PATCH 操作应用到团队上.
+func ApplyScimGroupPatch(team *Team, operations []ScimPatchOperation) error {
+	for _, op := range operations {
+		path := strings.ToLower(op.Path)
+		opName := strings.ToLower(op.Op)
+
+		switch {
+		case path == "" && opName != "remove":
+			var values struct {
+				DisplayName *string          `json:"displayName"`
+				Members     []ScimMultiValue `json:"members"`
+			}
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return ErrScimInvalidValue
+			}
+			if values.DisplayName != nil {
+				team.TeamName = *values.DisplayName
+				if err := team.Save("team_name"); err != nil {
+					return err
+				}
+			}
+			if values.Members != nil {
+				group := &ScimGroup{Members: values.Members}
+				if opName == "replace" {
+					if err := SyncScimGroupMembers(team.TeamId, group.MemberIds()); err != nil {
+						return err
+					}
+				} else if err := AddScimGroupMembers(team.TeamId, group.MemberIds()); err != nil {
+					return err
+				}
+			}
+		case path == "displayname":
+			if err := json.Unmarshal(op.Value, &team.TeamName); err != nil {
+				return ErrScimInvalidValue
+			}
+			if err := team.Save("team_name"); err != nil {
+				return err
+			}
+		case path == "members":
+			group := &ScimGroup{}
+			if len(op.Value) > 0 {
+				if err := json.Unmarshal(op.Value, &group.Members); err != nil {
+					return ErrScimInvalidValue
+				}
+			}
+			var err error
+			switch opName {
+			case "add":
+				err = AddScimGroupMembers(team.TeamId, group.MemberIds())
+			case "replace":
+				err = SyncScimGroupMembers(team.TeamId, group.MemberIds())
+			case "remove":
+				//未指定成员时移除全部成员
+				if len(group.Members) == 0 {
+					err = SyncScimGroupMembers(team.TeamId, []int{})
+				} else {
+					err = RemoveScimGroupMembers(team.TeamId, group.MemberIds())
+				}
+			default:
+				return ErrScimInvalidValue
+			}
+			if err != nil {
+				return err
+			}
+		case scimMemberPathRegexp.MatchString(op.Path) && opName == "remove":
+			matches := scimMemberPathRegexp.FindStringSubmatch(op.Path)
+			memberId, _ := strconv.Atoi(matches[1])
+			if err := RemoveScimGroupMembers(team.TeamId, []int{memberId}); err != nil {
+				return err
+			}
+		case strings.HasPrefix(path, "urn:") || path == "externalid":
+			//忽略扩展属性
+		default:
+			return ErrScimInvalidPath
+		}
+	}
+	return nil
+}
+
+func scimPrimaryValue(values []ScimMultiValue) string {
+	for _, item := range values {
+		if item.Primary && item.Value != "" {
+			return item.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+// 部分身份平台会以字符串形式传递布尔值，例如 "False".
+func scimBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, ErrScimInvalidValue
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, ErrScimInvalidValue
+	}
+	return b, nil
+}
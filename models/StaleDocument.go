@@ -0,0 +1,335 @@
+package models
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+	"github.com/mindoc-org/mindoc/conf"
+)
+
+// StaleOwnerField 指定文档负责人的自定义字段名称，未设置时文档创建人为负责人.
+const StaleOwnerField = "owner"
+
+// StaleReminder 过期文档最近一次邮件提醒的记录.
+type StaleReminder struct {
+	ReminderId int       `orm:"column(reminder_id);pk;auto;unique" json:"reminder_id"`
+	DocumentId int       `orm:"column(document_id);type(int);unique" json:"document_id"`
+	BookId     int       `orm:"column(book_id);type(int);index" json:"book_id"`
+	MemberId   int       `orm:"column(member_id);type(int);index" json:"member_id"`
+	RemindTime time.Time `orm:"column(remind_time);type(datetime)" json:"remind_time"`
+}
+
+// StaleDocumentResult 超过项目复审周期未修改的文档.
+type StaleDocumentResult struct {
+	DocumentId   int       `json:"doc_id"`
+	DocumentName string    `json:"doc_name"`
+	Identify     string    `json:"identify"`
+	BookId       int       `json:"book_id"`
+	BookName     string    `json:"book_name"`
+	BookIdentify string    `json:"book_identify"`
+	MemberId     int       `json:"member_id"`
+	Account      string    `json:"account"`
+	RealName     string    `json:"real_name"`
+	ModifyTime   time.Time `json:"modify_time"`
+	// StaleDays 距离最后修改的天数.
+	StaleDays int `json:"stale_days"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *StaleReminder) TableName() string {
+	return "stale_reminders"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *StaleReminder) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *StaleReminder) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewStaleReminder() *StaleReminder {
+	return &StaleReminder{}
+}
+
+// IsStale 判断文档内容是否超过复审周期未修改，reviewDays 为 0 时不检查.
+func (item *Document) IsStale(reviewDays int) bool {
+	return reviewDays > 0 && !item.ContentTime.IsZero() && item.ContentTime.Before(time.Now().AddDate(0, 0, -reviewDays))
+}
+
+// FindStaleDocuments 查询项目中内容超过复审周期未修改的文档，按内容最后修改时间排序.
+// 发布文档不算修改，因此不使用发布时会更新的 ModifyTime.
+func FindStaleDocuments(book *Book) ([]*StaleDocumentResult, error) {
+	results := make([]*StaleDocumentResult, 0)
+	if book.ReviewDays <= 0 {
+		return results, nil
+	}
+	o := orm.NewOrm()
+
+	var docs []*Document
+	_, err := o.QueryTable(NewDocument().TableNameWithPrefix()).
+		Filter("book_id", book.BookId).
+		Filter("content_time__lt", time.Now().AddDate(0, 0, -book.ReviewDays)).
+		OrderBy("content_time", "document_id").
+		All(&docs, "document_id", "document_name", "identify", "member_id", "content_time")
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return results, nil
+	}
+	owners, err := findDocumentOwners(book.BookId)
+	if err != nil {
+		return nil, err
+	}
+	memberIds := make([]int, 0, len(docs))
+	for _, doc := range docs {
+		if owner, ok := owners[doc.DocumentId]; ok {
+			doc.MemberId = owner
+		}
+		memberIds = append(memberIds, doc.MemberId)
+	}
+	var members []*Member
+	_, err = o.QueryTable(NewMember().TableNameWithPrefix()).
+		Filter("member_id__in", memberIds).
+		All(&members, "member_id", "account", "real_name")
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	memberIndex := make(map[int]*Member, len(members))
+	for _, member := range members {
+		memberIndex[member.MemberId] = member
+	}
+
+	for _, doc := range docs {
+		result := &StaleDocumentResult{
+			DocumentId:   doc.DocumentId,
+			DocumentName: doc.DocumentName,
+			Identify:     doc.Identify,
+			BookId:       book.BookId,
+			BookName:     book.BookName,
+			BookIdentify: book.Identify,
+			MemberId:     doc.MemberId,
+			ModifyTime:   doc.ContentTime,
+			StaleDays:    int(time.Since(doc.ContentTime).Hours() / 24),
+		}
+		if member, ok := memberIndex[doc.MemberId]; ok {
+			result.Account = member.Account
+			result.RealName = member.RealName
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// FindStaleDocumentsByMemberId 查询指定用户负责的全部过期文档.
+func FindStaleDocumentsByMemberId(memberId int) ([]*StaleDocumentResult, error) {
+	results := make([]*StaleDocumentResult, 0)
+
+	books, err := findReviewBooks()
+	if err != nil {
+		return nil, err
+	}
+	for _, book := range books {
+		docs, err := FindStaleDocuments(book)
+		if err != nil {
+			return nil, err
+		}
+		for _, doc := range docs {
+			if doc.MemberId == memberId {
+				results = append(results, doc)
+			}
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].ModifyTime.Before(results[j].ModifyTime)
+	})
+	return results, nil
+}
+
+// RemindStaleDocuments 检查全部设置了复审周期的项目，并通过站内通知和邮件提醒文档负责人，
+// 同一篇文档在提醒间隔内不会重复提醒，已经没有项目阅读权限的负责人不会收到提醒.
+func RemindStaleDocuments() error {
+	books, err := findReviewBooks()
+	if err != nil {
+		return err
+	}
+	o := orm.NewOrm()
+	interval, _ := strconv.Atoi(GetOptionValue("STALE_REMIND_INTERVAL_DAYS", "7"))
+	if interval <= 0 {
+		interval = 1
+	}
+	remindBefore := time.Now().AddDate(0, 0, -interval)
+	pending := make(map[int][]*StaleDocumentResult)
+	staleCount := 0
+	bookIndex := make(map[int]*Book, len(books))
+
+	for _, book := range books {
+		bookIndex[book.BookId] = book
+		docs, err := FindStaleDocuments(book)
+		if err != nil {
+			logs.Error("查询过期文档失败 ->", book.BookId, err)
+			continue
+		}
+		staleCount += len(docs)
+
+		docIds := make([]int, 0, len(docs))
+		for _, doc := range docs {
+			docIds = append(docIds, doc.DocumentId)
+		}
+		// 清理已经更新过的文档的提醒记录
+		qs := o.QueryTable(NewStaleReminder().TableNameWithPrefix()).Filter("book_id", book.BookId)
+		if len(docIds) > 0 {
+			qs = qs.Exclude("document_id__in", docIds)
+		}
+		if _, err := qs.Delete(); err != nil {
+			logs.Error("清理过期文档提醒记录失败 ->", book.BookId, err)
+		}
+		if len(docIds) == 0 {
+			continue
+		}
+		var reminders []*StaleReminder
+		_, err = o.QueryTable(NewStaleReminder().TableNameWithPrefix()).
+			Filter("document_id__in", docIds).
+			All(&reminders)
+		if err != nil && err != orm.ErrNoRows {
+			logs.Error("查询过期文档提醒记录失败 ->", book.BookId, err)
+			continue
+		}
+		reminded := make(map[int]*StaleReminder, len(reminders))
+		for _, reminder := range reminders {
+			reminded[reminder.DocumentId] = reminder
+		}
+		for _, doc := range docs {
+			if reminder, ok := reminded[doc.DocumentId]; ok && reminder.MemberId == doc.MemberId && reminder.RemindTime.After(remindBefore) {
+				continue
+			}
+			pending[doc.MemberId] = append(pending[doc.MemberId], doc)
+		}
+	}
+	logs.Info("过期文档检查完成 ->", staleCount)
+
+	mailConf := conf.GetMailConfig()
+	for memberId, docs := range pending {
+		member, err := NewMember().Find(memberId, "member_id", "account", "real_name", "email", "status", "role")
+		if err != nil || member.Status != 0 {
+			continue
+		}
+		readable := docs[:0]
+		for _, doc := range docs {
+			if CanReadBook(bookIndex[doc.BookId], member) {
+				readable = append(readable, doc)
+			}
+		}
+		docs = readable
+		if len(docs) == 0 {
+			continue
+		}
+		for _, doc := range docs {
+			_ = (&Notification{
+				MemberId:   memberId,
+				Type:       NotificationReviewDocument,
+				Subject:    doc.DocumentName,
+				Content:    strconv.Itoa(doc.StaleDays),
+				Url:        documentPath(doc.BookIdentify, doc.DocumentId),
+				BookId:     doc.BookId,
+				DocumentId: doc.DocumentId,
+			}).Insert()
+		}
+		if mailConf.EnableMail && member.Email != "" {
+			if err := sendStaleDocumentMail(member, docs); err != nil {
+				logs.Error("发送过期文档提醒邮件失败 ->", member.Account, err)
+			}
+		}
+		for _, doc := range docs {
+			if err := NewStaleReminder().save(doc, memberId); err != nil {
+				logs.Error("保存过期文档提醒记录失败 ->", doc.DocumentId, err)
+			}
+		}
+	}
+	return nil
+}
+
+// save 更新文档的提醒时间，没有提醒记录时新建.
+func (m *StaleReminder) save(doc *StaleDocumentResult, memberId int) error {
+	o := orm.NewOrm()
+	now := time.Now()
+
+	num, err := o.QueryTable(m.TableNameWithPrefix()).Filter("document_id", doc.DocumentId).Update(orm.Params{
+		"member_id":   memberId,
+		"remind_time": now,
+	})
+	if err != nil || num > 0 {
+		return err
+	}
+	m.DocumentId = doc.DocumentId
+	m.BookId = doc.BookId
+	m.MemberId = memberId
+	m.RemindTime = now
+	_, err = o.Insert(m)
+	return err
+}
+
+// findReviewBooks 查询设置了复审周期的项目.
+func findReviewBooks() ([]*Book, error) {
+	var books []*Book
+	_, err := orm.NewOrm().QueryTable(NewBook().TableNameWithPrefix()).
+		Filter("review_days__gt", 0).
+		All(&books, "book_id", "book_name", "identify", "review_days", "privately_owned")
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	return books, nil
+}
+
+// findDocumentOwners 查询项目中通过 owner 字段指定了负责人的文档.
+func findDocumentOwners(bookId int) (map[int]int, error) {
+	o := orm.NewOrm()
+	owners := make(map[int]int)
+
+	var fields []*DocumentField
+	_, err := o.QueryTable(NewDocumentField().TableNameWithPrefix()).
+		Filter("book_id", bookId).
+		Filter("field_name", StaleOwnerField).
+		All(&fields, "document_id", "field_value")
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return owners, nil
+	}
+	accounts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		accounts = append(accounts, field.FieldValue)
+	}
+	members, err := NewMember().FindByAccountList(accounts...)
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	memberIds := make(map[string]int, len(members))
+	for _, member := range members {
+		memberIds[member.Account] = member.MemberId
+	}
+	for _, field := range fields {
+		if memberId, ok := memberIds[field.FieldValue]; ok {
+			owners[field.DocumentId] = memberId
+		}
+	}
+	return owners, nil
+}
+
+// sendStaleDocumentMail 将负责人的过期文档合并为一封邮件发送.
+func sendStaleDocumentMail(member *Member, docs []*StaleDocumentResult) error {
+	lang, _ := web.AppConfig.String("default_lang")
+
+	data := map[string]interface{}{
+		"Member": member,
+		"Lists":  docs,
+	}
+	return SendTemplateMail(member.Email, MailStaleDocuments, lang, data)
+}
@@ -0,0 +1,531 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/client/orm"
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+	"github.com/mindoc-org/mindoc/conf"
+)
+
+// 订阅邮件的发送频率.
+const (
+	SubscriptionImmediate = "immediate"
+	SubscriptionDaily     = "daily"
+	SubscriptionWeekly    = "weekly"
+)
+
+// SubscriptionFrequencies 支持的发送频率.
+var SubscriptionFrequencies = []string{SubscriptionImmediate, SubscriptionDaily, SubscriptionWeekly}
+
+// ErrSubscriptionFrequency 不支持的发送频率.
+var ErrSubscriptionFrequency = errors.New("不支持的发送频率")
+
+// Subscription 用户订阅的项目或文档.
+type Subscription struct {
+	SubscriptionId int `orm:"column(subscription_id);pk;auto;unique" json:"subscription_id"`
+	MemberId       int `orm:"column(member_id);type(int);index;description(订阅用户id)" json:"member_id"`
+	BookId         int `orm:"column(book_id);type(int);index;description(项目id)" json:"book_id"`
+	// DocumentId 订阅的文档，为 0 时订阅整个项目
+	DocumentId int    `orm:"column(document_id);type(int);default(0);description(文档id，0 为订阅整个项目)" json:"doc_id"`
+	Frequency  string `orm:"column(frequency);size(20);default(immediate);description(发送频率 immediate/daily/weekly)" json:"frequency"`
+	// Token 退订链接使用的令牌
+	Token        string    `orm:"column(token);size(64);unique;description(退订令牌)" json:"-"`
+	CreateTime   time.Time `orm:"type(datetime);column(create_time);auto_now_add;description(订阅时间)" json:"create_time"`
+	LastSendTime time.Time `orm:"type(datetime);column(last_send_time);null;description(上次发送摘要的时间)" json:"last_send_time"`
+
+	BookName     string `orm:"-" json:"book_name"`
+	BookIdentify string `orm:"-" json:"book_identify"`
+	DocumentName string `orm:"-" json:"doc_name"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *Subscription) TableName() string {
+	return "subscriptions"
+}
+
+// TableUnique 同一用户对同一项目或文档只能订阅一次.
+func (m *Subscription) TableUnique() [][]string {
+	return [][]string{{"member_id", "book_id", "document_id"}}
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *Subscription) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *Subscription) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+func NewSubscription() *Subscription {
+	return &Subscription{}
+}
+
+// DocumentChange 文档发布记录，用于生成订阅摘要.
+type DocumentChange struct {
+	ChangeId   int       `orm:"column(change_id);pk;auto;unique" json:"change_id"`
+	BookId     int       `orm:"column(book_id);type(int);index;description(项目id)" json:"book_id"`
+	DocumentId int       `orm:"column(document_id);type(int);index;description(文档id)" json:"doc_id"`
+	ModifyAt   int       `orm:"column(modify_at);type(int);default(0);description(修改人id)" json:"modify_at"`
+	CreateTime time.Time `orm:"type(datetime);column(create_time);auto_now_add;index;description(发布时间)" json:"create_time"`
+}
+
+// TableName 获取对应数据库表名.
+func (m *DocumentChange) TableName() string {
+	return "document_changes"
+}
+
+// TableEngine 获取数据使用的引擎.
+func (m *DocumentChange) TableEngine() string {
+	return "INNODB"
+}
+
+func (m *DocumentChange) TableNameWithPrefix() string {
+	return conf.GetDatabasePrefix() + m.TableName()
+}
+
+// SubscriptionChange 订阅邮件中一篇文档的修改摘要.
+type SubscriptionChange struct {
+	DocumentId   int
+	DocumentName string
+	BookIdentify string
+	BookName     string
+	Editors      []string
+	ModifyTime   time.Time
+	// Added 和 Removed 为与修改前相比新增和删除的行数，HasDiff 为 false 时没有可比较的历史版本
+	Added   int
+	Removed int
+	HasDiff bool
+}
+
+// EditorNames 修改人名称，多个使用逗号分隔.
+func (m *SubscriptionChange) EditorNames() string {
+	return strings.Join(m.Editors, ", ")
+}
+
+// IsValidSubscriptionFrequency 判断发送频率是否有效.
+func IsValidSubscriptionFrequency(frequency string) bool {
+	for _, item := range SubscriptionFrequencies {
+		if item == frequency {
+			return true
+		}
+	}
+	return false
+}
+
+// Find 查询用户对项目或文档的订阅.
+func (m *Subscription) Find(memberId, bookId, documentId int) (*Subscription, error) {
+	err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("member_id", memberId).
+		Filter("book_id", bookId).
+		Filter("document_id", documentId).
+		One(m)
+	return m, err
+}
+
+// FindByToken 根据退订令牌查询订阅.
+func (m *Subscription) FindByToken(token string) (*Subscription, error) {
+	if token == "" {
+		return m, orm.ErrNoRows
+	}
+	err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("token", token).One(m)
+	return m, err
+}
+
+// Subscribe 订阅项目或文档，已订阅时修改发送频率.
+func (m *Subscription) Subscribe(memberId, bookId, documentId int, frequency string) (*Subscription, error) {
+	if !IsValidSubscriptionFrequency(frequency) {
+		return nil, ErrSubscriptionFrequency
+	}
+	o := orm.NewOrm()
+	subscription, err := NewSubscription().Find(memberId, bookId, documentId)
+	if err == nil {
+		subscription.Frequency = frequency
+		_, err = o.Update(subscription, "frequency")
+		return subscription, err
+	}
+	if err != orm.ErrNoRows {
+		return nil, err
+	}
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	subscription = &Subscription{
+		MemberId:     memberId,
+		BookId:       bookId,
+		DocumentId:   documentId,
+		Frequency:    frequency,
+		Token:        hex.EncodeToString(b),
+		LastSendTime: time.Now(),
+	}
+	_, err = o.Insert(subscription)
+	return subscription, err
+}
+
+// Unsubscribe 取消订阅.
+func (m *Subscription) Unsubscribe(memberId, bookId, documentId int) error {
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("member_id", memberId).
+		Filter("book_id", bookId).
+		Filter("document_id", documentId).
+		Delete()
+	return err
+}
+
+// Delete 删除订阅.
+func (m *Subscription) Delete() error {
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("subscription_id", m.SubscriptionId).Delete()
+	return err
+}
+
+// FindByMemberId 查询用户的全部订阅，并补充项目和文档名称.
+func (m *Subscription) FindByMemberId(memberId int) ([]*Subscription, error) {
+	var list []*Subscription
+	_, err := orm.NewOrm().QueryTable(m.TableNameWithPrefix()).
+		Filter("member_id", memberId).
+		OrderBy("book_id", "document_id").
+		All(&list)
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	results := make([]*Subscription, 0, len(list))
+	for _, item := range list {
+		book, err := NewBook().Find(item.BookId, "book_id", "book_name", "identify")
+		if err != nil {
+			continue
+		}
+		item.BookName = book.BookName
+		item.BookIdentify = book.Identify
+		if item.DocumentId > 0 {
+			doc, err := NewDocument().Find(item.DocumentId)
+			if err != nil {
+				continue
+			}
+			item.DocumentName = doc.DocumentName
+		}
+		results = append(results, item)
+	}
+	return results, nil
+}
+
+// findDocumentSubscriptions 查询订阅了文档或其所属项目的订阅，同一用户同时订阅时只保留对文档的订阅.
+func findDocumentSubscriptions(doc *Document) ([]*Subscription, error) {
+	var list []*Subscription
+	_, err := orm.NewOrm().QueryTable(NewSubscription().TableNameWithPrefix()).
+		Filter("book_id", doc.BookId).
+		Filter("document_id__in", 0, doc.DocumentId).
+		OrderBy("-document_id").
+		All(&list)
+	if err != nil && err != orm.ErrNoRows {
+		return nil, err
+	}
+	members := make(map[int]bool, len(list))
+	results := make([]*Subscription, 0, len(list))
+	for _, item := range list {
+		if members[item.MemberId] {
+			continue
+		}
+		members[item.MemberId] = true
+		results = append(results, item)
+	}
+	return results, nil
+}
+
+// CanReadBook 判断用户是否可以阅读项目，私有项目只有参与者和管理员可以阅读.
+func CanReadBook(book *Book, member *Member) bool {
+	if book.PrivatelyOwned == 0 || member.IsAdministrator() {
+		return true
+	}
+	_, err := NewBook().FindForRoleId(book.BookId, member.MemberId)
+	return err == nil
+}
+
+// NotifyDocumentSubscribers 记录文档的发布并通知订阅者，设置为即时发送的订阅者同时发送邮件.
+// 本次发布的修改人和没有项目阅读权限的订阅者不会收到通知，返回收到通知的用户.
+func NotifyDocumentSubscribers(doc *Document) map[int]bool {
+	o := orm.NewOrm()
+	// 上次发布之后的修改都属于本次发布
+	var last DocumentChange
+	since := doc.CreateTime
+	if err := o.QueryTable(new(DocumentChange).TableNameWithPrefix()).Filter("document_id", doc.DocumentId).OrderBy("-change_id").One(&last, "create_time"); err == nil {
+		since = last.CreateTime
+	}
+	if _, err := o.Insert(&DocumentChange{BookId: doc.BookId, DocumentId: doc.DocumentId, ModifyAt: doc.ModifyAt}); err != nil {
+		logs.Error("保存文档发布记录失败 ->", doc.DocumentId, err)
+	}
+	subscriptions, err := findDocumentSubscriptions(doc)
+	if err != nil {
+		logs.Error("查询文档订阅失败 ->", doc.DocumentId, err)
+		return nil
+	}
+	if len(subscriptions) == 0 {
+		return nil
+	}
+	book, err := NewBook().Find(doc.BookId, "book_id", "book_name", "identify", "privately_owned")
+	if err != nil {
+		return nil
+	}
+	mailConf := conf.GetMailConfig()
+	var immediate []*Subscription
+	members := make(map[int]*Member)
+	notified := make(map[int]bool)
+
+	for _, subscription := range subscriptions {
+		if subscription.MemberId == doc.ModifyAt {
+			continue
+		}
+		member, err := NewMember().Find(subscription.MemberId, "member_id", "account", "real_name", "email", "role", "status")
+		if err != nil || member.Status != 0 || !CanReadBook(book, member) {
+			continue
+		}
+		members[member.MemberId] = member
+		notified[member.MemberId] = true
+
+		_ = (&Notification{
+			MemberId:   subscription.MemberId,
+			SenderId:   doc.ModifyAt,
+			Type:       NotificationSubscription,
+			Subject:    doc.DocumentName,
+			Url:        documentPath(book.Identify, doc.DocumentId),
+			BookId:     doc.BookId,
+			DocumentId: doc.DocumentId,
+		}).Insert()
+
+		if subscription.Frequency == SubscriptionImmediate {
+			immediate = append(immediate, subscription)
+		}
+	}
+	if !mailConf.EnableMail || len(immediate) == 0 {
+		return notified
+	}
+	change := documentChange(doc, book, since, []int{doc.ModifyAt})
+
+	go func() {
+		for _, subscription := range immediate {
+			member := members[subscription.MemberId]
+			if member.Email == "" {
+				continue
+			}
+			if err := sendSubscriptionMail(member, []*SubscriptionChange{change}, []*Subscription{subscription}, SubscriptionImmediate); err != nil {
+				logs.Error("发送订阅邮件失败 ->", member.Account, err)
+			}
+		}
+	}()
+	return notified
+}
+
+// SendSubscriptionDigests 发送每日和每周订阅摘要，同一用户相同频率的多个订阅合并为一封邮件.
+func SendSubscriptionDigests() error {
+	mailConf := conf.GetMailConfig()
+	if !mailConf.EnableMail {
+		return nil
+	}
+	o := orm.NewOrm()
+	var subscriptions []*Subscription
+	_, err := o.QueryTable(NewSubscription().TableNameWithPrefix()).
+		Filter("frequency__in", SubscriptionDaily, SubscriptionWeekly).
+		OrderBy("member_id").
+		All(&subscriptions)
+	if err != nil && err != orm.ErrNoRows {
+		return err
+	}
+	now := time.Now()
+	pending := make(map[int]map[string][]*Subscription)
+	for _, subscription := range subscriptions {
+		interval := 24 * time.Hour
+		if subscription.Frequency == SubscriptionWeekly {
+			interval = 7 * 24 * time.Hour
+		}
+		if subscription.LastSendTime.IsZero() {
+			subscription.LastSendTime = subscription.CreateTime
+		}
+		if now.Sub(subscription.LastSendTime) >= interval {
+			if pending[subscription.MemberId] == nil {
+				pending[subscription.MemberId] = make(map[string][]*Subscription)
+			}
+			pending[subscription.MemberId][subscription.Frequency] = append(pending[subscription.MemberId][subscription.Frequency], subscription)
+		}
+	}
+	for memberId, groups := range pending {
+		member, err := NewMember().Find(memberId, "member_id", "account", "real_name", "email", "role", "status")
+		if err != nil || member.Status != 0 {
+			continue
+		}
+		for frequency, items := range groups {
+			if err := sendSubscriptionDigest(member, items, frequency); err != nil {
+				logs.Error("发送订阅摘要失败 ->", member.Account, frequency, err)
+				continue
+			}
+			for _, subscription := range items {
+				subscription.LastSendTime = now
+				if _, err := o.Update(subscription, "last_send_time"); err != nil {
+					logs.Error("更新订阅摘要发送时间失败 ->", subscription.SubscriptionId, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// sendSubscriptionDigest 将用户相同频率的订阅在上次发送之后的修改合并为一封摘要邮件，没有修改时不发送.
+func sendSubscriptionDigest(member *Member, items []*Subscription, frequency string) error {
+	changes := make([]*SubscriptionChange, 0)
+	found := make(map[int]bool)
+	for _, subscription := range items {
+		for _, change := range subscriptionChanges(subscription, member) {
+			if !found[change.DocumentId] {
+				found[change.DocumentId] = true
+				changes = append(changes, change)
+			}
+		}
+	}
+	if len(changes) == 0 || member.Email == "" {
+		return nil
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].ModifyTime.After(changes[j].ModifyTime)
+	})
+	return sendSubscriptionMail(member, changes, items, frequency)
+}
+
+// CleanDocumentChanges 清理过期的文档发布记录，发布记录只用于生成摘要，保留的时间超过每周摘要的间隔即可.
+// 未启用邮件时也需要定期清理.
+func CleanDocumentChanges() {
+	if _, err := orm.NewOrm().QueryTable(new(DocumentChange).TableNameWithPrefix()).Filter("create_time__lt", time.Now().AddDate(0, 0, -14)).Delete(); err != nil {
+		logs.Error("清理文档发布记录失败 ->", err)
+	}
+}
+
+// subscriptionChanges 查询订阅在上次发送摘要之后的修改，不包括用户自己的修改.
+func subscriptionChanges(subscription *Subscription, member *Member) []*SubscriptionChange {
+	book, err := NewBook().Find(subscription.BookId, "book_id", "book_name", "identify", "privately_owned")
+	if err != nil || !CanReadBook(book, member) {
+		return nil
+	}
+	qs := orm.NewOrm().QueryTable(new(DocumentChange).TableNameWithPrefix()).
+		Filter("book_id", subscription.BookId).
+		Filter("create_time__gt", subscription.LastSendTime).
+		Exclude("modify_at", member.MemberId)
+	if subscription.DocumentId > 0 {
+		qs = qs.Filter("document_id", subscription.DocumentId)
+	}
+	var records []*DocumentChange
+	if _, err := qs.OrderBy("change_id").All(&records); err != nil {
+		return nil
+	}
+	editors := make(map[int][]int)
+	var docIds []int
+	for _, record := range records {
+		if _, ok := editors[record.DocumentId]; !ok {
+			docIds = append(docIds, record.DocumentId)
+		}
+		editors[record.DocumentId] = append(editors[record.DocumentId], record.ModifyAt)
+	}
+	changes := make([]*SubscriptionChange, 0, len(docIds))
+	for _, docId := range docIds {
+		doc, err := NewDocument().Find(docId)
+		if err != nil {
+			continue
+		}
+		changes = append(changes, documentChange(doc, book, subscription.LastSendTime, editors[docId]))
+	}
+	return changes
+}
+
+// documentChange 生成文档在指定时间之后的修改摘要，通过文档历史计算新增和删除的行数.
+func documentChange(doc *Document, book *Book, since time.Time, editorIds []int) *SubscriptionChange {
+	change := &SubscriptionChange{
+		DocumentId:   doc.DocumentId,
+		DocumentName: doc.DocumentName,
+		BookIdentify: book.Identify,
+		BookName:     book.BookName,
+		ModifyTime:   doc.ModifyTime,
+	}
+	var histories []*DocumentHistory
+	_, _ = orm.NewOrm().QueryTable(NewDocumentHistory().TableNameWithPrefix()).
+		Filter("document_id", doc.DocumentId).
+		Filter("modify_time__gt", since).
+		OrderBy("history_id").
+		All(&histories, "history_id", "markdown", "modify_at")
+
+	// 历史记录保存的是修改前的内容，时间段内最早的一条即为修改前的版本
+	if len(histories) > 0 {
+		change.Added, change.Removed = diffLineStats(histories[0].Markdown, doc.Markdown)
+		change.HasDiff = true
+	}
+	for _, history := range histories {
+		editorIds = append(editorIds, history.ModifyAt)
+	}
+	found := make(map[int]bool)
+	for _, memberId := range editorIds {
+		if memberId <= 0 || found[memberId] {
+			continue
+		}
+		found[memberId] = true
+		if member, err := NewMember().Find(memberId, "member_id", "account", "real_name"); err == nil {
+			if member.RealName != "" {
+				change.Editors = append(change.Editors, member.RealName)
+			} else {
+				change.Editors = append(change.Editors, member.Account)
+			}
+		}
+	}
+	return change
+}
+
+// diffLineStats 统计两个版本之间新增和删除的行数，忽略首尾相同的行后按行计数比较.
+func diffLineStats(original, current string) (added, removed int) {
+	a := strings.Split(strings.ReplaceAll(original, "\r\n", "\n"), "\n")
+	b := strings.Split(strings.ReplaceAll(current, "\r\n", "\n"), "\n")
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+	counts := make(map[string]int, len(a))
+	for _, line := range a {
+		counts[line]++
+	}
+	for _, line := range b {
+		if counts[line] > 0 {
+			counts[line]--
+		} else {
+			added++
+		}
+	}
+	for _, count := range counts {
+		removed += count
+	}
+	return
+}
+
+// sendSubscriptionMail 发送订阅邮件，邮件中包含每个订阅的退订链接.
+func sendSubscriptionMail(member *Member, changes []*SubscriptionChange, subscriptions []*Subscription, frequency string) error {
+	for _, subscription := range subscriptions {
+		if book, err := NewBook().Find(subscription.BookId, "book_id", "book_name"); err == nil {
+			subscription.BookName = book.BookName
+		}
+		if subscription.DocumentId > 0 {
+			if doc, err := NewDocument().Find(subscription.DocumentId); err == nil {
+				subscription.DocumentName = doc.DocumentName
+			}
+		}
+	}
+	lang, _ := web.AppConfig.String("default_lang")
+	data := map[string]interface{}{
+		"Member":        member,
+		"Changes":       changes,
+		"Subscriptions": subscriptions,
+		"Frequency":     frequency,
+	}
+	return SendTemplateMail(member.Email, MailSubscription, lang, data)
+}
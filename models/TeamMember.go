@@ -186,6 +186,25 @@ func (m *TeamMember) FindToPager(teamId, pageIndex, pageSize int) (list []*TeamM
 	return
 }
 
+//查询团队的全部用户.
+func (m *TeamMember) FindAllByTeamId(teamId int) (list []*TeamMember, err error) {
+	if teamId <= 0 {
+		err = ErrInvalidParameter
+		return
+	}
+	_, err = orm.NewOrm().QueryTable(m.TableNameWithPrefix()).Filter("team_id", teamId).OrderBy("team_member_id").All(&list)
+
+	if err != nil && err != orm.ErrNoRows {
+		logs.Error("查询团队成员失败 ->", err)
+		return
+	}
+	for _, item := range list {
+		item.Lang = m.Lang
+		item.Include()
+	}
+	return list, nil
+}
+
 //查询关联数据.
 func (m *TeamMember) Include() *TeamMember {
 
@@ -38,6 +38,8 @@ func init() {
 	web.InsertFilter("/book/*", web.BeforeRouter, FilterUser)
 	web.InsertFilter("/api/*", web.BeforeRouter, FilterUser)
 	web.InsertFilter("/manage/*", web.BeforeRouter, FilterUser)
+	web.InsertFilter("/notifications", web.BeforeRouter, FilterUser)
+	web.InsertFilter("/notifications/*", web.BeforeRouter, FilterUser)
 
 	var FinishRouter = func(ctx *context.Context) {
 		ctx.ResponseWriter.Header().Add("MinDoc-Version", conf.VERSION)
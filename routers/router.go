@@ -123,6 +123,7 @@ func init() {
 	web.Router("/", &controllers.HomeController{}, "*:Index")
 
 	web.Router("/login", &controllers.AccountController{}, "*:Login")
+	web.Router("/login/2fa", &controllers.AccountController{}, "*:TwoFactor")
 	web.Router("/auth2/redirect/:app", &controllers.AccountController{}, "*:Auth2Redirect")
 	web.Router("/auth2/callback/:app", &controllers.AccountController{}, "*:Auth2Callback")
 	web.Router("/auth2/account/bind/:app", &controllers.AccountController{}, "*:Auth2BindAccount")
@@ -143,15 +144,32 @@ func init() {
 	web.Router("/manager/member/delete", &controllers.ManagerController{}, "post:DeleteMember")
 	web.Router("/manager/member/update-member-status", &controllers.ManagerController{}, "post:UpdateMemberStatus")
 	web.Router("/manager/member/change-member-role", &controllers.ManagerController{}, "post:ChangeMemberRole")
+	web.Router("/manager/member/reset-2fa", &controllers.ManagerController{}, "post:ResetTwoFactor")
+	web.Router("/manager/member/logout", &controllers.ManagerController{}, "post:LogoutMember")
+	web.Router("/manager/login-logs", &controllers.ManagerController{}, "get:LoginLogs")
+	web.Router("/manager/logs", &controllers.ManagerController{}, "get:Logs")
+	web.Router("/manager/logs/export", &controllers.ManagerController{}, "get:ExportLogs")
+	web.Router("/manager/recycle", &controllers.ManagerController{}, "get:Recycle")
+	web.Router("/manager/recycle/restore", &controllers.ManagerController{}, "post:RecycleRestore")
+	web.Router("/manager/recycle/purge", &controllers.ManagerController{}, "post:RecyclePurge")
 	web.Router("/manager/books", &controllers.ManagerController{}, "*:Books")
 	web.Router("/manager/books/edit/:key", &controllers.ManagerController{}, "*:EditBook")
 	web.Router("/manager/books/delete", &controllers.ManagerController{}, "*:DeleteBook")
 
 	web.Router("/manager/comments", &controllers.ManagerController{}, "*:Comments")
+	web.Router("/manager/comments/moderate", &controllers.ManagerController{}, "post:ModerateComments")
 	web.Router("/manager/setting", &controllers.ManagerController{}, "*:Setting")
+	web.Router("/manager/setting/mail/test", &controllers.ManagerController{}, "post:SendTestMail")
+	web.Router("/manager/mail/templates", &controllers.ManagerController{}, "get:MailTemplates")
+	web.Router("/manager/mail/templates/edit", &controllers.ManagerController{}, "get,post:MailTemplateEdit")
+	web.Router("/manager/mail/templates/reset", &controllers.ManagerController{}, "post:MailTemplateReset")
+	web.Router("/manager/mail/templates/preview", &controllers.ManagerController{}, "get,post:MailTemplatePreview")
+	web.Router("/manager/mail/queue", &controllers.ManagerController{}, "get:MailQueue")
+	web.Router("/manager/mail/queue/retry", &controllers.ManagerController{}, "post:MailQueueRetry")
 	web.Router("/manager/books/token", &controllers.ManagerController{}, "post:CreateToken")
 	web.Router("/manager/books/transfer", &controllers.ManagerController{}, "post:Transfer")
 	web.Router("/manager/books/open", &controllers.ManagerController{}, "post:PrivatelyOwned")
+	web.Router("/manager/books/links", &controllers.ManagerController{}, "post:CheckLinks")
 
 	web.Router("/manager/attach/list", &controllers.ManagerController{}, "*:AttachList")
 	web.Router("/manager/attach/clean", &controllers.ManagerController{}, "post:AttachClean")
@@ -181,18 +199,58 @@ func init() {
 	web.Router("/manager/itemsets", &controllers.ManagerController{}, "*:Itemsets")
 	web.Router("/manager/itemsets/edit", &controllers.ManagerController{}, "post:ItemsetsEdit")
 	web.Router("/manager/itemsets/delete", &controllers.ManagerController{}, "post:ItemsetsDelete")
+	web.Router("/manager/itemsets/:id/fields", &controllers.ManagerController{}, "get:ItemsetsFields")
+	web.Router("/manager/itemsets/:id/fields/save", &controllers.ManagerController{}, "post:ItemsetsFieldSave")
+	web.Router("/manager/itemsets/:id/fields/delete", &controllers.ManagerController{}, "post:ItemsetsFieldDelete")
 
 	web.Router("/setting", &controllers.SettingController{}, "*:Index")
 	web.Router("/setting/password", &controllers.SettingController{}, "*:Password")
 	web.Router("/setting/upload", &controllers.SettingController{}, "*:Upload")
+	web.Router("/setting/2fa", &controllers.SettingController{}, "get:TwoFactor")
+	web.Router("/setting/2fa/qrcode.png", &controllers.SettingController{}, "get:TwoFactorQrCode")
+	web.Router("/setting/2fa/enable", &controllers.SettingController{}, "post:TwoFactorEnable")
+	web.Router("/setting/2fa/disable", &controllers.SettingController{}, "post:TwoFactorDisable")
+	web.Router("/setting/2fa/recovery-codes", &controllers.SettingController{}, "post:TwoFactorRecoveryCodes")
+	web.Router("/setting/login-history", &controllers.SettingController{}, "get:LoginHistory")
+	web.Router("/setting/sessions", &controllers.SettingController{}, "get:Sessions")
+	web.Router("/setting/sessions/delete", &controllers.SettingController{}, "post:DeleteSession")
+	web.Router("/setting/sessions/delete-others", &controllers.SettingController{}, "post:DeleteOtherSessions")
+	web.Router("/setting/stale-documents", &controllers.SettingController{}, "get:StaleDocuments")
+
+	web.Router("/notifications", &controllers.NotificationController{}, "get:Index")
+	web.Router("/notifications/read", &controllers.NotificationController{}, "post:Read")
+	web.Router("/notifications/:id:int", &controllers.NotificationController{}, "get:Open")
+	web.Router("/api/notifications", &controllers.NotificationController{}, "get:List")
+	web.Router("/api/notifications/unread", &controllers.NotificationController{}, "get:Unread")
+
+	web.Router("/setting/subscriptions", &controllers.SubscriptionController{}, "get:Index")
+	web.Router("/api/subscription", &controllers.SubscriptionController{}, "get:Status")
+	web.Router("/api/subscription/save", &controllers.SubscriptionController{}, "post:Save")
+	web.Router("/api/subscription/delete", &controllers.SubscriptionController{}, "post:Delete")
+	web.Router("/subscription/unsubscribe/:token", &controllers.SubscriptionController{}, "get,post:Unsubscribe")
 
 	web.Router("/book", &controllers.BookController{}, "*:Index")
 	web.Router("/book/:key/dashboard", &controllers.BookController{}, "*:Dashboard")
 	web.Router("/book/:key/setting", &controllers.BookController{}, "*:Setting")
 	web.Router("/book/:key/users", &controllers.BookController{}, "*:Users")
 	web.Router("/book/:key/release", &controllers.BookController{}, "post:Release")
+	web.Router("/book/:key/links/check", &controllers.BookController{}, "post:CheckLinks")
 	web.Router("/book/:key/sort", &controllers.BookController{}, "post:SaveSort")
 	web.Router("/book/:key/teams", &controllers.BookController{}, "*:Team")
+	web.Router("/book/:key/recycle", &controllers.BookController{}, "get:Recycle")
+	web.Router("/book/:key/recycle/restore", &controllers.BookController{}, "post:RecycleRestore")
+	web.Router("/book/:key/recycle/purge", &controllers.BookController{}, "post:RecyclePurge")
+	web.Router("/book/:key/fields", &controllers.BookController{}, "get:Fields")
+	web.Router("/book/:key/fields/save", &controllers.BookController{}, "post:FieldSave")
+	web.Router("/book/:key/fields/delete", &controllers.BookController{}, "post:FieldDelete")
+	web.Router("/book/:key/comments", &controllers.BookController{}, "get:Comments")
+	web.Router("/book/:key/comments/moderate", &controllers.BookController{}, "post:CommentModerate")
+	web.Router("/book/:key/robots", &controllers.BookController{}, "get:Robots")
+	web.Router("/book/:key/robots/save", &controllers.BookController{}, "post:RobotSave")
+	web.Router("/book/:key/robots/delete", &controllers.BookController{}, "post:RobotDelete")
+	web.Router("/book/:key/robots/test", &controllers.BookController{}, "post:RobotTest")
+	web.Router("/book/:key/analytics", &controllers.BookController{}, "get:Analytics")
+	web.Router("/book/:key/analytics/export", &controllers.BookController{}, "get:AnalyticsExport")
 	web.Router("/book/updatebookorder", &controllers.BookController{}, "post:UpdateBookOrder")
 
 	web.Router("/book/create", &controllers.BookController{}, "*:Create")
@@ -226,6 +284,7 @@ func init() {
 	web.Router("/blogs", &controllers.BlogController{}, "*:List")
 	web.Router("/blog-attach/:id:int/:attach_id:int", &controllers.BlogController{}, "get:Download")
 	web.Router("/blog-:id([0-9]+).html", &controllers.BlogController{}, "*:Index")
+	web.Router("/blog/:key", &controllers.BlogController{}, "*:Index")
 
 	//模板相关接口
 	web.Router("/api/template/get", &controllers.TemplateController{}, "get:Get")
@@ -238,6 +297,9 @@ func init() {
 	web.Router("/api/upload", &controllers.DocumentController{}, "post:Upload")
 	web.Router("/api/:key/create", &controllers.DocumentController{}, "post:Create")
 	web.Router("/api/:key/delete", &controllers.DocumentController{}, "post:Delete")
+	web.Router("/api/:key/transfer", &controllers.DocumentController{}, "get:TransferTargets;post:Transfer")
+	web.Router("/api/:key/references", &controllers.DocumentController{}, "get:References")
+	web.Router("/api/:key/fields", &controllers.DocumentController{}, "get,post:Fields")
 	web.Router("/api/:key/content/?:id", &controllers.DocumentController{}, "*:Content")
 	web.Router("/api/:key/compare/:id", &controllers.DocumentController{}, "*:Compare")
 	web.Router("/api/search/user/:key", &controllers.SearchController{}, "*:User")
@@ -250,6 +312,10 @@ func init() {
 	web.Router("/docs/:key/check-password", &controllers.DocumentController{}, "post:CheckPassword")
 	web.Router("/docs/:key/:id", &controllers.DocumentController{}, "*:Read")
 	web.Router("/docs/:key/search", &controllers.DocumentController{}, "post:Search")
+	web.Router("/docs/:key/view", &controllers.DocumentController{}, "post:RecordView")
+	web.Router("/docs/:key/leave", &controllers.DocumentController{}, "post:LeaveDocument")
+	web.Router("/docs/:key/progress", &controllers.DocumentController{}, "post:SaveProgress")
+	web.Router("/docs/:key/mark-read", &controllers.DocumentController{}, "post:MarkBookRead")
 	web.Router("/export/:key", &controllers.DocumentController{}, "*:Export")
 	web.Router("/qrcode/:key.png", &controllers.DocumentController{}, "get:QrCode")
 
@@ -257,6 +323,7 @@ func init() {
 
 	web.Router("/comment/create", &controllers.CommentController{}, "post:Create")
 	web.Router("/comment/delete", &controllers.CommentController{}, "post:Delete")
+	web.Router("/comment/resolve", &controllers.CommentController{}, "post:Resolve")
 	web.Router("/comment/lists", &controllers.CommentController{}, "get:Lists")
 	web.Router("/comment/index", &controllers.CommentController{}, "*:Index")
 
@@ -268,4 +335,10 @@ func init() {
 	web.Router("/items", &controllers.ItemsetsController{}, "get:Index")
 	web.Router("/items/:key", &controllers.ItemsetsController{}, "get:List")
 
+	//SCIM 2.0 用户同步接口
+	web.Router("/scim/v2/Users", &controllers.ScimController{}, "get:Users;post:CreateUser")
+	web.Router("/scim/v2/Users/:id", &controllers.ScimController{}, "get:User;put:ReplaceUser;patch:PatchUser;delete:DeleteUser")
+	web.Router("/scim/v2/Groups", &controllers.ScimController{}, "get:Groups;post:CreateGroup")
+	web.Router("/scim/v2/Groups/:id", &controllers.ScimController{}, "get:Group;put:ReplaceGroup;patch:PatchGroup;delete:DeleteGroup")
+
 }
@@ -149,6 +149,7 @@ function openDeleteDocumentDialog($node) {
         'zh-CN': {
             saveSortSucc: '保存排序成功',
             confirmDeleteDoc: '你确定要删除该文档吗？',
+            confirmDeleteReferenced: '以下 %d 篇文档引用了该文档或其子文档，删除后这些链接将会失效：',
             confirm: '确定',
             cancel: '取消',
             deleteFailed: '删除失败',
@@ -157,6 +158,7 @@ function openDeleteDocumentDialog($node) {
         'en': {
             saveSortSucc: 'Save sort success',
             confirmDeleteDoc: 'Are you sure you want to delete this document?',
+            confirmDeleteReferenced: 'The following %d documents link to this document or its children, these links will be broken after deletion:',
             confirm: 'Confirm',
             cancel: 'Cancel',
             deleteFailed: 'Delete Failed',
@@ -164,29 +166,48 @@ function openDeleteDocumentDialog($node) {
         }
     }
     langs = locales[lang];
-    var index = layer.confirm(langs.confirmDeleteDoc, {
-        btn: [langs.confirm, langs.cancel] //按钮
-    }, function () {
-
-        $.post(window.deleteURL, {"identify": window.book.identify, "doc_id": $node.id}).done(function (res) {
-            layer.close(index);
-            if (res.errcode === 0) {
-                window.treeCatalog.delete_node($node);
-                window.documentCategory.remove(function (item) {
-                    return item.id == $node.id;
-                });
+    var confirmDelete = function (message) {
+        var index = layer.confirm(message, {
+            btn: [langs.confirm, langs.cancel] //按钮
+        }, function () {
+
+            $.post(window.deleteURL, {"identify": window.book.identify, "doc_id": $node.id}).done(function (res) {
+                layer.close(index);
+                if (res.errcode === 0) {
+                    window.treeCatalog.delete_node($node);
+                    window.documentCategory.remove(function (item) {
+                        return item.id == $node.id;
+                    });
 
 
-                // console.log(window.documentCategory)
-                setLastSelectNode();
-            } else {
+                    // console.log(window.documentCategory)
+                    setLastSelectNode();
+                } else {
+                    layer.msg(lang.deleteFailed, {icon: 2})
+                }
+            }).fail(function () {
+                layer.close(index);
                 layer.msg(lang.deleteFailed, {icon: 2})
-            }
-        }).fail(function () {
-            layer.close(index);
-            layer.msg(lang.deleteFailed, {icon: 2})
-        });
+            });
 
+        });
+    };
+    if (!window.referencesURL) {
+        confirmDelete(langs.confirmDeleteDoc);
+        return;
+    }
+    $.get(window.referencesURL, {"doc_id": $node.id}).done(function (res) {
+        if (res.errcode === 0 && res.data && res.data.length > 0) {
+            var items = $.map(res.data, function (item) {
+                var name = item.book_identify === window.book.identify ? item.doc_name : item.doc_name + " - " + item.book_name;
+                return "<li>" + $("<span>").text(name).html() + "</li>";
+            });
+            confirmDelete(langs.confirmDeleteReferenced.replace("%d", res.data.length) + "<ul style=\"margin: 10px 0 10px 20px;\">" + items.join("") + "</ul>" + langs.confirmDeleteDoc);
+        } else {
+            confirmDelete(langs.confirmDeleteDoc);
+        }
+    }).fail(function () {
+        confirmDelete(langs.confirmDeleteDoc);
     });
 }
 
@@ -222,6 +243,107 @@ function openEditCatalogDialog($node) {
     $then.modal({show: true});
 }
 
+/**
+ * 移动或复制文档到其他项目
+ * @param $node
+ */
+function openTransferDocumentDialog($node) {
+    var $then = $("#transferDocumentModal");
+    var $target = $then.find("select[name='target']");
+
+    $then.find("input[name='doc_id']").val($node.id);
+    $then.find("input[name='action'][value='move']").prop("checked", true);
+    $then.find("input[name='with_history']").prop("checked", false);
+    $("#transfer-error-message").text("");
+    $target.empty();
+
+    $.get(window.transferURL).done(function (res) {
+        if (res.errcode !== 0) {
+            layer.msg(res.message);
+            return;
+        }
+        $.each(res.data, function (i, book) {
+            $("<option>").val(book.identify).text(book.book_name).appendTo($target);
+        });
+        $target.val(window.book.identify).trigger("change");
+        $then.modal("show");
+    });
+}
+
+/**
+ * 编辑文档的自定义字段
+ * @param $node
+ */
+function openDocumentFieldsDialog($node) {
+    var $then = $("#documentFieldsModal");
+    var $form = $("#documentFieldsForm");
+    var $body = $then.find(".fields-body").empty();
+
+    $form.find("input[name='doc_id']").val($node.id);
+    $("#fields-error-message").text("");
+
+    $.get($form.attr("action"), {"doc_id": $node.id}).done(function (res) {
+        if (res.errcode !== 0) {
+            layer.msg(res.message);
+            return;
+        }
+        var readonly = res.data.front_matter;
+
+        $.each(res.data.fields, function (i, field) {
+            var $group = $("<div class='form-group'></div>").appendTo($body);
+            var $col = $("<div class='col-sm-9'></div>");
+            var $input;
+
+            $("<label class='col-sm-3 control-label'></label>").text(field.field_title).appendTo($group);
+            $col.appendTo($group);
+
+            if (field.field_type === "select") {
+                $input = $("<select class='form-control'></select>").append("<option value=''></option>");
+                $.each(field.options || [], function (j, option) {
+                    $("<option>").val(option).text(option).appendTo($input);
+                });
+            } else if (field.field_type === "date") {
+                $input = $("<input type='date' class='form-control'>");
+            } else if (field.field_type === "number") {
+                $input = $("<input type='number' step='any' class='form-control'>");
+            } else {
+                $input = $("<input type='text' class='form-control' maxlength='500'>");
+            }
+            $input.attr("name", "field_" + field.field_name).val(field.value).prop("disabled", readonly).appendTo($col);
+        });
+        if (res.data.fields.length === 0) {
+            $("<p class='text-center'></p>").text($body.data("empty")).appendTo($body);
+        }
+        $then.find(".fields-front-matter").toggle(readonly);
+        $("#btnSaveDocumentFields").prop("disabled", readonly || res.data.fields.length === 0);
+        $then.modal("show");
+    });
+}
+
+/**
+ * 加载目标项目的文档作为可选的父文档
+ */
+function loadTransferParents(identify) {
+    var $parent = $("#transferDocumentModal").find("select[name='parent_id']");
+    var $root = $parent.find("option[value='0']").detach();
+    $parent.empty().append($root);
+
+    $.get(window.transferURL, {"target": identify}).done(function (res) {
+        if (res.errcode !== 0) {
+            return;
+        }
+        var appendNodes = function (parentId, depth) {
+            $.each(res.data, function (i, item) {
+                if (Number(item.parent === "#" ? 0 : item.parent) === parentId) {
+                    $("<option>").val(item.id).text(new Array(depth + 1).join("\u3000") + item.text).appendTo($parent);
+                    appendNodes(item.id, depth + 1);
+                }
+            });
+        };
+        appendNodes(0, 1);
+    });
+}
+
 /**
  * 将一个节点推送到现有数组中
  * @param $node
@@ -315,6 +437,51 @@ $("#addDocumentModal").on("hidden.bs.modal", function () {
     window.sessionStorage.setItem("MinDoc::addDocumentModal", $(this).find("form").html())
 });
 
+$("#transferDocumentModal").on("change", "select[name='target']", function () {
+    loadTransferParents($(this).val());
+});
+$("#transferDocumentForm").on("submit", function (e) {
+    e.preventDefault();
+    var $then = $(this);
+    var $btn = $("#btnTransferDocument");
+    var docId = $then.find("input[name='doc_id']").val();
+    var target = $then.find("select[name='target']").val();
+    var isMove = $then.find("input[name='action']:checked").val() === "move";
+
+    $btn.button("loading");
+    $.post($then.attr("action"), $then.serialize()).done(function (res) {
+        $btn.button("reset");
+        if (res.errcode !== 0) {
+            showError(res.message, "#transfer-error-message");
+            return;
+        }
+        $("#transferDocumentModal").modal("hide");
+        if (isMove || target === window.book.identify) {
+            window.location.reload();
+        }
+    }).fail(function () {
+        $btn.button("reset");
+    });
+});
+
+$("#documentFieldsForm").on("submit", function (e) {
+    e.preventDefault();
+    var $then = $(this);
+    var $btn = $("#btnSaveDocumentFields");
+
+    $btn.button("loading");
+    $.post($then.attr("action"), $then.serialize()).done(function (res) {
+        $btn.button("reset");
+        if (res.errcode !== 0) {
+            showError(res.message, "#fields-error-message");
+            return;
+        }
+        $("#documentFieldsModal").modal("hide");
+    }).fail(function () {
+        $btn.button("reset");
+    });
+});
+
 function showError($msg, $id) {
     if (!$id) {
         $id = "#form-error-message"
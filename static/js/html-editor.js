@@ -101,6 +101,8 @@ $(function () {
             addDoc: '添加文档',
             edit: '编辑',
             delete: '删除',
+            moveOrCopy: '移动/复制',
+            documentFields: '文档属性',
             loadFailed: '加载失败请重试',
             tplNameEmpty: '模板名称不能为空',
             tplContentEmpty: '模板内容不能为空',
@@ -125,6 +127,8 @@ $(function () {
             addDoc: 'Add Document',
             edit: 'Edit',
             delete: 'Delete',
+            moveOrCopy: 'Move / Copy',
+            documentFields: 'Properties',
             loadFailed: 'Failed to load, please try again',
             tplNameEmpty: 'Template name cannot be empty',
             tplContentEmpty: 'Template content cannot be empty',
@@ -356,6 +360,30 @@ $(function () {
                         var node = inst.get_node(data.reference);
                         openDeleteDocumentDialog(node);
                     }
+                },
+                "移动": {
+                    "separator_before": false,
+                    "separator_after": false,
+                    "_disabled": false,
+                    "label": window.editormdLocales[window.lang].moveOrCopy,
+                    "icon": "fa fa-share",
+                    "action": function (data) {
+                        var inst = $.jstree.reference(data.reference);
+                        var node = inst.get_node(data.reference);
+                        openTransferDocumentDialog(node);
+                    }
+                },
+                "属性": {
+                    "separator_before": false,
+                    "separator_after": true,
+                    "_disabled": false,
+                    "label": window.editormdLocales[window.lang].documentFields,
+                    "icon": "fa fa-tags",
+                    "action": function (data) {
+                        var inst = $.jstree.reference(data.reference);
+                        var node = inst.get_node(data.reference);
+                        openDocumentFieldsDialog(node);
+                    }
                 }
             }
         }
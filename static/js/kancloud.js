@@ -81,35 +81,62 @@ function renderOperateSection(comment) {
     const deleteIcon = comment.show_del == 1
         ? `<i class="delete e-delete glyphicon glyphicon-remove" onclick="onDelComment(${comment.comment_id})"></i>`
         : '';
+    const number = comment.parent_id > 0 ? '' : `<span class="number">${comment.index}#</span>`;
+    const resolve = comment.show_resolve
+        ? `<a class="reply" href="javascript:;" onclick="onResolveComment(${comment.comment_id}, ${comment.resolved == 1 ? 'false' : 'true'})">${comment.resolved == 1 ? '重新打开' : '解决'}</a>`
+        : '';
 
     return `
+        <a class="reply" href="javascript:;" onclick="onReplyComment(${comment.comment_id}, this)">回复</a>
+        ${resolve}
         <span class="operate ${comment.show_del == 1 ? 'toggle' : ''}">
-            <span class="number">${comment.index}#</span>
+            ${number}
             ${deleteIcon}
         </span>`;
 }
 
-// 加载评论
-function loadComment($page, $docid) {
-    $("#commentList").empty();
-    let html = ""
-    let c = $page.List;
-    for (let i = 0; c && i < c.length; i++) {
-        const comment = c[i];
-        html += `
-        <div class="comment-item" data-id="${comment.comment_id}">
+// 渲染评论及其回复
+function renderComment(comment) {
+    let replies = "";
+    for (let i = 0; comment.replies && i < comment.replies.length; i++) {
+        replies += renderComment(comment.replies[i]);
+    }
+    const replyTo = comment.reply_to ? `<span class="reply-to">回复 ${comment.reply_to}</span>` : '';
+    let quote = "";
+    if (comment.anchor_text) {
+        const status = comment.orphaned == 1
+            ? '<span class="label label-default">原文已修改</span>'
+            : (comment.resolved == 1 ? '<span class="label label-success">已解决</span>' : '');
+        quote = `<blockquote class="comment-quote" data-id="${comment.comment_id}">${status}${escapeHtml(comment.anchor_text)}</blockquote>`;
+    }
+
+    return `
+        <div class="comment-item" data-id="${comment.comment_id}" data-author="${comment.author}" data-account="${comment.account}">
             <p class="info">
                 <img src="${comment.avatar}" alt="">
                 <a class="name">${comment.author}</a>
+                ${replyTo}
                 <span class="date">${timeFormat(comment.comment_date)}</span>
             </p>
-            <div class="content">${comment.content}</div>
+            ${quote}
+            <div class="content">${comment.html_content}</div>
             <p class="util">
                 ${renderOperateSection(comment)}
             </p>
+            ${replies ? `<div class="comment-replies">${replies}</div>` : ''}
         </div>`;
+}
+
+// 加载评论
+function loadComment($page, $docid) {
+    $("#commentList").empty().data("page", $page.PageNo);
+    let html = ""
+    let c = $page.List;
+    for (let i = 0; c && i < c.length; i++) {
+        html += renderComment(c[i]);
     }
     $("#commentList").append(html);
+    highlightCommentAnchors(c);
     if ($page.TotalPage > 1) {
         $("#page").bootstrapPaginator({
             currentPage: $page.PageNo,
@@ -125,6 +152,223 @@ function loadComment($page, $docid) {
     }
 }
 
+// 回复评论
+function onReplyComment($id, $el) {
+    cancelAnchorComment();
+    const $item = $($el).closest(".comment-item");
+    const account = $item.data("account");
+    $("#commentParentId").val($id);
+    $("#commentReplyTo").show().find(".name").text($item.data("author"));
+    const $content = $("#commentContent");
+    if (account && $content.val().indexOf("@" + account) < 0) {
+        $content.val("@" + account + " " + $content.val());
+    }
+    $content.focus();
+}
+
+// 取消回复
+function cancelReplyComment() {
+    $("#commentParentId").val(0);
+    $("#commentReplyTo").hide().find(".name").text("");
+}
+
+// 行内评论引用的上下文长度，与服务端一致
+const COMMENT_ANCHOR_CONTEXT = 32;
+
+function escapeHtml(text) {
+    return $("<div>").text(text).html();
+}
+
+// 合并连续的空白字符，与服务端提取文档文字的方式一致
+function collapseSpace(text) {
+    return text.replace(/\s+/g, " ");
+}
+
+// 提取文档内容的文字，返回规范化后的文字及每个字符对应的文本节点位置
+function anchorTextMap(root) {
+    let text = "";
+    const positions = [];
+    let space = false;
+    const walker = document.createTreeWalker(root, NodeFilter.SHOW_TEXT, {
+        acceptNode: function (node) {
+            return $(node).closest("script,style", root).length ? NodeFilter.FILTER_REJECT : NodeFilter.FILTER_ACCEPT;
+        }
+    });
+    for (let node = walker.nextNode(); node; node = walker.nextNode()) {
+        const data = node.data;
+        for (let i = 0; i < data.length; i++) {
+            if (/\s/.test(data[i])) {
+                space = text.length > 0;
+                continue;
+            }
+            if (space) {
+                text += " ";
+                positions.push({ node: node, offset: i });
+                space = false;
+            }
+            text += data[i];
+            positions.push({ node: node, offset: i });
+        }
+    }
+    return { text: text, positions: positions };
+}
+
+// 查找引用的原文在文档中的位置，存在多处时选择上下文最接近的一处
+function findCommentAnchor(text, comment) {
+    let best = -1, bestScore = -1;
+    for (let start = text.indexOf(comment.anchor_text); start >= 0; start = text.indexOf(comment.anchor_text, start + 1)) {
+        const prefix = comment.anchor_prefix || "", suffix = comment.anchor_suffix || "";
+        const end = start + comment.anchor_text.length;
+        let score = 0;
+        while (score < prefix.length && text[start - score - 1] === prefix[prefix.length - score - 1]) {
+            score++;
+        }
+        for (let i = 0; i < suffix.length && text[end + i] === suffix[i]; i++) {
+            score++;
+        }
+        if (score > bestScore) {
+            best = start;
+            bestScore = score;
+        }
+    }
+    return best;
+}
+
+// 高亮未解决的行内评论引用的原文
+function highlightCommentAnchors(comments) {
+    const root = document.getElementById("page-content");
+    if (!root) {
+        return;
+    }
+    $(root).find("mark.comment-anchor").contents().unwrap();
+    root.normalize();
+
+    for (let i = 0; comments && i < comments.length; i++) {
+        const comment = comments[i];
+        if (!comment.anchor_text || comment.orphaned == 1 || comment.resolved == 1) {
+            continue;
+        }
+        const map = anchorTextMap(root);
+        const start = findCommentAnchor(map.text, comment);
+        if (start < 0) {
+            continue;
+        }
+        // 原文可能跨越多个文本节点，分别高亮每个节点中的部分
+        const segments = [];
+        for (let j = start; j < start + comment.anchor_text.length; j++) {
+            const position = map.positions[j];
+            const last = segments[segments.length - 1];
+            if (last && last.node === position.node) {
+                last.end = position.offset + 1;
+            } else {
+                segments.push({ node: position.node, start: position.offset, end: position.offset + 1 });
+            }
+        }
+        for (let j = segments.length - 1; j >= 0; j--) {
+            const range = document.createRange();
+            range.setStart(segments[j].node, segments[j].start);
+            range.setEnd(segments[j].node, segments[j].end);
+            const mark = document.createElement("mark");
+            mark.className = "comment-anchor";
+            mark.setAttribute("data-id", comment.comment_id);
+            range.surroundContents(mark);
+        }
+    }
+}
+
+// 滚动到指定元素
+function scrollToElement($el) {
+    const $container = $(".manual-right");
+    $container.animate({ scrollTop: $el.offset().top - $container.offset().top + $container.scrollTop() - 80 }, 200);
+}
+
+// 获取选中的文字及其上下文和所在章节的标题
+function getSelectionAnchor() {
+    const root = document.getElementById("page-content");
+    const selection = window.getSelection();
+    if (!root || !selection || selection.rangeCount === 0 || selection.isCollapsed) {
+        return null;
+    }
+    const range = selection.getRangeAt(0);
+    if (!root.contains(range.commonAncestorContainer)) {
+        return null;
+    }
+    let exact = collapseSpace(range.toString());
+    if ($.trim(exact) === "") {
+        return null;
+    }
+    const before = document.createRange();
+    before.setStart(root, 0);
+    before.setEnd(range.startContainer, range.startOffset);
+    const after = document.createRange();
+    after.setStart(range.endContainer, range.endOffset);
+    after.setEnd(root, root.childNodes.length);
+
+    let prefix = collapseSpace(before.toString()).replace(/^ /, "");
+    let suffix = collapseSpace(after.toString());
+    if (exact[0] === " ") {
+        exact = exact.substring(1);
+        prefix = prefix && !/ $/.test(prefix) ? prefix + " " : prefix;
+    }
+    if (exact[exact.length - 1] === " ") {
+        exact = exact.substring(0, exact.length - 1);
+        suffix = " " + suffix;
+    }
+    suffix = collapseSpace(suffix).replace(/ $/, "");
+
+    let heading = "";
+    $(root).find("h1,h2,h3,h4,h5,h6").each(function () {
+        if (this.contains(range.startContainer) || this.compareDocumentPosition(range.startContainer) & Node.DOCUMENT_POSITION_FOLLOWING) {
+            heading = $.trim(collapseSpace($(this).text()));
+        }
+    });
+    return {
+        text: exact,
+        prefix: prefix.substring(prefix.length - COMMENT_ANCHOR_CONTEXT),
+        suffix: suffix.substring(0, COMMENT_ANCHOR_CONTEXT),
+        heading: heading
+    };
+}
+
+// 评论选中的文字
+function onAnchorComment($anchor) {
+    cancelReplyComment();
+    $("#commentAnchorText").val($anchor.text);
+    $("#commentAnchorPrefix").val($anchor.prefix);
+    $("#commentAnchorSuffix").val($anchor.suffix);
+    $("#commentAnchorHeading").val($anchor.heading);
+    $("#commentAnchor").show().find(".comment-quote").text($anchor.text);
+    $("#articleComment").removeClass('not-show-comment');
+    scrollToElement($("#commentForm"));
+    $("#commentContent").focus();
+}
+
+// 取消评论选中的文字
+function cancelAnchorComment() {
+    $("#commentAnchorText,#commentAnchorPrefix,#commentAnchorSuffix,#commentAnchorHeading").val("");
+    $("#commentAnchor").hide().find(".comment-quote").text("");
+}
+
+// 解决或重新打开行内评论
+function onResolveComment($id, $resolved) {
+    $.ajax({
+        url: "/comment/resolve",
+        data: { "id": $id, "resolved": $resolved },
+        type: "POST",
+        success: function ($res) {
+            if ($res.errcode == 0) {
+                layer.msg($resolved ? "已解决" : "已重新打开");
+                pageClicked($("#commentList").data("page") || -1, $res.data.document_id);
+            } else {
+                layer.msg($res.message);
+            }
+        },
+        error: function () {
+            layer.msg("操作失败");
+        }
+    });
+}
+
 // 删除评论
 function onDelComment($id) {
     $.ajax({
@@ -145,14 +389,153 @@ function onDelComment($id) {
     });
 }
 
+// 加载当前用户对项目和文档的订阅
+function loadSubscription() {
+    var $subscription = $("#subscription");
+    if ($subscription.length === 0) {
+        return;
+    }
+    $.get($subscription.data("url"), { "identify": window.book.identify, "doc_id": $subscription.data("doc") }, function (res) {
+        if (res.errcode !== 0) {
+            return;
+        }
+        var $menu = $subscription.find(".subscription-menu");
+        $menu.find("li").removeClass("checked");
+        $menu.find('a[data-scope="document"][data-frequency="' + res.data.document + '"]').parent().addClass("checked");
+        $menu.find('a[data-scope="book"][data-frequency="' + res.data.book + '"]').parent().addClass("checked");
+        $subscription.toggleClass("subscribed", res.data.document !== "" || res.data.book !== "");
+    }, "json");
+}
+
+// 订阅或取消订阅项目和文档
+function onSubscribe($link) {
+    var $subscription = $("#subscription");
+    var frequency = $link.data("frequency");
+    var docId = $link.data("scope") === "document" ? $subscription.data("doc") : 0;
+
+    $.post(frequency ? $subscription.data("save") : $subscription.data("delete"), {
+        "identify": window.book.identify,
+        "doc_id": docId,
+        "frequency": frequency
+    }, function (res) {
+        if (res.errcode === 0) {
+            loadSubscription();
+        } else {
+            layer.msg(res.message);
+        }
+    }, "json");
+}
+
+/**
+ * 离开当前文档时上报阅读时长
+ * @param $exit 是否离开了项目
+ */
+function leaveDocument($exit) {
+    var tracker = window.viewTracker;
+    if (!tracker || !tracker.id || !navigator.sendBeacon) {
+        return;
+    }
+    var data = new FormData();
+    data.append("view_key", tracker.id);
+    data.append("duration", Math.round((Date.now() - tracker.start) / 1000));
+    data.append("exit", $exit ? 1 : 0);
+    navigator.sendBeacon(window.book.leave_url, data);
+    tracker.id = 0;
+}
+
+/**
+ * 开始记录新打开文档的阅读时长
+ * @param $viewKey
+ */
+function trackDocumentView($viewKey) {
+    leaveDocument(false);
+    window.viewTracker = { id: $viewKey || "", start: Date.now() };
+}
+
+/**
+ * 从缓存打开文档时记录一次阅读
+ * @param $docId
+ */
+function recordCachedView($docId) {
+    $.post(window.book.view_url, { "doc_id": $docId }, function ($res) {
+        if ($res.errcode === 0 && window.book.doc_id == $docId) {
+            window.viewTracker.id = $res.data.view_key;
+            $("#view_count").text("阅读次数：" + $res.data.view_count);
+        }
+    }, "json");
+}
+
+/**
+ * 保存登录用户在当前文档中的滚动位置
+ * @param $beacon 页面关闭时使用 sendBeacon 发送
+ */
+function saveReadingProgress($beacon) {
+    clearTimeout(window.progressTimer);
+    if (!window.book || !window.book.progress || !window.book.doc_id) {
+        return;
+    }
+    var $right = $(".manual-right");
+    var max = $right[0].scrollHeight - $right.innerHeight();
+    var ratio = max > 0 ? Math.min(1, $right.scrollTop() / max) : 0;
+    var url = window.book.progress_url;
+
+    if ($beacon && navigator.sendBeacon) {
+        var data = new FormData();
+        data.append("doc_id", window.book.doc_id);
+        data.append("ratio", ratio.toFixed(4));
+        navigator.sendBeacon(url, data);
+    } else {
+        $.post(url, { "doc_id": window.book.doc_id, "ratio": ratio.toFixed(4) });
+    }
+}
+
+/**
+ * 去掉目录中文档的未读标记
+ * @param $id
+ */
+function markDocumentRead($id) {
+    $("#" + $id + "_anchor").removeAttr("data-unread");
+    if (window.jsTree) {
+        var node = window.jsTree.jstree(true).get_node($id);
+        if (node && node.a_attr) {
+            delete node.a_attr["data-unread"];
+        }
+    }
+}
+
+// 将项目中的全部文档标记为已读
+function markBookRead($btn) {
+    $btn.button("loading");
+    $.post($btn.data("url"), function ($res) {
+        if ($res.errcode === 0) {
+            $("#sidebar").find("[data-unread]").removeAttr("data-unread");
+            if (window.jsTree) {
+                $.each(window.jsTree.jstree(true).get_json("#", { flat: true }), function (i, item) {
+                    markDocumentRead(item.id);
+                });
+            }
+        }
+        layer.msg($res.message);
+    }, "json").always(function () {
+        $btn.button("reset");
+    });
+}
+
 // 重新渲染页面
 function renderPage($data) {
+    trackDocumentView($data.view_key);
+    clearTimeout(window.progressTimer);
+    window.book.doc_id = $data.doc_id;
+    markDocumentRead($data.doc_id);
+    $(".continue-reading").remove();
     $("#page-content").html($data.body);
     $("title").text($data.title);
     $("#article-title").text($data.doc_title);
     $("#article-info").text($data.doc_info);
     $("#view_count").text("阅读次数：" + $data.view_count);
     $("#doc_id").val($data.doc_id);
+    $("#subscription").data("doc", $data.doc_id);
+    loadSubscription();
     checkMarkdownTocElement();
     if ($data.page) {
         loadComment($data.page, $data.doc_id);
@@ -189,6 +572,7 @@ function loadDocument($url, $id, $callback) {
                     return true;
                 }
                 renderPage(data);
+                recordCachedView(data.doc_id);
 
                 loadCopySnippets();
                 events.trigger('article.open', { $url: $url, $id: $id });
@@ -207,6 +591,8 @@ function loadDocument($url, $id, $callback) {
                 }
                 renderPage(data);
                 loadCopySnippets();
+                // 阅读记录只使用一次，从缓存打开时重新记录
+                data.view_key = "";
                 events.data($id, data);
                 events.trigger('article.open', { $url: $url, $id: $id });
             } else if ($res.errcode === 6000) {
@@ -258,11 +644,36 @@ function handleEvent(event) {
 $(function () {
     window.addEventListener('keydown', handleEvent)
 
+    window.viewTracker = { id: window.book ? window.book.view_key : "", start: Date.now() };
+    // 刷新页面或在项目内跳转时服务端会取消离开标记
+    window.addEventListener('pagehide', function () {
+        leaveDocument(true);
+        saveReadingProgress(true);
+    });
+    // 从继续阅读打开时恢复上次的滚动位置
+    if (window.book && window.book.scroll_ratio > 0) {
+        $(window).on("load", function () {
+            var $right = $(".manual-right");
+            $right.scrollTop(($right[0].scrollHeight - $right.innerHeight()) * window.book.scroll_ratio);
+        });
+    }
+    $("#markBookRead").on("click", function () {
+        markBookRead($(this));
+    });
+
     checkMarkdownTocElement();
+    loadSubscription();
+    $("#subscription").on("click", "a[data-scope]", function () {
+        onSubscribe($(this));
+    });
     $(".view-backtop").on("click", function () {
         $('.manual-right').animate({ scrollTop: '0px' }, 200);
     });
     $(".manual-right").scroll(function () {
+        clearTimeout(window.progressTimer);
+        window.progressTimer = setTimeout(function () {
+            saveReadingProgress(false);
+        }, 2000);
         try {
             var top = $(".manual-right").scrollTop();
             if (top > 100) {
@@ -425,20 +836,65 @@ $(function () {
             $("#btnSubmitComment").button("loading");
         },
         success: function (res) {
-            if (res.errcode === 0) {
-                layer.msg("保存成功");
-            } else {
+            $("#btnSubmitComment").button("reset");
+            if (res.errcode !== 0) {
                 layer.msg(res.message);
+                return;
+            }
+            if (res.data.approved === 0) {
+                layer.msg("评论已提交，审核通过后将对其他人显示");
+            } else if (res.data.approved === 2) {
+                layer.msg("评论已提交，等待管理员审核");
+            } else {
+                layer.msg("保存成功");
             }
-            $("#btnSubmitComment").button("reset");
             $("#commentContent").val("");
-            pageClicked(-1, res.data.doc_id); // -1 表示请求最后一页
+            // 回复显示在所属评论下，新评论显示在最后一页
+            const page = $("#commentParentId").val() > 0 ? $("#commentList").data("page") || -1 : -1;
+            cancelReplyComment();
+            cancelAnchorComment();
+            pageClicked(page, res.data.doc_id); // -1 表示请求最后一页
         },
         error: function () {
             layer.msg("服务错误");
             $("#btnSubmitComment").button("reset");
         }
     });
+
+    // 选中文档中的文字后显示评论按钮
+    if (window.IS_DISPLAY_COMMENT && $("#commentForm").length) {
+        const $button = $('<a class="comment-anchor-button" href="javascript:;"><i class="fa fa-comment-o"></i> 评论</a>').hide().appendTo("body");
+        $(document).on("mouseup", function (e) {
+            if ($(e.target).closest(".comment-anchor-button").length) {
+                return;
+            }
+            setTimeout(function () {
+                const anchor = getSelectionAnchor();
+                if (!anchor) {
+                    $button.hide();
+                    return;
+                }
+                $button.data("anchor", anchor).css({ top: e.pageY + 10, left: e.pageX }).show();
+            }, 0);
+        });
+        $button.on("click", function () {
+            $button.hide();
+            onAnchorComment($button.data("anchor"));
+            window.getSelection().removeAllRanges();
+        });
+        $("#page-content").on("click", "mark.comment-anchor", function () {
+            const $item = $("#commentList .comment-item[data-id=" + $(this).data("id") + "]");
+            if ($item.length) {
+                scrollToElement($item);
+            }
+        });
+        $("#commentList").on("click", ".comment-quote", function () {
+            const $mark = $("#page-content mark.comment-anchor[data-id=" + $(this).data("id") + "]").first();
+            if ($mark.length) {
+                scrollToElement($mark);
+            }
+        });
+    }
     loadCopySnippets();
 });
 
@@ -0,0 +1,104 @@
+// Package batchcounter 在内存中累加计数，按间隔批量写入，避免每次计数都写数据库
+package batchcounter
+
+import (
+	"sync"
+	"time"
+)
+
+// FlushFunc 批量写入计数，deltas 为每个 id 自上次写入以来的增量.
+// 返回错误时这批增量会保留，下次写入时重试.
+type FlushFunc func(deltas map[int]int) error
+
+// Counter 缓冲计数器，可以被多个 goroutine 同时使用
+type Counter struct {
+	// Interval 自动写入的间隔
+	Interval time.Duration
+	// MaxPending 缓冲的 id 数量达到该值时提前写入，为 0 时不限制
+	MaxPending int
+
+	flush FlushFunc
+
+	mu     sync.Mutex
+	deltas map[int]int
+
+	// flushMu 保证同一时间只有一个写入
+	flushMu sync.Mutex
+
+	writer *Writer
+}
+
+// NewCounter 缓冲计数器构造函数
+func NewCounter(interval time.Duration, maxPending int, flush FlushFunc) *Counter {
+	c := &Counter{
+		Interval:   interval,
+		MaxPending: maxPending,
+		flush:      flush,
+		deltas:     make(map[int]int),
+	}
+	c.writer = NewWriter(interval, c.Flush)
+	return c
+}
+
+// Incr 计数加一.
+func (c *Counter) Incr(id int) {
+	c.Add(id, 1)
+}
+
+// Add 累加计数，只在内存中记录.
+func (c *Counter) Add(id, delta int) {
+	if delta == 0 {
+		return
+	}
+	c.mu.Lock()
+	c.deltas[id] += delta
+	full := c.MaxPending > 0 && len(c.deltas) >= c.MaxPending
+	c.mu.Unlock()
+
+	if full {
+		c.writer.Notify()
+	}
+}
+
+// Pending 获取尚未写入的增量.
+func (c *Counter) Pending(id int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deltas[id]
+}
+
+// Flush 立即写入缓冲的计数，写入失败时将增量放回缓冲区.
+func (c *Counter) Flush() error {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	c.mu.Lock()
+	if len(c.deltas) == 0 {
+		c.mu.Unlock()
+		return nil
+	}
+	deltas := c.deltas
+	c.deltas = make(map[int]int, len(deltas))
+	c.mu.Unlock()
+
+	err := c.flush(deltas)
+	if err != nil {
+		c.mu.Lock()
+		for id, delta := range deltas {
+			c.deltas[id] += delta
+		}
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// Start 启动后台写入，onError 用于记录写入失败，可以为 nil.
+func (c *Counter) Start(onError func(error)) {
+	c.writer.Interval = c.Interval
+	c.writer.Start(onError)
+}
+
+// Stop 停止后台写入并写入剩余的计数.
+func (c *Counter) Stop() error {
+	return c.writer.Stop()
+}
@@ -0,0 +1,188 @@
+package batchcounter
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder 记录写入的计数
+type recorder struct {
+	mu      sync.Mutex
+	totals  map[int]int
+	flushes int
+	fail    bool
+}
+
+func newRecorder() *recorder {
+	return &recorder{totals: make(map[int]int)}
+}
+
+func (r *recorder) flush(deltas map[int]int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail {
+		return errors.New("database is locked")
+	}
+	r.flushes++
+	for id, delta := range deltas {
+		r.totals[id] += delta
+	}
+	return nil
+}
+
+func (r *recorder) total(id int) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.totals[id]
+}
+
+// waitFor 等待条件成立，超时后测试失败
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("等待超时")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCounterConcurrentReaders(t *testing.T) {
+	r := newRecorder()
+	c := NewCounter(time.Millisecond, 3, r.flush)
+	c.Start(func(err error) { t.Error(err) })
+
+	const readers = 50
+	const views = 1000
+	const docs = 7
+
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < views; j++ {
+				c.Incr((i + j) % docs)
+			}
+		}(i)
+	}
+	// 读者计数的同时手动写入，与后台写入并发执行
+	stop := make(chan struct{})
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				if err := c.Flush(); err != nil {
+					t.Error(err)
+				}
+			}
+		}
+	}()
+	wg.Wait()
+	close(stop)
+	<-flushed
+
+	if err := c.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	sum := 0
+	for id := 0; id < docs; id++ {
+		sum += r.total(id)
+		if pending := c.Pending(id); pending != 0 {
+			t.Errorf("文档 %d 还有 %d 次未写入", id, pending)
+		}
+	}
+	if sum != readers*views {
+		t.Fatalf("写入的总数为 %d，应为 %d", sum, readers*views)
+	}
+	// 每个读者对每个文档的计数是确定的
+	for id := 0; id < docs; id++ {
+		expected := 0
+		for i := 0; i < readers; i++ {
+			for j := 0; j < views; j++ {
+				if (i+j)%docs == id {
+					expected++
+				}
+			}
+		}
+		if got := r.total(id); got != expected {
+			t.Errorf("文档 %d 写入 %d 次，应为 %d 次", id, got, expected)
+		}
+	}
+}
+
+func TestCounterFlushErrorKeepsDeltas(t *testing.T) {
+	r := newRecorder()
+	c := NewCounter(time.Hour, 0, r.flush)
+
+	c.Add(1, 3)
+	r.fail = true
+	if err := c.Flush(); err == nil {
+		t.Fatal("写入失败时应返回错误")
+	}
+	c.Incr(1)
+	if pending := c.Pending(1); pending != 4 {
+		t.Fatalf("写入失败后未写入的计数为 %d，应为 4", pending)
+	}
+
+	r.fail = false
+	if err := c.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.total(1); got != 4 {
+		t.Fatalf("写入 %d 次，应为 4 次", got)
+	}
+	if pending := c.Pending(1); pending != 0 {
+		t.Fatalf("写入成功后仍有 %d 次未写入", pending)
+	}
+}
+
+func TestCounterFlushOnInterval(t *testing.T) {
+	r := newRecorder()
+	c := NewCounter(10*time.Millisecond, 0, r.flush)
+	c.Start(nil)
+	defer c.Stop()
+
+	c.Incr(5)
+	waitFor(t, func() bool { return r.total(5) == 1 })
+}
+
+func TestCounterFlushOnMaxPending(t *testing.T) {
+	r := newRecorder()
+	c := NewCounter(time.Hour, 3, r.flush)
+	c.Start(nil)
+	defer c.Stop()
+
+	c.Incr(1)
+	c.Incr(2)
+	c.Incr(3)
+	waitFor(t, func() bool { return r.total(1)+r.total(2)+r.total(3) == 3 })
+}
+
+func TestCounterStopFlushes(t *testing.T) {
+	r := newRecorder()
+	c := NewCounter(time.Hour, 0, r.flush)
+
+	// 没有启动时停止也会写入剩余的计数
+	c.Incr(9)
+	c.Incr(9)
+	if err := c.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.total(9); got != 2 {
+		t.Fatalf("停止时写入 %d 次，应为 2 次", got)
+	}
+	if err := c.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if r.flushes != 1 {
+		t.Fatalf("没有计数时不应写入，实际写入 %d 次", r.flushes)
+	}
+}
@@ -0,0 +1,74 @@
+package batchcounter
+
+import (
+	"sync"
+	"time"
+)
+
+// Writer 在后台按间隔调用 flush 批量写入，缓冲的数据由调用方保存.
+// Counter 和其他需要批量写入的缓冲区共用它的启动和停止方式.
+type Writer struct {
+	// Interval 自动写入的间隔
+	Interval time.Duration
+
+	flush func() error
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	signal    chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// NewWriter 批量写入构造函数
+func NewWriter(interval time.Duration, flush func() error) *Writer {
+	return &Writer{
+		Interval: interval,
+		flush:    flush,
+		signal:   make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start 启动后台写入，onError 用于记录写入失败，可以为 nil.
+func (w *Writer) Start(onError func(error)) {
+	w.startOnce.Do(func() {
+		go func() {
+			defer close(w.done)
+			ticker := time.NewTicker(w.Interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+				case <-w.signal:
+				case <-w.stop:
+					return
+				}
+				if err := w.flush(); err != nil && onError != nil {
+					onError(err)
+				}
+			}
+		}()
+	})
+}
+
+// Notify 缓冲达到上限时通知后台提前写入.
+func (w *Writer) Notify() {
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+// Stop 停止后台写入并写入剩余的数据.
+func (w *Writer) Stop() error {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+		// 没有启动时不需要等待
+		w.startOnce.Do(func() { close(w.done) })
+		<-w.done
+	})
+	return w.flush()
+}
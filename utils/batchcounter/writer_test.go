@@ -0,0 +1,38 @@
+package batchcounter
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriterNotifyAndStop(t *testing.T) {
+	var mu sync.Mutex
+	flushes := 0
+	w := NewWriter(time.Hour, func() error {
+		mu.Lock()
+		defer mu.Unlock()
+		flushes++
+		return nil
+	})
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return flushes
+	}
+	w.Start(nil)
+
+	w.Notify()
+	waitFor(t, func() bool { return count() == 1 })
+
+	// 停止时再写入一次剩余的数据，重复停止也会写入
+	if err := w.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if got := count(); got != 2 {
+		t.Fatalf("停止后写入 %d 次，应为 2 次", got)
+	}
+	if err := w.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
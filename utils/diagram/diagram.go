@@ -0,0 +1,207 @@
+// Package diagram 使用本地命令将 Mermaid、PlantUML、Graphviz 等图表源码渲染为图片.
+package diagram
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	FormatSVG = "svg"
+	FormatPNG = "png"
+)
+
+// 默认的渲染超时时间.
+const defaultTimeout = 30 * time.Second
+
+var (
+	ErrEmptyCommand      = errors.New("diagram: empty render command")
+	ErrUnsupportedFormat = errors.New("diagram: unsupported output format")
+	ErrEmptyOutput       = errors.New("diagram: renderer returned empty output")
+	ErrUnsafeSource      = errors.New("diagram: source references external files")
+)
+
+// Renderer 将图表源码渲染为指定格式的图片.
+type Renderer interface {
+	// Render 渲染图表源码并返回图片内容.
+	Render(ctx context.Context, source []byte) ([]byte, error)
+	// Format 返回生成图片的格式，例如 svg 或 png.
+	Format() string
+}
+
+var (
+	locker    sync.RWMutex
+	renderers = make(map[string]Renderer)
+)
+
+// Register 注册代码块语言对应的渲染器，renderer 为 nil 时移除已注册的渲染器.
+func Register(lang string, renderer Renderer) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	locker.Lock()
+	defer locker.Unlock()
+
+	if renderer == nil {
+		delete(renderers, lang)
+		return
+	}
+	renderers[lang] = renderer
+}
+
+// Lookup 查找代码块语言对应的渲染器.
+func Lookup(lang string) (Renderer, bool) {
+	locker.RLock()
+	defer locker.RUnlock()
+
+	renderer, ok := renderers[strings.ToLower(strings.TrimSpace(lang))]
+	return renderer, ok
+}
+
+// Enabled 判断是否注册了任意渲染器.
+func Enabled() bool {
+	locker.RLock()
+	defer locker.RUnlock()
+
+	return len(renderers) > 0
+}
+
+// CommandRenderer 调用本地命令渲染图表.
+//
+// 参数中的 {input} 和 {output} 会被替换为临时文件路径；没有 {input} 时通过标准输入传入源码，
+// 没有 {output} 时从标准输出读取图片，例如：
+//
+//	dot -Tsvg
+//	plantuml -tsvg -pipe
+//	mmdc -i {input} -o {output}
+type CommandRenderer struct {
+	Name    string
+	Args    []string
+	Output  string
+	Timeout time.Duration
+	// Env 追加到渲染命令的环境变量，例如 PLANTUML_SECURITY_PROFILE=SANDBOX.
+	Env []string
+	// Check 渲染前检查源码，返回错误时不会执行渲染命令.
+	Check func(source []byte) error
+}
+
+// NewCommandRenderer 根据命令行创建渲染器，参数以空白分隔.
+func NewCommandRenderer(command, format string) (*CommandRenderer, error) {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil, ErrEmptyCommand
+	}
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" {
+		format = FormatSVG
+	}
+	if format != FormatSVG && format != FormatPNG {
+		return nil, ErrUnsupportedFormat
+	}
+	return &CommandRenderer{
+		Name:    fields[0],
+		Args:    fields[1:],
+		Output:  format,
+		Timeout: defaultTimeout,
+	}, nil
+}
+
+func (r *CommandRenderer) Format() string {
+	return r.Output
+}
+
+func (r *CommandRenderer) Render(ctx context.Context, source []byte) ([]byte, error) {
+	if r.Check != nil {
+		if err := r.Check(source); err != nil {
+			return nil, err
+		}
+	}
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	tempDir, err := os.MkdirTemp("", "mindoc-diagram-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	input := filepath.Join(tempDir, "input")
+	output := filepath.Join(tempDir, "output."+r.Output)
+	useInput, useOutput := false, false
+
+	args := make([]string, len(r.Args))
+	for i, arg := range r.Args {
+		if strings.Contains(arg, "{input}") {
+			useInput = true
+			arg = strings.ReplaceAll(arg, "{input}", input)
+		}
+		if strings.Contains(arg, "{output}") {
+			useOutput = true
+			arg = strings.ReplaceAll(arg, "{output}", output)
+		}
+		args[i] = arg
+	}
+	cmd := exec.CommandContext(ctx, r.Name, args...)
+	cmd.Dir = tempDir
+	if len(r.Env) > 0 {
+		cmd.Env = append(os.Environ(), r.Env...)
+	}
+
+	if useInput {
+		if err := os.WriteFile(input, source, 0644); err != nil {
+			return nil, err
+		}
+	} else {
+		cmd.Stdin = bytes.NewReader(source)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return nil, fmt.Errorf("diagram: %s: %v: %s", r.Name, err, strings.TrimSpace(stderr.String()))
+	}
+	body := stdout.Bytes()
+	if useOutput {
+		if body, err = os.ReadFile(output); err != nil {
+			return nil, err
+		}
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, ErrEmptyOutput
+	}
+	return body, nil
+}
+
+var (
+	plantumlUnsafe = regexp.MustCompile(`(?im)^[ \t]*!(include|import)|%(load_json|getenv)[ \t]*\(`)
+	graphvizUnsafe = regexp.MustCompile(`(?i)["']?\b(image|imagepath|shapefile|fontpath|(edge|head|label|tail)?(url|href))["']?[ \t\r\n]*=`)
+)
+
+// CheckPlantUML 拒绝引用本地文件、网络地址或环境变量的 PlantUML 源码，例如 !include 和 !includeurl.
+func CheckPlantUML(source []byte) error {
+	if plantumlUnsafe.Match(source) {
+		return ErrUnsafeSource
+	}
+	return nil
+}
+
+// CheckGraphviz 拒绝通过 image、shapefile 等属性读取本地文件，或通过 URL、href 等属性在 SVG 中插入链接的 Graphviz 源码.
+func CheckGraphviz(source []byte) error {
+	if graphvizUnsafe.Match(source) {
+		return ErrUnsafeSource
+	}
+	return nil
+}
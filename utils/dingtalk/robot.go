@@ -0,0 +1,104 @@
+package dingtalk
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Robot 钉钉自定义群机器人
+// doc: https://open.dingtalk.com/document/robots/custom-robot-access
+type Robot struct {
+	// Webhook 机器人的 Webhook 地址，包含 access_token 参数
+	Webhook string
+	// Secret 加签密钥，为空时不签名
+	Secret string
+	Client *http.Client
+}
+
+// NewRobot 钉钉群机器人构造函数
+func NewRobot(webhook, secret string) *Robot {
+	return &Robot{
+		Webhook: webhook,
+		Secret:  secret,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// robotResponse 群机器人接口的返回数据
+type robotResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// SendMarkdown 发送 Markdown 消息，title 为会话列表中显示的标题
+func (r *Robot) SendMarkdown(title, text string) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"title": title,
+			"text":  text,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	webhook, err := r.signedURL(time.Now())
+	if err != nil {
+		return err
+	}
+	resp, err := r.Client.Post(webhook, "application/json;charset=utf-8", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("钉钉机器人请求失败: %s", resp.Status)
+	}
+	var result robotResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return err
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("钉钉机器人发送失败: %d, %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
+
+// signedURL 设置了加签密钥时在 Webhook 地址中加入时间戳和签名
+func (r *Robot) signedURL(now time.Time) (string, error) {
+	endpoint, err := url.Parse(r.Webhook)
+	if err != nil {
+		return "", err
+	}
+	if r.Secret == "" {
+		return endpoint.String(), nil
+	}
+	timestamp := strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10)
+
+	query := endpoint.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", RobotSign(timestamp, r.Secret))
+	endpoint.RawQuery = query.Encode()
+	return endpoint.String(), nil
+}
+
+// RobotSign 计算群机器人的签名，签名内容为毫秒时间戳加换行符加密钥
+func RobotSign(timestamp, secret string) string {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp + "\n" + secret))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
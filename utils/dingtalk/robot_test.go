@@ -0,0 +1,64 @@
+package dingtalk
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newRobotStub 模拟钉钉群机器人接口，校验签名并记录收到的消息
+func newRobotStub(t *testing.T, secret string, messages *[]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("access_token") != "test" {
+			_, _ = w.Write([]byte(`{"errcode":300001,"errmsg":"token is not exist"}`))
+			return
+		}
+		if secret != "" && query.Get("sign") != RobotSign(query.Get("timestamp"), secret) {
+			_, _ = w.Write([]byte(`{"errcode":310000,"errmsg":"sign not match"}`))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var message map[string]interface{}
+		if err := json.Unmarshal(body, &message); err != nil {
+			t.Errorf("消息格式错误: %s", body)
+		}
+		*messages = append(*messages, message)
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+}
+
+func TestRobotSendMarkdown(t *testing.T) {
+	var messages []map[string]interface{}
+	server := newRobotStub(t, "SECtest", &messages)
+	defer server.Close()
+
+	if err := NewRobot(server.URL+"/robot/send?access_token=test", "SECtest").SendMarkdown("标题", "#### 内容"); err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("收到 %d 条消息", len(messages))
+	}
+	markdown, _ := messages[0]["markdown"].(map[string]interface{})
+	if messages[0]["msgtype"] != "markdown" || markdown["title"] != "标题" || markdown["text"] != "#### 内容" {
+		t.Errorf("消息内容错误: %v", messages[0])
+	}
+}
+
+func TestRobotSendMarkdownError(t *testing.T) {
+	var messages []map[string]interface{}
+	server := newRobotStub(t, "SECtest", &messages)
+	defer server.Close()
+
+	if err := NewRobot(server.URL+"/robot/send?access_token=test", "SECwrong").SendMarkdown("标题", "内容"); err == nil {
+		t.Error("签名错误时应返回错误")
+	}
+	if err := NewRobot(server.URL+"/robot/send?access_token=wrong", "SECtest").SendMarkdown("标题", "内容"); err == nil {
+		t.Error("access_token 错误时应返回错误")
+	}
+	if len(messages) != 0 {
+		t.Errorf("不应收到消息: %v", messages)
+	}
+}
@@ -0,0 +1,552 @@
+// Package mathml 将常用的 LaTeX 数学公式转换为 MathML，用于无法执行脚本的导出格式.
+package mathml
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"unicode"
+)
+
+var (
+	ErrUnbalancedBraces = errors.New("mathml: unbalanced braces")
+	ErrMissingArgument  = errors.New("mathml: missing argument")
+	ErrUnknownEnv       = errors.New("mathml: unsupported environment")
+)
+
+// Convert 将 LaTeX 公式转换为 MathML，display 为 true 时生成块级公式.
+func Convert(tex string, display bool) (string, error) {
+	p := &parser{tokens: tokenize(tex), display: display}
+
+	nodes, err := p.parseRow(stopEnd)
+	if err != nil {
+		return "", err
+	}
+	if p.pos < len(p.tokens) {
+		return "", ErrUnbalancedBraces
+	}
+	mode := "inline"
+	if display {
+		mode = "block"
+	}
+	var buf strings.Builder
+	buf.WriteString(`<math xmlns="http://www.w3.org/1998/Math/MathML" display="` + mode + `"><semantics>`)
+	buf.WriteString(row(nodes))
+	buf.WriteString(`<annotation encoding="application/x-tex">` + html.EscapeString(strings.TrimSpace(tex)) + `</annotation>`)
+	buf.WriteString(`</semantics></math>`)
+	return buf.String(), nil
+}
+
+type tokenKind int
+
+const (
+	tokenCommand tokenKind = iota
+	tokenLetter
+	tokenNumber
+	tokenSymbol
+	tokenOpen
+	tokenClose
+	tokenSup
+	tokenSub
+	tokenAlign
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	// space 表示源码中该标记前有空白，用于还原文字中的空格.
+	space bool
+}
+
+func tokenize(tex string) []token {
+	var tokens []token
+	runes := []rune(tex)
+	space := false
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		start := len(tokens)
+		switch {
+		case r == '\\':
+			if i+1 >= len(runes) {
+				tokens = append(tokens, token{kind: tokenSymbol, value: "\\"})
+				continue
+			}
+			j := i + 1
+			if unicode.IsLetter(runes[j]) {
+				for j < len(runes) && unicode.IsLetter(runes[j]) {
+					j++
+				}
+			} else {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenCommand, value: string(runes[i+1 : j])})
+			i = j - 1
+		case r == '{':
+			tokens = append(tokens, token{kind: tokenOpen, value: "{"})
+		case r == '}':
+			tokens = append(tokens, token{kind: tokenClose, value: "}"})
+		case r == '^':
+			tokens = append(tokens, token{kind: tokenSup, value: "^"})
+		case r == '_':
+			tokens = append(tokens, token{kind: tokenSub, value: "_"})
+		case r == '&':
+			tokens = append(tokens, token{kind: tokenAlign, value: "&"})
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			j := i
+			for j < len(runes) && (unicode.IsDigit(runes[j]) || runes[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, value: string(runes[i:j])})
+			i = j - 1
+		case unicode.IsLetter(r):
+			tokens = append(tokens, token{kind: tokenLetter, value: string(r)})
+		default:
+			tokens = append(tokens, token{kind: tokenSymbol, value: string(r)})
+		}
+		if len(tokens) > start {
+			tokens[start].space = space
+		}
+		space = false
+	}
+	return tokens
+}
+
+// stop 表示当前行结束的位置.
+type stop int
+
+const (
+	stopEnd stop = iota
+	stopBrace
+	stopRight
+	stopCell
+	stopBracket
+)
+
+type parser struct {
+	tokens  []token
+	pos     int
+	display bool
+}
+
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// parseRow 解析到结束位置之前的全部节点.
+func (p *parser) parseRow(until stop) ([]string, error) {
+	var nodes []string
+	for {
+		t, ok := p.peek()
+		if !ok {
+			if until == stopEnd || until == stopCell {
+				return nodes, nil
+			}
+			return nil, ErrUnbalancedBraces
+		}
+		switch {
+		case t.kind == tokenClose:
+			if until == stopBrace {
+				p.pos++
+				return nodes, nil
+			}
+			if until == stopCell {
+				return nodes, nil
+			}
+			return nil, ErrUnbalancedBraces
+		case t.kind == tokenSymbol && t.value == "]" && until == stopBracket:
+			p.pos++
+			return nodes, nil
+		case t.kind == tokenCommand && t.value == "right":
+			if until == stopRight {
+				p.pos++
+				return nodes, nil
+			}
+			if until == stopCell {
+				return nodes, nil
+			}
+			return nil, ErrUnbalancedBraces
+		case until == stopCell && (t.kind == tokenAlign || (t.kind == tokenCommand && (t.value == "\\" || t.value == "end"))):
+			return nodes, nil
+		case t.kind == tokenAlign || (t.kind == tokenCommand && t.value == "\\"):
+			// 环境之外的换行和对齐符号直接忽略
+			p.pos++
+			continue
+		}
+		node, err := p.parseScripted()
+		if err != nil {
+			return nil, err
+		}
+		if node != "" {
+			nodes = append(nodes, node)
+		}
+	}
+}
+
+// parseScripted 解析一个节点及其上下标.
+func (p *parser) parseScripted() (string, error) {
+	t, _ := p.peek()
+	base, err := p.parseAtom()
+	if err != nil {
+		return "", err
+	}
+	var sub, sup string
+	for {
+		next, ok := p.peek()
+		if !ok || (next.kind != tokenSub && next.kind != tokenSup) {
+			break
+		}
+		p.pos++
+		arg, err := p.parseArgument()
+		if err != nil {
+			return "", err
+		}
+		if next.kind == tokenSub {
+			sub = arg
+		} else {
+			sup = arg
+		}
+	}
+	if sub == "" && sup == "" {
+		return base, nil
+	}
+	if base == "" {
+		base = "<mrow></mrow>"
+	}
+	under := p.display && t.kind == tokenCommand && largeOperators[t.value] != "" && !integrals[t.value]
+	if t.kind == tokenCommand && limitFunctions[t.value] {
+		under = true
+	}
+	switch {
+	case sub != "" && sup != "":
+		if under {
+			return "<munderover>" + base + sub + sup + "</munderover>", nil
+		}
+		return "<msubsup>" + base + sub + sup + "</msubsup>", nil
+	case sub != "":
+		if under {
+			return "<munder>" + base + sub + "</munder>", nil
+		}
+		return "<msub>" + base + sub + "</msub>", nil
+	default:
+		if under {
+			return "<mover>" + base + sup + "</mover>", nil
+		}
+		return "<msup>" + base + sup + "</msup>", nil
+	}
+}
+
+// parseArgument 解析命令或上下标的参数，参数为一组花括号或单个节点.
+func (p *parser) parseArgument() (string, error) {
+	t, ok := p.peek()
+	if !ok {
+		return "", ErrMissingArgument
+	}
+	if t.kind == tokenOpen {
+		p.pos++
+		nodes, err := p.parseRow(stopBrace)
+		if err != nil {
+			return "", err
+		}
+		return row(nodes), nil
+	}
+	if t.kind == tokenClose || t.kind == tokenSub || t.kind == tokenSup {
+		return "", ErrMissingArgument
+	}
+	// 没有花括号时参数只有一个字符，例如 \frac12 和 x^12
+	if t.kind == tokenNumber && len(t.value) > 1 {
+		p.tokens[p.pos].value = t.value[1:]
+		p.tokens[p.pos].space = false
+		return "<mn>" + t.value[:1] + "</mn>", nil
+	}
+	return p.parseAtom()
+}
+
+// parseRawArgument 读取花括号中的原始文字，用于 \text 等命令.
+func (p *parser) parseRawArgument() (string, error) {
+	t, ok := p.peek()
+	if !ok {
+		return "", ErrMissingArgument
+	}
+	if t.kind != tokenOpen {
+		p.pos++
+		return t.value, nil
+	}
+	p.pos++
+	depth := 1
+	var buf strings.Builder
+	for p.pos < len(p.tokens) {
+		t := p.tokens[p.pos]
+		p.pos++
+		switch t.kind {
+		case tokenOpen:
+			depth++
+		case tokenClose:
+			depth--
+			if depth == 0 {
+				return buf.String(), nil
+			}
+		}
+		switch {
+		case t.kind == tokenCommand && len(t.value) == 1 && !unicode.IsLetter(rune(t.value[0])):
+			if t.value == "," || t.value == ";" || t.value == " " {
+				buf.WriteString(" ")
+			} else {
+				buf.WriteString(t.value)
+			}
+		case t.kind == tokenCommand:
+			buf.WriteString("\\" + t.value)
+		case t.kind == tokenOpen || t.kind == tokenClose:
+		default:
+			if t.space && buf.Len() > 0 {
+				buf.WriteString(" ")
+			}
+			buf.WriteString(t.value)
+		}
+	}
+	return "", ErrUnbalancedBraces
+}
+
+func (p *parser) parseAtom() (string, error) {
+	t, ok := p.peek()
+	if !ok {
+		return "", ErrMissingArgument
+	}
+	p.pos++
+	switch t.kind {
+	case tokenOpen:
+		nodes, err := p.parseRow(stopBrace)
+		if err != nil {
+			return "", err
+		}
+		return row(nodes), nil
+	case tokenLetter:
+		return "<mi>" + html.EscapeString(t.value) + "</mi>", nil
+	case tokenNumber:
+		return "<mn>" + t.value + "</mn>", nil
+	case tokenSymbol:
+		if t.value == "'" {
+			return "<mo>′</mo>", nil
+		}
+		return "<mo>" + html.EscapeString(t.value) + "</mo>", nil
+	case tokenCommand:
+		return p.parseCommand(t.value)
+	}
+	return "", ErrUnbalancedBraces
+}
+
+func (p *parser) parseCommand(name string) (string, error) {
+	if s, ok := identifiers[name]; ok {
+		return "<mi>" + s + "</mi>", nil
+	}
+	if s, ok := operators[name]; ok {
+		return "<mo>" + html.EscapeString(s) + "</mo>", nil
+	}
+	if s, ok := largeOperators[name]; ok {
+		return "<mo largeop=\"true\">" + s + "</mo>", nil
+	}
+	if functions[name] || limitFunctions[name] {
+		return "<mi mathvariant=\"normal\">" + name + "</mi>", nil
+	}
+	if width, ok := spaces[name]; ok {
+		return "<mspace width=\"" + width + "\"></mspace>", nil
+	}
+	if accent, ok := accents[name]; ok {
+		arg, err := p.parseArgument()
+		if err != nil {
+			return "", err
+		}
+		return "<mover accent=\"true\">" + arg + "<mo>" + accent + "</mo></mover>", nil
+	}
+	if variant, ok := variants[name]; ok {
+		text, err := p.parseRawArgument()
+		if err != nil {
+			return "", err
+		}
+		return "<mi mathvariant=\"" + variant + "\">" + html.EscapeString(text) + "</mi>", nil
+	}
+	switch name {
+	case "frac", "dfrac", "tfrac", "cfrac":
+		num, err := p.parseArgument()
+		if err != nil {
+			return "", err
+		}
+		den, err := p.parseArgument()
+		if err != nil {
+			return "", err
+		}
+		return "<mfrac>" + num + den + "</mfrac>", nil
+	case "binom", "dbinom", "tbinom":
+		top, err := p.parseArgument()
+		if err != nil {
+			return "", err
+		}
+		bottom, err := p.parseArgument()
+		if err != nil {
+			return "", err
+		}
+		return "<mrow><mo>(</mo><mfrac linethickness=\"0\">" + top + bottom + "</mfrac><mo>)</mo></mrow>", nil
+	case "sqrt":
+		if t, ok := p.peek(); ok && t.kind == tokenSymbol && t.value == "[" {
+			p.pos++
+			index, err := p.parseRow(stopBracket)
+			if err != nil {
+				return "", err
+			}
+			arg, err := p.parseArgument()
+			if err != nil {
+				return "", err
+			}
+			return "<mroot>" + arg + row(index) + "</mroot>", nil
+		}
+		arg, err := p.parseArgument()
+		if err != nil {
+			return "", err
+		}
+		return "<msqrt>" + arg + "</msqrt>", nil
+	case "text", "textrm", "textnormal", "mbox", "textit", "textbf":
+		text, err := p.parseRawArgument()
+		if err != nil {
+			return "", err
+		}
+		return "<mtext>" + html.EscapeString(text) + "</mtext>", nil
+	case "operatorname":
+		text, err := p.parseRawArgument()
+		if err != nil {
+			return "", err
+		}
+		return "<mi mathvariant=\"normal\">" + html.EscapeString(text) + "</mi>", nil
+	case "overline", "underline":
+		arg, err := p.parseArgument()
+		if err != nil {
+			return "", err
+		}
+		if name == "overline" {
+			return "<mover accent=\"true\">" + arg + "<mo>&#x203E;</mo></mover>", nil
+		}
+		return "<munder accentunder=\"true\">" + arg + "<mo>_</mo></munder>", nil
+	case "overbrace", "underbrace":
+		arg, err := p.parseArgument()
+		if err != nil {
+			return "", err
+		}
+		if name == "overbrace" {
+			return "<mover>" + arg + "<mo>&#x23DE;</mo></mover>", nil
+		}
+		return "<munder>" + arg + "<mo>&#x23DF;</mo></munder>", nil
+	case "left":
+		open := p.delimiter()
+		nodes, err := p.parseRow(stopRight)
+		if err != nil {
+			return "", err
+		}
+		closing := p.delimiter()
+		return "<mrow>" + fence(open) + strings.Join(nodes, "") + fence(closing) + "</mrow>", nil
+	case "big", "Big", "bigg", "Bigg", "bigl", "bigr", "Bigl", "Bigr", "biggl", "biggr", "Biggl", "Biggr":
+		return fence(p.delimiter()), nil
+	case "begin":
+		env, err := p.parseRawArgument()
+		if err != nil {
+			return "", err
+		}
+		return p.parseEnvironment(env)
+	case "displaystyle", "textstyle", "limits", "nolimits":
+		return "", nil
+	}
+	return "<mi>" + html.EscapeString("\\"+name) + "</mi>", nil
+}
+
+// delimiter 读取 \left、\right 后的定界符.
+func (p *parser) delimiter() string {
+	t, ok := p.peek()
+	if !ok {
+		return ""
+	}
+	p.pos++
+	if t.kind == tokenCommand {
+		if s, ok := delimiters[t.value]; ok {
+			return s
+		}
+		return ""
+	}
+	if t.value == "." {
+		return ""
+	}
+	return t.value
+}
+
+// parseEnvironment 解析矩阵和分段函数等环境.
+func (p *parser) parseEnvironment(env string) (string, error) {
+	delims, ok := environments[env]
+	open, closing := delims[0], delims[1]
+	if !ok {
+		return "", ErrUnknownEnv
+	}
+	if env == "array" {
+		// 忽略列格式参数
+		if _, err := p.parseRawArgument(); err != nil {
+			return "", err
+		}
+	}
+	var rows []string
+	var cells []string
+	for {
+		nodes, err := p.parseRow(stopCell)
+		if err != nil {
+			return "", err
+		}
+		cells = append(cells, "<mtd>"+row(nodes)+"</mtd>")
+
+		t, ok := p.peek()
+		if !ok {
+			return "", ErrUnknownEnv
+		}
+		p.pos++
+		switch {
+		case t.kind == tokenAlign:
+			continue
+		case t.kind == tokenCommand && t.value == "\\":
+			rows = append(rows, "<mtr>"+strings.Join(cells, "")+"</mtr>")
+			cells = nil
+			continue
+		case t.kind == tokenCommand && t.value == "end":
+			if _, err := p.parseRawArgument(); err != nil {
+				return "", err
+			}
+			if len(cells) > 1 || cells[0] != "<mtd><mrow></mrow></mtd>" {
+				rows = append(rows, "<mtr>"+strings.Join(cells, "")+"</mtr>")
+			}
+			table := "<mtable>" + strings.Join(rows, "") + "</mtable>"
+			if env == "cases" {
+				table = "<mtable columnalign=\"left left\">" + strings.Join(rows, "") + "</mtable>"
+			}
+			if open == "" && closing == "" {
+				return table, nil
+			}
+			return "<mrow>" + fence(open) + table + fence(closing) + "</mrow>", nil
+		default:
+			return "", ErrUnbalancedBraces
+		}
+	}
+}
+
+func fence(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "<mo fence=\"true\" stretchy=\"true\">" + html.EscapeString(s) + "</mo>"
+}
+
+func row(nodes []string) string {
+	if len(nodes) == 1 {
+		return nodes[0]
+	}
+	return "<mrow>" + strings.Join(nodes, "") + "</mrow>"
+}
@@ -0,0 +1,97 @@
+package mathml
+
+import (
+	"strings"
+	"testing"
+)
+
+// body 返回公式转换结果中 semantics 内除注解以外的部分
+func body(t *testing.T, tex string, display bool) string {
+	t.Helper()
+	s, err := Convert(tex, display)
+	if err != nil {
+		t.Fatalf("转换 %q 失败: %v", tex, err)
+	}
+	start := strings.Index(s, "<semantics>") + len("<semantics>")
+	end := strings.Index(s, "<annotation")
+	if start < len("<semantics>") || end < start {
+		t.Fatalf("转换 %q 的结果格式错误: %s", tex, s)
+	}
+	return s[start:end]
+}
+
+func TestConvert(t *testing.T) {
+	cases := []struct {
+		name    string
+		tex     string
+		display bool
+		want    string
+	}{
+		{"分式", `\frac{a}{b}`, false, `<mfrac><mi>a</mi><mi>b</mi></mfrac>`},
+		{"嵌套分式", `\frac{1}{\frac{x}{2}}`, false, `<mfrac><mn>1</mn><mfrac><mi>x</mi><mn>2</mn></mfrac></mfrac>`},
+		{"单字符参数分式", `\frac12`, false, `<mfrac><mn>1</mn><mn>2</mn></mfrac>`},
+		{"平方根", `\sqrt{x+1}`, false, `<msqrt><mrow><mi>x</mi><mo>+</mo><mn>1</mn></mrow></msqrt>`},
+		{"n 次方根", `\sqrt[3]{x}`, false, `<mroot><mi>x</mi><mn>3</mn></mroot>`},
+		{"下标", `x_i`, false, `<msub><mi>x</mi><mi>i</mi></msub>`},
+		{"上标", `x^{2}`, false, `<msup><mi>x</mi><mn>2</mn></msup>`},
+		{"上下标", `x_i^2`, false, `<msubsup><mi>x</mi><mi>i</mi><mn>2</mn></msubsup>`},
+		{"上下标顺序无关", `x^2_i`, false, `<msubsup><mi>x</mi><mi>i</mi><mn>2</mn></msubsup>`},
+		{"行内求和", `\sum_{i=1}^n`, false, `<msubsup><mo largeop="true">∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></msubsup>`},
+		{"块级求和", `\sum_{i=1}^n`, true, `<munderover><mo largeop="true">∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></munderover>`},
+		{"矩阵", `\begin{pmatrix}a&b\\c&d\end{pmatrix}`, false,
+			`<mrow><mo fence="true" stretchy="true">(</mo><mtable><mtr><mtd><mi>a</mi></mtd><mtd><mi>b</mi></mtd></mtr><mtr><mtd><mi>c</mi></mtd><mtd><mi>d</mi></mtd></mtr></mtable><mo fence="true" stretchy="true">)</mo></mrow>`},
+		{"分段函数", `\begin{cases}1&x>0\\0&x\le0\end{cases}`, false,
+			`<mrow><mo fence="true" stretchy="true">{</mo><mtable columnalign="left left"><mtr><mtd><mn>1</mn></mtd><mtd><mrow><mi>x</mi><mo>&gt;</mo><mn>0</mn></mrow></mtd></mtr><mtr><mtd><mn>0</mn></mtd><mtd><mrow><mi>x</mi><mo>≤</mo><mn>0</mn></mrow></mtd></mtr></mtable></mrow>`},
+		{"末尾换行不产生空行", `\begin{matrix}a\\\end{matrix}`, false, `<mtable><mtr><mtd><mi>a</mi></mtd></mtr></mtable>`},
+		{"多位数上标只取一位", `x^12`, false, `<mrow><msup><mi>x</mi><mn>1</mn></msup><mn>2</mn></mrow>`},
+		{"文字", `\text{if x}`, false, `<mtext>if x</mtext>`},
+		{"转义特殊字符", `a<b`, false, `<mrow><mi>a</mi><mo>&lt;</mo><mi>b</mi></mrow>`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := body(t, c.tex, c.display); got != c.want {
+				t.Errorf("转换 %q\n得到 %s\n应为 %s", c.tex, got, c.want)
+			}
+		})
+	}
+}
+
+func TestConvertDisplayAndAnnotation(t *testing.T) {
+	s, err := Convert(` a<b `, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(s, `display="block"`) {
+		t.Errorf("块级公式应使用 display=\"block\": %s", s)
+	}
+	if !strings.Contains(s, `<annotation encoding="application/x-tex">a&lt;b</annotation>`) {
+		t.Errorf("注解中应保留转义后的原始公式: %s", s)
+	}
+	if s, _ := Convert(`x`, false); !strings.Contains(s, `display="inline"`) {
+		t.Errorf("行内公式应使用 display=\"inline\": %s", s)
+	}
+}
+
+func TestConvertMalformed(t *testing.T) {
+	cases := []struct {
+		name string
+		tex  string
+		err  error
+	}{
+		{"缺少右花括号", `\frac{a}{b`, ErrUnbalancedBraces},
+		{"多余的右花括号", `a}`, ErrUnbalancedBraces},
+		{"分式缺少参数", `\frac{a}`, ErrMissingArgument},
+		{"上标缺少参数", `x^`, ErrMissingArgument},
+		{"下标后紧跟右花括号", `{x_}`, ErrMissingArgument},
+		{"不支持的环境", `\begin{tikzpicture}a\end{tikzpicture}`, ErrUnknownEnv},
+		{"环境没有结束", `\begin{matrix}a&b`, ErrUnknownEnv},
+		{"缺少 right", `\left( x`, ErrUnbalancedBraces},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := Convert(c.tex, false); err != c.err {
+				t.Errorf("转换 %q 返回 %v，应为 %v", c.tex, err, c.err)
+			}
+		})
+	}
+}
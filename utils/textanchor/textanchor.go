@@ -0,0 +1,289 @@
+// Package textanchor 根据引用的文字及其上下文在文本中定位文字片段，文字被修改后使用近似匹配重新定位.
+package textanchor
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ContextLength 保存的上下文文字长度.
+const ContextLength = 32
+
+// DefaultThreshold 近似匹配时要求的最低相似度.
+const DefaultThreshold = 0.75
+
+// 使用上下文定位时要求的最短上下文长度.
+const minContextLength = 4
+
+// 引用文字的最大长度，超过时截断，避免近似匹配耗时过长.
+const maxExactLength = 500
+
+// Selector 引用的文字及其前后的上下文.
+type Selector struct {
+	Exact  string
+	Prefix string
+	Suffix string
+}
+
+// Match 定位结果，Start 和 End 为文字在规范化文本中的字符位置.
+type Match struct {
+	Selector
+	Start int
+	End   int
+	// Score 相似度，完全一致时为 1.
+	Score float64
+}
+
+// Normalize 合并连续的空白字符并去掉首尾空白，页面中的文字与服务端提取的文字空白可能不一致.
+func Normalize(text string) string {
+	var buf strings.Builder
+	space := false
+	for _, r := range strings.TrimSpace(text) {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			buf.WriteByte(' ')
+			space = false
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// NewSelector 根据文字在文本中的位置生成选择器，位置为规范化文本中的字符位置.
+func NewSelector(text []rune, start, end int) Selector {
+	prefixStart := start - ContextLength
+	if prefixStart < 0 {
+		prefixStart = 0
+	}
+	suffixEnd := end + ContextLength
+	if suffixEnd > len(text) {
+		suffixEnd = len(text)
+	}
+	return Selector{
+		Exact:  string(text[start:end]),
+		Prefix: string(text[prefixStart:start]),
+		Suffix: string(text[end:suffixEnd]),
+	}
+}
+
+// Find 在文本中定位选择器引用的文字.
+// 优先查找完全一致的文字，存在多处时选择上下文最接近的一处；找不到时使用近似匹配，相似度低于 threshold 时返回 false.
+func Find(text string, selector Selector, threshold float64) (Match, bool) {
+	content := []rune(Normalize(text))
+	exact := []rune(Normalize(selector.Exact))
+	if len(exact) > maxExactLength {
+		exact = exact[:maxExactLength]
+	}
+	if len(exact) == 0 || len(content) == 0 {
+		return Match{}, false
+	}
+	prefix := []rune(Normalize(selector.Prefix))
+	suffix := []rune(Normalize(selector.Suffix))
+
+	best := Match{Start: -1}
+	bestContext := -1.0
+
+	for _, start := range indexAll(content, exact) {
+		end := start + len(exact)
+		if score := contextScore(content, start, end, prefix, suffix); score > bestContext {
+			best = Match{Start: start, End: end, Score: 1}
+			bestContext = score
+		}
+	}
+	if best.Start < 0 {
+		maxDistance := int(float64(len(exact)) * (1 - threshold))
+		best.Score = threshold
+		for _, candidate := range approximate(content, exact, maxDistance) {
+			score := 1 - float64(candidate.distance)/float64(len(exact))
+			context := contextScore(content, candidate.start, candidate.end, prefix, suffix)
+			if score > best.Score || score == best.Score && (best.Start < 0 || context > bestContext) {
+				best = Match{Start: candidate.start, End: candidate.end, Score: score}
+				bestContext = context
+			}
+		}
+	}
+	// 引用的文字改动较多时，如果前后的上下文没有变化，则定位到上下文之间的文字
+	if best.Start < 0 {
+		if start, end, ok := between(content, prefix, suffix, len(exact)); ok {
+			distance := levenshtein(exact, content[start:end])
+			score := 1 - float64(distance)/float64(maxInt(len(exact), end-start))
+			if score >= threshold/2 {
+				best = Match{Start: start, End: end, Score: score}
+			}
+		}
+	}
+	if best.Start < 0 {
+		return Match{}, false
+	}
+	// 近似匹配的结果可能包含首尾的空格
+	for best.Start < best.End-1 && content[best.Start] == ' ' {
+		best.Start++
+	}
+	for best.End > best.Start+1 && content[best.End-1] == ' ' {
+		best.End--
+	}
+	best.Selector = NewSelector(content, best.Start, best.End)
+	return best, true
+}
+
+// indexAll 查找 pattern 在 text 中全部出现的位置.
+func indexAll(text, pattern []rune) []int {
+	var positions []int
+	for i := 0; i+len(pattern) <= len(text); i++ {
+		if text[i] != pattern[0] {
+			continue
+		}
+		matched := true
+		for j := 1; j < len(pattern); j++ {
+			if text[i+j] != pattern[j] {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			positions = append(positions, i)
+		}
+	}
+	return positions
+}
+
+type candidate struct {
+	start    int
+	end      int
+	distance int
+}
+
+// approximate 查找与 pattern 编辑距离最小且不超过 maxDistance 的子串.
+// 使用 Sellers 算法，子串可以从文本任意位置开始，时间复杂度为 O(len(text)*len(pattern)).
+func approximate(text, pattern []rune, maxDistance int) []candidate {
+	m := len(pattern)
+	distance := make([]int, m+1)
+	starts := make([]int, m+1)
+	next := make([]int, m+1)
+	nextStarts := make([]int, m+1)
+	for i := 0; i <= m; i++ {
+		distance[i] = i
+	}
+
+	var candidates []candidate
+	minDistance := maxDistance + 1
+
+	for j := 1; j <= len(text); j++ {
+		next[0], nextStarts[0] = 0, j
+		for i := 1; i <= m; i++ {
+			cost := 1
+			if pattern[i-1] == text[j-1] {
+				cost = 0
+			}
+			// 替换或匹配
+			value, start := distance[i-1]+cost, starts[i-1]
+			// 文本中多出的字符
+			if distance[i]+1 < value {
+				value, start = distance[i]+1, starts[i]
+			}
+			// 文本中缺少的字符
+			if next[i-1]+1 < value {
+				value, start = next[i-1]+1, nextStarts[i-1]
+			}
+			next[i], nextStarts[i] = value, start
+		}
+		distance, next = next, distance
+		starts, nextStarts = nextStarts, starts
+
+		if distance[m] < minDistance {
+			minDistance = distance[m]
+			candidates = candidates[:0]
+		}
+		if distance[m] == minDistance && distance[m] <= maxDistance {
+			start := starts[m]
+			// 同一起点只保留最先结束的匹配
+			if n := len(candidates); n > 0 && candidates[n-1].start == start {
+				continue
+			}
+			candidates = append(candidates, candidate{start: start, end: j, distance: distance[m]})
+		}
+	}
+	return candidates
+}
+
+// contextScore 计算匹配位置前后文字与保存的上下文的相似程度.
+func contextScore(text []rune, start, end int, prefix, suffix []rune) float64 {
+	score := 0.0
+	if len(prefix) > 0 {
+		n := 0
+		for n < len(prefix) && start-n-1 >= 0 && text[start-n-1] == prefix[len(prefix)-n-1] {
+			n++
+		}
+		score += float64(n) / float64(len(prefix))
+	}
+	if len(suffix) > 0 {
+		n := 0
+		for n < len(suffix) && end+n < len(text) && text[end+n] == suffix[n] {
+			n++
+		}
+		score += float64(n) / float64(len(suffix))
+	}
+	return score
+}
+
+// between 查找前后上下文都没有变化时两者之间的文字，文字长度不能超过原长度的两倍.
+func between(text, prefix, suffix []rune, length int) (int, int, bool) {
+	if len(prefix) < minContextLength || len(suffix) < minContextLength {
+		return 0, 0, false
+	}
+	if len(prefix) > ContextLength/4 {
+		prefix = prefix[len(prefix)-ContextLength/4:]
+	}
+	if len(suffix) > ContextLength/4 {
+		suffix = suffix[:ContextLength/4]
+	}
+	for _, p := range indexAll(text, prefix) {
+		start := p + len(prefix)
+		for _, s := range indexAll(text[start:], suffix) {
+			if s > length*2 {
+				break
+			}
+			if s > 0 {
+				return start, start + s, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// levenshtein 计算两段文字的编辑距离.
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
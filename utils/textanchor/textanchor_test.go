@@ -0,0 +1,119 @@
+package textanchor
+
+import "testing"
+
+// selectorOf 根据文字在文本中第 n 次出现的位置生成选择器
+func selectorOf(t *testing.T, text, exact string, n int) Selector {
+	t.Helper()
+	content := []rune(Normalize(text))
+	positions := indexAll(content, []rune(exact))
+	if len(positions) <= n {
+		t.Fatalf("文本中找不到第 %d 处 %q", n+1, exact)
+	}
+	start := positions[n]
+	return NewSelector(content, start, start+len([]rune(exact)))
+}
+
+func TestFindExact(t *testing.T) {
+	text := "MinDoc 是一款针对IT团队开发的简单好用的文档管理系统。\n\n  它可以用来储存日常接口文档、数据库字典和手册说明等文档。"
+	selector := selectorOf(t, text, "文档管理系统", 0)
+
+	match, ok := Find(text, selector, DefaultThreshold)
+	if !ok {
+		t.Fatal("应找到完全一致的文字")
+	}
+	if match.Score != 1 {
+		t.Errorf("完全一致时相似度为 %v，应为 1", match.Score)
+	}
+	content := []rune(Normalize(text))
+	if got := string(content[match.Start:match.End]); got != "文档管理系统" {
+		t.Errorf("定位到 %q，应为 %q", got, "文档管理系统")
+	}
+	if match.Exact != "文档管理系统" || match.Prefix != selector.Prefix || match.Suffix != selector.Suffix {
+		t.Errorf("返回的选择器 %+v 与原选择器 %+v 不一致", match.Selector, selector)
+	}
+}
+
+func TestFindAfterEdit(t *testing.T) {
+	original := "The quick brown fox jumps over the lazy dog near the river bank at dawn."
+	selector := selectorOf(t, original, "jumps over the lazy dog", 0)
+
+	// 引用的文字前插入了新内容
+	moved := "Yesterday morning, the quick brown fox jumps over the lazy dog near the river bank at dawn."
+	match, ok := Find(moved, selector, DefaultThreshold)
+	if !ok || match.Score != 1 || match.Exact != "jumps over the lazy dog" {
+		t.Fatalf("文字位置变化后应完全匹配，得到 %+v %v", match, ok)
+	}
+
+	// 引用的文字本身被小幅修改
+	edited := "The quick brown fox jumped over the lazy dog near the river bank at dawn."
+	match, ok = Find(edited, selector, DefaultThreshold)
+	if !ok {
+		t.Fatal("小幅修改后应能近似匹配")
+	}
+	if match.Score >= 1 || match.Score < DefaultThreshold {
+		t.Errorf("近似匹配的相似度为 %v", match.Score)
+	}
+	if match.Exact != "jumped over the lazy dog" {
+		t.Errorf("近似匹配定位到 %q", match.Exact)
+	}
+
+	// 文字改动较多但上下文不变时定位到上下文之间
+	rewritten := "The quick brown fox jumps over the sleepy hound near the river bank at dawn."
+	match, ok = Find(rewritten, selector, DefaultThreshold)
+	if !ok {
+		t.Fatal("上下文不变时应定位到上下文之间的文字")
+	}
+	if match.Exact != "jumps over the sleepy hound" || match.Score >= DefaultThreshold {
+		t.Errorf("根据上下文定位到 %q", match.Exact)
+	}
+}
+
+func TestFindDuplicateText(t *testing.T) {
+	text := "第一章 安装：请先下载安装包。第二章 升级：请先下载安装包，然后备份数据。第三章 卸载：请先下载安装包。"
+	for n := 0; n < 3; n++ {
+		selector := selectorOf(t, text, "请先下载安装包", n)
+		want := indexAll([]rune(Normalize(text)), []rune("请先下载安装包"))[n]
+
+		match, ok := Find(text, selector, DefaultThreshold)
+		if !ok {
+			t.Fatalf("第 %d 处重复文字应能找到", n+1)
+		}
+		if match.Start != want {
+			t.Errorf("第 %d 处重复文字定位到位置 %d，应为 %d", n+1, match.Start, want)
+		}
+	}
+
+	// 前面插入内容后仍按上下文定位到原来的那一处
+	selector := selectorOf(t, text, "请先下载安装包", 1)
+	edited := "前言。" + text
+	match, ok := Find(edited, selector, DefaultThreshold)
+	want := indexAll([]rune(Normalize(edited)), []rune("请先下载安装包"))[1]
+	if !ok || match.Start != want {
+		t.Errorf("插入内容后定位到位置 %d，应为 %d", match.Start, want)
+	}
+}
+
+func TestFindOrphaned(t *testing.T) {
+	text := "部署前需要配置数据库连接，并确认端口 8181 没有被占用。"
+	selector := selectorOf(t, text, "确认端口 8181 没有被占用", 0)
+
+	cases := map[string]string{
+		"文字和上下文都被删除": "本文档已经废弃，请参考新的安装说明。",
+		"空文档":        "",
+	}
+	for name, edited := range cases {
+		if match, ok := Find(edited, selector, DefaultThreshold); ok {
+			t.Errorf("%s时不应定位到文字，得到 %+v", name, match)
+		}
+	}
+	if _, ok := Find(text, Selector{}, DefaultThreshold); ok {
+		t.Error("空的选择器不应定位到文字")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if got := Normalize("  a\n\n b\t\tc  "); got != "a b c" {
+		t.Errorf("规范化结果为 %q", got)
+	}
+}
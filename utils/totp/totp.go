@@ -0,0 +1,108 @@
+// Package totp 实现 RFC 6238 基于时间的一次性密码，兼容 Google Authenticator 等验证器.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// 时间步长，单位秒.
+	Period = 30
+	// 验证码位数.
+	Digits = 6
+	// 验证时允许前后偏差的时间步数.
+	Skew = 1
+)
+
+var ErrInvalidSecret = errors.New("invalid totp secret")
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret 生成一个 base32 编码的随机密钥.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// Step 获取指定时间所在的时间步.
+func Step(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// GenerateCode 生成指定时间步的验证码.
+func GenerateCode(secret string, step int64) (string, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.Replace(secret, " ", "", -1)))
+	if err != nil || len(key) == 0 {
+		return "", ErrInvalidSecret
+	}
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(step))
+
+	h := hmac.New(sha1.New, key)
+	h.Write(msg)
+	sum := h.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", Digits, value%mod), nil
+}
+
+// Validate 校验验证码，成功时返回匹配的时间步，调用方可据此防止验证码被重复使用.
+func Validate(secret, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != Digits {
+		return 0, false
+	}
+	current := Step(t)
+	for i := -Skew; i <= Skew; i++ {
+		expected, err := GenerateCode(secret, current+int64(i))
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return current + int64(i), true
+		}
+	}
+	return 0, false
+}
+
+// ValidateAfter 校验验证码并拒绝不晚于 lastStep 的时间步，防止已使用过的验证码在有效期内被重复使用.
+func ValidateAfter(secret, code string, t time.Time, lastStep int64) (int64, bool) {
+	step, ok := Validate(secret, code, t)
+	if !ok || step <= lastStep {
+		return 0, false
+	}
+	return step, true
+}
+
+// URL 生成验证器扫码使用的 otpauth 地址.
+func URL(issuer, account, secret string) string {
+	v := url.Values{}
+	v.Set("secret", secret)
+	v.Set("issuer", issuer)
+	v.Set("algorithm", "SHA1")
+	v.Set("digits", fmt.Sprint(Digits))
+	v.Set("period", fmt.Sprint(Period))
+
+	label := url.PathEscape(issuer + ":" + account)
+
+	return "otpauth://totp/" + label + "?" + v.Encode()
+}
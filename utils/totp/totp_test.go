@@ -0,0 +1,95 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret 是 RFC 6238 附录 B 中 SHA-1 使用的种子 "12345678901234567890"
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestGenerateCodeRFC6238(t *testing.T) {
+	// RFC 6238 附录 B 的 8 位验证码取后 6 位
+	cases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, c := range cases {
+		code, err := GenerateCode(rfcSecret, Step(time.Unix(c.unix, 0)))
+		if err != nil {
+			t.Fatalf("时间 %d 生成验证码失败: %v", c.unix, err)
+		}
+		if code != c.code {
+			t.Errorf("时间 %d 的验证码为 %s，应为 %s", c.unix, code, c.code)
+		}
+		if _, ok := Validate(rfcSecret, c.code, time.Unix(c.unix, 0)); !ok {
+			t.Errorf("时间 %d 的验证码 %s 应校验通过", c.unix, c.code)
+		}
+	}
+}
+
+func TestValidateWindow(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+
+	for offset := int64(-Skew); offset <= Skew; offset++ {
+		code, _ := GenerateCode(rfcSecret, current+offset)
+		step, ok := Validate(rfcSecret, code, now)
+		if !ok {
+			t.Fatalf("偏移 %d 个时间步的验证码应校验通过", offset)
+		}
+		if step != current+offset {
+			t.Fatalf("偏移 %d 返回的时间步为 %d，应为 %d", offset, step, current+offset)
+		}
+	}
+
+	for _, offset := range []int64{-Skew - 1, Skew + 1} {
+		code, _ := GenerateCode(rfcSecret, current+offset)
+		if _, ok := Validate(rfcSecret, code, now); ok {
+			t.Fatalf("偏移 %d 个时间步的验证码不应校验通过", offset)
+		}
+	}
+
+	code, _ := GenerateCode(rfcSecret, current)
+	if _, ok := Validate(rfcSecret, code[:Digits-1], now); ok {
+		t.Fatal("位数不足的验证码不应校验通过")
+	}
+	if _, ok := Validate(rfcSecret, " "+code+" ", now); !ok {
+		t.Fatal("首尾空白应被忽略")
+	}
+	if _, ok := Validate("!!invalid!!", code, now); ok {
+		t.Fatal("无效密钥不应校验通过")
+	}
+}
+
+func TestValidateAfterRejectsReplay(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+	code, _ := GenerateCode(rfcSecret, current)
+
+	step, ok := ValidateAfter(rfcSecret, code, now, 0)
+	if !ok || step != current {
+		t.Fatalf("首次使用应校验通过，返回时间步 %d", step)
+	}
+	// 同一验证码在有效窗口内再次提交
+	if _, ok := ValidateAfter(rfcSecret, code, now.Add(Period*time.Second), step); ok {
+		t.Fatal("已使用的验证码不应再次校验通过")
+	}
+	// 窗口内更早的验证码同样拒绝
+	previous, _ := GenerateCode(rfcSecret, current-1)
+	if _, ok := ValidateAfter(rfcSecret, previous, now, step); ok {
+		t.Fatal("早于上次使用时间步的验证码不应校验通过")
+	}
+	// 下一个时间步的新验证码可以使用
+	next, _ := GenerateCode(rfcSecret, current+1)
+	if s, ok := ValidateAfter(rfcSecret, next, now.Add(Period*time.Second), step); !ok || s != current+1 {
+		t.Fatal("新时间步的验证码应校验通过")
+	}
+}
@@ -0,0 +1,49 @@
+package workweixin
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/beego/beego/v2/client/httplib"
+)
+
+// doc
+// - 群机器人配置说明: https://developer.work.weixin.qq.com/document/path/91770
+
+// 群机器人-请求响应结构
+type RobotResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// 群机器人发送 Markdown 消息，webhook 中的 key 即为机器人的凭据
+func SendRobotMarkdown(webhook string, content string) error {
+	req := httplib.Post(webhook)
+	req.SetTimeout(5*time.Second, 10*time.Second)
+	req.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: false})
+	if _, err := req.JSONBody(map[string]interface{}{
+		"msgtype": "markdown",
+		"markdown": map[string]string{
+			"content": content,
+		},
+	}); err != nil {
+		return err
+	}
+	resp, err := req.Response()
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("企业微信机器人请求失败: %s", resp.Status)
+	}
+	var result RobotResponse
+	if err := req.ToJSON(&result); err != nil {
+		return err
+	}
+	if result.ErrCode != 0 {
+		return fmt.Errorf("企业微信机器人发送失败: %d, %s", result.ErrCode, result.ErrMsg)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package workweixin
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSendRobotMarkdown(t *testing.T) {
+	var messages []map[string]interface{}
+	// 模拟企业微信群机器人接口
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "test" {
+			_, _ = w.Write([]byte(`{"errcode":93000,"errmsg":"invalid webhook url"}`))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		var message map[string]interface{}
+		if err := json.Unmarshal(body, &message); err != nil {
+			t.Errorf("消息格式错误: %s", body)
+		}
+		messages = append(messages, message)
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer server.Close()
+
+	if err := SendRobotMarkdown(server.URL+"/cgi-bin/webhook/send?key=test", "**内容**"); err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("收到 %d 条消息", len(messages))
+	}
+	markdown, _ := messages[0]["markdown"].(map[string]interface{})
+	if messages[0]["msgtype"] != "markdown" || markdown["content"] != "**内容**" {
+		t.Errorf("消息内容错误: %v", messages[0])
+	}
+	if err := SendRobotMarkdown(server.URL+"/cgi-bin/webhook/send?key=wrong", "**内容**"); err == nil {
+		t.Error("key 错误时应返回错误")
+	}
+}
@@ -128,7 +128,8 @@
                             if(data.errcode == 0) {
                                 layer.close(index);
                                 // layer.msg(JSON.stringify(data), {icon: 1, time: 15500});
-                                window.location.href = window.home_url;
+                                // 已启用两步验证时跳转到验证页面
+                                window.location.href = data.data || window.home_url;
                             }
                             else {
                                 layer.msg(data.message, {icon: 5, time: 3500});
@@ -0,0 +1,115 @@
+<!DOCTYPE html>
+<html lang="zh-cn">
+<head>
+    <meta charset="utf-8">
+    <link rel="shortcut icon" href="{{cdnimg "/static/favicon.ico"}}">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge,chrome=1" />
+    <meta name="renderer" content="webkit" />
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <meta name="author" content="MinDoc" />
+    <title>{{i18n .Lang "uc.two_factor"}} - Powered by MinDoc</title>
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet">
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+    <!-- jQuery (necessary for Bootstrap's JavaScript plugins) -->
+    <script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}"></script>
+</head>
+<body class="manual-container">
+<header class="navbar navbar-static-top smart-nav navbar-fixed-top manual-header" role="banner">
+    <div class="container">
+        <div class="navbar-header col-sm-12 col-md-6 col-lg-5">
+            <a href="{{.BaseUrl}}" class="navbar-brand">{{.SITE_NAME}}</a>
+        </div>
+    </div>
+</header>
+<div class="container manual-body">
+    <div class="row login">
+        <div class="login-body">
+            <form role="form" method="post">
+            {{ .xsrfdata }}
+                <h3 class="text-center">{{i18n .Lang "uc.two_factor"}}</h3>
+                <p style="color: #999;font-size: 12px;">{{i18n .Lang "uc.two_factor_login_tips"}}</p>
+                <div class="form-group">
+                    <div class="input-group">
+                        <div class="input-group-addon">
+                            <i class="fa fa-shield"></i>
+                        </div>
+                        <input type="text" class="form-control" placeholder="{{i18n .Lang "uc.two_factor_code_placeholder"}}" name="code" id="code" maxlength="20" autocomplete="one-time-code" autofocus>
+                    </div>
+                </div>
+                <div class="form-group">
+                    <button type="button" id="btn-verify" class="btn btn-success" style="width: 100%"  data-loading-text="{{i18n .Lang "message.logging_in"}}" autocomplete="off">{{i18n .Lang "common.login"}}</button>
+                </div>
+                <div class="form-group">
+                    <a href="{{urlfor "AccountController.Login"}}">{{i18n .Lang "message.return_account_login"}}</a>
+                </div>
+            </form>
+        </div>
+    </div>
+    <div class="clearfix"></div>
+</div>
+{{template "widgets/footer.tpl" .}}
+<!-- Include all compiled plugins (below), or include individual files as needed -->
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}" type="text/javascript"></script>
+<script src="{{cdnjs "/static/layer/layer.js"}}" type="text/javascript"></script>
+
+<script type="text/javascript">
+    $(document).ready(function () {
+        $("#code").on('focus', function () {
+            $(this).tooltip('destroy').parents('.form-group').removeClass('has-error');
+        });
+
+        $(document).keydown(function (e) {
+            var event = document.all ? window.event : e;
+            if (event.keyCode === 13) {
+                $("#btn-verify").click();
+                return false;
+            }
+        });
+
+        $("#btn-verify").on('click', function () {
+            var $btn = $(this).button('loading');
+            var code = $.trim($("#code").val());
+
+            if (code === "") {
+                $("#code").tooltip({ placement: "auto", title: "{{i18n .Lang "message.two_factor_code_empty"}}", trigger: 'manual' })
+                    .tooltip('show')
+                    .parents('.form-group').addClass('has-error');
+                $btn.button('reset');
+                return false;
+            }
+            $.ajax({
+                url: "{{urlfor "AccountController.TwoFactor" "url" .url}}",
+                data: $("form").serializeArray(),
+                dataType: "json",
+                type: "POST",
+                success: function (res) {
+                    if (res.errcode !== 0) {
+                        $("#code").val('');
+                        layer.msg(res.message);
+                        $btn.button('reset');
+                        if (res.data) {
+                            setTimeout(function () {
+                                window.location = res.data;
+                            }, 1500);
+                        }
+                    } else {
+                        var turl = res.data;
+                        if (turl === "") {
+                            turl = "/";
+                        }
+                        window.location = turl;
+                    }
+                },
+                error: function () {
+                    layer.msg('{{i18n .Lang "message.system_error"}}');
+                    $btn.button('reset');
+                }
+            });
+            return false;
+        });
+    });
+</script>
+</body>
+</html>
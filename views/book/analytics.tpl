@@ -0,0 +1,223 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n $.Lang "blog.analytics"}} - {{.Model.BookName}} - Powered by MinDoc</title>
+
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet">
+
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+
+    <style type="text/css">
+        .table > tbody > tr > td {
+            vertical-align: middle;
+        }
+        .analytics-summary .summary-item {
+            padding: 15px 0;
+            text-align: center;
+        }
+        .analytics-summary .summary-value {
+            font-size: 24px;
+            font-weight: bold;
+        }
+        .analytics-summary .summary-label {
+            color: #999;
+        }
+        .analytics-trend {
+            display: flex;
+            align-items: flex-end;
+            height: 160px;
+            padding: 10px 0;
+            border-bottom: 1px solid #ddd;
+        }
+        .analytics-trend .trend-bar {
+            flex: 1;
+            margin: 0 1px;
+            min-height: 1px;
+            background-color: #44B036;
+        }
+        .analytics-trend-label {
+            color: #999;
+            font-size: 12px;
+            margin-bottom: 20px;
+        }
+        .analytics-section {
+            margin-top: 20px;
+        }
+    </style>
+</head>
+<body>
+<div class="manual-reader">
+{{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+            <div class="page-left">
+                <ul class="menu">
+                    <li><a href="{{urlfor "BookController.Dashboard" ":key" .Model.Identify}}" class="item"><i class="fa fa-dashboard" aria-hidden="true"></i> {{i18n $.Lang "blog.summary"}}</a></li>
+                {{if eq .Model.RoleId 0 1}}
+                    <li><a href="{{urlfor "BookController.Users" ":key" .Model.Identify}}" class="item"><i class="fa fa-user" aria-hidden="true"></i> {{i18n $.Lang "blog.member"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Team" ":key" .Model.Identify}}" class="item"><i class="fa fa-group" aria-hidden="true"></i> {{i18n $.Lang "blog.team"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Setting" ":key" .Model.Identify}}" class="item"><i class="fa fa-gear" aria-hidden="true"></i> {{i18n $.Lang "common.setting"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Recycle" ":key" .Model.Identify}}" class="item"><i class="fa fa-trash" aria-hidden="true"></i> {{i18n $.Lang "blog.recycle_bin"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Fields" ":key" .Model.Identify}}" class="item"><i class="fa fa-tags" aria-hidden="true"></i> {{i18n $.Lang "blog.custom_fields"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Comments" ":key" .Model.Identify}}" class="item"><i class="fa fa-comments-o" aria-hidden="true"></i> {{i18n $.Lang "blog.comment_moderation"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Robots" ":key" .Model.Identify}}" class="item"><i class="fa fa-bullhorn" aria-hidden="true"></i> {{i18n $.Lang "blog.robots"}}</a></li>
+                    <li class="active"><a href="{{urlfor "BookController.Analytics" ":key" .Model.Identify}}" class="item"><i class="fa fa-bar-chart" aria-hidden="true"></i> {{i18n $.Lang "blog.analytics"}}</a></li>
+                {{end}}
+                </ul>
+
+            </div>
+            <div class="page-right">
+                <div class="m-box">
+                    <div class="box-head">
+                        <strong class="box-title"> {{i18n $.Lang "blog.analytics"}}</strong>
+                        <div class="btn-group btn-group-sm pull-right">
+                        {{range $days := .Periods}}
+                            <a href="{{urlfor "BookController.Analytics" ":key" $.Model.Identify "days" $days}}" class="btn btn-default{{if eq $days $.Analytics.Days}} active{{end}}">{{i18n $.Lang "blog.analytics_days" $days}}</a>
+                        {{end}}
+                        </div>
+                    </div>
+                </div>
+                <div class="box-body">
+                    <p style="color: #999;font-size: 12px;">{{i18n $.Lang "blog.analytics_tips" .Analytics.StartDay .Analytics.EndDay}}</p>
+                    <div class="row analytics-summary">
+                        <div class="col-sm-4 summary-item">
+                            <div class="summary-value">{{.Analytics.Views}}</div>
+                            <div class="summary-label">{{i18n $.Lang "blog.analytics_views"}}</div>
+                        </div>
+                        <div class="col-sm-4 summary-item">
+                            <div class="summary-value">{{.Analytics.Readers}}</div>
+                            <div class="summary-label">{{i18n $.Lang "blog.analytics_readers"}}</div>
+                        </div>
+                        <div class="col-sm-4 summary-item">
+                            <div class="summary-value">{{i18n $.Lang "blog.analytics_seconds" .Analytics.AverageDuration}}</div>
+                            <div class="summary-label">{{i18n $.Lang "blog.analytics_duration"}}</div>
+                        </div>
+                    </div>
+
+                    <div class="analytics-section">
+                        <strong>{{i18n $.Lang "blog.analytics_trend"}}</strong>
+                        <a href="{{urlfor "BookController.AnalyticsExport" ":key" .Model.Identify "type" "trend" "days" .Analytics.Days}}" class="btn btn-default btn-xs pull-right"><i class="fa fa-download" aria-hidden="true"></i> {{i18n $.Lang "blog.analytics_export"}}</a>
+                        <div class="analytics-trend">
+                        {{range $item := .Analytics.Trend}}
+                            <div class="trend-bar" style="height: {{$item.Percent}}%;" title="{{$item.Day}}: {{i18n $.Lang "blog.analytics_views"}} {{$item.Views}}, {{i18n $.Lang "blog.analytics_readers"}} {{$item.Readers}}"></div>
+                        {{end}}
+                        </div>
+                        <div class="analytics-trend-label">
+                            <span>{{.Analytics.StartDay}}</span>
+                            <span class="pull-right">{{.Analytics.EndDay}}</span>
+                        </div>
+                    </div>
+
+                    <div class="analytics-section">
+                        <strong>{{i18n $.Lang "blog.analytics_top_pages"}}</strong>
+                        <a href="{{urlfor "BookController.AnalyticsExport" ":key" .Model.Identify "type" "pages" "days" .Analytics.Days}}" class="btn btn-default btn-xs pull-right"><i class="fa fa-download" aria-hidden="true"></i> {{i18n $.Lang "blog.analytics_export"}}</a>
+                        <table class="table">
+                            <thead>
+                            <tr>
+                                <th>{{i18n $.Lang "blog.analytics_document"}}</th>
+                                <th width="100">{{i18n $.Lang "blog.analytics_views"}}</th>
+                                <th width="100">{{i18n $.Lang "blog.analytics_readers"}}</th>
+                                <th width="120">{{i18n $.Lang "blog.analytics_duration"}}</th>
+                            </tr>
+                            </thead>
+                            <tbody>
+                            {{range $item := .Analytics.TopPages}}
+                            <tr>
+                                <td><a href="{{urlfor "DocumentController.Read" ":key" $.Model.Identify ":id" $item.DocumentId}}" target="_blank">{{if $item.DocumentName}}{{$item.DocumentName}}{{else}}#{{$item.DocumentId}}{{end}}</a></td>
+                                <td>{{$item.Views}}</td>
+                                <td>{{$item.Readers}}</td>
+                                <td>{{i18n $.Lang "blog.analytics_seconds" $item.AverageDuration}}</td>
+                            </tr>
+                            {{else}}
+                            <tr><td class="text-center" colspan="4">{{i18n $.Lang "message.no_data"}}</td></tr>
+                            {{end}}
+                            </tbody>
+                        </table>
+                    </div>
+
+                    <div class="analytics-section">
+                        <strong>{{i18n $.Lang "blog.analytics_exit_pages"}}</strong>
+                        <table class="table">
+                            <thead>
+                            <tr>
+                                <th>{{i18n $.Lang "blog.analytics_document"}}</th>
+                                <th width="100">{{i18n $.Lang "blog.analytics_exits"}}</th>
+                                <th width="100">{{i18n $.Lang "blog.analytics_exit_rate"}}</th>
+                            </tr>
+                            </thead>
+                            <tbody>
+                            {{range $item := .Analytics.ExitPages}}
+                            <tr>
+                                <td><a href="{{urlfor "DocumentController.Read" ":key" $.Model.Identify ":id" $item.DocumentId}}" target="_blank">{{if $item.DocumentName}}{{$item.DocumentName}}{{else}}#{{$item.DocumentId}}{{end}}</a></td>
+                                <td>{{$item.Exits}}</td>
+                                <td>{{$item.ExitRate}}%</td>
+                            </tr>
+                            {{else}}
+                            <tr><td class="text-center" colspan="3">{{i18n $.Lang "message.no_data"}}</td></tr>
+                            {{end}}
+                            </tbody>
+                        </table>
+                    </div>
+
+                    <div class="row">
+                        <div class="col-sm-6 analytics-section">
+                            <strong>{{i18n $.Lang "blog.analytics_referers"}}</strong>
+                            <table class="table">
+                                <thead>
+                                <tr>
+                                    <th>{{i18n $.Lang "blog.analytics_referer"}}</th>
+                                    <th width="100">{{i18n $.Lang "blog.analytics_views"}}</th>
+                                </tr>
+                                </thead>
+                                <tbody>
+                                {{range $item := .Analytics.Referers}}
+                                <tr>
+                                    <td>{{$item.RefererHost}}</td>
+                                    <td>{{$item.Views}}</td>
+                                </tr>
+                                {{else}}
+                                <tr><td class="text-center" colspan="2">{{i18n $.Lang "message.no_data"}}</td></tr>
+                                {{end}}
+                                </tbody>
+                            </table>
+                        </div>
+                        <div class="col-sm-6 analytics-section">
+                            <strong>{{i18n $.Lang "blog.analytics_zero_searches"}}</strong>
+                            <a href="{{urlfor "BookController.AnalyticsExport" ":key" .Model.Identify "type" "searches" "days" .Analytics.Days}}" class="btn btn-default btn-xs pull-right"><i class="fa fa-download" aria-hidden="true"></i> {{i18n $.Lang "blog.analytics_export"}}</a>
+                            <table class="table">
+                                <thead>
+                                <tr>
+                                    <th>{{i18n $.Lang "blog.analytics_keyword"}}</th>
+                                    <th width="100">{{i18n $.Lang "blog.analytics_zero_results"}}</th>
+                                </tr>
+                                </thead>
+                                <tbody>
+                                {{range $item := .Analytics.ZeroSearches}}
+                                <tr>
+                                    <td>{{$item.Keyword}}</td>
+                                    <td>{{$item.ZeroResults}}</td>
+                                </tr>
+                                {{else}}
+                                <tr><td class="text-center" colspan="2">{{i18n $.Lang "message.no_data"}}</td></tr>
+                                {{end}}
+                                </tbody>
+                            </table>
+                        </div>
+                    </div>
+                </div>
+            </div>
+        </div>
+    </div>
+{{template "widgets/footer.tpl" .}}
+</div>
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}"></script>
+<script src="{{cdnjs "/static/js/main.js"}}" type="text/javascript"></script>
+</body>
+</html>
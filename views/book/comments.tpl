@@ -0,0 +1,115 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n $.Lang "blog.comment_moderation"}} - {{.Model.BookName}} - Powered by MinDoc</title>
+
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet">
+
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+
+    <style type="text/css">
+        .table > tbody > tr > td {
+            vertical-align: middle;
+        }
+        .comment-content {
+            max-width: 360px;
+            word-wrap: break-word;
+            white-space: pre-wrap;
+        }
+        .comment-quote {
+            max-width: 360px;
+            margin: 0 0 5px;
+            padding: 2px 8px;
+            font-size: 12px;
+            color: #777;
+            border-left: 3px solid #f0ad4e;
+        }
+    </style>
+</head>
+<body>
+<div class="manual-reader">
+{{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+            <div class="page-left">
+                <ul class="menu">
+                    <li><a href="{{urlfor "BookController.Dashboard" ":key" .Model.Identify}}" class="item"><i class="fa fa-dashboard" aria-hidden="true"></i> {{i18n $.Lang "blog.summary"}}</a></li>
+                {{if eq .Model.RoleId 0 1}}
+                    <li><a href="{{urlfor "BookController.Users" ":key" .Model.Identify}}" class="item"><i class="fa fa-user" aria-hidden="true"></i> {{i18n $.Lang "blog.member"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Team" ":key" .Model.Identify}}" class="item"><i class="fa fa-group" aria-hidden="true"></i> {{i18n $.Lang "blog.team"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Setting" ":key" .Model.Identify}}" class="item"><i class="fa fa-gear" aria-hidden="true"></i> {{i18n $.Lang "common.setting"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Recycle" ":key" .Model.Identify}}" class="item"><i class="fa fa-trash" aria-hidden="true"></i> {{i18n $.Lang "blog.recycle_bin"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Fields" ":key" .Model.Identify}}" class="item"><i class="fa fa-tags" aria-hidden="true"></i> {{i18n $.Lang "blog.custom_fields"}}</a></li>
+                    <li class="active"><a href="{{urlfor "BookController.Comments" ":key" .Model.Identify}}" class="item"><i class="fa fa-comments-o" aria-hidden="true"></i> {{i18n $.Lang "blog.comment_moderation"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Robots" ":key" .Model.Identify}}" class="item"><i class="fa fa-bullhorn" aria-hidden="true"></i> {{i18n $.Lang "blog.robots"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Analytics" ":key" .Model.Identify}}" class="item"><i class="fa fa-bar-chart" aria-hidden="true"></i> {{i18n $.Lang "blog.analytics"}}</a></li>
+                {{end}}
+                </ul>
+
+            </div>
+            <div class="page-right">
+                <div class="m-box">
+                    <div class="box-head">
+                        <strong class="box-title"> {{i18n $.Lang "blog.comment_moderation"}}</strong>
+                    </div>
+                </div>
+                <div class="box-body">
+                    <p style="color: #999;font-size: 12px;">{{i18n $.Lang "blog.comment_moderation_tips"}}</p>
+                    {{template "widgets/comment_moderation.tpl" .}}
+                </div>
+            </div>
+        </div>
+    </div>
+{{template "widgets/footer.tpl" .}}
+</div>
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}"></script>
+<script src="{{cdnjs "/static/js/main.js"}}" type="text/javascript"></script>
+<script type="text/javascript">
+    $(function () {
+        $("#checkAllComment").on("change", function () {
+            $("#commentModeration input[name='comment_id']").prop("checked", $(this).prop("checked"));
+        });
+        $(".btn-moderate").on("click", function () {
+            var $btn = $(this);
+            var ids = $("#commentModeration input[name='comment_id']:checked").map(function () {
+                return $(this).val();
+            }).get();
+            if (ids.length === 0) {
+                showError("{{i18n .Lang "blog.comment_select_tips"}}");
+                return;
+            }
+            if ($btn.data("confirm") && !confirm($btn.data("confirm"))) {
+                return;
+            }
+            $btn.prop("disabled", true);
+            $.ajax({
+                url: $("#commentModeration").data("url"),
+                type: "POST",
+                data: { "action": $btn.data("action"), "comment_id": ids },
+                traditional: true,
+                dataType: "json",
+                success: function (res) {
+                    if (res.errcode === 0) {
+                        window.location.reload();
+                    } else {
+                        $btn.prop("disabled", false);
+                        showError(res.message);
+                    }
+                },
+                error: function () {
+                    $btn.prop("disabled", false);
+                    showError("{{i18n .Lang "message.system_error"}}");
+                }
+            });
+        });
+    });
+</script>
+</body>
+</html>
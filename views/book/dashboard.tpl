@@ -31,6 +31,11 @@
                         <li><a href="{{urlfor "BookController.Users" ":key" .Model.Identify}}" class="item"><i class="fa fa-user" aria-hidden="true"></i> {{i18n $.Lang "blog.member"}}</a> </li>
                         <li><a href="{{urlfor "BookController.Team" ":key" .Model.Identify}}" class="item"><i class="fa fa-group" aria-hidden="true"></i> {{i18n $.Lang "blog.team"}}</a> </li>
                         <li><a href="{{urlfor "BookController.Setting" ":key" .Model.Identify}}" class="item"><i class="fa fa-gear" aria-hidden="true"></i> {{i18n $.Lang "common.setting"}}</a> </li>
+                        <li><a href="{{urlfor "BookController.Recycle" ":key" .Model.Identify}}" class="item"><i class="fa fa-trash" aria-hidden="true"></i> {{i18n $.Lang "blog.recycle_bin"}}</a> </li>
+                        <li><a href="{{urlfor "BookController.Fields" ":key" .Model.Identify}}" class="item"><i class="fa fa-tags" aria-hidden="true"></i> {{i18n $.Lang "blog.custom_fields"}}</a></li>
+                        <li><a href="{{urlfor "BookController.Comments" ":key" .Model.Identify}}" class="item"><i class="fa fa-comments-o" aria-hidden="true"></i> {{i18n $.Lang "blog.comment_moderation"}}</a></li>
+                        <li><a href="{{urlfor "BookController.Robots" ":key" .Model.Identify}}" class="item"><i class="fa fa-bullhorn" aria-hidden="true"></i> {{i18n $.Lang "blog.robots"}}</a></li>
+                        <li><a href="{{urlfor "BookController.Analytics" ":key" .Model.Identify}}" class="item"><i class="fa fa-bar-chart" aria-hidden="true"></i> {{i18n $.Lang "blog.analytics"}}</a></li>
                     {{end}}
                 </ul>
 
@@ -96,6 +101,73 @@
                         <div class="summary">{{.Description}} </div>
 
                     </div>
+                    <div class="clearfix"></div>
+                    <div class="link-check">
+                        <h4>
+                            {{i18n $.Lang "blog.broken_links"}}
+                            {{if eq .Model.RoleId 0 1 2}}
+                            <button class="btn btn-default btn-sm pull-right" id="btnCheckLinks" data-loading-text="{{i18n $.Lang "message.processing"}}"{{if .LinkCheckRunning}} disabled{{end}}><i class="fa fa-chain-broken" aria-hidden="true"></i> {{i18n $.Lang "blog.check_links"}}</button>
+                            {{end}}
+                        </h4>
+                        {{if .LinkCheckRunning}}
+                        <p style="color: #999;font-size: 12px;">{{i18n $.Lang "blog.link_check_in_progress"}}</p>
+                        {{end}}
+                        {{if .LinkCheck}}
+                        <p style="color: #999;font-size: 12px;">{{i18n $.Lang "blog.link_check_result" (date_format .LinkCheck.CheckTime "2006-01-02 15:04:05") .LinkCheck.LinkCount .LinkCheck.BrokenCount}}</p>
+                        {{if .BrokenLinks}}
+                        <table class="table">
+                            <thead>
+                            <tr>
+                                <th>{{i18n $.Lang "doc.doc_name"}}</th>
+                                <th>{{i18n $.Lang "blog.link_url"}}</th>
+                                <th width="100">{{i18n $.Lang "blog.link_type"}}</th>
+                                <th width="160">{{i18n $.Lang "blog.link_reason"}}</th>
+                            </tr>
+                            </thead>
+                            <tbody>
+                            {{range $index,$item := .BrokenLinks}}
+                            <tr>
+                                <td><a href="{{urlfor "DocumentController.Read" ":key" $.Model.Identify ":id" $item.DocumentId}}" target="_blank">{{$item.DocumentName}}</a></td>
+                                <td style="word-break: break-all;">{{$item.Url}}</td>
+                                <td>{{i18n $.Lang (print "blog.link_type_" $item.LinkType)}}</td>
+                                <td>{{$item.Reason}}</td>
+                            </tr>
+                            {{end}}
+                            </tbody>
+                        </table>
+                        {{end}}
+                        {{else}}
+                        <p style="color: #999;font-size: 12px;">{{i18n $.Lang "blog.link_check_never"}}</p>
+                        {{end}}
+                    </div>
+                    {{if gt .Model.ReviewDays 0}}
+                    <div class="link-check">
+                        <h4>{{i18n $.Lang "blog.stale_documents"}}</h4>
+                        <p style="color: #999;font-size: 12px;">{{i18n $.Lang "blog.stale_documents_tips" .Model.ReviewDays}}</p>
+                        {{if .StaleDocuments}}
+                        <table class="table">
+                            <thead>
+                            <tr>
+                                <th>{{i18n $.Lang "doc.doc_name"}}</th>
+                                <th width="160">{{i18n $.Lang "blog.stale_owner"}}</th>
+                                <th width="160">{{i18n $.Lang "blog.stale_modify_time"}}</th>
+                            </tr>
+                            </thead>
+                            <tbody>
+                            {{range $index,$item := .StaleDocuments}}
+                            <tr>
+                                <td><a href="{{urlfor "DocumentController.Read" ":key" $.Model.Identify ":id" $item.DocumentId}}" target="_blank">{{$item.DocumentName}}</a></td>
+                                <td>{{if $item.RealName}}{{$item.RealName}}{{else}}{{$item.Account}}{{end}}</td>
+                                <td>{{date_format $item.ModifyTime "2006-01-02"}} ({{i18n $.Lang "blog.stale_days" $item.StaleDays}})</td>
+                            </tr>
+                            {{end}}
+                            </tbody>
+                        </table>
+                        {{else}}
+                        <p style="color: #999;font-size: 12px;">{{i18n $.Lang "blog.stale_documents_empty"}}</p>
+                        {{end}}
+                    </div>
+                    {{end}}
                 </div>
             </div>
         </div>
@@ -108,6 +180,27 @@
 <script src="{{cdnjs "/static/js/main.js"}}" type="text/javascript"></script>
 <script type="text/javascript">
     $(function () {
+        $("#btnCheckLinks").on("click",function () {
+            var $btn = $(this);
+            $btn.button("loading");
+            $.ajax({
+                url : "{{urlfor "BookController.CheckLinks" ":key" .Model.Identify}}",
+                type : "post",
+                dataType : "json",
+                success : function (res) {
+                    layer.msg(res.message);
+                    if(res.errcode === 0){
+                        $btn.button("reset").prop("disabled", true);
+                    }else{
+                        $btn.button("reset");
+                    }
+                },
+                error : function () {
+                    $btn.button("reset");
+                    layer.msg("{{i18n $.Lang "message.system_error"}}");
+                }
+            });
+        });
         $("#btnRelease").on("click",function () {
             $.ajax({
                 url : "{{urlfor "BookController.Release" ":key" .Model.Identify}}",
@@ -0,0 +1,207 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n $.Lang "blog.custom_fields"}} - {{.Model.BookName}} - Powered by MinDoc</title>
+
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet">
+
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+
+    <style type="text/css">
+        .table > tbody > tr > td {
+            vertical-align: middle;
+        }
+    </style>
+</head>
+<body>
+<div class="manual-reader">
+{{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+            <div class="page-left">
+                <ul class="menu">
+                    <li><a href="{{urlfor "BookController.Dashboard" ":key" .Model.Identify}}" class="item"><i class="fa fa-dashboard" aria-hidden="true"></i> {{i18n $.Lang "blog.summary"}}</a></li>
+                {{if eq .Model.RoleId 0 1}}
+                    <li><a href="{{urlfor "BookController.Users" ":key" .Model.Identify}}" class="item"><i class="fa fa-user" aria-hidden="true"></i> {{i18n $.Lang "blog.member"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Team" ":key" .Model.Identify}}" class="item"><i class="fa fa-group" aria-hidden="true"></i> {{i18n $.Lang "blog.team"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Setting" ":key" .Model.Identify}}" class="item"><i class="fa fa-gear" aria-hidden="true"></i> {{i18n $.Lang "common.setting"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Recycle" ":key" .Model.Identify}}" class="item"><i class="fa fa-trash" aria-hidden="true"></i> {{i18n $.Lang "blog.recycle_bin"}}</a></li>
+                    <li class="active"><a href="{{urlfor "BookController.Fields" ":key" .Model.Identify}}" class="item"><i class="fa fa-tags" aria-hidden="true"></i> {{i18n $.Lang "blog.custom_fields"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Comments" ":key" .Model.Identify}}" class="item"><i class="fa fa-comments-o" aria-hidden="true"></i> {{i18n $.Lang "blog.comment_moderation"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Robots" ":key" .Model.Identify}}" class="item"><i class="fa fa-bullhorn" aria-hidden="true"></i> {{i18n $.Lang "blog.robots"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Analytics" ":key" .Model.Identify}}" class="item"><i class="fa fa-bar-chart" aria-hidden="true"></i> {{i18n $.Lang "blog.analytics"}}</a></li>
+                {{end}}
+                </ul>
+
+            </div>
+            <div class="page-right">
+                <div class="m-box">
+                    <div class="box-head">
+                        <strong class="box-title"> {{i18n $.Lang "blog.custom_fields"}}</strong>
+                        <button type="button" class="btn btn-success btn-sm pull-right" id="btnAddField"><i class="fa fa-plus" aria-hidden="true"></i> {{i18n $.Lang "blog.add_field"}}</button>
+                    </div>
+                </div>
+                <div class="box-body">
+                    <p style="color: #999;font-size: 12px;">{{i18n $.Lang "blog.custom_fields_tips"}}</p>
+                    <p><span id="form-error-message" class="error-message"></span></p>
+                    <table class="table">
+                        <thead>
+                        <tr>
+                            <th>{{i18n $.Lang "blog.field_name"}}</th>
+                            <th>{{i18n $.Lang "blog.field_title"}}</th>
+                            <th>{{i18n $.Lang "blog.field_type"}}</th>
+                            <th width="160">{{i18n $.Lang "common.operate"}}</th>
+                        </tr>
+                        </thead>
+                        <tbody>
+                        {{range $index,$item := .ItemFields}}
+                        <tr>
+                            <td>{{$item.FieldName}}</td>
+                            <td>{{$item.FieldTitle}}</td>
+                            <td>{{i18n $.Lang (print "blog.field_type_" $item.FieldType)}}</td>
+                            <td><span class="label label-default">{{i18n $.Lang "blog.field_from_itemset"}}</span></td>
+                        </tr>
+                        {{end}}
+                        {{range $index,$item := .Lists}}
+                        <tr>
+                            <td>{{$item.FieldName}}</td>
+                            <td>{{$item.FieldTitle}}</td>
+                            <td>{{i18n $.Lang (print "blog.field_type_" $item.FieldType)}}</td>
+                            <td>
+                                <button type="button" class="btn btn-default btn-sm btn-edit-field" data-id="{{$item.FieldId}}" data-name="{{$item.FieldName}}" data-title="{{$item.FieldTitle}}" data-type="{{$item.FieldType}}" data-options="{{$item.Options}}" data-sort="{{$item.OrderSort}}">{{i18n $.Lang "common.edit"}}</button>
+                                <button type="button" class="btn btn-danger btn-sm btn-delete-field" data-id="{{$item.FieldId}}" data-loading-text="{{i18n $.Lang "message.processing"}}">{{i18n $.Lang "common.delete"}}</button>
+                            </td>
+                        </tr>
+                        {{else}}
+                        {{if not .ItemFields}}
+                        <tr><td class="text-center" colspan="4">{{i18n $.Lang "message.no_data"}}</td></tr>
+                        {{end}}
+                        {{end}}
+                        </tbody>
+                    </table>
+                </div>
+            </div>
+        </div>
+    </div>
+{{template "widgets/footer.tpl" .}}
+</div>
+<div class="modal fade" id="fieldModal" tabindex="-1" role="dialog" aria-labelledby="fieldModalLabel">
+    <div class="modal-dialog" role="document">
+        <form method="post" autocomplete="off" class="form-horizontal" action="{{urlfor "BookController.FieldSave" ":key" .Model.Identify}}" id="fieldForm">
+            <input type="hidden" name="field_id" value="0">
+            <div class="modal-content">
+                <div class="modal-header">
+                    <button type="button" class="close" data-dismiss="modal" aria-label="Close"><span aria-hidden="true">&times;</span></button>
+                    <h4 class="modal-title" id="fieldModalLabel">{{i18n $.Lang "blog.custom_fields"}}</h4>
+                </div>
+                <div class="modal-body">
+                    <div class="form-group">
+                        <label class="col-sm-3 control-label">{{i18n $.Lang "blog.field_name"}}<span class="error-message">*</span></label>
+                        <div class="col-sm-9">
+                            <input type="text" name="field_name" class="form-control" maxlength="50" placeholder="owner">
+                            <p class="text" style="color: #999;font-size: 12px;">{{i18n $.Lang "blog.field_name_tips"}}</p>
+                        </div>
+                    </div>
+                    <div class="form-group">
+                        <label class="col-sm-3 control-label">{{i18n $.Lang "blog.field_title"}}<span class="error-message">*</span></label>
+                        <div class="col-sm-9">
+                            <input type="text" name="field_title" class="form-control" maxlength="100">
+                        </div>
+                    </div>
+                    <div class="form-group">
+                        <label class="col-sm-3 control-label">{{i18n $.Lang "blog.field_type"}}</label>
+                        <div class="col-sm-9">
+                            <select name="field_type" class="form-control">
+                                {{range $index,$type := .FieldTypes}}
+                                <option value="{{$type}}">{{i18n $.Lang (print "blog.field_type_" $type)}}</option>
+                                {{end}}
+                            </select>
+                        </div>
+                    </div>
+                    <div class="form-group field-options">
+                        <label class="col-sm-3 control-label">{{i18n $.Lang "blog.field_options"}}</label>
+                        <div class="col-sm-9">
+                            <textarea name="options" class="form-control" rows="4" placeholder="{{i18n $.Lang "blog.field_options_tips"}}"></textarea>
+                        </div>
+                    </div>
+                    <div class="form-group">
+                        <label class="col-sm-3 control-label">{{i18n $.Lang "blog.field_sort"}}</label>
+                        <div class="col-sm-9">
+                            <input type="number" name="order_sort" class="form-control" value="0">
+                        </div>
+                    </div>
+                </div>
+                <div class="modal-footer">
+                    <span id="field-error-message" class="error-message"></span>
+                    <button type="button" class="btn btn-default" data-dismiss="modal">{{i18n $.Lang "common.cancel"}}</button>
+                    <button type="submit" class="btn btn-success" id="btnSaveField" data-loading-text="{{i18n $.Lang "message.processing"}}">{{i18n $.Lang "common.save"}}</button>
+                </div>
+            </div>
+        </form>
+    </div>
+</div>
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}"></script>
+<script src="{{cdnjs "/static/js/main.js"}}" type="text/javascript"></script>
+<script type="text/javascript">
+    $(function () {
+        var $modal = $("#fieldModal");
+        var $form = $("#fieldForm");
+
+        function showFieldModal(field) {
+            $form.find("input[name='field_id']").val(field.id || 0);
+            $form.find("input[name='field_name']").val(field.name || "").prop("readonly", !!field.id);
+            $form.find("input[name='field_title']").val(field.title || "");
+            $form.find("select[name='field_type']").val(field.type || "text").trigger("change");
+            $form.find("textarea[name='options']").val(field.options || "");
+            $form.find("input[name='order_sort']").val(field.sort || 0);
+            showError("", "#field-error-message");
+            $modal.modal("show");
+        }
+
+        $form.find("select[name='field_type']").on("change", function () {
+            $form.find(".field-options").toggle($(this).val() === "select");
+        });
+        $("#btnAddField").on("click", function () {
+            showFieldModal({});
+        });
+        $(".btn-edit-field").on("click", function () {
+            showFieldModal($(this).data());
+        });
+        $form.on("submit", function (e) {
+            e.preventDefault();
+            var $btn = $("#btnSaveField").button("loading");
+            $.post($form.attr("action"), $form.serialize(), function (res) {
+                $btn.button("reset");
+                if (res.errcode === 0) {
+                    window.location.reload();
+                } else {
+                    showError(res.message, "#field-error-message");
+                }
+            }, "json");
+        });
+        $(".btn-delete-field").on("click", function () {
+            var $btn = $(this);
+            if (!confirm("{{i18n $.Lang "blog.field_delete_confirm"}}")) {
+                return;
+            }
+            $btn.button("loading");
+            $.post("{{urlfor "BookController.FieldDelete" ":key" .Model.Identify}}", { "field_id" : $btn.data("id") }, function (res) {
+                if (res.errcode === 0) {
+                    $btn.closest("tr").remove();
+                } else {
+                    $btn.button("reset");
+                    showError(res.message);
+                }
+            }, "json");
+        });
+    });
+</script>
+</body>
+</html>
@@ -0,0 +1,111 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n $.Lang "blog.recycle_bin"}} - {{.Model.BookName}} - Powered by MinDoc</title>
+
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet">
+
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+
+    <style type="text/css">
+        .table > tbody > tr > td {
+            vertical-align: middle;
+        }
+    </style>
+</head>
+<body>
+<div class="manual-reader">
+{{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+            <div class="page-left">
+                <ul class="menu">
+                    <li><a href="{{urlfor "BookController.Dashboard" ":key" .Model.Identify}}" class="item"><i class="fa fa-dashboard" aria-hidden="true"></i> {{i18n $.Lang "blog.summary"}}</a></li>
+                {{if eq .Model.RoleId 0 1}}
+                    <li><a href="{{urlfor "BookController.Users" ":key" .Model.Identify}}" class="item"><i class="fa fa-user" aria-hidden="true"></i> {{i18n $.Lang "blog.member"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Team" ":key" .Model.Identify}}" class="item"><i class="fa fa-group" aria-hidden="true"></i> {{i18n $.Lang "blog.team"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Setting" ":key" .Model.Identify}}" class="item"><i class="fa fa-gear" aria-hidden="true"></i> {{i18n $.Lang "common.setting"}}</a></li>
+                    <li class="active"><a href="{{urlfor "BookController.Recycle" ":key" .Model.Identify}}" class="item"><i class="fa fa-trash" aria-hidden="true"></i> {{i18n $.Lang "blog.recycle_bin"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Fields" ":key" .Model.Identify}}" class="item"><i class="fa fa-tags" aria-hidden="true"></i> {{i18n $.Lang "blog.custom_fields"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Comments" ":key" .Model.Identify}}" class="item"><i class="fa fa-comments-o" aria-hidden="true"></i> {{i18n $.Lang "blog.comment_moderation"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Robots" ":key" .Model.Identify}}" class="item"><i class="fa fa-bullhorn" aria-hidden="true"></i> {{i18n $.Lang "blog.robots"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Analytics" ":key" .Model.Identify}}" class="item"><i class="fa fa-bar-chart" aria-hidden="true"></i> {{i18n $.Lang "blog.analytics"}}</a></li>
+                {{end}}
+                </ul>
+
+            </div>
+            <div class="page-right">
+                <div class="m-box">
+                    <div class="box-head">
+                        <strong class="box-title"> {{i18n $.Lang "blog.recycle_bin"}}</strong>
+                    </div>
+                </div>
+                <div class="box-body">
+                    <p style="color: #999;font-size: 12px;">{{i18n $.Lang "blog.recycle_bin_tips"}}</p>
+                    <p><span id="form-error-message" class="error-message"></span></p>
+                    <table class="table">
+                        <thead>
+                        <tr>
+                            <th>{{i18n $.Lang "doc.doc_name"}}</th>
+                            <th width="100">{{i18n $.Lang "blog.recycle_document_count"}}</th>
+                            <th>{{i18n $.Lang "blog.recycle_delete_by"}}</th>
+                            <th>{{i18n $.Lang "blog.recycle_delete_time"}}</th>
+                            <th width="160">{{i18n $.Lang "common.operate"}}</th>
+                        </tr>
+                        </thead>
+                        <tbody>
+                        {{range $index,$item := .Lists}}
+                        <tr>
+                            <td>{{$item.Title}}</td>
+                            <td>{{$item.DocumentCount}}</td>
+                            <td>{{$item.Account}}</td>
+                            <td>{{date $item.CreateTime "Y-m-d H:i:s"}}</td>
+                            <td>
+                                <button type="button" class="btn btn-success btn-sm btn-recycle" data-url="{{urlfor "BookController.RecycleRestore" ":key" $.Model.Identify}}" data-id="{{$item.RecycleId}}" data-loading-text="{{i18n $.Lang "message.processing"}}">{{i18n $.Lang "blog.recycle_restore"}}</button>
+                                <button type="button" class="btn btn-danger btn-sm btn-recycle" data-url="{{urlfor "BookController.RecyclePurge" ":key" $.Model.Identify}}" data-id="{{$item.RecycleId}}" data-confirm="{{i18n $.Lang "blog.recycle_purge_confirm"}}" data-loading-text="{{i18n $.Lang "message.processing"}}">{{i18n $.Lang "blog.recycle_purge"}}</button>
+                            </td>
+                        </tr>
+                        {{else}}
+                        <tr><td class="text-center" colspan="5">{{i18n $.Lang "message.no_data"}}</td></tr>
+                        {{end}}
+                        </tbody>
+                    </table>
+                    <nav class="pagination-container">
+                        {{.PageHtml}}
+                    </nav>
+                </div>
+            </div>
+        </div>
+    </div>
+{{template "widgets/footer.tpl" .}}
+</div>
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}"></script>
+<script src="{{cdnjs "/static/js/main.js"}}" type="text/javascript"></script>
+<script type="text/javascript">
+    $(function () {
+        $(".btn-recycle").on("click", function () {
+            var $btn = $(this);
+            if ($btn.data("confirm") && !confirm($btn.data("confirm"))) {
+                return;
+            }
+            $btn.button("loading");
+            $.post($btn.data("url"), { "recycle_id" : $btn.data("id") }, function (res) {
+                if(res.errcode === 0){
+                    $btn.closest("tr").remove();
+                }else{
+                    $btn.button("reset");
+                    showError(res.message);
+                }
+            }, "json");
+        });
+    });
+</script>
+</body>
+</html>
@@ -0,0 +1,226 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n $.Lang "blog.robots"}} - {{.Model.BookName}} - Powered by MinDoc</title>
+
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet">
+
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+
+    <style type="text/css">
+        .table > tbody > tr > td {
+            vertical-align: middle;
+        }
+    </style>
+</head>
+<body>
+<div class="manual-reader">
+{{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+            <div class="page-left">
+                <ul class="menu">
+                    <li><a href="{{urlfor "BookController.Dashboard" ":key" .Model.Identify}}" class="item"><i class="fa fa-dashboard" aria-hidden="true"></i> {{i18n $.Lang "blog.summary"}}</a></li>
+                {{if eq .Model.RoleId 0 1}}
+                    <li><a href="{{urlfor "BookController.Users" ":key" .Model.Identify}}" class="item"><i class="fa fa-user" aria-hidden="true"></i> {{i18n $.Lang "blog.member"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Team" ":key" .Model.Identify}}" class="item"><i class="fa fa-group" aria-hidden="true"></i> {{i18n $.Lang "blog.team"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Setting" ":key" .Model.Identify}}" class="item"><i class="fa fa-gear" aria-hidden="true"></i> {{i18n $.Lang "common.setting"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Recycle" ":key" .Model.Identify}}" class="item"><i class="fa fa-trash" aria-hidden="true"></i> {{i18n $.Lang "blog.recycle_bin"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Fields" ":key" .Model.Identify}}" class="item"><i class="fa fa-tags" aria-hidden="true"></i> {{i18n $.Lang "blog.custom_fields"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Comments" ":key" .Model.Identify}}" class="item"><i class="fa fa-comments-o" aria-hidden="true"></i> {{i18n $.Lang "blog.comment_moderation"}}</a></li>
+                    <li class="active"><a href="{{urlfor "BookController.Robots" ":key" .Model.Identify}}" class="item"><i class="fa fa-bullhorn" aria-hidden="true"></i> {{i18n $.Lang "blog.robots"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Analytics" ":key" .Model.Identify}}" class="item"><i class="fa fa-bar-chart" aria-hidden="true"></i> {{i18n $.Lang "blog.analytics"}}</a></li>
+                {{end}}
+                </ul>
+
+            </div>
+            <div class="page-right">
+                <div class="m-box">
+                    <div class="box-head">
+                        <strong class="box-title"> {{i18n $.Lang "blog.robots"}}</strong>
+                        <button type="button" class="btn btn-success btn-sm pull-right" id="btnAddRobot"><i class="fa fa-plus" aria-hidden="true"></i> {{i18n $.Lang "blog.add_robot"}}</button>
+                    </div>
+                </div>
+                <div class="box-body">
+                    <p style="color: #999;font-size: 12px;">{{i18n $.Lang "blog.robots_tips"}}</p>
+                    <p><span id="form-error-message" class="error-message"></span></p>
+                    <table class="table">
+                        <thead>
+                        <tr>
+                            <th>{{i18n $.Lang "blog.robot_name"}}</th>
+                            <th>{{i18n $.Lang "blog.robot_platform"}}</th>
+                            <th>{{i18n $.Lang "blog.robot_events"}}</th>
+                            <th>{{i18n $.Lang "blog.robot_status"}}</th>
+                            <th width="220">{{i18n $.Lang "common.operate"}}</th>
+                        </tr>
+                        </thead>
+                        <tbody>
+                        {{range $index,$item := .Lists}}
+                        <tr>
+                            <td>{{$item.Name}}</td>
+                            <td>{{i18n $.Lang (print "blog.robot_platform_" $item.Platform)}}</td>
+                            <td>{{range $i,$event := $item.EventList}}{{if $i}}, {{end}}{{i18n $.Lang (print "blog.robot_event_" $event)}}{{end}}</td>
+                            <td>
+                                {{if eq $item.Enabled 1}}<span class="label label-success">{{i18n $.Lang "blog.robot_enabled"}}</span>{{else}}<span class="label label-default">{{i18n $.Lang "blog.robot_disabled"}}</span>{{end}}
+                                {{if $item.LastError}}<p class="text-danger" style="font-size: 12px;margin: 5px 0 0;" title="{{date $item.LastSendTime "Y-m-d H:i:s"}}">{{$item.LastError}}</p>{{end}}
+                            </td>
+                            <td>
+                                <button type="button" class="btn btn-default btn-sm btn-test-robot" data-id="{{$item.RobotId}}" data-loading-text="{{i18n $.Lang "message.processing"}}">{{i18n $.Lang "blog.robot_test"}}</button>
+                                <button type="button" class="btn btn-default btn-sm btn-edit-robot" data-id="{{$item.RobotId}}" data-name="{{$item.Name}}" data-platform="{{$item.Platform}}" data-webhook="{{$item.Webhook}}" data-events="{{$item.Events}}" data-enabled="{{$item.Enabled}}">{{i18n $.Lang "common.edit"}}</button>
+                                <button type="button" class="btn btn-danger btn-sm btn-delete-robot" data-id="{{$item.RobotId}}" data-loading-text="{{i18n $.Lang "message.processing"}}">{{i18n $.Lang "common.delete"}}</button>
+                            </td>
+                        </tr>
+                        {{else}}
+                        <tr><td class="text-center" colspan="5">{{i18n $.Lang "message.no_data"}}</td></tr>
+                        {{end}}
+                        </tbody>
+                    </table>
+                </div>
+            </div>
+        </div>
+    </div>
+{{template "widgets/footer.tpl" .}}
+</div>
+<div class="modal fade" id="robotModal" tabindex="-1" role="dialog" aria-labelledby="robotModalLabel">
+    <div class="modal-dialog" role="document">
+        <form method="post" autocomplete="off" class="form-horizontal" action="{{urlfor "BookController.RobotSave" ":key" .Model.Identify}}" id="robotForm">
+            <input type="hidden" name="robot_id" value="0">
+            <div class="modal-content">
+                <div class="modal-header">
+                    <button type="button" class="close" data-dismiss="modal" aria-label="Close"><span aria-hidden="true">&times;</span></button>
+                    <h4 class="modal-title" id="robotModalLabel">{{i18n $.Lang "blog.robots"}}</h4>
+                </div>
+                <div class="modal-body">
+                    <div class="form-group">
+                        <label class="col-sm-3 control-label">{{i18n $.Lang "blog.robot_platform"}}</label>
+                        <div class="col-sm-9">
+                            <select name="platform" class="form-control">
+                                {{range $index,$platform := .Platforms}}
+                                <option value="{{$platform}}">{{i18n $.Lang (print "blog.robot_platform_" $platform)}}</option>
+                                {{end}}
+                            </select>
+                        </div>
+                    </div>
+                    <div class="form-group">
+                        <label class="col-sm-3 control-label">{{i18n $.Lang "blog.robot_name"}}<span class="error-message">*</span></label>
+                        <div class="col-sm-9">
+                            <input type="text" name="name" class="form-control" maxlength="100">
+                        </div>
+                    </div>
+                    <div class="form-group">
+                        <label class="col-sm-3 control-label">Webhook<span class="error-message">*</span></label>
+                        <div class="col-sm-9">
+                            <input type="text" name="webhook" class="form-control" maxlength="1000">
+                            <p class="text" style="color: #999;font-size: 12px;">{{i18n $.Lang "blog.robot_webhook_tips"}}</p>
+                        </div>
+                    </div>
+                    <div class="form-group robot-secret">
+                        <label class="col-sm-3 control-label">{{i18n $.Lang "blog.robot_secret"}}</label>
+                        <div class="col-sm-9">
+                            <input type="password" name="secret" class="form-control" maxlength="255" autocomplete="new-password">
+                            <p class="text" style="color: #999;font-size: 12px;">{{i18n $.Lang "blog.robot_secret_tips"}}</p>
+                        </div>
+                    </div>
+                    <div class="form-group">
+                        <label class="col-sm-3 control-label">{{i18n $.Lang "blog.robot_events"}}</label>
+                        <div class="col-sm-9">
+                            {{range $index,$event := .Events}}
+                            <label class="checkbox-inline"><input type="checkbox" name="events" value="{{$event}}"> {{i18n $.Lang (print "blog.robot_event_" $event)}}</label>
+                            {{end}}
+                        </div>
+                    </div>
+                    <div class="form-group">
+                        <div class="col-sm-9 col-sm-offset-3">
+                            <label class="checkbox-inline"><input type="checkbox" name="enabled" value="1"> {{i18n $.Lang "blog.robot_enabled"}}</label>
+                        </div>
+                    </div>
+                </div>
+                <div class="modal-footer">
+                    <span id="robot-error-message" class="error-message"></span>
+                    <button type="button" class="btn btn-default" data-dismiss="modal">{{i18n $.Lang "common.cancel"}}</button>
+                    <button type="submit" class="btn btn-success" id="btnSaveRobot" data-loading-text="{{i18n $.Lang "message.processing"}}">{{i18n $.Lang "common.save"}}</button>
+                </div>
+            </div>
+        </form>
+    </div>
+</div>
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}"></script>
+<script src="{{cdnjs "/static/js/main.js"}}" type="text/javascript"></script>
+<script type="text/javascript">
+    $(function () {
+        var $modal = $("#robotModal");
+        var $form = $("#robotForm");
+
+        function showRobotModal(robot) {
+            var events = robot.id ? String(robot.events).split(",") : ["document", "comment", "book"];
+            $form.find("input[name='robot_id']").val(robot.id || 0);
+            $form.find("select[name='platform']").val(robot.platform || "dingtalk").trigger("change");
+            $form.find("input[name='name']").val(robot.name || "");
+            $form.find("input[name='webhook']").val(robot.webhook || "");
+            $form.find("input[name='secret']").val("").attr("placeholder", robot.id ? "{{i18n $.Lang "blog.robot_secret_keep"}}" : "SEC...");
+            $form.find("input[name='events']").each(function () {
+                $(this).prop("checked", $.inArray($(this).val(), events) >= 0);
+            });
+            $form.find("input[name='enabled']").prop("checked", !robot.id || robot.enabled === 1);
+            showError("", "#robot-error-message");
+            $modal.modal("show");
+        }
+
+        $form.find("select[name='platform']").on("change", function () {
+            $form.find(".robot-secret").toggle($(this).val() === "dingtalk");
+        });
+        $("#btnAddRobot").on("click", function () {
+            showRobotModal({});
+        });
+        $(".btn-edit-robot").on("click", function () {
+            showRobotModal($(this).data());
+        });
+        $form.on("submit", function (e) {
+            e.preventDefault();
+            var $btn = $("#btnSaveRobot").button("loading");
+            $.post($form.attr("action"), $form.serialize(), function (res) {
+                $btn.button("reset");
+                if (res.errcode === 0) {
+                    window.location.reload();
+                } else {
+                    showError(res.message, "#robot-error-message");
+                }
+            }, "json");
+        });
+        $(".btn-test-robot").on("click", function () {
+            var $btn = $(this).button("loading");
+            $.post("{{urlfor "BookController.RobotTest" ":key" .Model.Identify}}", { "robot_id" : $btn.data("id") }, function (res) {
+                $btn.button("reset");
+                if (res.errcode === 0) {
+                    showSuccess("{{i18n $.Lang "blog.robot_test_success"}}");
+                } else {
+                    showError(res.message);
+                }
+            }, "json");
+        });
+        $(".btn-delete-robot").on("click", function () {
+            var $btn = $(this);
+            if (!confirm("{{i18n $.Lang "blog.robot_delete_confirm"}}")) {
+                return;
+            }
+            $btn.button("loading");
+            $.post("{{urlfor "BookController.RobotDelete" ":key" .Model.Identify}}", { "robot_id" : $btn.data("id") }, function (res) {
+                if (res.errcode === 0) {
+                    $btn.closest("tr").remove();
+                } else {
+                    $btn.button("reset");
+                    showError(res.message);
+                }
+            }, "json");
+        });
+    });
+</script>
+</body>
+</html>
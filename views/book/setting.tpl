@@ -29,6 +29,11 @@
                     <li><a href="{{urlfor "BookController.Users" ":key" .Model.Identify}}" class="item"><i class="fa fa-user" aria-hidden="true"></i> {{i18n $.Lang "blog.member"}}</a> </li>
                     <li><a href="{{urlfor "BookController.Team" ":key" .Model.Identify}}" class="item"><i class="fa fa-group" aria-hidden="true"></i> {{i18n $.Lang "blog.team"}}</a> </li>
                     <li class="active"><a href="{{urlfor "BookController.Setting" ":key" .Model.Identify}}" class="item"><i class="fa fa-gear" aria-hidden="true"></i> {{i18n $.Lang "common.setting"}}</a> </li>
+                    <li><a href="{{urlfor "BookController.Recycle" ":key" .Model.Identify}}" class="item"><i class="fa fa-trash" aria-hidden="true"></i> {{i18n $.Lang "blog.recycle_bin"}}</a> </li>
+                    <li><a href="{{urlfor "BookController.Fields" ":key" .Model.Identify}}" class="item"><i class="fa fa-tags" aria-hidden="true"></i> {{i18n $.Lang "blog.custom_fields"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Comments" ":key" .Model.Identify}}" class="item"><i class="fa fa-comments-o" aria-hidden="true"></i> {{i18n $.Lang "blog.comment_moderation"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Robots" ":key" .Model.Identify}}" class="item"><i class="fa fa-bullhorn" aria-hidden="true"></i> {{i18n $.Lang "blog.robots"}}</a></li>
+                    <li><a href="{{urlfor "BookController.Analytics" ":key" .Model.Identify}}" class="item"><i class="fa fa-bar-chart" aria-hidden="true"></i> {{i18n $.Lang "blog.analytics"}}</a></li>
                 </ul>
 
             </div>
@@ -58,7 +63,7 @@
                             </div>
                             <div class="form-group">
                                 <label>{{i18n $.Lang "blog.project_id"}}</label>
-                                <input type="text" class="form-control" value="{{urlfor "DocumentController.Index" ":key" .Model.Identify}}" placeholder="{{i18n $.Lang "blog.project_id"}}" disabled>
+                                <input type="text" class="form-control" name="book_identify" value="{{.Model.Identify}}" placeholder="{{i18n $.Lang "blog.project_id"}}" maxlength="50">
                                 <p class="text">{{i18n $.Lang "message.project_id_desc"}}</p>
                             </div>
                             <div class="form-group">
@@ -76,6 +81,11 @@
                                 <input type="text" class="form-control" name="history_count" value="{{.Model.HistoryCount}}" placeholder="{{i18n $.Lang "blog.history_record_amount"}}">
                                 <p class="text">{{i18n $.Lang "message.history_record_amount_desc"}}</p>
                             </div>
+                            <div class="form-group">
+                                <label>{{i18n $.Lang "blog.review_days"}}</label>
+                                <input type="number" min="0" class="form-control" name="review_days" value="{{.Model.ReviewDays}}" placeholder="{{i18n $.Lang "blog.review_days"}}">
+                                <p class="text">{{i18n $.Lang "message.review_days_desc"}}</p>
+                            </div>
                             <div class="form-group">
                                 <label>{{i18n $.Lang "blog.corp_id"}}</label>
                                 <input type="text" class="form-control" name="publisher" value="{{.Model.Publisher}}" placeholder="{{i18n $.Lang "blog.corp_id"}}">
@@ -186,6 +196,15 @@
                         <p class="text">{{i18n $.Lang "message.auto_save_desc"}}</p>
                     </div>
                 </div>
+                <div class="form-group">
+                    <label for="commentModerate">{{i18n $.Lang "blog.comment_moderate"}}</label>
+                    <div class="controls">
+                        <div class="switch switch-small" data-on="primary" data-off="info">
+                            <input type="checkbox" id="commentModerate" name="comment_moderate"{{if .Model.CommentModerate }} checked{{end}} data-size="small" placeholder="{{i18n $.Lang "blog.comment_moderate"}}">
+                        </div>
+                        <p class="text">{{i18n $.Lang "message.comment_moderate_desc"}}</p>
+                    </div>
+                </div>
                 <div class="form-group">
                     <button type="submit" id="btnSaveBookInfo" class="btn btn-success" data-loading-text="{{i18n $.Lang "common.processing"}}">{{i18n $.Lang "common.save"}}</button>
                     <span id="form-error-message" class="error-message"></span>
@@ -352,7 +371,7 @@
         }).on("show.bs.modal",function () {
             window.modalHtml = $("#upload-logo-panel").find(".modal-body").html();
         });
-        $("#autoRelease,#enableShare,#isDownload,#isUseFirstDocument,#autoSave").bootstrapSwitch();
+        $("#autoRelease,#enableShare,#isDownload,#isUseFirstDocument,#autoSave,#commentModerate").bootstrapSwitch();
 
         $('input[name="label"]').tagsinput({
             confirmKeys: [13,44],
@@ -415,9 +434,12 @@
             },
             success : function (res) {
                 if(res.errcode === 0){
-                    showSuccess("{{i18n $.Lang "message.success"}}")
+                    showSuccess("{{i18n $.Lang "message.success"}}");
+                    if (res.data) {
+                        window.location.href = res.data;
+                    }
                 }else{
-                    showError("{{i18n $.Lang "message.failed"}}")
+                    showError(res.message)
                 }
                 $("#btnSaveBookInfo").button("reset");
             },
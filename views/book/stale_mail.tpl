@@ -0,0 +1,50 @@
+<!DOCTYPE html>
+<html>
+<head>
+    <meta http-equiv="Content-Type" content="text/html;charset=utf-8"/>
+    <title>{{i18n .Lang "uc.stale_documents"}} - Powered by MinDoc</title>
+    <style type="text/css">
+        html,body{background-color: transparent;margin:0;padding: 0;}
+        body{font: 14px/1.5 "Microsoft Yahei", "微软雅黑", verdana;word-wrap:break-word;}
+        table{border-collapse: collapse;width: 100%;}
+        th,td{border-bottom: 1px solid #E9E9E9;padding: 6px 4px;text-align: left;}
+        a{color:#0066CC;}
+    </style>
+</head>
+<body>
+<div>
+    <div class="wrapper" style="margin: 20px auto 0; width: 600px; padding-top:16px; padding-bottom:10px;">
+        <div class="header clearfix">
+            <a class="logo" href="{{.BaseUrl}}" target="_blank"><b>{{.SITE_NAME}}</b></a>
+        </div>
+        <br style="clear:both; height:0">
+        <div class="content" style="background: none repeat scroll 0 0 #FFFFFF; border: 1px solid #E9E9E9; margin: 2px 0 0; padding: 30px;">
+            <p>{{i18n .Lang "message.stale_mail_hello" (or .Member.RealName .Member.Account)}}</p>
+            <p>{{i18n .Lang "message.stale_mail_body"}}</p>
+            <table>
+                <thead>
+                <tr>
+                    <th>{{i18n .Lang "doc.doc_name"}}</th>
+                    <th>{{i18n .Lang "uc.stale_book"}}</th>
+                    <th>{{i18n .Lang "blog.stale_modify_time"}}</th>
+                </tr>
+                </thead>
+                <tbody>
+                {{range $index,$item := .Lists}}
+                <tr>
+                    <td><a href="{{urlfor "DocumentController.Read" ":key" $item.BookIdentify ":id" $item.DocumentId}}" target="_blank">{{$item.DocumentName}}</a></td>
+                    <td>{{$item.BookName}}</td>
+                    <td>{{date_format $item.ModifyTime "2006-01-02"}} ({{i18n $.Lang "blog.stale_days" $item.StaleDays}})</td>
+                </tr>
+                {{end}}
+                </tbody>
+            </table>
+            <p class="footer" style="border-top: 1px solid #DDDDDD; padding-top:6px; margin-top:25px; color:#838383;">
+                {{i18n .Lang "message.stale_mail_footer"}}<br><br>
+                <a href="{{.BaseUrl}}" target="_blank">{{.SITE_NAME}}</a>
+            </p>
+        </div>
+    </div>
+</div>
+</body>
+</html>
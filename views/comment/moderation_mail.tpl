@@ -0,0 +1,32 @@
+<!DOCTYPE html>
+<html>
+<head>
+    <meta http-equiv="Content-Type" content="text/html;charset=utf-8"/>
+    <title>{{i18n .Lang "blog.comment_moderation"}} - Powered by MinDoc</title>
+    <style type="text/css">
+        html,body{background-color: transparent;margin:0;padding: 0;}
+        body{font: 14px/1.5 "Microsoft Yahei", "微软雅黑", verdana;word-wrap:break-word;}
+        a{color:#0066CC;}
+    </style>
+</head>
+<body>
+<div>
+    <div class="wrapper" style="margin: 20px auto 0; width: 600px; padding-top:16px; padding-bottom:10px;">
+        <div class="header clearfix">
+            <a class="logo" href="{{.BaseUrl}}" target="_blank"><b>{{.SITE_NAME}}</b></a>
+        </div>
+        <br style="clear:both; height:0">
+        <div class="content" style="background: none repeat scroll 0 0 #FFFFFF; border: 1px solid #E9E9E9; margin: 2px 0 0; padding: 30px;">
+            <p>{{i18n .Lang "message.comment_mail_hello" (or .Member.RealName .Member.Account)}}</p>
+            <p>{{i18n .Lang "message.comment_moderation_body" .Comment.Author .BookName .DocumentName}}</p>
+            <blockquote style="margin: 0 0 10px; padding: 10px 15px; border-left: 4px solid #E9E9E9; color: #555;">{{.Comment.Content}}</blockquote>
+            <p><a href="{{urlfor "BookController.Comments" ":key" .BookIdentify}}" target="_blank">{{i18n .Lang "blog.comment_moderation"}}</a></p>
+            <p class="footer" style="border-top: 1px solid #DDDDDD; padding-top:6px; margin-top:25px; color:#838383;">
+                {{i18n .Lang "message.comment_moderation_footer"}}<br><br>
+                <a href="{{.BaseUrl}}" target="_blank">{{.SITE_NAME}}</a>
+            </p>
+        </div>
+    </div>
+</div>
+</body>
+</html>
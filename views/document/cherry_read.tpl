@@ -33,7 +33,7 @@
         window.IS_DOCUMENT_INDEX = '{{if .IS_DOCUMENT_INDEX}}true{{end}}' === 'true';
         window.IS_DISPLAY_COMMENT = '{{if .Model.IsDisplayComment}}true{{end}}' === 'true';
     </script>
-    <script type="text/javascript">window.book={"identify":"{{.Model.Identify}}"};</script>
+    <script type="text/javascript">window.book={"identify":"{{.Model.Identify}}", "doc_id": {{if .DocumentId}}{{.DocumentId}}{{else}}0{{end}}, "view_key": {{.ViewKey}}, "view_url": "{{urlfor "DocumentController.RecordView" ":key" .Model.Identify}}", "leave_url": "{{urlfor "DocumentController.LeaveDocument" ":key" .Model.Identify}}", "progress_url": "{{urlfor "DocumentController.SaveProgress" ":key" .Model.Identify}}", "scroll_ratio": {{if .ScrollRatio}}{{.ScrollRatio}}{{else}}0{{end}}, "progress": {{if gt .Member.MemberId 0}}true{{else}}false{{end}}};</script>
     <style>
         .btn-mobile {
             position: absolute;
@@ -85,6 +85,29 @@
                 {{end}}
                 {{end}}
                 </div>
+                {{if gt .Member.MemberId 0}}
+                <div class="dropdown pull-right" style="margin-right: 10px;">
+                    <button type="button" class="btn btn-default" id="markBookRead" data-url="{{urlfor "DocumentController.MarkBookRead" ":key" .Model.Identify}}" data-loading-text="{{i18n .Lang "message.processing"}}"><i class="fa fa-check-square-o" aria-hidden="true"></i> {{i18n .Lang "doc.mark_book_read"}}</button>
+                </div>
+                <div class="dropdown pull-right" id="subscription" style="margin-right: 10px;" data-url="{{urlfor "SubscriptionController.Status"}}" data-save="{{urlfor "SubscriptionController.Save"}}" data-delete="{{urlfor "SubscriptionController.Delete"}}" data-doc="{{.DocumentId}}">
+                    <button type="button" class="btn btn-default" data-toggle="dropdown" aria-haspopup="true" aria-expanded="false">
+                        <i class="fa fa-rss" aria-hidden="true"></i> {{i18n .Lang "doc.subscribe"}} <span class="caret"></span>
+                    </button>
+                    <ul class="dropdown-menu subscription-menu" role="menu" style="margin-top: -5px;">
+                        <li class="dropdown-header">{{i18n .Lang "doc.subscribe_document"}}</li>
+                        <li><a href="javascript:;" data-scope="document" data-frequency="immediate"><i class="fa fa-check" aria-hidden="true"></i> {{i18n .Lang "doc.subscribe_immediate"}}</a></li>
+                        <li><a href="javascript:;" data-scope="document" data-frequency="daily"><i class="fa fa-check" aria-hidden="true"></i> {{i18n .Lang "doc.subscribe_daily"}}</a></li>
+                        <li><a href="javascript:;" data-scope="document" data-frequency="weekly"><i class="fa fa-check" aria-hidden="true"></i> {{i18n .Lang "doc.subscribe_weekly"}}</a></li>
+                        <li><a href="javascript:;" data-scope="document" data-frequency=""><i class="fa fa-check" aria-hidden="true"></i> {{i18n .Lang "doc.unsubscribe"}}</a></li>
+                        <li role="separator" class="divider"></li>
+                        <li class="dropdown-header">{{i18n .Lang "doc.subscribe_book"}}</li>
+                        <li><a href="javascript:;" data-scope="book" data-frequency="immediate"><i class="fa fa-check" aria-hidden="true"></i> {{i18n .Lang "doc.subscribe_immediate"}}</a></li>
+                        <li><a href="javascript:;" data-scope="book" data-frequency="daily"><i class="fa fa-check" aria-hidden="true"></i> {{i18n .Lang "doc.subscribe_daily"}}</a></li>
+                        <li><a href="javascript:;" data-scope="book" data-frequency="weekly"><i class="fa fa-check" aria-hidden="true"></i> {{i18n .Lang "doc.subscribe_weekly"}}</a></li>
+                        <li><a href="javascript:;" data-scope="book" data-frequency=""><i class="fa fa-check" aria-hidden="true"></i> {{i18n .Lang "doc.unsubscribe"}}</a></li>
+                    </ul>
+                </div>
+                {{end}}
                 {{if .Model.IsDownload}}
                 <div class="dropdown pull-right" style="margin-right: 10px;">
                     <button type="button" class="btn btn-primary" data-toggle="dropdown" aria-haspopup="true" aria-expanded="false">
@@ -169,6 +192,9 @@
                                 </div>
                                 <div class="col-md-8 text-center {{if eq .Model.Editor "cherry_markdown"}} markdown-title {{else}} editor-content{{end}}">
                                     <h1 id="article-title">{{.Title}}</h1>
+                                    {{if .ContinueUrl}}
+                                    <p class="continue-reading"><a href="{{.ContinueUrl}}"><i class="fa fa-bookmark" aria-hidden="true"></i> {{i18n .Lang "doc.continue_reading"}}: {{.ContinueName}}</a></p>
+                                    {{end}}
                                 </div>
                                 <div class="col-md-2">
                                 </div>
@@ -186,7 +212,7 @@
                             {{range $i, $c := .Page.List}}
                             <div class="comment-item" data-id="{{$c.CommentId}}">
                                 <p class="info"><a class="name">{{$c.Author}}</a><span class="date">{{date $c.CommentDate "Y-m-d H:i:s"}}</span></p>
-                                <div class="content">{{$c.Content}}</div>
+                                <div class="content">{{str2html $c.HtmlContent}}</div>
                                 <p class="util">
                                     <span class="operate {{if eq $c.ShowDel 1}}toggle{{end}}">
                                         <span class="number">{{$c.Index}}#</span>
@@ -205,9 +231,16 @@
                         <!-- 发表评论 -->
                         <div class="comment-post">
                             <form class="form" id="commentForm" action="{{urlfor "CommentController.Create"}}" method="post">
+                                <p class="comment-reply-to" id="commentReplyTo" style="display: none;">回复 <span class="name"></span> <a href="javascript:;" onclick="cancelReplyComment()">取消</a></p>
+                                <div class="comment-anchor-quote" id="commentAnchor" style="display: none;"><blockquote class="comment-quote"></blockquote><a href="javascript:;" onclick="cancelAnchorComment()">取消引用</a></div>
                                 <label class="enter w-textarea textarea-full">
                                     <textarea class="textarea-input form-control" name="content" id="commentContent" placeholder="文明上网，理性发言" style="height: 72px;"></textarea>
                                     <input type="hidden" name="doc_id" id="doc_id" value="{{.DocumentId}}">
+                                    <input type="hidden" name="parent_id" id="commentParentId" value="0">
+                                    <input type="hidden" name="anchor_text" id="commentAnchorText" value="">
+                                    <input type="hidden" name="anchor_prefix" id="commentAnchorPrefix" value="">
+                                    <input type="hidden" name="anchor_suffix" id="commentAnchorSuffix" value="">
+                                    <input type="hidden" name="anchor_heading" id="commentAnchorHeading" value="">
                                 </label>
                                 <div class="pull-right">
                                     <button class="btn btn-success btn-sm" type="submit" id="btnSubmitComment" data-loading-text="提交中...">提交评论</button>
@@ -23,6 +23,8 @@
         window.book = {{.ModelResult}};
         window.selectNode = null;
         window.deleteURL = "{{urlfor "DocumentController.Delete" ":key" .Model.Identify}}";
+        window.transferURL = "{{urlfor "DocumentController.TransferTargets" ":key" .Model.Identify}}";
+        window.referencesURL = "{{urlfor "DocumentController.References" ":key" .Model.Identify}}";
         window.editURL = "{{urlfor "DocumentController.Content" ":key" .Model.Identify ":id" ""}}";
         window.releaseURL = "{{urlfor "BookController.Release" ":key" .Model.Identify}}";
         window.sortURL = "{{urlfor "BookController.SaveSort" ":key" .Model.Identify}}";
@@ -211,6 +213,72 @@
 <script src="{{cdnjs "/static/layer/layer.js"}}" type="text/javascript" ></script>
 <script src="{{cdnjs "/static/to-markdown/dist/to-markdown.js"}}" type="text/javascript"></script>
 <script src="{{cdnjs "/static/js/jquery.form.js"}}" type="text/javascript"></script>
+<!-- 文档属性 -->
+<div class="modal fade" id="documentFieldsModal" tabindex="-1" role="dialog" aria-labelledby="documentFieldsModalLabel">
+    <div class="modal-dialog" role="document">
+        <form method="post" action="{{urlfor "DocumentController.Fields" ":key" .Model.Identify}}" id="documentFieldsForm" class="form-horizontal">
+            <input type="hidden" name="doc_id" value="0">
+            <div class="modal-content">
+                <div class="modal-header">
+                    <button type="button" class="close" data-dismiss="modal" aria-label="Close"><span aria-hidden="true">&times;</span></button>
+                    <h4 class="modal-title" id="documentFieldsModalLabel">{{i18n .Lang "doc.doc_fields"}}</h4>
+                </div>
+                <div class="modal-body">
+                    <p class="fields-front-matter" style="color: #999;font-size: 12px;">{{i18n .Lang "doc.fields_front_matter_tips"}}</p>
+                    <div class="fields-body" data-empty="{{i18n .Lang "doc.fields_empty"}}"></div>
+                </div>
+                <div class="modal-footer">
+                    <span id="fields-error-message" class="error-message"></span>
+                    <button type="button" class="btn btn-default" data-dismiss="modal">{{i18n .Lang "common.cancel"}}</button>
+                    <button type="submit" class="btn btn-primary" id="btnSaveDocumentFields" data-loading-text="{{i18n .Lang "message.processing"}}">{{i18n .Lang "common.save"}}</button>
+                </div>
+            </div>
+        </form>
+    </div>
+</div>
+<!-- 移动或复制文档 -->
+<div class="modal fade" id="transferDocumentModal" tabindex="-1" role="dialog" aria-labelledby="transferDocumentModalLabel">
+    <div class="modal-dialog" role="document">
+        <form method="post" action="{{urlfor "DocumentController.Transfer" ":key" .Model.Identify}}" id="transferDocumentForm" class="form-horizontal">
+            <input type="hidden" name="doc_id" value="0">
+            <div class="modal-content">
+                <div class="modal-header">
+                    <button type="button" class="close" data-dismiss="modal" aria-label="Close"><span aria-hidden="true">&times;</span></button>
+                    <h4 class="modal-title" id="transferDocumentModalLabel">{{i18n .Lang "doc.transfer_doc"}}</h4>
+                </div>
+                <div class="modal-body">
+                    <div class="form-group">
+                        <label class="col-sm-3 control-label">{{i18n .Lang "doc.transfer_target_book"}}</label>
+                        <div class="col-sm-9">
+                            <select name="target" class="form-control"></select>
+                        </div>
+                    </div>
+                    <div class="form-group">
+                        <label class="col-sm-3 control-label">{{i18n .Lang "doc.transfer_parent"}}</label>
+                        <div class="col-sm-9">
+                            <select name="parent_id" class="form-control">
+                                <option value="0">{{i18n .Lang "doc.transfer_root"}}</option>
+                            </select>
+                        </div>
+                    </div>
+                    <div class="form-group">
+                        <div class="col-sm-9 col-sm-offset-3">
+                            <label class="radio-inline"><input type="radio" name="action" value="move" checked> {{i18n .Lang "doc.transfer_move"}}</label>
+                            <label class="radio-inline"><input type="radio" name="action" value="copy"> {{i18n .Lang "doc.transfer_copy"}}</label>
+                            <label class="checkbox-inline"><input type="checkbox" name="with_history" value="true"> {{i18n .Lang "doc.transfer_with_history"}}</label>
+                            <p style="color: #999;font-size: 12px;margin-top: 5px;">{{i18n .Lang "doc.transfer_tips"}}</p>
+                        </div>
+                    </div>
+                </div>
+                <div class="modal-footer">
+                    <span id="transfer-error-message" class="error-message"></span>
+                    <button type="button" class="btn btn-default" data-dismiss="modal">{{i18n .Lang "common.cancel"}}</button>
+                    <button type="submit" class="btn btn-primary" id="btnTransferDocument" data-loading-text="{{i18n .Lang "message.processing"}}">{{i18n .Lang "common.confirm"}}</button>
+                </div>
+            </div>
+        </form>
+    </div>
+</div>
 <script src="{{cdnjs "/static/js/editor.js"}}" type="text/javascript"></script>
 <script src="{{cdnjs "/static/js/html-editor.js"}}" type="text/javascript"></script>
 <script src="{{cdnjs "/static/js/custom-elements-builtin-0.6.5.min.js"}}" type="text/javascript"></script>
@@ -24,6 +24,23 @@
     <div class="container manual-body">
         <div class="row">
              <div class="manual-list">
+                {{if .ContinueReading}}
+                    <div class="panel panel-default">
+                        <div class="panel-heading">
+                            <h3 class="panel-title">
+                                <i class="fa fa-bookmark"></i> {{i18n $.Lang "doc.continue_reading"}}
+                            </h3>
+                        </div>
+                        <div class="list-group">
+                        {{range $item := .ContinueReading}}
+                            <a href="{{$item.Url}}" class="list-group-item">
+                                <span class="pull-right text-muted small">{{date_format $item.LastReadTime "2006-01-02 15:04"}}</span>
+                                <strong>{{$item.BookName}}</strong> / {{$item.DocumentName}}
+                            </a>
+                        {{end}}
+                        </div>
+                    </div>
+                {{end}}
                 {{range $idx, $itemId := .GroupedOrder}}
                     {{$books := index $.GroupedBooks $itemId}}
                     {{if gt (len $books) 0}}
@@ -29,6 +29,7 @@
                 <div class="m-box">
                     <div class="box-head">
                         <strong class="box-title">{{i18n .Lang "mgr.proj_list"}}</strong>
+                        <button type="button" class="btn btn-default btn-sm pull-right" id="btnCheckLinks" data-loading-text="{{i18n .Lang "message.processing"}}"><i class="fa fa-chain-broken" aria-hidden="true"></i> {{i18n .Lang "mgr.check_all_links"}}</button>
                     </div>
                 </div>
                 <div class="box-body" id="bookList">
@@ -82,6 +83,9 @@
                                 </span>
                                     <span title="{{i18n $.Lang "mgr.creator"}}" data-toggle="tooltip" data-placement="bottom"><i class="fa fa-user"></i> {{if eq $item.RealName "" }}{{$item.CreateName}}{{else}}{{$item.RealName}}{{end}}</span>
                                     <span title="{{i18n $.Lang "mgr.doc_amount"}}" data-toggle="tooltip" data-placement="bottom"><i class="fa fa-pie-chart"></i> {{$item.DocCount}}</span>
+                                    {{with index $.LinkChecks $item.BookId}}{{if gt .BrokenCount 0}}
+                                    <span title="{{i18n $.Lang "mgr.broken_link_amount"}}" data-toggle="tooltip" data-placement="bottom"><a href="{{urlfor "BookController.Dashboard" ":key" $item.Identify}}" class="text-danger"><i class="fa fa-chain-broken"></i> {{.BrokenCount}}</a></span>
+                                    {{end}}{{end}}
                                    {{if ne $item.LastModifyText ""}}
                                     <span title="{{i18n $.Lang "mgr.last_edit"}}" data-toggle="tooltip" data-placement="bottom"><i class="fa fa-pencil"></i> {{i18n .Lang "mgr.last_edit"}}: {{$item.LastModifyText}}</span>
                                     {{end}}
@@ -129,6 +133,7 @@
 <script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}"></script>
 <script src="{{cdnjs "/static/vuejs/vue.min.js"}}" type="text/javascript"></script>
 <script src="{{cdnjs "/static/js/jquery.form.js"}}" type="text/javascript"></script>
+<script src="{{cdnjs "/static/layer/layer.js"}}" type="text/javascript"></script>
 <script src="{{cdnjs "/static/js/main.js"}}" type="text/javascript"></script>
 <script type="text/javascript">
         /**
@@ -142,6 +147,14 @@
             /**
              * 删除项目
              */
+            $("#btnCheckLinks").on("click", function () {
+                var $btn = $(this);
+                $btn.button("loading");
+                $.post("{{urlfor "ManagerController.CheckLinks"}}", function (res) {
+                    $btn.button("reset");
+                    layer.msg(res.message);
+                }, "json");
+            });
             $("#deleteBookForm").ajaxForm({
                 beforeSubmit : function () {
                     $("#btnDeleteBook").button("loading");
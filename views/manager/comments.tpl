@@ -5,48 +5,100 @@
     <meta http-equiv="X-UA-Compatible" content="IE=edge">
     <meta name="viewport" content="width=device-width, initial-scale=1">
 
-    <title>用户中心 - Powered by MinDoc</title>
+    <title>{{i18n .Lang "mgr.comment_menu"}} - Powered by MinDoc</title>
 
     <!-- Bootstrap -->
-    <link href="/static/bootstrap/css/bootstrap.min.css" rel="stylesheet">
-    <link href="/static/font-awesome/css/font-awesome.min.css" rel="stylesheet">
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet" type="text/css">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet" type="text/css">
 
-    <link href="/static/css/main.css" rel="stylesheet">
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
     <!-- HTML5 shim and Respond.js for IE8 support of HTML5 elements and media queries -->
     <!-- WARNING: Respond.js doesn't work if you view the page via file:// -->
     <!--[if lt IE 9]>
-    <script src="/static/html5shiv/3.7.3/html5shiv.min.js"></script>
-    <script src="/static/respond.js/1.4.2/respond.min.js"></script>
+    <script src="{{cdnjs "/static/html5shiv/3.7.3/html5shiv.min.js"}}"></script>
+    <script src="{{cdnjs "/static/respond.js/1.4.2/respond.min.js" }}"></script>
     <![endif]-->
+    <style type="text/css">
+        .table > tbody > tr > td {
+            vertical-align: middle;
+        }
+        .comment-content {
+            max-width: 360px;
+            word-wrap: break-word;
+            white-space: pre-wrap;
+        }
+        .comment-quote {
+            max-width: 360px;
+            margin: 0 0 5px;
+            padding: 2px 8px;
+            font-size: 12px;
+            color: #777;
+            border-left: 3px solid #f0ad4e;
+        }
+    </style>
 </head>
 <body>
 <div class="manual-reader">
     {{template "widgets/header.tpl" .}}
     <div class="container manual-body">
         <div class="row">
-            <div class="page-left">
-                <ul class="menu">
-                    <li><a href="{{urlfor "ManagerController.Index"}}" class="item"><i class="fa fa-dashboard" aria-hidden="true"></i> 仪表盘</a> </li>
-                    <li><a href="{{urlfor "ManagerController.Users" }}" class="item"><i class="fa fa-users" aria-hidden="true"></i> 用户管理</a> </li>
-                    <li><a href="{{urlfor "ManagerController.Books" }}" class="item"><i class="fa fa-book" aria-hidden="true"></i> 项目管理</a> </li>
-                    <li class="active"><a href="{{urlfor "ManagerController.Comments" }}" class="item"><i class="fa fa-comments-o" aria-hidden="true"></i> 评论管理</a> </li>
-                    <li><a href="{{urlfor "ManagerController.Setting" }}" class="item"><i class="fa fa-cogs" aria-hidden="true"></i> 配置管理</a> </li>
-                </ul>
-            </div>
+        {{template "manager/widgets.tpl" .}}
             <div class="page-right">
                 <div class="m-box">
                     <div class="box-head">
-                        <strong class="box-title">评论管理</strong>
+                        <strong class="box-title">{{i18n .Lang "mgr.comment_menu"}}</strong>
                     </div>
                 </div>
                 <div class="box-body">
-
+                    {{template "widgets/comment_moderation.tpl" .}}
                 </div>
             </div>
         </div>
     </div>
+    {{template "widgets/footer.tpl" .}}
 </div>
-<script src="/static/jquery/1.12.4/jquery.min.js"></script>
-<script src="/static/bootstrap/js/bootstrap.min.js"></script>
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}"></script>
+<script src="{{cdnjs "/static/js/main.js"}}" type="text/javascript"></script>
+<script type="text/javascript">
+    $(function () {
+        $("#checkAllComment").on("change", function () {
+            $("#commentModeration input[name='comment_id']").prop("checked", $(this).prop("checked"));
+        });
+        $(".btn-moderate").on("click", function () {
+            var $btn = $(this);
+            var ids = $("#commentModeration input[name='comment_id']:checked").map(function () {
+                return $(this).val();
+            }).get();
+            if (ids.length === 0) {
+                showError("{{i18n .Lang "blog.comment_select_tips"}}");
+                return;
+            }
+            if ($btn.data("confirm") && !confirm($btn.data("confirm"))) {
+                return;
+            }
+            $btn.prop("disabled", true);
+            $.ajax({
+                url: $("#commentModeration").data("url"),
+                type: "POST",
+                data: { "action": $btn.data("action"), "comment_id": ids },
+                traditional: true,
+                dataType: "json",
+                success: function (res) {
+                    if (res.errcode === 0) {
+                        window.location.reload();
+                    } else {
+                        $btn.prop("disabled", false);
+                        showError(res.message);
+                    }
+                },
+                error: function () {
+                    $btn.prop("disabled", false);
+                    showError("{{i18n .Lang "message.system_error"}}");
+                }
+            });
+        });
+    });
+</script>
 </body>
-</html>
\ No newline at end of file
+</html>
@@ -64,11 +64,42 @@
                         </div>
                         <div class="form-group">
                             <button type="submit" id="btnMemberInfo" class="btn btn-success" data-loading-text="{{i18n .Lang "message.processing"}}">{{i18n .Lang "common.save"}}</button>
+                            {{if .TwoFactorEnabled}}
+                            <button type="button" id="btnResetTwoFactor" class="btn btn-danger" data-loading-text="{{i18n .Lang "message.processing"}}">{{i18n .Lang "uc.reset_two_factor"}}</button>
+                            {{end}}
+                            {{if .Sessions}}
+                            <button type="button" id="btnLogoutMember" class="btn btn-danger" data-loading-text="{{i18n .Lang "message.processing"}}">{{i18n .Lang "uc.force_logout"}}</button>
+                            {{end}}
                             <span id="form-error-message" class="error-message"></span>
                         </div>
                     </form>
 
                     <div class="clearfix"></div>
+                    {{if .Sessions}}
+                    <div class="form-group" id="memberSessions">
+                        <label>{{i18n .Lang "uc.sessions"}}</label>
+                        <table class="table">
+                            <thead>
+                            <tr>
+                                <th>{{i18n .Lang "uc.user_agent"}}</th>
+                                <th>{{i18n .Lang "uc.ip_address"}}</th>
+                                <th>{{i18n .Lang "uc.login_time"}}</th>
+                                <th>{{i18n .Lang "uc.last_seen_time"}}</th>
+                            </tr>
+                            </thead>
+                            <tbody>
+                            {{range $index,$item := .Sessions}}
+                            <tr>
+                                <td style="word-break: break-all;">{{$item.UserAgent}}</td>
+                                <td>{{$item.IPAddress}}</td>
+                                <td>{{date $item.CreateTime "Y-m-d H:i:s"}}</td>
+                                <td>{{date $item.LastSeenTime "Y-m-d H:i:s"}}</td>
+                            </tr>
+                            {{end}}
+                            </tbody>
+                        </table>
+                    </div>
+                    {{end}}
 
                 </div>
             </div>
@@ -107,6 +138,37 @@
                 $("#btnMemberInfo").button("reset");
             }
         });
+        $("#btnResetTwoFactor").on("click", function () {
+            if (!confirm({{i18n .Lang "uc.reset_two_factor_confirm"}})) {
+                return;
+            }
+            var $btn = $(this).button("loading");
+            $.post({{urlfor "ManagerController.ResetTwoFactor"}}, { "member_id" : {{.Model.MemberId}} }, function (res) {
+                if(res.errcode === 0) {
+                    showSuccess({{i18n .Lang "message.success"}});
+                    $btn.remove();
+                }else{
+                    showError(res.message);
+                    $btn.button("reset");
+                }
+            }, "json");
+        });
+        $("#btnLogoutMember").on("click", function () {
+            if (!confirm({{i18n .Lang "uc.force_logout_confirm"}})) {
+                return;
+            }
+            var $btn = $(this).button("loading");
+            $.post({{urlfor "ManagerController.LogoutMember"}}, { "member_id" : {{.Model.MemberId}} }, function (res) {
+                if(res.errcode === 0) {
+                    showSuccess({{i18n .Lang "message.success"}});
+                    $btn.remove();
+                    $("#memberSessions").remove();
+                }else{
+                    showError(res.message);
+                    $btn.button("reset");
+                }
+            }, "json");
+        });
     });
 </script>
 </body>
@@ -52,6 +52,7 @@
                                     {{if ne $item.ItemId 1}}
                                     <button type="button" data-method="delete" class="btn btn-danger btn-sm" data-id="{{$item.ItemId}}" data-loading-text="{{i18n $.Lang "message.processing"}}">{{i18n $.Lang "common.delete"}}</button>
                                     {{end}}
+                                    <a href="{{urlfor "ManagerController.ItemsetsFields" ":id" $item.ItemId}}" class="btn btn-default btn-sm">{{i18n $.Lang "blog.custom_fields"}}</a>
                                     <a href="{{urlfor "ItemsetsController.List" ":key" $item.ItemKey}}" class="btn btn-success btn-sm" target="_blank">{{i18n $.Lang "common.detail"}}</a>
                                 </td>
                             </tr>
@@ -0,0 +1,78 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n .Lang "mgr.login_log_mgr"}} - Powered by MinDoc</title>
+
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet" type="text/css">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet" type="text/css">
+
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+    <!-- HTML5 shim and Respond.js for IE8 support of HTML5 elements and media queries -->
+    <!-- WARNING: Respond.js doesn't work if you view the page via file:// -->
+    <!--[if lt IE 9]>
+    <script src="{{cdnjs "/static/html5shiv/3.7.3/html5shiv.min.js"}}"></script>
+    <script src="{{cdnjs "/static/respond.js/1.4.2/respond.min.js" }}"></script>
+    <![endif]-->
+</head>
+<body>
+<div class="manual-reader">
+    {{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+        {{template "manager/widgets.tpl" .}}
+            <div class="page-right">
+                <div class="m-box">
+                    <div class="box-head">
+                        <strong class="box-title">{{i18n .Lang "mgr.login_log_mgr"}}</strong>
+                        <form class="form-inline pull-right" method="get" action="{{urlfor "ManagerController.LoginLogs"}}">
+                            <input type="text" class="form-control input-sm" name="account" value="{{.Account}}" placeholder="{{i18n .Lang "uc.username"}}">
+                            <button type="submit" class="btn btn-default btn-sm">{{i18n .Lang "doc.search"}}</button>
+                        </form>
+                    </div>
+                </div>
+                <div class="box-body">
+                    <table class="table">
+                        <thead>
+                        <tr>
+                            <th>{{i18n .Lang "uc.username"}}</th>
+                            <th>{{i18n .Lang "uc.login_time"}}</th>
+                            <th>{{i18n .Lang "uc.login_status"}}</th>
+                            <th>{{i18n .Lang "uc.login_method"}}</th>
+                            <th>{{i18n .Lang "uc.ip_address"}}</th>
+                            <th>{{i18n .Lang "uc.user_agent"}}</th>
+                        </tr>
+                        </thead>
+                        <tbody>
+                        {{range $index,$item := .Lists}}
+                        <tr>
+                            <td>{{$item.Account}}</td>
+                            <td>{{date $item.CreateTime "Y-m-d H:i:s"}}</td>
+                            <td>{{if eq $item.Status 0}}<span class="text-success">{{i18n $.Lang "uc.login_success"}}</span>{{else}}<span class="text-danger">{{i18n $.Lang "uc.login_failed"}}({{i18n $.Lang (printf "uc.login_reason_%s" $item.Reason)}})</span>{{end}}</td>
+                            <td>{{$item.AuthMethod}}</td>
+                            <td>{{$item.IPAddress}}</td>
+                            <td style="word-break: break-all;">{{$item.UserAgent}}</td>
+                        </tr>
+                        {{else}}
+                        <tr><td class="text-center" colspan="6">{{i18n .Lang "message.no_data"}}</td></tr>
+                        {{end}}
+                        </tbody>
+                    </table>
+                    <nav class="pagination-container">
+                        {{.PageHtml}}
+                    </nav>
+                </div>
+            </div>
+        </div>
+    </div>
+    {{template "widgets/footer.tpl" .}}
+</div>
+
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}"></script>
+</body>
+</html>
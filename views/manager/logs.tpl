@@ -0,0 +1,114 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n .Lang "mgr.audit_log_mgr"}} - Powered by MinDoc</title>
+
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet" type="text/css">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet" type="text/css">
+
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+    <!-- HTML5 shim and Respond.js for IE8 support of HTML5 elements and media queries -->
+    <!-- WARNING: Respond.js doesn't work if you view the page via file:// -->
+    <!--[if lt IE 9]>
+    <script src="{{cdnjs "/static/html5shiv/3.7.3/html5shiv.min.js"}}"></script>
+    <script src="{{cdnjs "/static/respond.js/1.4.2/respond.min.js" }}"></script>
+    <![endif]-->
+</head>
+<body>
+<div class="manual-reader">
+    {{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+        {{template "manager/widgets.tpl" .}}
+            <div class="page-right">
+                <div class="m-box">
+                    <div class="box-head">
+                        <strong class="box-title">{{i18n .Lang "mgr.audit_log_mgr"}}</strong>
+                        <div class="pull-right">
+                            <a href="{{urlfor "ManagerController.ExportLogs"}}?format=csv&{{.Query}}" class="btn btn-default btn-sm"><i class="fa fa-download"></i> CSV</a>
+                            <a href="{{urlfor "ManagerController.ExportLogs"}}?format=json&{{.Query}}" class="btn btn-default btn-sm"><i class="fa fa-download"></i> JSON</a>
+                        </div>
+                    </div>
+                </div>
+                <div class="box-body">
+                    <form class="form-inline" method="get" action="{{urlfor "ManagerController.Logs"}}" style="margin-bottom: 15px;">
+                        <select class="form-control input-sm" name="category">
+                            <option value="">{{i18n .Lang "mgr.audit_log_all_categories"}}</option>
+                            {{range $item := .Categories}}
+                            <option value="{{$item}}"{{if eq $item $.Filter.Category}} selected{{end}}>{{i18n $.Lang (printf "mgr.audit_category_%s" $item)}}</option>
+                            {{end}}
+                        </select>
+                        <select class="form-control input-sm" name="action">
+                            <option value="">{{i18n .Lang "mgr.audit_log_all_actions"}}</option>
+                            {{range $item := .AuditActions}}
+                            <option value="{{$item}}"{{if eq $item $.Filter.Action}} selected{{end}}>{{i18n $.Lang (printf "mgr.audit_action_%s" $item)}}</option>
+                            {{end}}
+                        </select>
+                        <input type="text" class="form-control input-sm" name="account" value="{{.Filter.Account}}" placeholder="{{i18n .Lang "uc.username"}}">
+                        <input type="date" class="form-control input-sm" name="start_date" value="{{.StartDate}}">
+                        <input type="date" class="form-control input-sm" name="end_date" value="{{.EndDate}}">
+                        <button type="submit" class="btn btn-default btn-sm">{{i18n .Lang "doc.search"}}</button>
+                    </form>
+                    <table class="table">
+                        <thead>
+                        <tr>
+                            <th>{{i18n .Lang "mgr.audit_log_time"}}</th>
+                            <th>{{i18n .Lang "uc.username"}}</th>
+                            <th>{{i18n .Lang "mgr.audit_log_category"}}</th>
+                            <th>{{i18n .Lang "mgr.audit_log_action"}}</th>
+                            <th>{{i18n .Lang "mgr.audit_log_object"}}</th>
+                            <th>{{i18n .Lang "uc.ip_address"}}</th>
+                            <th></th>
+                        </tr>
+                        </thead>
+                        <tbody>
+                        {{range $index,$item := .Lists}}
+                        <tr>
+                            <td>{{date $item.CreateTime "Y-m-d H:i:s"}}</td>
+                            <td>{{$item.Account}}</td>
+                            <td>{{i18n $.Lang (printf "mgr.audit_category_%s" $item.Category)}}</td>
+                            <td>{{if $item.Action}}{{i18n $.Lang (printf "mgr.audit_action_%s" $item.Action)}}{{end}}</td>
+                            <td style="word-break: break-all;">{{$item.Content}}</td>
+                            <td>{{$item.IPAddress}}</td>
+                            <td>{{if or $item.OriginalData $item.PresentData}}<a href="javascript:;" class="btn-log-detail" data-target="#logDetail{{$item.LoggerId}}">{{i18n $.Lang "mgr.audit_log_detail"}}</a>{{end}}</td>
+                        </tr>
+                        {{if or $item.OriginalData $item.PresentData}}
+                        <tr id="logDetail{{$item.LoggerId}}" style="display: none;">
+                            <td colspan="7" style="word-break: break-all;">
+                                {{if $item.OriginalData}}<p><strong>{{i18n $.Lang "mgr.audit_log_original"}}:</strong> <code>{{$item.OriginalData}}</code></p>{{end}}
+                                {{if $item.PresentData}}<p><strong>{{i18n $.Lang "mgr.audit_log_present"}}:</strong> <code>{{$item.PresentData}}</code></p>{{end}}
+                                <p style="color: #999;">{{$item.UserAgent}}</p>
+                            </td>
+                        </tr>
+                        {{end}}
+                        {{else}}
+                        <tr><td class="text-center" colspan="7">{{i18n .Lang "message.no_data"}}</td></tr>
+                        {{end}}
+                        </tbody>
+                    </table>
+                    <nav class="pagination-container">
+                        {{.PageHtml}}
+                    </nav>
+                </div>
+            </div>
+        </div>
+    </div>
+    {{template "widgets/footer.tpl" .}}
+</div>
+
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}"></script>
+<script type="text/javascript">
+    $(function () {
+        $(".btn-log-detail").on("click", function () {
+            $($(this).data("target")).toggle();
+        });
+    });
+</script>
+</body>
+</html>
@@ -0,0 +1,111 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n .Lang "mgr.mail_queue"}} - Powered by MinDoc</title>
+
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet" type="text/css">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet" type="text/css">
+
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+    <!-- HTML5 shim and Respond.js for IE8 support of HTML5 elements and media queries -->
+    <!-- WARNING: Respond.js doesn't work if you view the page via file:// -->
+    <!--[if lt IE 9]>
+    <script src="{{cdnjs "/static/html5shiv/3.7.3/html5shiv.min.js"}}"></script>
+    <script src="{{cdnjs "/static/respond.js/1.4.2/respond.min.js" }}"></script>
+    <![endif]-->
+</head>
+<body>
+<div class="manual-reader">
+    {{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+        {{template "manager/widgets.tpl" .}}
+            <div class="page-right">
+                <div class="m-box">
+                    <div class="box-head">
+                        <strong class="box-title">{{i18n .Lang "mgr.mail_menu"}}</strong>
+                    </div>
+                </div>
+                <div class="box-body">
+                    <ul class="nav nav-tabs" style="margin-bottom: 15px;">
+                        <li><a href="{{urlfor "ManagerController.MailTemplates"}}">{{i18n .Lang "mgr.mail_templates"}}</a></li>
+                        <li class="active"><a href="{{urlfor "ManagerController.MailQueue"}}">{{i18n .Lang "mgr.mail_queue"}}</a></li>
+                    </ul>
+                    <div class="btn-group" style="margin-bottom: 10px;">
+                        <a href="?status=-1" class="btn btn-default btn-sm{{if eq .Status -1}} active{{end}}">{{i18n .Lang "mgr.mail_status_all"}}</a>
+                        <a href="?status=0" class="btn btn-default btn-sm{{if eq .Status 0}} active{{end}}">{{i18n .Lang "mgr.mail_status_0"}}</a>
+                        <a href="?status=1" class="btn btn-default btn-sm{{if eq .Status 1}} active{{end}}">{{i18n .Lang "mgr.mail_status_1"}}</a>
+                        <a href="?status=2" class="btn btn-default btn-sm{{if eq .Status 2}} active{{end}}">{{i18n .Lang "mgr.mail_status_2"}}</a>
+                    </div>
+                    <p><span id="form-error-message" class="error-message"></span></p>
+                    <table class="table">
+                        <thead>
+                        <tr>
+                            <th>{{i18n .Lang "mgr.mail_to"}}</th>
+                            <th>{{i18n .Lang "mgr.mail_subject"}}</th>
+                            <th>{{i18n .Lang "mgr.mail_template_name"}}</th>
+                            <th>{{i18n .Lang "mgr.mail_status"}}</th>
+                            <th>{{i18n .Lang "mgr.mail_attempts"}}</th>
+                            <th width="160">{{i18n .Lang "mgr.mail_time"}}</th>
+                            <th></th>
+                        </tr>
+                        </thead>
+                        <tbody>
+                        {{range $index,$item := .Lists}}
+                        <tr>
+                            <td>{{$item.MailTo}}</td>
+                            <td style="word-break: break-all;">{{$item.Subject}}</td>
+                            <td>{{i18n $.Lang (printf "mgr.mail_template_%s" $item.Template)}}</td>
+                            <td>
+                                {{if eq $item.Status 1}}<span class="label label-success">{{else if eq $item.Status 2}}<span class="label label-danger">{{else}}<span class="label label-default">{{end}}{{i18n $.Lang (printf "mgr.mail_status_%d" $item.Status)}}</span>
+                                {{if $item.LastError}}<p class="text-danger" style="margin: 5px 0 0; font-size: 12px; word-break: break-all;">{{$item.LastError}}</p>{{end}}
+                            </td>
+                            <td>{{$item.Attempts}}</td>
+                            <td>
+                                {{date $item.CreateTime "Y-m-d H:i:s"}}
+                                {{if eq $item.Status 0}}<br><span class="text-muted" title="{{i18n $.Lang "mgr.mail_next_time"}}"><i class="fa fa-clock-o"></i> {{date $item.NextTime "Y-m-d H:i:s"}}</span>{{end}}
+                                {{if eq $item.Status 1}}<br><span class="text-muted" title="{{i18n $.Lang "mgr.mail_send_time"}}"><i class="fa fa-check"></i> {{date $item.SendTime "Y-m-d H:i:s"}}</span>{{end}}
+                            </td>
+                            <td>{{if ne $item.Status 1}}<button type="button" class="btn btn-default btn-sm btn-retry" data-id="{{$item.MailId}}">{{i18n $.Lang "mgr.mail_retry"}}</button>{{end}}</td>
+                        </tr>
+                        {{else}}
+                        <tr><td class="text-center" colspan="7">{{i18n .Lang "message.no_data"}}</td></tr>
+                        {{end}}
+                        </tbody>
+                    </table>
+                    <nav class="pagination-container">
+                        {{.PageHtml}}
+                    </nav>
+                </div>
+            </div>
+        </div>
+    </div>
+    {{template "widgets/footer.tpl" .}}
+</div>
+
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}"></script>
+<script src="{{cdnjs "/static/js/main.js"}}" type="text/javascript"></script>
+<script type="text/javascript">
+    $(function () {
+        $(".btn-retry").on("click", function () {
+            var $btn = $(this);
+            $btn.prop("disabled", true);
+            $.post("{{urlfor "ManagerController.MailQueueRetry"}}", { "mail_id": $btn.data("id") }, function (res) {
+                if (res.errcode === 0) {
+                    window.location.reload();
+                } else {
+                    $btn.prop("disabled", false);
+                    showError(res.message);
+                }
+            }, "json");
+        });
+    });
+</script>
+</body>
+</html>
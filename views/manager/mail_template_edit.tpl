@@ -0,0 +1,152 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n .Lang "mgr.mail_template_edit"}} - Powered by MinDoc</title>
+
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet" type="text/css">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet" type="text/css">
+
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+    <!-- HTML5 shim and Respond.js for IE8 support of HTML5 elements and media queries -->
+    <!-- WARNING: Respond.js doesn't work if you view the page via file:// -->
+    <!--[if lt IE 9]>
+    <script src="{{cdnjs "/static/html5shiv/3.7.3/html5shiv.min.js"}}"></script>
+    <script src="{{cdnjs "/static/respond.js/1.4.2/respond.min.js" }}"></script>
+    <![endif]-->
+    <style type="text/css">
+        .mail-template-form textarea {
+            font-family: Consolas, Monaco, monospace;
+            font-size: 12px;
+        }
+        .mail-preview-frame {
+            width: 100%;
+            height: 480px;
+            border: 1px solid #ddd;
+        }
+        .mail-preview-text {
+            max-height: 300px;
+            overflow: auto;
+            white-space: pre-wrap;
+        }
+    </style>
+</head>
+<body>
+<div class="manual-reader">
+    {{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+        {{template "manager/widgets.tpl" .}}
+            <div class="page-right">
+                <div class="m-box">
+                    <div class="box-head">
+                        <strong class="box-title">{{i18n .Lang (printf "mgr.mail_template_%s" .Define.Name)}} - {{.LanguageName}}</strong>
+                        <div class="pull-right">
+                            <a href="{{urlfor "ManagerController.MailTemplates"}}" class="btn btn-default btn-sm"><i class="fa fa-reply"></i> {{i18n .Lang "common.back"}}</a>
+                        </div>
+                    </div>
+                </div>
+                <div class="box-body">
+                    <div class="row">
+                        <div class="col-sm-6">
+                            <form method="post" id="mailTemplateForm" class="mail-template-form" action="{{urlfor "ManagerController.MailTemplateEdit"}}">
+                                <input type="hidden" name="name" value="{{.Model.Name}}">
+                                <input type="hidden" name="lang" value="{{.Model.Lang}}">
+                                <p class="text-muted">{{i18n .Lang "mgr.mail_template_edit_tips"}}</p>
+                                <p class="text-muted">{{i18n .Lang "mgr.mail_template_variables"}}: {{range $item := .Variables}}<code>{{$item}}</code> {{end}}</p>
+                                <div class="form-group">
+                                    <label>{{i18n .Lang "mgr.mail_subject"}}</label>
+                                    <input type="text" class="form-control" name="subject" value="{{.Model.Subject}}" placeholder="{{.DefaultSubject}}">
+                                </div>
+                                <div class="form-group">
+                                    <label>{{i18n .Lang "mgr.mail_html"}}</label>
+                                    <a href="javascript:;" id="btnLoadDefault" class="pull-right">{{i18n .Lang "mgr.mail_template_load_default"}}</a>
+                                    <textarea class="form-control" rows="16" name="html" placeholder="{{i18n .Lang "mgr.mail_template_default"}}">{{.Model.Html}}</textarea>
+                                    <textarea id="defaultHtml" style="display: none;">{{.DefaultHtml}}</textarea>
+                                </div>
+                                <div class="form-group">
+                                    <label>{{i18n .Lang "mgr.mail_text"}}</label>
+                                    <textarea class="form-control" rows="6" name="text" placeholder="{{i18n .Lang "mgr.mail_text_tips"}}">{{.Model.Text}}</textarea>
+                                </div>
+                                <div class="form-group">
+                                    <button type="submit" id="btnSaveTemplate" class="btn btn-success" data-loading-text="{{i18n .Lang "message.processing"}}">{{i18n .Lang "common.save"}}</button>
+                                    <button type="button" id="btnPreview" class="btn btn-default">{{i18n .Lang "mgr.mail_template_preview"}}</button>
+                                    <button type="button" id="btnReset" class="btn btn-danger" data-confirm="{{i18n .Lang "mgr.mail_template_reset_confirm"}}">{{i18n .Lang "mgr.mail_template_reset"}}</button>
+                                    <span id="form-error-message" class="error-message"></span>
+                                </div>
+                            </form>
+                        </div>
+                        <div class="col-sm-6">
+                            <p><strong>{{i18n .Lang "mgr.mail_subject"}}:</strong> <span id="previewSubject"></span></p>
+                            <iframe id="previewHtml" class="mail-preview-frame" sandbox=""></iframe>
+                            <label style="margin-top: 10px;">{{i18n .Lang "mgr.mail_text"}}</label>
+                            <pre id="previewText" class="mail-preview-text"></pre>
+                        </div>
+                    </div>
+                </div>
+            </div>
+        </div>
+    </div>
+    {{template "widgets/footer.tpl" .}}
+</div>
+
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}"></script>
+<script src="{{cdnjs "/static/js/jquery.form.js"}}" type="text/javascript"></script>
+<script src="{{cdnjs "/static/js/main.js"}}" type="text/javascript"></script>
+<script type="text/javascript">
+    $(function () {
+        var $form = $("#mailTemplateForm");
+
+        function preview() {
+            $.post("{{urlfor "ManagerController.MailTemplatePreview"}}", $form.serialize(), function (res) {
+                if (res.errcode === 0) {
+                    $("#previewSubject").text(res.data.Subject);
+                    $("#previewHtml").attr("srcdoc", res.data.HTML);
+                    $("#previewText").text(res.data.Text);
+                    $("#form-error-message").hide();
+                } else {
+                    showError(res.message);
+                }
+            }, "json");
+        }
+
+        $form.ajaxForm({
+            beforeSubmit: function () {
+                $("#btnSaveTemplate").button("loading");
+            },
+            success: function (res) {
+                if (res.errcode === 0) {
+                    showSuccess("{{i18n .Lang "message.success"}}");
+                    preview();
+                } else {
+                    showError(res.message);
+                }
+                $("#btnSaveTemplate").button("reset");
+            }
+        });
+        $("#btnPreview").on("click", preview);
+        $("#btnLoadDefault").on("click", function () {
+            $form.find("textarea[name='html']").val($("#defaultHtml").val());
+        });
+        $("#btnReset").on("click", function () {
+            if (!confirm($(this).data("confirm"))) {
+                return;
+            }
+            $.post("{{urlfor "ManagerController.MailTemplateReset"}}", { "name": "{{.Model.Name}}", "lang": "{{.Model.Lang}}" }, function (res) {
+                if (res.errcode === 0) {
+                    window.location.reload();
+                } else {
+                    showError(res.message);
+                }
+            }, "json");
+        });
+        preview();
+    });
+</script>
+</body>
+</html>
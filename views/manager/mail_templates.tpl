@@ -0,0 +1,78 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n .Lang "mgr.mail_templates"}} - Powered by MinDoc</title>
+
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet" type="text/css">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet" type="text/css">
+
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+    <!-- HTML5 shim and Respond.js for IE8 support of HTML5 elements and media queries -->
+    <!-- WARNING: Respond.js doesn't work if you view the page via file:// -->
+    <!--[if lt IE 9]>
+    <script src="{{cdnjs "/static/html5shiv/3.7.3/html5shiv.min.js"}}"></script>
+    <script src="{{cdnjs "/static/respond.js/1.4.2/respond.min.js" }}"></script>
+    <![endif]-->
+</head>
+<body>
+<div class="manual-reader">
+    {{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+        {{template "manager/widgets.tpl" .}}
+            <div class="page-right">
+                <div class="m-box">
+                    <div class="box-head">
+                        <strong class="box-title">{{i18n .Lang "mgr.mail_menu"}}</strong>
+                    </div>
+                </div>
+                <div class="box-body">
+                    <ul class="nav nav-tabs" style="margin-bottom: 15px;">
+                        <li class="active"><a href="{{urlfor "ManagerController.MailTemplates"}}">{{i18n .Lang "mgr.mail_templates"}}</a></li>
+                        <li><a href="{{urlfor "ManagerController.MailQueue"}}">{{i18n .Lang "mgr.mail_queue"}}</a></li>
+                    </ul>
+                    <p class="text-muted">{{i18n .Lang "mgr.mail_templates_tips"}}</p>
+                    <table class="table">
+                        <thead>
+                        <tr>
+                            <th>{{i18n .Lang "mgr.mail_template_name"}}</th>
+                            {{range $lang, $name := .Languages}}
+                            <th>{{$name}}</th>
+                            {{end}}
+                        </tr>
+                        </thead>
+                        <tbody>
+                        {{range $index,$item := .Lists}}
+                        <tr>
+                            <td>{{i18n $.Lang (printf "mgr.mail_template_%s" $item.Name)}}<br><code>{{$item.Name}}</code></td>
+                            {{range $lang, $name := $.Languages}}
+                            <td>
+                                {{if index $.Customized (printf "%s/%s" $item.Name $lang)}}
+                                <span class="label label-warning">{{i18n $.Lang "mgr.mail_template_customized"}}</span>
+                                {{else}}
+                                <span class="label label-default">{{i18n $.Lang "mgr.mail_template_default"}}</span>
+                                {{end}}
+                                <a href="{{urlfor "ManagerController.MailTemplateEdit" "name" $item.Name "lang" $lang}}" class="btn btn-default btn-sm" style="margin-left: 5px;">{{i18n $.Lang "common.edit"}}</a>
+                                <a href="{{urlfor "ManagerController.MailTemplatePreview" "name" $item.Name "lang" $lang}}" class="btn btn-default btn-sm" target="_blank">{{i18n $.Lang "mgr.mail_template_preview"}}</a>
+                            </td>
+                            {{end}}
+                        </tr>
+                        {{end}}
+                        </tbody>
+                    </table>
+                </div>
+            </div>
+        </div>
+    </div>
+    {{template "widgets/footer.tpl" .}}
+</div>
+
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}"></script>
+</body>
+</html>
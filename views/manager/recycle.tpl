@@ -0,0 +1,99 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n .Lang "mgr.recycle_bin"}} - Powered by MinDoc</title>
+
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet" type="text/css">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet" type="text/css">
+
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+    <!-- HTML5 shim and Respond.js for IE8 support of HTML5 elements and media queries -->
+    <!-- WARNING: Respond.js doesn't work if you view the page via file:// -->
+    <!--[if lt IE 9]>
+    <script src="{{cdnjs "/static/html5shiv/3.7.3/html5shiv.min.js"}}"></script>
+    <script src="{{cdnjs "/static/respond.js/1.4.2/respond.min.js" }}"></script>
+    <![endif]-->
+</head>
+<body>
+<div class="manual-reader">
+    {{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+        {{template "manager/widgets.tpl" .}}
+            <div class="page-right">
+                <div class="m-box">
+                    <div class="box-head">
+                        <strong class="box-title">{{i18n .Lang "mgr.recycle_bin"}}</strong>
+                    </div>
+                </div>
+                <div class="box-body">
+                    <p style="color: #999;font-size: 12px;">{{i18n .Lang "mgr.recycle_bin_tips"}}</p>
+                    <p><span id="form-error-message" class="error-message"></span></p>
+                    <table class="table">
+                        <thead>
+                        <tr>
+                            <th>{{i18n .Lang "mgr.recycle_type"}}</th>
+                            <th>{{i18n .Lang "mgr.recycle_title"}}</th>
+                            <th>{{i18n .Lang "blog.recycle_document_count"}}</th>
+                            <th>{{i18n .Lang "blog.recycle_delete_by"}}</th>
+                            <th>{{i18n .Lang "blog.recycle_delete_time"}}</th>
+                            <th width="160">{{i18n .Lang "common.operate"}}</th>
+                        </tr>
+                        </thead>
+                        <tbody>
+                        {{range $index,$item := .Lists}}
+                        <tr>
+                            <td>{{i18n $.Lang (printf "mgr.recycle_type_%s" $item.ObjectType)}}</td>
+                            <td>{{$item.Title}}{{if and (eq $item.ObjectType "book") $item.Identify}} <span style="color: #999;">({{$item.Identify}})</span>{{end}}</td>
+                            <td>{{$item.DocumentCount}}</td>
+                            <td>{{$item.Account}}</td>
+                            <td>{{date $item.CreateTime "Y-m-d H:i:s"}}</td>
+                            <td>
+                                <button type="button" class="btn btn-success btn-sm btn-recycle" data-url="{{urlfor "ManagerController.RecycleRestore"}}" data-id="{{$item.RecycleId}}" data-loading-text="{{i18n $.Lang "message.processing"}}">{{i18n $.Lang "blog.recycle_restore"}}</button>
+                                <button type="button" class="btn btn-danger btn-sm btn-recycle" data-url="{{urlfor "ManagerController.RecyclePurge"}}" data-id="{{$item.RecycleId}}" data-confirm="{{i18n $.Lang "blog.recycle_purge_confirm"}}" data-loading-text="{{i18n $.Lang "message.processing"}}">{{i18n $.Lang "blog.recycle_purge"}}</button>
+                            </td>
+                        </tr>
+                        {{else}}
+                        <tr><td class="text-center" colspan="6">{{i18n .Lang "message.no_data"}}</td></tr>
+                        {{end}}
+                        </tbody>
+                    </table>
+                    <nav class="pagination-container">
+                        {{.PageHtml}}
+                    </nav>
+                </div>
+            </div>
+        </div>
+    </div>
+    {{template "widgets/footer.tpl" .}}
+</div>
+
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}"></script>
+<script src="{{cdnjs "/static/js/main.js"}}" type="text/javascript"></script>
+<script type="text/javascript">
+    $(function () {
+        $(".btn-recycle").on("click", function () {
+            var $btn = $(this);
+            if ($btn.data("confirm") && !confirm($btn.data("confirm"))) {
+                return;
+            }
+            $btn.button("loading");
+            $.post($btn.data("url"), { "recycle_id" : $btn.data("id") }, function (res) {
+                if(res.errcode === 0){
+                    $btn.closest("tr").remove();
+                }else{
+                    $btn.button("reset");
+                    showError(res.message);
+                }
+            }, "json");
+        });
+    });
+</script>
+</body>
+</html>
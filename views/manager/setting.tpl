@@ -98,6 +98,77 @@
                                 </label>
                             </div>
                         </div>
+                        <div class="form-group">
+                            <label>{{i18n .Lang "mgr.require_admin_two_factor"}}</label>
+                            <div class="radio">
+                                <label class="radio-inline">
+                                    <input type="radio" {{if eq .REQUIRE_ADMIN_TWO_FACTOR "true"}}checked{{end}} name="REQUIRE_ADMIN_TWO_FACTOR" value="true">{{i18n .Lang "mgr.enable"}}<span class="text"></span>
+                                </label>
+                                <label class="radio-inline">
+                                    <input type="radio" {{if ne .REQUIRE_ADMIN_TWO_FACTOR "true"}}checked{{end}} name="REQUIRE_ADMIN_TWO_FACTOR" value="false">{{i18n .Lang "mgr.disable"}}<span class="text"></span>
+                                </label>
+                            </div>
+                        </div>
+                        <div class="form-group">
+                            <label>{{i18n .Lang "mgr.password_min_length"}}</label>
+                            <input type="number" class="form-control" name="PASSWORD_MIN_LENGTH" min="6" max="50" value="{{.PASSWORD_MIN_LENGTH}}">
+                            <p class="text">{{i18n .Lang "mgr.password_min_length_tips"}}</p>
+                        </div>
+                        <div class="form-group">
+                            <label>{{i18n .Lang "mgr.password_complexity"}}</label>
+                            <input type="number" class="form-control" name="PASSWORD_COMPLEXITY" min="1" max="4" value="{{.PASSWORD_COMPLEXITY}}">
+                            <p class="text">{{i18n .Lang "mgr.password_complexity_tips"}}</p>
+                        </div>
+                        <div class="form-group">
+                            <label>{{i18n .Lang "mgr.password_expire_days"}}</label>
+                            <input type="number" class="form-control" name="PASSWORD_EXPIRE_DAYS" min="0" value="{{.PASSWORD_EXPIRE_DAYS}}">
+                            <p class="text">{{i18n .Lang "mgr.password_expire_days_tips"}}</p>
+                        </div>
+                        <div class="form-group">
+                            <label>{{i18n .Lang "mgr.login_lockout_threshold"}}</label>
+                            <input type="number" class="form-control" name="LOGIN_LOCKOUT_THRESHOLD" min="0" value="{{.LOGIN_LOCKOUT_THRESHOLD}}">
+                            <p class="text">{{i18n .Lang "mgr.login_lockout_threshold_tips"}}</p>
+                        </div>
+                        <div class="form-group">
+                            <label>{{i18n .Lang "mgr.login_lockout_minutes"}}</label>
+                            <input type="number" class="form-control" name="LOGIN_LOCKOUT_MINUTES" min="1" value="{{.LOGIN_LOCKOUT_MINUTES}}">
+                            <p class="text">{{i18n .Lang "mgr.login_lockout_minutes_tips"}}</p>
+                        </div>
+                        <div class="form-group">
+                            <label>{{i18n .Lang "mgr.audit_log_retention_days"}}</label>
+                            <input type="number" class="form-control" name="AUDIT_LOG_RETENTION_DAYS" min="0" value="{{.AUDIT_LOG_RETENTION_DAYS}}">
+                            <p class="text">{{i18n .Lang "mgr.audit_log_retention_days_tips"}}</p>
+                        </div>
+                        <div class="form-group">
+                            <label>{{i18n .Lang "mgr.recycle_retention_days"}}</label>
+                            <input type="number" class="form-control" name="RECYCLE_BIN_RETENTION_DAYS" min="0" value="{{.RECYCLE_BIN_RETENTION_DAYS}}">
+                            <p class="text">{{i18n .Lang "mgr.recycle_retention_days_tips"}}</p>
+                        </div>
+                        <div class="form-group">
+                            <label>{{i18n .Lang "mgr.link_check_interval"}}</label>
+                            <input type="number" class="form-control" name="LINK_CHECK_INTERVAL_HOURS" min="0" value="{{.LINK_CHECK_INTERVAL_HOURS}}">
+                            <p class="text">{{i18n .Lang "mgr.link_check_interval_tips"}}</p>
+                        </div>
+                        <div class="form-group">
+                            <label>{{i18n .Lang "mgr.link_check_allowed_hosts"}}</label>
+                            <textarea class="form-control" rows="3" name="LINK_CHECK_ALLOWED_HOSTS">{{.LINK_CHECK_ALLOWED_HOSTS}}</textarea>
+                            <p class="text">{{i18n .Lang "mgr.link_check_allowed_hosts_tips"}}</p>
+                        </div>
+                        <div class="form-group">
+                            <label>{{i18n .Lang "mgr.stale_remind_interval_days"}}</label>
+                            <input type="number" min="0" class="form-control" name="STALE_REMIND_INTERVAL_DAYS" value="{{.STALE_REMIND_INTERVAL_DAYS}}">
+                            <p class="text">{{i18n .Lang "mgr.stale_remind_interval_days_tips"}}</p>
+                        </div>
+                        <div class="form-group">
+                            <label>{{i18n .Lang "mgr.comment_spam_keywords"}}</label>
+                            <textarea class="form-control" rows="3" name="COMMENT_SPAM_KEYWORDS">{{.COMMENT_SPAM_KEYWORDS}}</textarea>
+                            <p class="text">{{i18n .Lang "mgr.comment_spam_keywords_tips"}}</p>
+                        </div>
+                        <div class="form-group">
+                            <label>{{i18n .Lang "mgr.comment_spam_max_links"}}</label>
+                            <input type="number" min="0" class="form-control" name="COMMENT_SPAM_MAX_LINKS" value="{{.COMMENT_SPAM_MAX_LINKS}}">
+                            <p class="text">{{i18n .Lang "mgr.comment_spam_max_links_tips"}}</p>
+                        </div>
 
                         <div class="form-group">
                             <button type="submit" id="btnSaveBookInfo" class="btn btn-success" data-loading-text="{{i18n .Lang "message.processing"}}">{{i18n .Lang "common.save"}}</button>
@@ -106,6 +177,16 @@
                         </form>
 
                     <div class="clearfix"></div>
+                    <hr>
+                    <form method="post" id="testMailForm" class="form-inline" action="{{urlfor "ManagerController.SendTestMail"}}">
+                        <label>{{i18n .Lang "mgr.mail_test"}}</label>
+                        <div class="form-group">
+                            <input type="email" class="form-control" name="email" value="{{.Member.Email}}" placeholder="{{i18n .Lang "common.email"}}">
+                        </div>
+                        <button type="submit" id="btnSendTestMail" class="btn btn-default" data-loading-text="{{i18n .Lang "message.processing"}}">{{i18n .Lang "mgr.mail_test_send"}}</button>
+                        <a href="{{urlfor "ManagerController.MailTemplates"}}" class="btn btn-link">{{i18n .Lang "mgr.mail_templates"}}</a>
+                        <p class="text">{{i18n .Lang "mgr.mail_test_tips"}} <span id="test-mail-message" class="error-message"></span></p>
+                    </form>
 
                 </div>
             </div>
@@ -139,6 +220,18 @@
                 $("#btnSaveBookInfo").button("reset");
             }
         });
+        $("#testMailForm").ajaxForm({
+            beforeSubmit : function () {
+                $("#btnSendTestMail").button("loading");
+            },success : function (res) {
+                if(res.errcode === 0) {
+                    showSuccess(res.message, "#test-mail-message");
+                }else{
+                    showError(res.message, "#test-mail-message");
+                }
+                $("#btnSendTestMail").button("reset");
+            }
+        });
     });
 </script>
 </body>
@@ -0,0 +1,108 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n .Lang "uc.user_center"}} - Powered by MinDoc</title>
+
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet">
+
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+    <!-- HTML5 shim and Respond.js for IE8 support of HTML5 elements and media queries -->
+    <!-- WARNING: Respond.js doesn't work if you view the page via file:// -->
+    <!--[if lt IE 9]>
+    <script src="/static/html5shiv/3.7.3/html5shiv.min.js"></script>
+    <script src="/static/respond.js/1.4.2/respond.min.js"></script>
+    <![endif]-->
+</head>
+<body>
+<div class="manual-reader">
+    {{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+            <div class="page-left">
+                <ul class="menu">
+                    <li><a href="{{urlfor "SettingController.Index"}}" class="item"><i class="fa fa-sitemap" aria-hidden="true"></i> {{i18n .Lang "uc.base_info"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.Password"}}" class="item"><i class="fa fa-user" aria-hidden="true"></i> {{i18n .Lang "uc.change_pwd"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.TwoFactor"}}" class="item"><i class="fa fa-shield" aria-hidden="true"></i> {{i18n .Lang "uc.two_factor"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.LoginHistory"}}" class="item"><i class="fa fa-history" aria-hidden="true"></i> {{i18n .Lang "uc.login_history"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.Sessions"}}" class="item"><i class="fa fa-desktop" aria-hidden="true"></i> {{i18n .Lang "uc.sessions"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.StaleDocuments"}}" class="item"><i class="fa fa-clock-o" aria-hidden="true"></i> {{i18n .Lang "uc.stale_documents"}}</a> </li>
+                    <li class="active"><a href="{{urlfor "NotificationController.Index"}}" class="item"><i class="fa fa-bell-o" aria-hidden="true"></i> {{i18n .Lang "uc.notifications"}}</a> </li>
+                    <li><a href="{{urlfor "SubscriptionController.Index"}}" class="item"><i class="fa fa-rss" aria-hidden="true"></i> {{i18n .Lang "uc.subscriptions"}}</a> </li>
+                </ul>
+            </div>
+            <div class="page-right">
+                <div class="m-box">
+                    <div class="box-head">
+                        <strong class="box-title">{{i18n .Lang "uc.notifications"}}</strong>
+                        <button type="button" class="btn btn-default btn-sm pull-right" id="btnReadAll" data-loading-text="{{i18n .Lang "message.processing"}}">{{i18n .Lang "uc.notification_read_all"}}</button>
+                    </div>
+                </div>
+                <div class="box-body">
+                    <ul class="nav nav-tabs" style="margin-bottom: 15px;">
+                        <li{{if not .Unread}} class="active"{{end}}><a href="{{urlfor "NotificationController.Index"}}">{{i18n .Lang "uc.notification_all"}}</a></li>
+                        <li{{if .Unread}} class="active"{{end}}><a href="{{urlfor "NotificationController.Index"}}?unread=true">{{i18n .Lang "uc.notification_unread"}}{{if .UnreadNotifications}} <span class="badge">{{.UnreadNotifications}}</span>{{end}}</a></li>
+                    </ul>
+                    <p><span id="form-error-message" class="error-message"></span></p>
+                    <div class="list-group notification-list">
+                        {{range $index,$item := .Lists}}
+                        <div class="list-group-item{{if eq $item.IsRead 0}} unread{{end}}" data-id="{{$item.NotificationId}}">
+                            {{if eq $item.IsRead 0}}<a href="javascript:;" class="pull-right btn-read" title="{{i18n $.Lang "uc.notification_mark_read"}}"><i class="fa fa-check" aria-hidden="true"></i></a>{{end}}
+                            <a href="{{urlfor "NotificationController.Open" ":id" $item.NotificationId}}" class="notification-message">{{$item.Message}}</a>
+                            {{if and $item.Content (eq $item.Type "mention" "reply" "review_comment")}}<p class="notification-content">{{$item.Content}}</p>{{end}}
+                            <p class="notification-content">{{date $item.CreateTime "Y-m-d H:i:s"}}</p>
+                        </div>
+                        {{else}}
+                        <div class="list-group-item text-center">{{i18n .Lang "message.no_data"}}</div>
+                        {{end}}
+                    </div>
+                    <nav class="pagination-container">
+                        {{.PageHtml}}
+                    </nav>
+                </div>
+            </div>
+        </div>
+    </div>
+    {{template "widgets/footer.tpl" .}}
+</div>
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}" type="text/javascript"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}" type="text/javascript"></script>
+<script src="{{cdnjs "/static/js/main.js"}}" type="text/javascript"></script>
+<script type="text/javascript">
+    $(function () {
+        function updateUnread(count) {
+            $(".notification-bell .badge").text(count).toggle(count > 0);
+        }
+        $(".btn-read").on("click", function () {
+            var $item = $(this).closest(".list-group-item");
+            $.post({{urlfor "NotificationController.Read"}}, { "id" : $item.data("id") }, function (res) {
+                if(res.errcode === 0){
+                    $item.removeClass("unread").find(".btn-read").remove();
+                    updateUnread(res.data.unread);
+                }else{
+                    showError(res.message);
+                }
+            }, "json");
+        });
+        $("#btnReadAll").on("click", function () {
+            var $btn = $(this);
+            $btn.button("loading");
+            $.post({{urlfor "NotificationController.Read"}}, { "all" : true }, function (res) {
+                $btn.button("reset");
+                if(res.errcode === 0){
+                    $(".notification-list .unread").removeClass("unread").find(".btn-read").remove();
+                    updateUnread(res.data.unread);
+                }else{
+                    showError(res.message);
+                }
+            }, "json");
+        });
+    });
+</script>
+</body>
+</html>
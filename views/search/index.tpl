@@ -20,6 +20,19 @@
     <div class="container manual-body">
         <div class="search-head">
             <strong class="search-title">{{i18n .Lang "search.search_title" .Keyword}}</strong>
+            {{if .Fields}}
+            <form class="form-inline search-filter" method="get" action="{{urlfor "SearchController.Index"}}">
+                <input type="hidden" name="keyword" value="{{.Keyword}}">
+                <select name="field" class="form-control input-sm">
+                    <option value="">{{i18n .Lang "search.field_any"}}</option>
+                    {{range $index,$item := .Fields}}
+                    <option value="{{$item.FieldName}}"{{if eq $item.FieldName $.Field}} selected{{end}}>{{$item.FieldTitle}}</option>
+                    {{end}}
+                </select>
+                <input type="text" name="value" class="form-control input-sm" value="{{.FieldValue}}" placeholder="{{i18n .Lang "search.field_value"}}">
+                <button type="submit" class="btn btn-default btn-sm">{{i18n .Lang "search.filter"}}</button>
+            </form>
+            {{end}}
         </div>
         <div class="row">
             <div class="manual-list">
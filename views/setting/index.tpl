@@ -31,6 +31,12 @@
                     <li class="active"><a href="{{urlfor "SettingController.Index"}}" class="item"><i class="fa fa-sitemap" aria-hidden="true"></i> {{i18n .Lang "uc.base_info"}}</a> </li>
                     {{if ne .Member.AuthMethod "ldap"}}
                     <li><a href="{{urlfor "SettingController.Password"}}" class="item"><i class="fa fa-user" aria-hidden="true"></i> {{i18n .Lang "uc.change_pwd"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.TwoFactor"}}" class="item"><i class="fa fa-shield" aria-hidden="true"></i> {{i18n .Lang "uc.two_factor"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.LoginHistory"}}" class="item"><i class="fa fa-history" aria-hidden="true"></i> {{i18n .Lang "uc.login_history"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.Sessions"}}" class="item"><i class="fa fa-desktop" aria-hidden="true"></i> {{i18n .Lang "uc.sessions"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.StaleDocuments"}}" class="item"><i class="fa fa-clock-o" aria-hidden="true"></i> {{i18n .Lang "uc.stale_documents"}}</a> </li>
+                    <li><a href="{{urlfor "NotificationController.Index"}}" class="item"><i class="fa fa-bell-o" aria-hidden="true"></i> {{i18n .Lang "uc.notifications"}}</a> </li>
+                    <li><a href="{{urlfor "SubscriptionController.Index"}}" class="item"><i class="fa fa-rss" aria-hidden="true"></i> {{i18n .Lang "uc.subscriptions"}}</a> </li>
                     {{end}}
                 </ul>
             </div>
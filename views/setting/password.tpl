@@ -28,6 +28,12 @@
                 <ul class="menu">
                     <li><a href="{{urlfor "SettingController.Index"}}" class="item"><i class="fa fa-sitemap" aria-hidden="true"></i> {{i18n .Lang "uc.base_info"}}</a> </li>
                     <li class="active"><a href="{{urlfor "SettingController.Password"}}" class="item"><i class="fa fa-user" aria-hidden="true"></i> {{i18n .Lang "uc.change_pwd"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.TwoFactor"}}" class="item"><i class="fa fa-shield" aria-hidden="true"></i> {{i18n .Lang "uc.two_factor"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.LoginHistory"}}" class="item"><i class="fa fa-history" aria-hidden="true"></i> {{i18n .Lang "uc.login_history"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.Sessions"}}" class="item"><i class="fa fa-desktop" aria-hidden="true"></i> {{i18n .Lang "uc.sessions"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.StaleDocuments"}}" class="item"><i class="fa fa-clock-o" aria-hidden="true"></i> {{i18n .Lang "uc.stale_documents"}}</a> </li>
+                    <li><a href="{{urlfor "NotificationController.Index"}}" class="item"><i class="fa fa-bell-o" aria-hidden="true"></i> {{i18n .Lang "uc.notifications"}}</a> </li>
+                    <li><a href="{{urlfor "SubscriptionController.Index"}}" class="item"><i class="fa fa-rss" aria-hidden="true"></i> {{i18n .Lang "uc.subscriptions"}}</a> </li>
                 </ul>
             </div>
             <div class="page-right">
@@ -37,6 +43,9 @@
                     </div>
                 </div>
                 <div class="box-body" style="width: 300px;">
+                    {{if .PasswordExpired}}
+                    <p class="text-danger"><i class="fa fa-exclamation-circle"></i> {{i18n .Lang "message.password_expired"}}</p>
+                    {{end}}
                     <form role="form" method="post" id="securityForm">
                         <div class="form-group">
                             <label for="password1">{{i18n .Lang "uc.origin_pwd"}}</label>
@@ -45,6 +54,7 @@
                         <div class="form-group">
                             <label for="password2">{{i18n .Lang "uc.new_pwd"}}</label>
                             <input type="password" class="form-control" name="password2" id="password2" max="50" placeholder="{{i18n .Lang "uc.new_pwd"}}">
+                            <p style="color: #999;font-size: 12px;">{{.PasswordPolicyTips}}</p>
                         </div>
                         <div class="form-group">
                             <label for="password3">{{i18n .Lang "uc.confirm_pwd"}}</label>
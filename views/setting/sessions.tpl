@@ -0,0 +1,117 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n .Lang "uc.user_center"}} - Powered by MinDoc</title>
+
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet">
+
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+    <!-- HTML5 shim and Respond.js for IE8 support of HTML5 elements and media queries -->
+    <!-- WARNING: Respond.js doesn't work if you view the page via file:// -->
+    <!--[if lt IE 9]>
+    <script src="/static/html5shiv/3.7.3/html5shiv.min.js"></script>
+    <script src="/static/respond.js/1.4.2/respond.min.js"></script>
+    <![endif]-->
+</head>
+<body>
+<div class="manual-reader">
+    {{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+            <div class="page-left">
+                <ul class="menu">
+                    <li><a href="{{urlfor "SettingController.Index"}}" class="item"><i class="fa fa-sitemap" aria-hidden="true"></i> {{i18n .Lang "uc.base_info"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.Password"}}" class="item"><i class="fa fa-user" aria-hidden="true"></i> {{i18n .Lang "uc.change_pwd"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.TwoFactor"}}" class="item"><i class="fa fa-shield" aria-hidden="true"></i> {{i18n .Lang "uc.two_factor"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.LoginHistory"}}" class="item"><i class="fa fa-history" aria-hidden="true"></i> {{i18n .Lang "uc.login_history"}}</a> </li>
+                    <li class="active"><a href="{{urlfor "SettingController.Sessions"}}" class="item"><i class="fa fa-desktop" aria-hidden="true"></i> {{i18n .Lang "uc.sessions"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.StaleDocuments"}}" class="item"><i class="fa fa-clock-o" aria-hidden="true"></i> {{i18n .Lang "uc.stale_documents"}}</a> </li>
+                    <li><a href="{{urlfor "NotificationController.Index"}}" class="item"><i class="fa fa-bell-o" aria-hidden="true"></i> {{i18n .Lang "uc.notifications"}}</a> </li>
+                    <li><a href="{{urlfor "SubscriptionController.Index"}}" class="item"><i class="fa fa-rss" aria-hidden="true"></i> {{i18n .Lang "uc.subscriptions"}}</a> </li>
+                </ul>
+            </div>
+            <div class="page-right">
+                <div class="m-box">
+                    <div class="box-head">
+                        <strong class="box-title">{{i18n .Lang "uc.sessions"}}</strong>
+                        <button type="button" class="btn btn-danger btn-sm pull-right" id="btnDeleteOthers" data-url="{{urlfor "SettingController.DeleteOtherSessions"}}" data-loading-text="{{i18n .Lang "message.processing"}}">{{i18n .Lang "uc.logout_other_sessions"}}</button>
+                    </div>
+                </div>
+                <div class="box-body">
+                    <p><span id="form-error-message" class="error-message"></span></p>
+                    <table class="table">
+                        <thead>
+                        <tr>
+                            <th>{{i18n .Lang "uc.user_agent"}}</th>
+                            <th>{{i18n .Lang "uc.ip_address"}}</th>
+                            <th>{{i18n .Lang "uc.login_time"}}</th>
+                            <th>{{i18n .Lang "uc.last_seen_time"}}</th>
+                            <th>{{i18n .Lang "common.operate"}}</th>
+                        </tr>
+                        </thead>
+                        <tbody>
+                        {{range $index,$item := .Lists}}
+                        <tr>
+                            <td style="word-break: break-all;">{{$item.UserAgent}}</td>
+                            <td>{{$item.IPAddress}}</td>
+                            <td>{{date $item.CreateTime "Y-m-d H:i:s"}}</td>
+                            <td>{{date $item.LastSeenTime "Y-m-d H:i:s"}}</td>
+                            <td>
+                                {{if eq $item.Token $.CurrentToken}}
+                                <span class="text-success">{{i18n $.Lang "uc.current_session"}}</span>
+                                {{else}}
+                                <button type="button" class="btn btn-default btn-sm btn-delete-session" data-id="{{$item.SessionId}}" data-loading-text="{{i18n $.Lang "message.processing"}}">{{i18n $.Lang "uc.logout_session"}}</button>
+                                {{end}}
+                            </td>
+                        </tr>
+                        {{else}}
+                        <tr><td class="text-center" colspan="5">{{i18n .Lang "message.no_data"}}</td></tr>
+                        {{end}}
+                        </tbody>
+                    </table>
+                </div>
+            </div>
+        </div>
+    </div>
+    {{template "widgets/footer.tpl" .}}
+</div>
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}" type="text/javascript"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}" type="text/javascript"></script>
+<script src="{{cdnjs "/static/js/main.js"}}" type="text/javascript"></script>
+<script type="text/javascript">
+    $(function () {
+        $(".btn-delete-session").on("click", function () {
+            var $btn = $(this);
+            $btn.button("loading");
+            $.post({{urlfor "SettingController.DeleteSession"}}, { "session_id" : $btn.data("id") }, function (res) {
+                if(res.errcode === 0){
+                    $btn.closest("tr").remove();
+                }else{
+                    $btn.button("reset");
+                    showError(res.message);
+                }
+            }, "json");
+        });
+        $("#btnDeleteOthers").on("click", function () {
+            var $btn = $(this);
+            $btn.button("loading");
+            $.post($btn.data("url"), function (res) {
+                $btn.button("reset");
+                if(res.errcode === 0){
+                    $(".btn-delete-session").closest("tr").remove();
+                    showSuccess({{i18n .Lang "message.success"}});
+                }else{
+                    showError(res.message);
+                }
+            }, "json");
+        });
+    });
+</script>
+</body>
+</html>
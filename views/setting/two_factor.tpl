@@ -0,0 +1,158 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n .Lang "uc.user_center"}} - Powered by MinDoc</title>
+
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet">
+
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+    <style type="text/css">
+        .recovery-codes{display: none;margin-top: 15px;}
+        .recovery-codes pre{font-size: 14px;line-height: 1.8;}
+    </style>
+    <!-- HTML5 shim and Respond.js for IE8 support of HTML5 elements and media queries -->
+    <!-- WARNING: Respond.js doesn't work if you view the page via file:// -->
+    <!--[if lt IE 9]>
+    <script src="/static/html5shiv/3.7.3/html5shiv.min.js"></script>
+    <script src="/static/respond.js/1.4.2/respond.min.js"></script>
+    <![endif]-->
+</head>
+<body>
+<div class="manual-reader">
+    {{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+            <div class="page-left">
+                <ul class="menu">
+                    <li><a href="{{urlfor "SettingController.Index"}}" class="item"><i class="fa fa-sitemap" aria-hidden="true"></i> {{i18n .Lang "uc.base_info"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.Password"}}" class="item"><i class="fa fa-user" aria-hidden="true"></i> {{i18n .Lang "uc.change_pwd"}}</a> </li>
+                    <li class="active"><a href="{{urlfor "SettingController.TwoFactor"}}" class="item"><i class="fa fa-shield" aria-hidden="true"></i> {{i18n .Lang "uc.two_factor"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.LoginHistory"}}" class="item"><i class="fa fa-history" aria-hidden="true"></i> {{i18n .Lang "uc.login_history"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.Sessions"}}" class="item"><i class="fa fa-desktop" aria-hidden="true"></i> {{i18n .Lang "uc.sessions"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.StaleDocuments"}}" class="item"><i class="fa fa-clock-o" aria-hidden="true"></i> {{i18n .Lang "uc.stale_documents"}}</a> </li>
+                    <li><a href="{{urlfor "NotificationController.Index"}}" class="item"><i class="fa fa-bell-o" aria-hidden="true"></i> {{i18n .Lang "uc.notifications"}}</a> </li>
+                    <li><a href="{{urlfor "SubscriptionController.Index"}}" class="item"><i class="fa fa-rss" aria-hidden="true"></i> {{i18n .Lang "uc.subscriptions"}}</a> </li>
+                </ul>
+            </div>
+            <div class="page-right">
+                <div class="m-box">
+                    <div class="box-head">
+                        <strong class="box-title">{{i18n .Lang "uc.two_factor"}}</strong>
+                    </div>
+                </div>
+                <div class="box-body" style="width: 400px;">
+                    {{if .TwoFactorEnabled}}
+                    <p class="text-success"><i class="fa fa-check-circle"></i> {{i18n .Lang "uc.two_factor_status_enabled"}}</p>
+                    <p style="color: #999;font-size: 12px;">{{i18n .Lang "uc.recovery_code_count" .RecoveryCodeCount}}</p>
+                    <form role="form" method="post" id="twoFactorForm">
+                        <div class="form-group">
+                            <label for="code">{{i18n .Lang "uc.two_factor_code"}}</label>
+                            <input type="text" class="form-control" name="code" id="code" maxlength="20" placeholder="{{i18n .Lang "uc.two_factor_code_placeholder"}}" autocomplete="off">
+                        </div>
+                        <div class="form-group">
+                            <span id="form-error-message" class="error-message"></span>
+                        </div>
+                        <div class="form-group">
+                            <button type="button" class="btn btn-success" id="btnRecoveryCodes" data-url="{{urlfor "SettingController.TwoFactorRecoveryCodes"}}" data-loading-text="{{i18n .Lang "message.processing"}}">{{i18n .Lang "uc.regenerate_recovery_codes"}}</button>
+                            {{if not .TwoFactorRequired}}
+                            <button type="button" class="btn btn-danger" id="btnDisable" data-url="{{urlfor "SettingController.TwoFactorDisable"}}" data-loading-text="{{i18n .Lang "message.processing"}}">{{i18n .Lang "uc.two_factor_disable"}}</button>
+                            {{end}}
+                        </div>
+                    </form>
+                    {{else}}
+                    {{if .TwoFactorRequired}}
+                    <p class="text-danger"><i class="fa fa-exclamation-circle"></i> {{i18n .Lang "uc.two_factor_required_tips"}}</p>
+                    {{end}}
+                    <p>{{i18n .Lang "uc.two_factor_status_disabled"}}</p>
+                    <p style="color: #999;font-size: 12px;">{{i18n .Lang "uc.two_factor_scan_tips"}}</p>
+                    <form role="form" method="post" id="twoFactorForm" action="{{urlfor "SettingController.TwoFactorEnable"}}">
+                        <div class="form-group">
+                            <img src="{{urlfor "SettingController.TwoFactorQrCode"}}" alt="QR Code" style="width: 200px;height: 200px;">
+                        </div>
+                        <div class="form-group">
+                            <label>{{i18n .Lang "uc.two_factor_secret"}}</label>
+                            <input type="text" class="form-control" value="{{.Secret}}" readonly>
+                        </div>
+                        <div class="form-group">
+                            <label for="code">{{i18n .Lang "uc.two_factor_code"}}</label>
+                            <input type="text" class="form-control" name="code" id="code" maxlength="6" placeholder="{{i18n .Lang "uc.two_factor_code"}}" autocomplete="off">
+                        </div>
+                        <div class="form-group">
+                            <span id="form-error-message" class="error-message"></span>
+                        </div>
+                        <div class="form-group">
+                            <button type="submit" class="btn btn-success" id="btnEnable" data-loading-text="{{i18n .Lang "message.processing"}}">{{i18n .Lang "uc.two_factor_enable"}}</button>
+                        </div>
+                    </form>
+                    {{end}}
+                    <div class="recovery-codes" id="recoveryCodes">
+                        <strong>{{i18n .Lang "uc.recovery_codes"}}</strong>
+                        <p class="text-danger" style="font-size: 12px;">{{i18n .Lang "uc.recovery_codes_tips"}}</p>
+                        <pre></pre>
+                    </div>
+                </div>
+            </div>
+        </div>
+    </div>
+    {{template "widgets/footer.tpl" .}}
+</div>
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}" type="text/javascript"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}" type="text/javascript"></script>
+<script src="{{cdnjs "/static/js/jquery.form.js"}}" type="text/javascript"></script>
+<script src="{{cdnjs "/static/js/main.js"}}" type="text/javascript"></script>
+<script type="text/javascript">
+    $(function () {
+        function showRecoveryCodes(codes) {
+            $("#recoveryCodes").show().find("pre").text(codes.join("\n"));
+        }
+
+        $("#twoFactorForm").ajaxForm({
+            beforeSubmit : function () {
+                if(!$.trim($("#code").val())){
+                    return showError({{i18n .Lang "message.two_factor_code_empty"}});
+                }
+                $("#btnEnable").button("loading");
+            },
+            success : function (res) {
+                $("#btnEnable").button("reset");
+                if(res.errcode === 0){
+                    showSuccess({{i18n .Lang "uc.two_factor_status_enabled"}});
+                    $("#twoFactorForm").find("img,input,button").closest(".form-group").hide();
+                    showRecoveryCodes(res.data);
+                }else{
+                    showError(res.message);
+                }
+            }
+        });
+
+        $("#btnRecoveryCodes,#btnDisable").on("click", function () {
+            var $btn = $(this);
+            var code = $.trim($("#code").val());
+            if(!code){
+                return showError({{i18n .Lang "message.two_factor_code_empty"}});
+            }
+            $btn.button("loading");
+            $.post($btn.data("url"), { "code" : code }, function (res) {
+                $btn.button("reset");
+                $("#code").val('');
+                if(res.errcode !== 0){
+                    return showError(res.message);
+                }
+                if($btn.attr("id") === "btnDisable"){
+                    window.location.reload();
+                }else{
+                    showSuccess({{i18n .Lang "message.success"}});
+                    showRecoveryCodes(res.data);
+                }
+            }, "json");
+        });
+    });
+</script>
+</body>
+</html>
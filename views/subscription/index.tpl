@@ -0,0 +1,120 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n .Lang "uc.user_center"}} - Powered by MinDoc</title>
+
+    <!-- Bootstrap -->
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet">
+
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+    <!-- HTML5 shim and Respond.js for IE8 support of HTML5 elements and media queries -->
+    <!-- WARNING: Respond.js doesn't work if you view the page via file:// -->
+    <!--[if lt IE 9]>
+    <script src="/static/html5shiv/3.7.3/html5shiv.min.js"></script>
+    <script src="/static/respond.js/1.4.2/respond.min.js"></script>
+    <![endif]-->
+</head>
+<body>
+<div class="manual-reader">
+    {{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+            <div class="page-left">
+                <ul class="menu">
+                    <li><a href="{{urlfor "SettingController.Index"}}" class="item"><i class="fa fa-sitemap" aria-hidden="true"></i> {{i18n .Lang "uc.base_info"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.Password"}}" class="item"><i class="fa fa-user" aria-hidden="true"></i> {{i18n .Lang "uc.change_pwd"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.TwoFactor"}}" class="item"><i class="fa fa-shield" aria-hidden="true"></i> {{i18n .Lang "uc.two_factor"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.LoginHistory"}}" class="item"><i class="fa fa-history" aria-hidden="true"></i> {{i18n .Lang "uc.login_history"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.Sessions"}}" class="item"><i class="fa fa-desktop" aria-hidden="true"></i> {{i18n .Lang "uc.sessions"}}</a> </li>
+                    <li><a href="{{urlfor "SettingController.StaleDocuments"}}" class="item"><i class="fa fa-clock-o" aria-hidden="true"></i> {{i18n .Lang "uc.stale_documents"}}</a> </li>
+                    <li><a href="{{urlfor "NotificationController.Index"}}" class="item"><i class="fa fa-bell-o" aria-hidden="true"></i> {{i18n .Lang "uc.notifications"}}</a> </li>
+                    <li class="active"><a href="{{urlfor "SubscriptionController.Index"}}" class="item"><i class="fa fa-rss" aria-hidden="true"></i> {{i18n .Lang "uc.subscriptions"}}</a> </li>
+                </ul>
+            </div>
+            <div class="page-right">
+                <div class="m-box">
+                    <div class="box-head">
+                        <strong class="box-title">{{i18n .Lang "uc.subscriptions"}}</strong>
+                    </div>
+                </div>
+                <div class="box-body">
+                    <p class="text-muted">{{i18n .Lang "uc.subscriptions_tips"}}</p>
+                    <p><span id="form-error-message" class="error-message"></span></p>
+                    <table class="table">
+                        <thead>
+                        <tr>
+                            <th>{{i18n .Lang "uc.subscription_target"}}</th>
+                            <th width="160">{{i18n .Lang "uc.subscription_frequency"}}</th>
+                            <th width="160">{{i18n .Lang "uc.subscription_time"}}</th>
+                            <th width="80">{{i18n .Lang "common.operate"}}</th>
+                        </tr>
+                        </thead>
+                        <tbody>
+                        {{range $index,$item := .Lists}}
+                        <tr data-id="{{$item.SubscriptionId}}" data-identify="{{$item.BookIdentify}}" data-doc="{{$item.DocumentId}}">
+                            <td>
+                                {{if gt $item.DocumentId 0}}
+                                <a href="{{urlfor "DocumentController.Read" ":key" $item.BookIdentify ":id" $item.DocumentId}}" target="_blank">{{$item.DocumentName}}</a>
+                                <span class="text-muted"> - {{$item.BookName}}</span>
+                                {{else}}
+                                <a href="{{urlfor "DocumentController.Index" ":key" $item.BookIdentify}}" target="_blank">{{$item.BookName}}</a>
+                                <span class="text-muted"> - {{i18n $.Lang "uc.subscription_book"}}</span>
+                                {{end}}
+                            </td>
+                            <td>
+                                <select class="form-control input-sm subscription-frequency">
+                                    <option value="immediate"{{if eq $item.Frequency "immediate"}} selected{{end}}>{{i18n $.Lang "doc.subscribe_immediate"}}</option>
+                                    <option value="daily"{{if eq $item.Frequency "daily"}} selected{{end}}>{{i18n $.Lang "doc.subscribe_daily"}}</option>
+                                    <option value="weekly"{{if eq $item.Frequency "weekly"}} selected{{end}}>{{i18n $.Lang "doc.subscribe_weekly"}}</option>
+                                </select>
+                            </td>
+                            <td>{{date $item.CreateTime "Y-m-d H:i:s"}}</td>
+                            <td><button type="button" class="btn btn-danger btn-sm btn-unsubscribe">{{i18n $.Lang "uc.unsubscribe"}}</button></td>
+                        </tr>
+                        {{else}}
+                        <tr><td colspan="4" class="text-center">{{i18n .Lang "message.no_data"}}</td></tr>
+                        {{end}}
+                        </tbody>
+                    </table>
+                </div>
+            </div>
+        </div>
+    </div>
+    {{template "widgets/footer.tpl" .}}
+</div>
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}" type="text/javascript"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}" type="text/javascript"></script>
+<script src="{{cdnjs "/static/js/main.js"}}" type="text/javascript"></script>
+<script type="text/javascript">
+    $(function () {
+        $(".subscription-frequency").on("change", function () {
+            var $row = $(this).closest("tr");
+            $.post({{urlfor "SubscriptionController.Save"}}, {
+                "identify" : $row.data("identify"),
+                "doc_id" : $row.data("doc"),
+                "frequency" : $(this).val()
+            }, function (res) {
+                if(res.errcode !== 0){
+                    showError(res.message);
+                }
+            }, "json");
+        });
+        $(".btn-unsubscribe").on("click", function () {
+            var $row = $(this).closest("tr");
+            $.post({{urlfor "SubscriptionController.Delete"}}, { "subscription_id" : $row.data("id") }, function (res) {
+                if(res.errcode === 0){
+                    $row.remove();
+                }else{
+                    showError(res.message);
+                }
+            }, "json");
+        });
+    });
+</script>
+</body>
+</html>
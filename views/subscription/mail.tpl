@@ -0,0 +1,46 @@
+<!DOCTYPE html>
+<html>
+<head>
+    <meta http-equiv="Content-Type" content="text/html;charset=utf-8"/>
+    <title>{{i18n .Lang "uc.subscriptions"}} - Powered by MinDoc</title>
+    <style type="text/css">
+        html,body{background-color: transparent;margin:0;padding: 0;}
+        body{font: 14px/1.5 "Microsoft Yahei", "微软雅黑", verdana;word-wrap:break-word;}
+        a{color:#0066CC;}
+    </style>
+</head>
+<body>
+<div>
+    <div class="wrapper" style="margin: 20px auto 0; width: 600px; padding-top:16px; padding-bottom:10px;">
+        <div class="header clearfix">
+            <a class="logo" href="{{.BaseUrl}}" target="_blank"><b>{{.SITE_NAME}}</b></a>
+        </div>
+        <br style="clear:both; height:0">
+        <div class="content" style="background: none repeat scroll 0 0 #FFFFFF; border: 1px solid #E9E9E9; margin: 2px 0 0; padding: 30px;">
+            <p>{{i18n .Lang "message.comment_mail_hello" (or .Member.RealName .Member.Account)}}</p>
+            <p>{{i18n .Lang (printf "message.subscription_%s_body" .Frequency) (len .Changes)}}</p>
+            <table style="width: 100%; border-collapse: collapse; margin-bottom: 10px;">
+                {{range $item := .Changes}}
+                <tr>
+                    <td style="padding: 8px 0; border-bottom: 1px solid #E9E9E9;">
+                        <a href="{{urlfor "DocumentController.Read" ":key" $item.BookIdentify ":id" $item.DocumentId}}" target="_blank"><b>{{$item.DocumentName}}</b></a>
+                        <span style="color:#838383;"> - {{$item.BookName}}</span><br>
+                        <span style="color:#838383;">{{i18n $.Lang "message.subscription_editors" $item.EditorNames}} · {{date $item.ModifyTime "Y-m-d H:i"}}</span>
+                        {{if $item.HasDiff}}<br><span style="color:#3c763d;">+{{$item.Added}}</span> <span style="color:#a94442;">-{{$item.Removed}}</span> <span style="color:#838383;">{{i18n $.Lang "message.subscription_lines"}}</span>{{end}}
+                    </td>
+                </tr>
+                {{end}}
+            </table>
+            <p class="footer" style="border-top: 1px solid #DDDDDD; padding-top:6px; margin-top:25px; color:#838383;">
+                {{i18n .Lang "message.subscription_footer"}}<br>
+                {{range $item := .Subscriptions}}
+                {{i18n $.Lang "message.subscription_unsubscribe" (or $item.DocumentName $item.BookName)}} <a href="{{urlfor "SubscriptionController.Unsubscribe" ":token" $item.Token}}" target="_blank">{{i18n $.Lang "uc.unsubscribe"}}</a><br>
+                {{end}}
+                <br>
+                <a href="{{urlfor "SubscriptionController.Index"}}" target="_blank">{{i18n .Lang "uc.subscriptions"}}</a> · <a href="{{.BaseUrl}}" target="_blank">{{.SITE_NAME}}</a>
+            </p>
+        </div>
+    </div>
+</div>
+</body>
+</html>
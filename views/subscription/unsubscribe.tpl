@@ -0,0 +1,37 @@
+<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+    <meta charset="utf-8">
+    <meta http-equiv="X-UA-Compatible" content="IE=edge">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+
+    <title>{{i18n .Lang "uc.unsubscribe"}} - Powered by MinDoc</title>
+
+    <link href="{{cdncss "/static/bootstrap/css/bootstrap.min.css"}}" rel="stylesheet">
+    <link href="{{cdncss "/static/font-awesome/css/font-awesome.min.css"}}" rel="stylesheet">
+    <link href="{{cdncss "/static/css/main.css" "version"}}" rel="stylesheet">
+</head>
+<body>
+<div class="manual-reader">
+    {{template "widgets/header.tpl" .}}
+    <div class="container manual-body">
+        <div class="row">
+            <div class="col-sm-6 col-sm-offset-3 text-center" style="padding: 60px 0;">
+                <h3>{{i18n .Lang "uc.unsubscribe"}}</h3>
+                <p>{{.Message}}</p>
+                {{if .Confirm}}
+                <form method="post" style="margin: 20px 0;">
+                    {{ .xsrfdata }}
+                    <button type="submit" class="btn btn-danger">{{i18n .Lang "uc.unsubscribe"}}</button>
+                </form>
+                {{end}}
+                <p><a href="{{urlfor "HomeController.Index"}}">{{i18n .Lang "common.home"}}</a>{{if gt .Member.MemberId 0}} · <a href="{{urlfor "SubscriptionController.Index"}}">{{i18n .Lang "uc.subscriptions"}}</a>{{end}}</p>
+            </div>
+        </div>
+    </div>
+    {{template "widgets/footer.tpl" .}}
+</div>
+<script src="{{cdnjs "/static/jquery/1.12.4/jquery.min.js"}}" type="text/javascript"></script>
+<script src="{{cdnjs "/static/bootstrap/js/bootstrap.min.js"}}" type="text/javascript"></script>
+</body>
+</html>
@@ -0,0 +1,42 @@
+<ul class="nav nav-tabs" style="margin-bottom: 15px;">
+    <li{{if and (eq .Approved 0) (not .Orphaned)}} class="active"{{end}}><a href="?approved=0">{{i18n .Lang "blog.comment_pending"}} <span class="badge">{{index .Counts 0}}</span></a></li>
+    <li{{if and (eq .Approved 1) (not .Orphaned)}} class="active"{{end}}><a href="?approved=1">{{i18n .Lang "blog.comment_approved"}} <span class="badge">{{index .Counts 1}}</span></a></li>
+    <li{{if and (eq .Approved 2) (not .Orphaned)}} class="active"{{end}}><a href="?approved=2">{{i18n .Lang "blog.comment_spam"}} <span class="badge">{{index .Counts 2}}</span></a></li>
+    <li{{if .Orphaned}} class="active"{{end}}><a href="?orphaned=true" title="{{i18n .Lang "blog.comment_orphaned_tips"}}">{{i18n .Lang "blog.comment_orphaned"}} <span class="badge">{{.OrphanedCount}}</span></a></li>
+</ul>
+<div class="btn-group" style="margin-bottom: 10px;">
+    {{if and (ne .Approved 1) (not .Orphaned)}}<button type="button" class="btn btn-success btn-sm btn-moderate" data-action="approve">{{i18n .Lang "blog.comment_approve"}}</button>{{end}}
+    {{if and (ne .Approved 2) (not .Orphaned)}}<button type="button" class="btn btn-warning btn-sm btn-moderate" data-action="spam">{{i18n .Lang "blog.comment_mark_spam"}}</button>{{end}}
+    <button type="button" class="btn btn-danger btn-sm btn-moderate" data-action="delete" data-confirm="{{i18n .Lang "blog.comment_delete_confirm"}}">{{i18n .Lang "common.delete"}}</button>
+</div>
+<p><span id="form-error-message" class="error-message"></span></p>
+<table class="table" id="commentModeration" data-url="{{.ModerateUrl}}">
+    <thead>
+    <tr>
+        <th width="30"><input type="checkbox" id="checkAllComment"></th>
+        <th>{{i18n .Lang "blog.comment_author"}}</th>
+        <th>{{i18n .Lang "blog.comment_content"}}</th>
+        <th>{{i18n .Lang "doc.doc_name"}}</th>
+        <th width="160">{{i18n .Lang "blog.comment_date"}}</th>
+    </tr>
+    </thead>
+    <tbody>
+    {{range $index,$item := .Lists}}
+    <tr>
+        <td><input type="checkbox" name="comment_id" value="{{$item.CommentId}}"></td>
+        <td>{{$item.Author}}<br><small class="text-muted">{{$item.IPAddress}}</small></td>
+        <td>{{if $item.AnchorText}}<blockquote class="comment-quote">{{$item.AnchorText}}</blockquote>{{end}}<div class="comment-content">{{$item.Content}}</div></td>
+        <td>
+            {{if $item.BookIdentify}}<a href="{{urlfor "DocumentController.Read" ":key" $item.BookIdentify ":id" $item.DocumentId}}" target="_blank">{{$item.DocumentName}}</a>{{else}}{{$item.DocumentName}}{{end}}
+            {{if not $.Model}}<br><small class="text-muted">{{$item.BookName}}</small>{{end}}
+        </td>
+        <td>{{date $item.CommentDate "Y-m-d H:i:s"}}</td>
+    </tr>
+    {{else}}
+    <tr><td class="text-center" colspan="5">{{i18n .Lang "message.no_data"}}</td></tr>
+    {{end}}
+    </tbody>
+</table>
+<nav class="pagination-container">
+    {{.PageHtml}}
+</nav>
@@ -41,6 +41,9 @@
                             <li>
                                 <a href="{{urlfor "SettingController.Index"}}" title={{i18n .Lang "common.person_center"}}><i class="fa fa-user" aria-hidden="true"></i> {{i18n .Lang "common.person_center"}}</a>
                             </li>
+                            <li>
+                                <a href="{{urlfor "NotificationController.Index"}}" title={{i18n .Lang "common.notifications"}}><i class="fa fa-bell-o" aria-hidden="true"></i> {{i18n .Lang "common.notifications"}}{{if .UnreadNotifications}} <span class="badge">{{.UnreadNotifications}}</span>{{end}}</a>
+                            </li>
                             <li>
                                 <a href="{{urlfor "BookController.Index"}}" title={{i18n .Lang "common.my_project"}}><i class="fa fa-book" aria-hidden="true"></i> {{i18n .Lang "common.my_project"}}</a>
                             </li>
@@ -66,6 +69,12 @@
         <nav class="navbar-collapse hidden-xs hidden-sm" role="navigation">
             <ul class="nav navbar-nav navbar-right">
                 {{if gt .Member.MemberId 0}}
+                <li>
+                    <a href="{{urlfor "NotificationController.Index"}}" class="notification-bell" title="{{i18n .Lang "common.notifications"}}">
+                        <i class="fa fa-bell-o" aria-hidden="true"></i>
+                        {{if .UnreadNotifications}}<span class="badge">{{.UnreadNotifications}}</span>{{end}}
+                    </a>
+                </li>
                 <li>
                     <div class="img user-info" data-toggle="dropdown">
                         <img src="{{cdnimg .Member.Avatar}}" onerror="this.src='{{cdnimg "/static/images/headimgurl.jpg"}}';" class="img-circle userbar-avatar" alt="{{.Member.Account}}">